@@ -1,9 +1,11 @@
 package v1beta1
 
 import (
+	"k8s.io/api/autoscaling/v2beta2"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"path"
 )
 
@@ -14,6 +16,9 @@ const (
 	reloadPath           = "/-/reload"
 	snapshotCreate       = "/snapshot/create"
 	snapshotDelete       = "/snapshot/delete"
+
+	// AdditionalServiceLabel - name of component service, which additional service was built from.
+	AdditionalServiceLabel = "operator.victoriametrics.com/additional-service"
 )
 
 var (
@@ -89,6 +94,91 @@ type EmbeddedPersistentVolumeClaim struct {
 	Status v1.PersistentVolumeClaimStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
 }
 
+// EmbeddedHPA defines HorizontalPodAutoscaler of component.
+// Operator creates autoscaling/v2beta2 HorizontalPodAutoscaler and doesn't change replicas of component while it's set.
+type EmbeddedHPA struct {
+	// MinReplicas lower limit for the number of pods, defaults to 1.
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas upper limit for the number of pods.
+	MaxReplicas int32 `json:"maxReplicas"`
+	// Metrics used to calculate desired replica count,
+	// defaults to 80% average CPU utilization.
+	// +optional
+	Metrics []v2beta2.MetricSpec `json:"metrics,omitempty"`
+	// Behavior configures scaling behavior in both up and down directions.
+	// +optional
+	Behavior *v2beta2.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+}
+
+// EmbeddedPodDisruptionBudgetSpec defines PodDisruptionBudget of component.
+// Only one of MinAvailable and MaxUnavailable must be set.
+type EmbeddedPodDisruptionBudgetSpec struct {
+	// MinAvailable number or percentage of pods, which must be available after eviction.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable number or percentage of pods, which can be unavailable after eviction.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// ServiceSpec defines overrides of Service generated by operator for component.
+type ServiceSpec struct {
+	// EmbeddedObjectMetadata defines labels and annotations of service.
+	// Name is required for additional services and ignored for service of component.
+	// +optional
+	EmbeddedObjectMetadata `json:"metadata,omitempty"`
+	// Spec is merged into generated spec of service, ports are merged by name.
+	// Selector cannot be changed, type and clusterIP cannot be changed for headless services.
+	Spec v1.ServiceSpec `json:"spec"`
+}
+
+// EmbeddedIngress defines Ingress created by operator for web endpoints of component.
+// Operator creates networking.k8s.io/v1beta1 Ingress, it requires kubernetes 1.18-1.21.
+type EmbeddedIngress struct {
+	// Host of ingress rule, ingress matches all hosts if host isn't set.
+	// +optional
+	Host string `json:"host,omitempty"`
+	// TLSSecretName is the name of secret with tls certificate and key for host.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// ClassName is the name of IngressClass, which handles ingress.
+	// +optional
+	ClassName *string `json:"className,omitempty"`
+	// PathPrefix of component at ingress, it must start with /.
+	// It's used as http.pathPrefix of component or routePrefix of VMAlertmanager, if they aren't set explicitly.
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	// Annotations added to ingress, ingress controllers are usually configured with it.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// IngressPath returns http path of component at ingress.
+func (ei *EmbeddedIngress) IngressPath() string {
+	if ei.PathPrefix == "" {
+		return "/"
+	}
+	return ei.PathPrefix
+}
+
+// ExtraArgsWithIngressPrefix returns extraArgs of component with http.pathPrefix set to ingress path prefix,
+// if it isn't set at extraArgs explicitly.
+func ExtraArgsWithIngressPrefix(extraArgs map[string]string, ingress *EmbeddedIngress) map[string]string {
+	if ingress == nil || ingress.PathPrefix == "" {
+		return extraArgs
+	}
+	if _, ok := extraArgs[vmPathPrefixFlagName]; ok {
+		return extraArgs
+	}
+	args := make(map[string]string, len(extraArgs)+1)
+	for arg, value := range extraArgs {
+		args[arg] = value
+	}
+	args[vmPathPrefixFlagName] = ingress.PathPrefix
+	return args
+}
+
 // BasicAuth allow an endpoint to authenticate over basic authentication
 // More info: https://prometheus.io/docs/operating/configuration/#endpoints
 // +k8s:openapi-gen=true
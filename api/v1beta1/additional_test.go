@@ -45,3 +45,44 @@ func Test_buildPathWithPrefixFlag(t *testing.T) {
 		})
 	}
 }
+
+func TestExtraArgsWithIngressPrefix(t *testing.T) {
+	tests := []struct {
+		name      string
+		extraArgs map[string]string
+		ingress   *EmbeddedIngress
+		want      string
+	}{
+		{
+			name: "without ingress",
+			want: healthPath,
+		},
+		{
+			name:    "ingress without prefix",
+			ingress: &EmbeddedIngress{Host: "vmsingle.example.com"},
+			want:    healthPath,
+		},
+		{
+			name:    "prefix from ingress",
+			ingress: &EmbeddedIngress{PathPrefix: "/vmsingle"},
+			want:    "/vmsingle" + healthPath,
+		},
+		{
+			name:      "prefix from extra args",
+			extraArgs: map[string]string{vmPathPrefixFlagName: "/custom"},
+			ingress:   &EmbeddedIngress{PathPrefix: "/vmsingle"},
+			want:      "/custom" + healthPath,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := VMSingle{Spec: VMSingleSpec{ExtraArgs: tt.extraArgs, Ingress: tt.ingress}}
+			if got := cr.HealthPath(); got != tt.want {
+				t.Errorf("HealthPath() = %v, want %v", got, tt.want)
+			}
+			if _, ok := cr.Spec.ExtraArgs[vmPathPrefixFlagName]; ok != (tt.extraArgs != nil) {
+				t.Errorf("extraArgs of spec must not be modified: %v", cr.Spec.ExtraArgs)
+			}
+		})
+	}
+}
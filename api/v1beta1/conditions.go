@@ -0,0 +1,75 @@
+package v1beta1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionAvailable - all desired replicas are available.
+	ConditionAvailable = "Available"
+	// ConditionProgressing - rollout of owned deployment or statefulset is in progress.
+	ConditionProgressing = "Progressing"
+	// ConditionConfigValid - operator successfully generated configuration for application.
+	ConditionConfigValid = "ConfigValid"
+	// ConditionReconcileFailed - last reconcile loop returned error.
+	ConditionReconcileFailed = "ReconcileFailed"
+	// ConditionDegraded - application update failed and was rolled back to the last known good revision.
+	ConditionDegraded = "Degraded"
+)
+
+// Condition describes the state of object at a certain point.
+// +k8s:openapi-gen=true
+type Condition struct {
+	// Type of condition: Available, Progressing, ConfigValid, ReconcileFailed or Degraded.
+	Type string `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a one-word CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human-readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// FindCondition returns condition with given type or nil.
+func FindCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates condition at the given list.
+// LastTransitionTime is changed only if condition status was changed.
+func SetCondition(conditions []Condition, newCondition Condition) []Condition {
+	existing := FindCondition(conditions, newCondition.Type)
+	if existing == nil {
+		if newCondition.LastTransitionTime.IsZero() {
+			newCondition.LastTransitionTime = metav1.Now()
+		}
+		return append(conditions, newCondition)
+	}
+	if existing.Status != newCondition.Status {
+		existing.Status = newCondition.Status
+		existing.LastTransitionTime = newCondition.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.Reason = newCondition.Reason
+	existing.Message = newCondition.Message
+	return conditions
+}
+
+// IsConditionTrue checks if condition with given type has status True.
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	c := FindCondition(conditions, conditionType)
+	return c != nil && c.Status == v1.ConditionTrue
+}
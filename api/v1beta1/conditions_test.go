@@ -0,0 +1,63 @@
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetCondition(t *testing.T) {
+	transitionTime := metav1.NewTime(time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name               string
+		conditions         []Condition
+		newCondition       Condition
+		wantLen            int
+		wantTransitionTime bool
+	}{
+		{
+			name:         "add new condition",
+			newCondition: Condition{Type: ConditionAvailable, Status: v1.ConditionTrue},
+			wantLen:      1,
+		},
+		{
+			name:               "same status keeps transition time",
+			conditions:         []Condition{{Type: ConditionAvailable, Status: v1.ConditionTrue, LastTransitionTime: transitionTime}},
+			newCondition:       Condition{Type: ConditionAvailable, Status: v1.ConditionTrue, Reason: "MinimumReplicasAvailable"},
+			wantLen:            1,
+			wantTransitionTime: true,
+		},
+		{
+			name: "changed status updates transition time",
+			conditions: []Condition{
+				{Type: ConditionProgressing, Status: v1.ConditionFalse},
+				{Type: ConditionAvailable, Status: v1.ConditionTrue, LastTransitionTime: transitionTime},
+			},
+			newCondition: Condition{Type: ConditionAvailable, Status: v1.ConditionFalse},
+			wantLen:      2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SetCondition(tt.conditions, tt.newCondition)
+			if len(got) != tt.wantLen {
+				t.Fatalf("SetCondition() len = %d, want %d", len(got), tt.wantLen)
+			}
+			c := FindCondition(got, tt.newCondition.Type)
+			if c == nil {
+				t.Fatalf("SetCondition() condition %s not found", tt.newCondition.Type)
+			}
+			if c.Status != tt.newCondition.Status || c.Reason != tt.newCondition.Reason {
+				t.Errorf("SetCondition() = %v, want %v", c, tt.newCondition)
+			}
+			if c.LastTransitionTime.IsZero() {
+				t.Errorf("SetCondition() transition time must be set")
+			}
+			if got := c.LastTransitionTime.Equal(&transitionTime); got != tt.wantTransitionTime {
+				t.Errorf("SetCondition() transition time kept = %v, want %v", got, tt.wantTransitionTime)
+			}
+		})
+	}
+}
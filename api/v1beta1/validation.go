@@ -0,0 +1,229 @@
+package v1beta1
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var (
+	webhookLog = logf.Log.WithName("webhook")
+
+	// retentionPeriodRe matches months without suffix or duration with h,d,w,y suffix.
+	retentionPeriodRe = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[hdwy]?$`)
+	// amRetentionRe matches alertmanager data retention format.
+	amRetentionRe = regexp.MustCompile(`^[0-9]+(ms|s|m|h)$`)
+	metricNameRe  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+)
+
+// toValidationError converts field errors into api error, it returns nil for empty list.
+func toValidationError(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: kind}, name, errs)
+}
+
+func validateDuration(fldPath *field.Path, value string) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	if _, err := model.ParseDuration(value); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, value, err.Error())}
+	}
+	return nil
+}
+
+func validateOptionalDuration(fldPath *field.Path, value *string) field.ErrorList {
+	if value == nil {
+		return nil
+	}
+	return validateDuration(fldPath, *value)
+}
+
+func validateRetentionPeriod(fldPath *field.Path, value string) field.ErrorList {
+	if value == "" {
+		return field.ErrorList{field.Required(fldPath, "retentionPeriod must be set")}
+	}
+	if !retentionPeriodRe.MatchString(value) {
+		return field.ErrorList{field.Invalid(fldPath, value, "must be number of months or duration with one of h,d,w,y suffixes")}
+	}
+	return nil
+}
+
+// validateURL checks that value is absolute url with host.
+func validateURL(fldPath *field.Path, value string) field.ErrorList {
+	if value == "" {
+		return field.ErrorList{field.Required(fldPath, "url must be set")}
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, value, err.Error())}
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return field.ErrorList{field.Invalid(fldPath, value, "url must contain scheme and host")}
+	}
+	return nil
+}
+
+func validateOptionalURL(fldPath *field.Path, value *string) field.ErrorList {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return validateURL(fldPath, *value)
+}
+
+func validateTLSConfig(fldPath *field.Path, tlsConfig *TLSConfig) field.ErrorList {
+	if tlsConfig == nil {
+		return nil
+	}
+	if err := tlsConfig.Validate(); err != nil {
+		return field.ErrorList{field.Forbidden(fldPath, err.Error())}
+	}
+	return nil
+}
+
+func validateReplicaCount(fldPath *field.Path, replicas *int32) field.ErrorList {
+	if replicas != nil && *replicas < 0 {
+		return field.ErrorList{field.Invalid(fldPath, *replicas, "must be greater than or equal to 0")}
+	}
+	return nil
+}
+
+func validateHPA(fldPath *field.Path, hpa *EmbeddedHPA) field.ErrorList {
+	if hpa == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if hpa.MaxReplicas < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxReplicas"), hpa.MaxReplicas, "must be greater than or equal to 1"))
+	}
+	if hpa.MinReplicas != nil {
+		if *hpa.MinReplicas < 1 {
+			errs = append(errs, field.Invalid(fldPath.Child("minReplicas"), *hpa.MinReplicas, "must be greater than or equal to 1"))
+		} else if *hpa.MinReplicas > hpa.MaxReplicas {
+			errs = append(errs, field.Invalid(fldPath.Child("minReplicas"), *hpa.MinReplicas, "must be less than or equal to maxReplicas"))
+		}
+	}
+	return errs
+}
+
+func validatePDB(fldPath *field.Path, pdb *EmbeddedPodDisruptionBudgetSpec) field.ErrorList {
+	if pdb == nil {
+		return nil
+	}
+	if pdb.MinAvailable == nil && pdb.MaxUnavailable == nil {
+		return field.ErrorList{field.Required(fldPath, "one of minAvailable or maxUnavailable must be set")}
+	}
+	if pdb.MinAvailable != nil && pdb.MaxUnavailable != nil {
+		return field.ErrorList{field.Forbidden(fldPath.Child("maxUnavailable"), "minAvailable and maxUnavailable cannot be set at the same time")}
+	}
+	return nil
+}
+
+// validateServices checks overrides of component service and additional services.
+// Type of headless service cannot be changed, since it's required for pods DNS names.
+func validateServices(fldPath *field.Path, spec *ServiceSpec, extraServices []ServiceSpec, headless bool) field.ErrorList {
+	var errs field.ErrorList
+	if spec != nil {
+		specPath := fldPath.Child("serviceSpec", "spec")
+		errs = append(errs, validateServiceSpec(specPath, &spec.Spec)...)
+		if headless && spec.Spec.Type != "" && spec.Spec.Type != v1.ServiceTypeClusterIP {
+			errs = append(errs, field.Forbidden(specPath.Child("type"), "type of headless service cannot be changed, use extraServices instead"))
+		}
+	}
+	names := make(map[string]struct{}, len(extraServices))
+	for i, extra := range extraServices {
+		extraPath := fldPath.Child("extraServices").Index(i)
+		if extra.Name == "" {
+			errs = append(errs, field.Required(extraPath.Child("metadata", "name"), "name must be set"))
+		}
+		if _, ok := names[extra.Name]; ok {
+			errs = append(errs, field.Duplicate(extraPath.Child("metadata", "name"), extra.Name))
+		}
+		names[extra.Name] = struct{}{}
+		errs = append(errs, validateServiceSpec(extraPath.Child("spec"), &extra.Spec)...)
+	}
+	return errs
+}
+
+func validateServiceSpec(fldPath *field.Path, spec *v1.ServiceSpec) field.ErrorList {
+	var errs field.ErrorList
+	if len(spec.Selector) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("selector"), "selector is generated by operator"))
+	}
+	if spec.Type == v1.ServiceTypeExternalName {
+		errs = append(errs, field.NotSupported(fldPath.Child("type"), spec.Type, []string{
+			string(v1.ServiceTypeClusterIP), string(v1.ServiceTypeNodePort), string(v1.ServiceTypeLoadBalancer),
+		}))
+	}
+	return errs
+}
+
+// validateIngress checks ingress path prefix, which must match path prefix configured for component.
+// componentPrefix is path prefix set explicitly at component flags, it's empty if isn't set.
+func validateIngress(fldPath *field.Path, ingress *EmbeddedIngress, componentPrefix string) field.ErrorList {
+	if ingress == nil || ingress.PathPrefix == "" {
+		return nil
+	}
+	var errs field.ErrorList
+	prefixPath := fldPath.Child("pathPrefix")
+	if !strings.HasPrefix(ingress.PathPrefix, "/") {
+		errs = append(errs, field.Invalid(prefixPath, ingress.PathPrefix, "pathPrefix must start with /"))
+	}
+	if componentPrefix != "" && strings.TrimSuffix(componentPrefix, "/") != strings.TrimSuffix(ingress.PathPrefix, "/") {
+		errs = append(errs, field.Invalid(prefixPath, ingress.PathPrefix, fmt.Sprintf("pathPrefix must match path prefix of component: %s", componentPrefix)))
+	}
+	return errs
+}
+
+func validateRelabelConfigs(fldPath *field.Path, relabelConfigs []*RelabelConfig) field.ErrorList {
+	var errs field.ErrorList
+	for i, rc := range relabelConfigs {
+		if rc == nil {
+			continue
+		}
+		if rc.Regex != "" {
+			if _, err := regexp.Compile("^(?:" + rc.Regex + ")$"); err != nil {
+				errs = append(errs, field.Invalid(fldPath.Index(i).Child("regex"), rc.Regex, err.Error()))
+			}
+		}
+		if strings.ToLower(rc.Action) == "hashmod" && rc.Modulus == 0 {
+			errs = append(errs, field.Required(fldPath.Index(i).Child("modulus"), "modulus must be set for hashmod action"))
+		}
+	}
+	return errs
+}
+
+// validateStorageSpecUpdate refuses changes, which cannot be applied to statefulset volumeClaimTemplates.
+func validateStorageSpecUpdate(fldPath *field.Path, oldSpec, newSpec *StorageSpec) field.ErrorList {
+	if oldSpec == nil || newSpec == nil {
+		return nil
+	}
+	return validatePVCSpecUpdate(fldPath.Child("volumeClaimTemplate", "spec"), &oldSpec.VolumeClaimTemplate.Spec, &newSpec.VolumeClaimTemplate.Spec)
+}
+
+func validatePVCSpecUpdate(fldPath *field.Path, oldSpec, newSpec *v1.PersistentVolumeClaimSpec) field.ErrorList {
+	if oldSpec == nil || newSpec == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	oldSize, oldOk := oldSpec.Resources.Requests[v1.ResourceStorage]
+	newSize, newOk := newSpec.Resources.Requests[v1.ResourceStorage]
+	if oldOk && newOk && newSize.Cmp(oldSize) < 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("resources", "requests", "storage"),
+			fmt.Sprintf("storage size cannot be decreased from %s to %s", oldSize.String(), newSize.String())))
+	}
+	if oldSpec.StorageClassName != nil && newSpec.StorageClassName != nil && *oldSpec.StorageClassName != *newSpec.StorageClassName {
+		errs = append(errs, field.Forbidden(fldPath.Child("storageClassName"), "field is immutable"))
+	}
+	return errs
+}
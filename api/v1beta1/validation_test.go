@@ -0,0 +1,390 @@
+package v1beta1
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func storageSpecWithSize(size string) *StorageSpec {
+	return &StorageSpec{
+		VolumeClaimTemplate: EmbeddedPersistentVolumeClaim{
+			Spec: v1.PersistentVolumeClaimSpec{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		},
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func withGroups(cr *VMCluster, groups ...string) *VMCluster {
+	for _, group := range groups {
+		cr.Spec.StorageGroups = append(cr.Spec.StorageGroups, VMStorageGroup{Name: group})
+	}
+	return cr
+}
+
+func TestVMCluster_ValidateUpdate(t *testing.T) {
+	newCluster := func(storageSize string) *VMCluster {
+		return &VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: VMClusterSpec{
+				RetentionPeriod: "1",
+				VMStorage: &VMStorage{
+					ReplicaCount: int32Ptr(2),
+					Storage:      storageSpecWithSize(storageSize),
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		old     *VMCluster
+		cr      *VMCluster
+		wantErr bool
+	}{
+		{
+			name: "increase storage size",
+			old:  newCluster("10Gi"),
+			cr:   newCluster("20Gi"),
+		},
+		{
+			name:    "shrink storage size",
+			old:     newCluster("20Gi"),
+			cr:      newCluster("10Gi"),
+			wantErr: true,
+		},
+		{
+			name: "broken retention period",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				cr.Spec.RetentionPeriod = "1month"
+				return cr
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "add storage group",
+			old:  withGroups(newCluster("10Gi"), "zone-a"),
+			cr:   withGroups(newCluster("10Gi"), "zone-a", "zone-b"),
+		},
+		{
+			name:    "duplicate storage group",
+			old:     withGroups(newCluster("10Gi"), "zone-a"),
+			cr:      withGroups(newCluster("10Gi"), "zone-a", "zone-a"),
+			wantErr: true,
+		},
+		{
+			name:    "switch to storage groups",
+			old:     newCluster("10Gi"),
+			cr:      withGroups(newCluster("10Gi"), "zone-a"),
+			wantErr: true,
+		},
+		{
+			name: "pdb with both limits",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				minAvailable, maxUnavailable := intstr.FromInt(1), intstr.FromInt(1)
+				cr.Spec.VMStorage.PodDisruptionBudget = &EmbeddedPodDisruptionBudgetSpec{MinAvailable: &minAvailable, MaxUnavailable: &maxUnavailable}
+				return cr
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "headless vmstorage service type change",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				cr.Spec.VMStorage.ServiceSpec = &ServiceSpec{Spec: v1.ServiceSpec{Type: v1.ServiceTypeNodePort}}
+				return cr
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "vminsert service type change",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				cr.Spec.VMInsert = &VMInsert{ReplicaCount: int32Ptr(1), ServiceSpec: &ServiceSpec{Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer}}}
+				return cr
+			}(),
+		},
+		{
+			name: "duplicated extra vmselect services",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				extra := ServiceSpec{EmbeddedObjectMetadata: EmbeddedObjectMetadata{Name: "vmselect-public"}}
+				cr.Spec.VMSelect = &VMSelect{ReplicaCount: int32Ptr(1), ExtraServices: []ServiceSpec{extra, extra}}
+				return cr
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "vmselect ingress prefix mismatch",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				cr.Spec.VMSelect = &VMSelect{
+					ReplicaCount: int32Ptr(1),
+					ExtraArgs:    map[string]string{"http.pathPrefix": "/select"},
+					Ingress:      &EmbeddedIngress{PathPrefix: "/vmselect"},
+				}
+				return cr
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "vmselect ingress prefix without leading slash",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				cr.Spec.VMSelect = &VMSelect{ReplicaCount: int32Ptr(1), Ingress: &EmbeddedIngress{PathPrefix: "vmselect"}}
+				return cr
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "vmselect ingress",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				cr.Spec.VMSelect = &VMSelect{
+					ReplicaCount: int32Ptr(1),
+					ExtraArgs:    map[string]string{"http.pathPrefix": "/vmselect/"},
+					Ingress:      &EmbeddedIngress{Host: "vm.example.com", PathPrefix: "/vmselect"},
+				}
+				return cr
+			}(),
+		},
+		{
+			name: "empty pdb",
+			old:  newCluster("10Gi"),
+			cr: func() *VMCluster {
+				cr := newCluster("10Gi")
+				cr.Spec.VMStorage.PodDisruptionBudget = &EmbeddedPodDisruptionBudgetSpec{}
+				return cr
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "shrink storage group storage size",
+			old: func() *VMCluster {
+				cr := withGroups(newCluster("10Gi"), "zone-a")
+				cr.Spec.StorageGroups[0].Storage = storageSpecWithSize("20Gi")
+				return cr
+			}(),
+			cr: func() *VMCluster {
+				cr := withGroups(newCluster("10Gi"), "zone-a")
+				cr.Spec.StorageGroups[0].Storage = storageSpecWithSize("10Gi")
+				return cr
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cr.ValidateUpdate(tt.old); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVMAgent_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    VMAgentSpec
+		wantErr bool
+	}{
+		{
+			name: "valid spec",
+			spec: VMAgentSpec{
+				ScrapeInterval: "30s",
+				RemoteWrite:    []VMAgentRemoteWriteSpec{{URL: "http://vmsingle:8428/api/v1/write"}},
+			},
+		},
+		{
+			name: "broken scrape interval",
+			spec: VMAgentSpec{
+				ScrapeInterval: "30 seconds",
+				RemoteWrite:    []VMAgentRemoteWriteSpec{{URL: "http://vmsingle:8428/api/v1/write"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "missing remote write",
+			spec:    VMAgentSpec{},
+			wantErr: true,
+		},
+		{
+			name: "invalid tls config",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					URL: "https://vmsingle:8428/api/v1/write",
+					TLSConfig: &TLSConfig{
+						CertFile: "/etc/cert",
+						Cert:     SecretOrConfigMap{Secret: &v1.SecretKeySelector{Key: "cert"}},
+					},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid hpa",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://vmsingle:8428/api/v1/write"}},
+				HPA:         &EmbeddedHPA{MinReplicas: int32Ptr(2), MaxReplicas: 5},
+			},
+		},
+		{
+			name: "hpa min replicas greater than max",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://vmsingle:8428/api/v1/write"}},
+				HPA:         &EmbeddedHPA{MinReplicas: int32Ptr(6), MaxReplicas: 5},
+			},
+			wantErr: true,
+		},
+		{
+			name: "service with selector",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://vmsingle:8428/api/v1/write"}},
+				ServiceSpec: &ServiceSpec{Spec: v1.ServiceSpec{Selector: map[string]string{"app": "vmagent"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "extra service without name",
+			spec: VMAgentSpec{
+				RemoteWrite:   []VMAgentRemoteWriteSpec{{URL: "http://vmsingle:8428/api/v1/write"}},
+				ExtraServices: []ServiceSpec{{Spec: v1.ServiceSpec{Type: v1.ServiceTypeNodePort}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hpa without max replicas",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://vmsingle:8428/api/v1/write"}},
+				HPA:         &EmbeddedHPA{},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &VMAgent{ObjectMeta: metav1.ObjectMeta{Name: "agent"}, Spec: tt.spec}
+			if err := cr.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVMServiceScrape_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []Endpoint
+		wantErr   bool
+	}{
+		{
+			name: "valid relabel config",
+			endpoints: []Endpoint{{
+				Port:           "http",
+				RelabelConfigs: []*RelabelConfig{{Regex: "(.+)", Action: "replace"}},
+			}},
+		},
+		{
+			name: "invalid relabel regex",
+			endpoints: []Endpoint{{
+				Port:                 "http",
+				MetricRelabelConfigs: []*RelabelConfig{{Regex: "(.+", Action: "drop"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "hashmod without modulus",
+			endpoints: []Endpoint{{
+				Port:           "http",
+				RelabelConfigs: []*RelabelConfig{{Action: "hashmod"}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &VMServiceScrape{ObjectMeta: metav1.ObjectMeta{Name: "scrape"}, Spec: VMServiceScrapeSpec{Endpoints: tt.endpoints}}
+			if err := cr.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVMRule_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  []RuleGroup
+		wantErr bool
+	}{
+		{
+			name: "valid rules",
+			groups: []RuleGroup{{
+				Name: "group",
+				Rules: []Rule{
+					{Alert: "down", Expr: intstr.FromString("up == 0"), For: "5m"},
+					{Record: "job:up:sum", Expr: intstr.FromString("sum(up) by (job)")},
+				},
+			}},
+		},
+		{
+			name: "duplicated group names",
+			groups: []RuleGroup{
+				{Name: "group", Rules: []Rule{{Alert: "down", Expr: intstr.FromString("up == 0")}}},
+				{Name: "group", Rules: []Rule{{Alert: "down", Expr: intstr.FromString("up == 0")}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "recording rule with for",
+			groups: []RuleGroup{{
+				Name:  "group",
+				Rules: []Rule{{Record: "job:up:sum", Expr: intstr.FromString("sum(up) by (job)"), For: "5m"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "broken expr",
+			groups: []RuleGroup{{
+				Name:  "group",
+				Rules: []Rule{{Alert: "down", Expr: intstr.FromString("sum(rate(up[5m]) by (job)")}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "missing expr",
+			groups: []RuleGroup{{
+				Name:  "group",
+				Rules: []Rule{{Alert: "down"}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &VMRule{ObjectMeta: metav1.ObjectMeta{Name: "rule"}, Spec: VMRuleSpec{Groups: tt.groups}}
+			if err := cr.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -53,6 +53,18 @@ type VMAgentSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
 	// +optional
 	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// HPA enables HorizontalPodAutoscaler for VMAgent, ReplicaCount is used only for initial creation then.
+	// +optional
+	HPA *EmbeddedHPA `json:"hpa,omitempty"`
+	// PodDisruptionBudget created by operator for VMAgent pods.
+	// +optional
+	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// ServiceSpec overrides service generated for VMAgent.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMAgent pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
 	// Volumes allows configuration of additional volumes on the output deploy definition.
 	// Volumes specified will be appended to other volumes that are generated as a result of
 	// StorageSpec objects.
@@ -264,6 +276,15 @@ type VMAgentStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas"`
 	// UnavailableReplicas Total number of unavailable pods targeted by this VMAlert cluster.
 	UnavailableReplicas int32 `json:"unavailableReplicas"`
+	// ObservedGeneration is the most recent generation of VMAgent observed by operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of VMAgent: Available, Progressing, ConfigValid and ReconcileFailed.
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+	// LastError is the error message of the last failed reconcile.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
 // VMAgent - is a tiny but brave agent, which helps you collect metrics from various sources and stores them in VictoriaMetrics
@@ -363,6 +384,11 @@ func (cr VMAgent) TLSAssetName() string {
 	return fmt.Sprintf("tls-assets-vmagent-%s", cr.Name)
 }
 
+// CredentialsSecretName returns name of secret with remote credentials, passed to vmagent as env vars
+func (cr VMAgent) CredentialsSecretName() string {
+	return fmt.Sprintf("credentials-vmagent-%s", cr.Name)
+}
+
 func (cr VMAgent) HealthPath() string {
 	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, healthPath)
 }
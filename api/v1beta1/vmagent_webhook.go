@@ -0,0 +1,67 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMAgent.
+func (cr *VMAgent) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmagent,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmagents,verbs=create;update,versions=v1beta1,name=vvmagent.kb.io
+
+var _ webhook.Validator = &VMAgent{}
+
+func (cr *VMAgent) validate() field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	errs = append(errs, validateReplicaCount(specPath.Child("replicaCount"), cr.Spec.ReplicaCount)...)
+	errs = append(errs, validatePDB(specPath.Child("podDisruptionBudget"), cr.Spec.PodDisruptionBudget)...)
+	errs = append(errs, validateServices(specPath, cr.Spec.ServiceSpec, cr.Spec.ExtraServices, false)...)
+	errs = append(errs, validateHPA(specPath.Child("hpa"), cr.Spec.HPA)...)
+	errs = append(errs, validateDuration(specPath.Child("scrapeInterval"), cr.Spec.ScrapeInterval)...)
+	if cr.Spec.APIServerConfig != nil {
+		apiPath := specPath.Child("aPIServerConfig")
+		if cr.Spec.APIServerConfig.Host == "" {
+			errs = append(errs, field.Required(apiPath.Child("host"), "host must be set"))
+		}
+		errs = append(errs, validateTLSConfig(apiPath.Child("tlsConfig"), cr.Spec.APIServerConfig.TLSConfig)...)
+	}
+	if len(cr.Spec.RemoteWrite) == 0 {
+		errs = append(errs, field.Required(specPath.Child("remoteWrite"), "at least one remoteWrite target must be set"))
+	}
+	for i, rw := range cr.Spec.RemoteWrite {
+		rwPath := specPath.Child("remoteWrite").Index(i)
+		errs = append(errs, validateURL(rwPath.Child("url"), rw.URL)...)
+		errs = append(errs, validateOptionalDuration(rwPath.Child("flushInterval"), rw.FlushInterval)...)
+		errs = append(errs, validateOptionalDuration(rwPath.Child("sendTimeout"), rw.SendTimeout)...)
+		errs = append(errs, validateTLSConfig(rwPath.Child("tlsConfig"), rw.TLSConfig)...)
+		if rw.BasicAuth != nil && rw.BearerTokenSecret != nil {
+			errs = append(errs, field.Forbidden(rwPath.Child("bearerTokenSecret"), "basicAuth and bearerTokenSecret cannot be set at the same time"))
+		}
+	}
+	return errs
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMAgent) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmagent", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMAgent", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMAgent) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmagent", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMAgent", cr.Name, cr.validate())
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMAgent) ValidateDelete() error {
+	return nil
+}
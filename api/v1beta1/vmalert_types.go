@@ -58,6 +58,18 @@ type VMAlertSpec struct {
 	// +optional
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
 	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// PodDisruptionBudget created by operator for VMAlert pods.
+	// +optional
+	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// ServiceSpec overrides service generated for VMAlert.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMAlert pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
+	// Ingress created by operator for VMAlert web endpoints.
+	// +optional
+	Ingress *EmbeddedIngress `json:"ingress,omitempty"`
 	// Volumes allows configuration of additional volumes on the output Deployment definition.
 	// Volumes specified will be appended to other volumes that are generated as a result of
 	// StorageSpec objects.
@@ -116,6 +128,8 @@ type VMAlertSpec struct {
 	// EnforcedNamespaceLabel enforces adding a namespace label of origin for each alert
 	// and metric that is user created. The label value will always be the namespace of the object that is
 	// being created.
+	// Every series selector at rule expressions is restricted with this label
+	// and VMRule namespace, so rule cannot query series from other namespaces.
 	// +optional
 	EnforcedNamespaceLabel string `json:"enforcedNamespaceLabel,omitempty"`
 	// RuleSelector selector to select which VMRules to mount for loading alerting
@@ -245,6 +259,15 @@ type VMAlertStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas"`
 	// UnavailableReplicas Total number of unavailable pods targeted by this VMAlert cluster.
 	UnavailableReplicas int32 `json:"unavailableReplicas"`
+	// ObservedGeneration is the most recent generation of VMAlert observed by operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of VMAlert: Available, Progressing, ConfigValid and ReconcileFailed.
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+	// LastError is the error message of the last failed reconcile.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
 // VMAlert  executes a list of given alerting or recording rules against configured address.
@@ -341,14 +364,19 @@ func (cr VMAlert) PrefixedName() string {
 func (cr VMAlert) TLSAssetName() string {
 	return fmt.Sprintf("tls-assets-vmalert-%s", cr.Name)
 }
+
+// CredentialsSecretName returns name of secret with remote credentials, passed to vmalert as env vars
+func (cr VMAlert) CredentialsSecretName() string {
+	return fmt.Sprintf("credentials-vmalert-%s", cr.Name)
+}
 func (cr VMAlert) HealthPath() string {
-	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, healthPath)
+	return buildPathWithPrefixFlag(ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress), healthPath)
 }
 func (cr VMAlert) MetricPath() string {
-	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, metricPath)
+	return buildPathWithPrefixFlag(ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress), metricPath)
 }
 func (cr VMAlert) ReloadPathWithPort(port string) string {
-	return fmt.Sprintf("http://localhost:%s%s", port, buildPathWithPrefixFlag(cr.Spec.ExtraArgs, reloadPath))
+	return fmt.Sprintf("http://localhost:%s%s", port, buildPathWithPrefixFlag(ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress), reloadPath))
 }
 
 func (cr VMAlert) NeedDedupRules() bool {
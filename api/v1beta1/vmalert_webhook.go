@@ -0,0 +1,63 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMAlert.
+func (cr *VMAlert) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmalert,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmalerts,verbs=create;update,versions=v1beta1,name=vvmalert.kb.io
+
+var _ webhook.Validator = &VMAlert{}
+
+func (cr *VMAlert) validate() field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	errs = append(errs, validateReplicaCount(specPath.Child("replicaCount"), cr.Spec.ReplicaCount)...)
+	errs = append(errs, validatePDB(specPath.Child("podDisruptionBudget"), cr.Spec.PodDisruptionBudget)...)
+	errs = append(errs, validateServices(specPath, cr.Spec.ServiceSpec, cr.Spec.ExtraServices, false)...)
+	errs = append(errs, validateIngress(specPath.Child("ingress"), cr.Spec.Ingress, cr.Spec.ExtraArgs[vmPathPrefixFlagName])...)
+	errs = append(errs, validateDuration(specPath.Child("evaluationInterval"), cr.Spec.EvaluationInterval)...)
+	errs = append(errs, validateURL(specPath.Child("datasource", "url"), cr.Spec.Datasource.URL)...)
+	errs = append(errs, validateTLSConfig(specPath.Child("datasource", "tlsConfig"), cr.Spec.Datasource.TLSConfig)...)
+	errs = append(errs, validateURL(specPath.Child("notifier", "url"), cr.Spec.Notifier.URL)...)
+	errs = append(errs, validateTLSConfig(specPath.Child("notifier", "tlsConfig"), cr.Spec.Notifier.TLSConfig)...)
+	if cr.Spec.RemoteWrite != nil {
+		rwPath := specPath.Child("remoteWrite")
+		errs = append(errs, validateURL(rwPath.Child("url"), cr.Spec.RemoteWrite.URL)...)
+		errs = append(errs, validateOptionalDuration(rwPath.Child("flushInterval"), cr.Spec.RemoteWrite.FlushInterval)...)
+		errs = append(errs, validateTLSConfig(rwPath.Child("tlsConfig"), cr.Spec.RemoteWrite.TLSConfig)...)
+	}
+	if cr.Spec.RemoteRead != nil {
+		rrPath := specPath.Child("remoteRead")
+		errs = append(errs, validateURL(rrPath.Child("url"), cr.Spec.RemoteRead.URL)...)
+		errs = append(errs, validateOptionalDuration(rrPath.Child("lookback"), cr.Spec.RemoteRead.Lookback)...)
+		errs = append(errs, validateTLSConfig(rrPath.Child("tlsConfig"), cr.Spec.RemoteRead.TLSConfig)...)
+	}
+	return errs
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMAlert) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmalert", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMAlert", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMAlert) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmalert", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMAlert", cr.Name, cr.validate())
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMAlert) ValidateDelete() error {
+	return nil
+}
@@ -74,6 +74,17 @@ type VMAlertmanagerSpec struct {
 	// +optional
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Secret with alertmanager config",xDescriptors="urn:alm:descriptor:io.kubernetes:Secret"
 	ConfigSecret string `json:"configSecret,omitempty"`
+	// ConfigSelector defines VMAlertmanagerConfigs to be selected for config merge.
+	// if neither configNamespaceSelector nor configSelector are specified,
+	// VMAlertmanagerConfigs are ignored.
+	// Operator merges selected configs with ConfigRawYaml or ConfigSecret content
+	// and writes result into 'vmalertmanager-<alertmanager-name>' secret.
+	// +optional
+	ConfigSelector *metav1.LabelSelector `json:"configSelector,omitempty"`
+	// ConfigNamespaceSelector Namespaces to be selected for VMAlertmanagerConfig discovery. If nil, only
+	// check own namespace.
+	// +optional
+	ConfigNamespaceSelector *metav1.LabelSelector `json:"configNamespaceSelector,omitempty"`
 	// Log level for VMAlertmanager to be configured with.
 	// +optional
 	LogLevel string `json:"logLevel,omitempty"`
@@ -86,6 +97,18 @@ type VMAlertmanagerSpec struct {
 	// +optional
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
 	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// PodDisruptionBudget created by operator for VMAlertmanager pods.
+	// +optional
+	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// ServiceSpec overrides service generated for VMAlertmanager.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMAlertmanager pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
+	// Ingress created by operator for VMAlertmanager web endpoints.
+	// +optional
+	Ingress *EmbeddedIngress `json:"ingress,omitempty"`
 	// Retention Time duration VMAlertmanager shall retain data for. Default is '120h',
 	// and must match the regular expression `[0-9]+(ms|s|m|h)` (milliseconds seconds minutes hours).
 	// +kubebuilder:validation:Pattern:="[0-9]+(ms|s|m|h)"
@@ -280,6 +303,19 @@ func (cr VMAlertmanager) PrefixedName() string {
 	return fmt.Sprintf("vmalertmanager-%s", cr.Name)
 }
 
+// HasConfigSelectors returns true if VMAlertmanagerConfigs must be merged into configuration.
+func (cr VMAlertmanager) HasConfigSelectors() bool {
+	return cr.Spec.ConfigSelector != nil || cr.Spec.ConfigNamespaceSelector != nil
+}
+
+// ConfigSecretName returns name of secret, which is mounted into alertmanager pods.
+func (cr VMAlertmanager) ConfigSecretName() string {
+	if cr.HasConfigSelectors() || cr.Spec.ConfigSecret == "" {
+		return cr.PrefixedName()
+	}
+	return cr.Spec.ConfigSecret
+}
+
 func init() {
 	SchemeBuilder.Register(&VMAlertmanager{}, &VMAlertmanagerList{})
 }
@@ -0,0 +1,77 @@
+package v1beta1
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMAlertmanager.
+func (cr *VMAlertmanager) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmalertmanager,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmalertmanagers,verbs=create;update,versions=v1beta1,name=vvmalertmanager.kb.io
+
+var _ webhook.Validator = &VMAlertmanager{}
+
+func (cr *VMAlertmanager) validate() field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	errs = append(errs, validateReplicaCount(specPath.Child("replicaCount"), cr.Spec.ReplicaCount)...)
+	errs = append(errs, validatePDB(specPath.Child("podDisruptionBudget"), cr.Spec.PodDisruptionBudget)...)
+	errs = append(errs, validateServices(specPath, cr.Spec.ServiceSpec, cr.Spec.ExtraServices, true)...)
+	errs = append(errs, validateIngress(specPath.Child("ingress"), cr.Spec.Ingress, cr.Spec.RoutePrefix)...)
+	if cr.Spec.Retention != "" && !amRetentionRe.MatchString(cr.Spec.Retention) {
+		errs = append(errs, field.Invalid(specPath.Child("retention"), cr.Spec.Retention, "must match [0-9]+(ms|s|m|h)"))
+	}
+	if cr.Spec.ConfigRawYaml != "" {
+		var amCfg map[string]interface{}
+		if err := yaml.Unmarshal([]byte(cr.Spec.ConfigRawYaml), &amCfg); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("configRawYaml"), "", fmt.Sprintf("cannot parse config: %s", err)))
+		}
+	}
+	for _, sel := range []struct {
+		name     string
+		selector *metav1.LabelSelector
+	}{
+		{name: "configSelector", selector: cr.Spec.ConfigSelector},
+		{name: "configNamespaceSelector", selector: cr.Spec.ConfigNamespaceSelector},
+	} {
+		if sel.selector == nil {
+			continue
+		}
+		if _, err := metav1.LabelSelectorAsSelector(sel.selector); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child(sel.name), sel.selector.String(), err.Error()))
+		}
+	}
+	return errs
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMAlertmanager) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmalertmanager", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMAlertmanager", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMAlertmanager) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmalertmanager", cr.Name, "namespace", cr.Namespace)
+	errs := cr.validate()
+	if oldCR, ok := old.(*VMAlertmanager); ok {
+		errs = append(errs, validateStorageSpecUpdate(field.NewPath("spec", "storage"), oldCR.Spec.Storage, cr.Spec.Storage)...)
+	}
+	return toValidationError("VMAlertmanager", cr.Name, errs)
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMAlertmanager) ValidateDelete() error {
+	return nil
+}
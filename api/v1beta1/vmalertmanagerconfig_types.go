@@ -0,0 +1,381 @@
+package v1beta1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VMAlertmanagerConfigSpec defines configuration for VMAlertmanagerConfig
+// it's merged by operator into VMAlertmanager configuration.
+// +k8s:openapi-gen=true
+type VMAlertmanagerConfigSpec struct {
+	// Route definition for alertmanager, may include nested routes.
+	// Operator adds namespace matcher to it, so it matches only alerts
+	// from VMAlertmanagerConfig namespace.
+	// +optional
+	Route *Route `json:"route,omitempty"`
+	// Receivers list of alert receivers, which can be referenced by route.
+	// +optional
+	Receivers []Receiver `json:"receivers,omitempty"`
+	// InhibitRules will only apply for alerts matching
+	// the resource's namespace.
+	// +optional
+	InhibitRules []InhibitRule `json:"inhibitRules,omitempty"`
+	// MuteTimeIntervals is a list of time intervals, which can be referenced by route,
+	// requires alertmanager v0.22.0 or higher.
+	// +optional
+	MuteTimeIntervals []MuteTimeInterval `json:"muteTimeIntervals,omitempty"`
+}
+
+// Route defines a node in the routing tree.
+// +k8s:openapi-gen=true
+type Route struct {
+	// Receiver name, it must be defined at receivers of the same VMAlertmanagerConfig.
+	Receiver string `json:"receiver"`
+	// GroupBy list of labels to group by.
+	// +optional
+	GroupBy []string `json:"groupBy,omitempty"`
+	// GroupWait how long to wait before sending initial notification.
+	// +optional
+	GroupWait string `json:"groupWait,omitempty"`
+	// GroupInterval for alerts.
+	// +optional
+	GroupInterval string `json:"groupInterval,omitempty"`
+	// RepeatInterval for alerts.
+	// +optional
+	RepeatInterval string `json:"repeatInterval,omitempty"`
+	// Matchers defines alert labels to match.
+	// namespace matcher is added by operator and cannot be overridden.
+	// +optional
+	Matchers []Matcher `json:"matchers,omitempty"`
+	// Continue indicating whether an alert should continue matching subsequent
+	// sibling nodes.
+	// +optional
+	Continue bool `json:"continue,omitempty"`
+	// MuteTimeIntervals names of mute time intervals, defined at the same VMAlertmanagerConfig.
+	// +optional
+	MuteTimeIntervals []string `json:"muteTimeIntervals,omitempty"`
+	// Routes child routes.
+	// +optional
+	Routes []SubRoute `json:"routes,omitempty"`
+}
+
+// SubRoute defines a child node in the routing tree.
+// +k8s:openapi-gen=true
+type SubRoute struct {
+	// Receiver name, it must be defined at receivers of the same VMAlertmanagerConfig.
+	// parent receiver is used if missing.
+	// +optional
+	Receiver string `json:"receiver,omitempty"`
+	// GroupBy list of labels to group by.
+	// +optional
+	GroupBy []string `json:"groupBy,omitempty"`
+	// GroupWait how long to wait before sending initial notification.
+	// +optional
+	GroupWait string `json:"groupWait,omitempty"`
+	// GroupInterval for alerts.
+	// +optional
+	GroupInterval string `json:"groupInterval,omitempty"`
+	// RepeatInterval for alerts.
+	// +optional
+	RepeatInterval string `json:"repeatInterval,omitempty"`
+	// Matchers defines alert labels to match.
+	// +optional
+	Matchers []Matcher `json:"matchers,omitempty"`
+	// Continue indicating whether an alert should continue matching subsequent
+	// sibling nodes.
+	// +optional
+	Continue bool `json:"continue,omitempty"`
+	// MuteTimeIntervals names of mute time intervals, defined at the same VMAlertmanagerConfig.
+	// +optional
+	MuteTimeIntervals []string `json:"muteTimeIntervals,omitempty"`
+}
+
+// Matcher defines how to match alert label.
+// +k8s:openapi-gen=true
+type Matcher struct {
+	// Name of the label to match.
+	Name string `json:"name"`
+	// Value to match.
+	Value string `json:"value"`
+	// Regex defines whether value is regular expression.
+	// +optional
+	Regex bool `json:"regex,omitempty"`
+}
+
+// InhibitRule defines an inhibition rule that allows to mute alerts when other
+// alerts are already firing.
+// Operator adds namespace matcher to the source and target matchers.
+// +k8s:openapi-gen=true
+type InhibitRule struct {
+	// SourceMatchers defines a list of matchers for which one or more alerts have
+	// to exist for the inhibition to take effect.
+	// +optional
+	SourceMatchers []Matcher `json:"sourceMatchers,omitempty"`
+	// TargetMatchers defines a list of matchers that have to be fulfilled by the target
+	// alerts to be muted.
+	// +optional
+	TargetMatchers []Matcher `json:"targetMatchers,omitempty"`
+	// Equal labels which must have an equal value in the source and target
+	// alert for the inhibition to take effect.
+	// +optional
+	Equal []string `json:"equal,omitempty"`
+}
+
+// MuteTimeInterval defines named set of time intervals, when alerts must be muted.
+// +k8s:openapi-gen=true
+type MuteTimeInterval struct {
+	// Name of interval, it can be referenced by route.
+	Name string `json:"name"`
+	// TimeIntervals interval configuration.
+	TimeIntervals []TimeInterval `json:"timeIntervals"`
+}
+
+// TimeInterval describes intervals of time.
+// syntax the same as alertmanager has.
+// +k8s:openapi-gen=true
+type TimeInterval struct {
+	// Times defines time range for mute.
+	// +optional
+	Times []TimeRange `json:"times,omitempty"`
+	// Weekdays defines list of days of the week, for example monday:friday or saturday.
+	// +optional
+	Weekdays []string `json:"weekdays,omitempty"`
+	// DaysOfMonth defines list of numerical days in the month, for example 1:5 or -3:-1.
+	// +optional
+	DaysOfMonth []string `json:"daysOfMonth,omitempty"`
+	// Months defines list of calendar months, for example january:march or 1:3.
+	// +optional
+	Months []string `json:"months,omitempty"`
+	// Years defines numerical list of years, for example 2020:2022.
+	// +optional
+	Years []string `json:"years,omitempty"`
+}
+
+// TimeRange ranges inclusive of the starting time and exclusive of the end time.
+// +k8s:openapi-gen=true
+type TimeRange struct {
+	// StartTime for example 17:00
+	StartTime string `json:"startTime"`
+	// EndTime for example 24:00
+	EndTime string `json:"endTime"`
+}
+
+// Receiver defines one or more notification integrations.
+// +k8s:openapi-gen=true
+type Receiver struct {
+	// Name of the receiver. Must be unique across all items from the list.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// EmailConfigs defines email notification configurations.
+	// +optional
+	EmailConfigs []EmailConfig `json:"emailConfigs,omitempty"`
+	// SlackConfigs defines slack notification configurations.
+	// +optional
+	SlackConfigs []SlackConfig `json:"slackConfigs,omitempty"`
+	// PagerDutyConfigs defines pager duty notification configurations.
+	// +optional
+	PagerDutyConfigs []PagerDutyConfig `json:"pagerdutyConfigs,omitempty"`
+	// WebhookConfigs defines webhook notification configurations.
+	// +optional
+	WebhookConfigs []WebhookConfig `json:"webhookConfigs,omitempty"`
+}
+
+// EmailConfig configures notifications via Email.
+// +k8s:openapi-gen=true
+type EmailConfig struct {
+	// SendResolved controls notify about resolved alerts.
+	// +optional
+	SendResolved *bool `json:"sendResolved,omitempty"`
+	// To is the email address to send notifications to.
+	// +optional
+	To string `json:"to,omitempty"`
+	// From is the sender address.
+	// +optional
+	From string `json:"from,omitempty"`
+	// Hello is the hostname to identify to the SMTP server.
+	// +optional
+	Hello string `json:"hello,omitempty"`
+	// Smarthost is the SMTP host through which emails are sent.
+	// +optional
+	Smarthost string `json:"smarthost,omitempty"`
+	// AuthUsername is the username to use for authentication.
+	// +optional
+	AuthUsername string `json:"authUsername,omitempty"`
+	// AuthPassword defines secret name and key at CRD namespace.
+	// +optional
+	AuthPassword *v1.SecretKeySelector `json:"authPassword,omitempty"`
+	// AuthSecret defines secret name and key at CRD namespace.
+	// It must contain the CRAM-MD5 secret.
+	// +optional
+	AuthSecret *v1.SecretKeySelector `json:"authSecret,omitempty"`
+	// AuthIdentity is the identity to use for authentication.
+	// +optional
+	AuthIdentity string `json:"authIdentity,omitempty"`
+	// Headers is a set of email header key/value pairs.
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+	// HTML body of the email notification.
+	// +optional
+	HTML string `json:"html,omitempty"`
+	// Text body of the email notification.
+	// +optional
+	Text string `json:"text,omitempty"`
+	// RequireTLS enforces SMTP TLS requirement.
+	// +optional
+	RequireTLS *bool `json:"requireTLS,omitempty"`
+}
+
+// SlackConfig configures notifications via Slack.
+// +k8s:openapi-gen=true
+type SlackConfig struct {
+	// SendResolved controls notify about resolved alerts.
+	// +optional
+	SendResolved *bool `json:"sendResolved,omitempty"`
+	// APIURL defines secret name and key at CRD namespace.
+	// It must contain the Slack webhook URL.
+	// +optional
+	APIURL *v1.SecretKeySelector `json:"apiURL,omitempty"`
+	// Channel is the channel or user to send notifications to.
+	// +optional
+	Channel string `json:"channel,omitempty"`
+	// Username for the bot.
+	// +optional
+	Username string `json:"username,omitempty"`
+	// Color of the message attachment.
+	// +optional
+	Color string `json:"color,omitempty"`
+	// Title of the message.
+	// +optional
+	Title string `json:"title,omitempty"`
+	// TitleLink for the message title.
+	// +optional
+	TitleLink string `json:"titleLink,omitempty"`
+	// Text of the message.
+	// +optional
+	Text string `json:"text,omitempty"`
+	// Fallback text of the message.
+	// +optional
+	Fallback string `json:"fallback,omitempty"`
+	// IconEmoji for the bot.
+	// +optional
+	IconEmoji string `json:"iconEmoji,omitempty"`
+	// IconURL for the bot.
+	// +optional
+	IconURL string `json:"iconURL,omitempty"`
+}
+
+// PagerDutyConfig configures notifications via PagerDuty.
+// +k8s:openapi-gen=true
+type PagerDutyConfig struct {
+	// SendResolved controls notify about resolved alerts.
+	// +optional
+	SendResolved *bool `json:"sendResolved,omitempty"`
+	// RoutingKey defines secret name and key at CRD namespace.
+	// It must contain the PagerDuty integration key (when using Events API v2).
+	// Either this field or `serviceKey` needs to be defined.
+	// +optional
+	RoutingKey *v1.SecretKeySelector `json:"routingKey,omitempty"`
+	// ServiceKey defines secret name and key at CRD namespace.
+	// It must contain the PagerDuty integration key (when using Events API v1).
+	// Either this field or `routingKey` needs to be defined.
+	// +optional
+	ServiceKey *v1.SecretKeySelector `json:"serviceKey,omitempty"`
+	// URL to send requests to.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// Client of alerts, identification of the monitoring system.
+	// +optional
+	Client string `json:"client,omitempty"`
+	// ClientURL backlink to the sender of notification.
+	// +optional
+	ClientURL string `json:"clientURL,omitempty"`
+	// Description of the incident.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Severity of the incident.
+	// +optional
+	Severity string `json:"severity,omitempty"`
+	// Class the class/type of the event.
+	// +optional
+	Class string `json:"class,omitempty"`
+	// Group a cluster or grouping of sources.
+	// +optional
+	Group string `json:"group,omitempty"`
+	// Component the part or component of the affected system that is broken.
+	// +optional
+	Component string `json:"component,omitempty"`
+	// Details arbitrary key/value pairs that provide further detail about the incident.
+	// +optional
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// WebhookConfig configures notifications via a generic receiver supporting the webhook payload.
+// +k8s:openapi-gen=true
+type WebhookConfig struct {
+	// SendResolved controls notify about resolved alerts.
+	// +optional
+	SendResolved *bool `json:"sendResolved,omitempty"`
+	// URL to send requests to,
+	// one of `urlSecret` and `url` must be defined.
+	// +optional
+	URL *string `json:"url,omitempty"`
+	// URLSecret defines secret name and key at the CRD namespace.
+	// It must contain the webhook URL.
+	// one of `urlSecret` and `url` must be defined.
+	// +optional
+	URLSecret *v1.SecretKeySelector `json:"urlSecret,omitempty"`
+	// HTTPConfig defines http client configuration for webhook requests.
+	// +optional
+	HTTPConfig *HTTPConfig `json:"httpConfig,omitempty"`
+	// MaxAlerts maximum number of alerts to be sent per webhook message. When 0, all alerts are included.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxAlerts int32 `json:"maxAlerts,omitempty"`
+}
+
+// HTTPConfig defines a client HTTP configuration for alertmanager receivers.
+// +k8s:openapi-gen=true
+type HTTPConfig struct {
+	// BasicAuth for the client.
+	// +optional
+	BasicAuth *BasicAuth `json:"basicAuth,omitempty"`
+	// BearerTokenSecret defines secret name and key at CRD namespace.
+	// It must contain the bearer token for the client.
+	// +optional
+	BearerTokenSecret *v1.SecretKeySelector `json:"bearerTokenSecret,omitempty"`
+	// ProxyURL optional proxy URL.
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+}
+
+// VMAlertmanagerConfigStatus defines the observed state of VMAlertmanagerConfig
+// +k8s:openapi-gen=true
+type VMAlertmanagerConfigStatus struct {
+}
+
+// VMAlertmanagerConfig is the Schema for the vmalertmanagerconfigs API
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=vmalertmanagerconfigs,scope=Namespaced
+type VMAlertmanagerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VMAlertmanagerConfigSpec   `json:"spec,omitempty"`
+	Status VMAlertmanagerConfigStatus `json:"status,omitempty"`
+}
+
+// VMAlertmanagerConfigList contains a list of VMAlertmanagerConfig
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type VMAlertmanagerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VMAlertmanagerConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VMAlertmanagerConfig{}, &VMAlertmanagerConfigList{})
+}
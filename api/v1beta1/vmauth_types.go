@@ -0,0 +1,244 @@
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+// VMAuthSpec defines the desired state of VMAuth
+// +k8s:openapi-gen=true
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".spec.image.tag",description="The version of VMAuth"
+// +kubebuilder:printcolumn:name="ReplicaCount",type="integer",JSONPath=".spec.replicaCount",description="The desired replicas number of VMAuth"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type VMAuthSpec struct {
+	// PodMetadata configures Labels and Annotations which are propagated to the VMAuth pods.
+	// +optional
+	PodMetadata *EmbeddedObjectMetadata `json:"podMetadata,omitempty"`
+	// Image - docker image settings for VMAuth
+	// if no specified operator uses default config version
+	// +optional
+	Image Image `json:"image,omitempty"`
+	// ImagePullSecrets An optional list of references to secrets in the same namespace
+	// to use for pulling images from registries
+	// see http://kubernetes.io/docs/user-guide/images#specifying-imagepullsecrets-on-a-pod
+	// +optional
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// Secrets is a list of Secrets in the same namespace as the VMAuth
+	// object, which shall be mounted into the VMAuth Pods.
+	// +optional
+	Secrets []string `json:"secrets,omitempty"`
+	// ConfigMaps is a list of ConfigMaps in the same namespace as the VMAuth
+	// object, which shall be mounted into the VMAuth Pods.
+	// +optional
+	ConfigMaps []string `json:"configMaps,omitempty"`
+	// LogLevel for VMAuth to be configured with.
+	// +optional
+	// +kubebuilder:validation:Enum=INFO;WARN;ERROR;FATAL;PANIC
+	LogLevel string `json:"logLevel,omitempty"`
+	// LogFormat for VMAuth to be configured with.
+	// +optional
+	// +kubebuilder:validation:Enum=default;json
+	LogFormat string `json:"logFormat,omitempty"`
+	// ReplicaCount is the expected size of the VMAuth
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
+	// +optional
+	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// ServiceSpec overrides service generated for VMAuth.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMAuth pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
+	// Volumes allows configuration of additional volumes on the output deploy definition.
+	// Volumes specified will be appended to other volumes that are generated as a result of
+	// StorageSpec objects.
+	// +optional
+	Volumes []v1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts allows configuration of additional VolumeMounts on the output Deployment definition.
+	// VolumeMounts specified will be appended to other VolumeMounts in the VMAuth container,
+	// that are generated as a result of StorageSpec objects.
+	// +optional
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+	// Resources container resource request and limits, https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+	// if not defined default resources from operator config will be used
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Resources",xDescriptors="urn:alm:descriptor:com.tectonic.ui:resourceRequirements"
+	// +optional
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+	// Affinity If specified, the pod's scheduling constraints.
+	// +optional
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// Tolerations If specified, the pod's tolerations.
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+	// SecurityContext holds pod-level security attributes and common container settings.
+	// This defaults to the default PodSecurityContext.
+	// +optional
+	SecurityContext *v1.PodSecurityContext `json:"securityContext,omitempty"`
+	// ServiceAccountName is the name of the ServiceAccount to use to run the
+	// VMAuth Pods.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// Containers property allows to inject additions sidecars. It can be useful for proxies, backup, etc.
+	// +optional
+	Containers []v1.Container `json:"containers,omitempty"`
+	// InitContainers allows adding initContainers to the pod definition. Those can be used to e.g.
+	// fetch secrets for injection into the VMAuth configuration from external sources. Any
+	// errors during the execution of an initContainer will lead to a restart of the Pod. More info: https://kubernetes.io/docs/concepts/workloads/pods/init-containers/
+	// Using initContainers for any use case other then secret fetching is entirely outside the scope
+	// of what the maintainers will support and by doing so, you accept that this behaviour may break
+	// at any time without notice.
+	// +optional
+	InitContainers []v1.Container `json:"initContainers,omitempty"`
+	// PriorityClassName assigned to the Pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// HostNetwork controls whether the pod may use the node network namespace
+	// +optional
+	HostNetwork bool `json:"hostNetwork,omitempty"`
+	// DNSPolicy sets DNS policy for the pod
+	// +optional
+	DNSPolicy v1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// Port listen port
+	// +optional
+	Port string `json:"port,omitempty"`
+
+	// UserSelector defines VMUsers to be selected for config file generation.
+	// if neither userNamespaceSelector nor userSelector are specified, only VMUsers
+	// at VMAuth namespace are selected.
+	// +optional
+	UserSelector *metav1.LabelSelector `json:"userSelector,omitempty"`
+	// UserNamespaceSelector Namespaces to be selected for VMUser discovery. If nil, only
+	// check own namespace.
+	// +optional
+	UserNamespaceSelector *metav1.LabelSelector `json:"userNamespaceSelector,omitempty"`
+
+	// ExtraArgs that will be passed to  VMAuth pod
+	// for example remoteWrite.tmpDataPath: /tmp
+	// +optional
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+	// ExtraEnvs that will be added to VMAuth pod
+	// +optional
+	ExtraEnvs []v1.EnvVar `json:"extraEnvs,omitempty"`
+}
+
+// VMAuthStatus defines the observed state of VMAuth
+// +k8s:openapi-gen=true
+type VMAuthStatus struct {
+}
+
+// VMAuth is the Schema for the vmauths API
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +operator-sdk:gen-csv:customresourcedefinitions.displayName="VMAuth App"
+// +operator-sdk:gen-csv:customresourcedefinitions.resources="Deployment,apps"
+// +operator-sdk:gen-csv:customresourcedefinitions.resources="Service,v1"
+// +operator-sdk:gen-csv:customresourcedefinitions.resources="Secret,v1"
+// +genclient
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=vmauths,scope=Namespaced
+type VMAuth struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VMAuthSpec   `json:"spec,omitempty"`
+	Status VMAuthStatus `json:"status,omitempty"`
+}
+
+// VMAuthList contains a list of VMAuth
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type VMAuthList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VMAuth `json:"items"`
+}
+
+func (cr *VMAuth) AsOwner() []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		{
+			APIVersion:         cr.APIVersion,
+			Kind:               cr.Kind,
+			Name:               cr.Name,
+			UID:                cr.UID,
+			Controller:         pointer.BoolPtr(true),
+			BlockOwnerDeletion: pointer.BoolPtr(true),
+		},
+	}
+}
+
+func (cr VMAuth) PodAnnotations() map[string]string {
+	annotations := map[string]string{}
+	if cr.Spec.PodMetadata != nil {
+		for annotation, value := range cr.Spec.PodMetadata.Annotations {
+			annotations[annotation] = value
+		}
+	}
+	return annotations
+}
+
+func (cr VMAuth) Annotations() map[string]string {
+	annotations := make(map[string]string)
+	for annotation, value := range cr.ObjectMeta.Annotations {
+		if !strings.HasPrefix(annotation, "kubectl.kubernetes.io/") {
+			annotations[annotation] = value
+		}
+	}
+	return annotations
+}
+
+func (cr VMAuth) SelectorLabels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":      "vmauth",
+		"app.kubernetes.io/instance":  cr.Name,
+		"app.kubernetes.io/component": "monitoring",
+		"managed-by":                  "vm-operator",
+	}
+}
+
+func (cr VMAuth) PodLabels() map[string]string {
+	labels := cr.SelectorLabels()
+	if cr.Spec.PodMetadata != nil {
+		for label, value := range cr.Spec.PodMetadata.Labels {
+			labels[label] = value
+		}
+	}
+	return labels
+}
+
+func (cr VMAuth) FinalLabels() map[string]string {
+	labels := cr.SelectorLabels()
+	if cr.ObjectMeta.Labels != nil {
+		for label, value := range cr.ObjectMeta.Labels {
+			labels[label] = value
+		}
+	}
+	return labels
+}
+
+func (cr VMAuth) PrefixedName() string {
+	return fmt.Sprintf("vmauth-%s", cr.Name)
+}
+
+func (cr VMAuth) ConfigSecretName() string {
+	return fmt.Sprintf("vmauth-config-%s", cr.Name)
+}
+
+func (cr VMAuth) HealthPath() string {
+	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, healthPath)
+}
+
+func (cr VMAuth) MetricPath() string {
+	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, metricPath)
+}
+
+func (cr VMAuth) ReloadPathWithPort(port string) string {
+	return fmt.Sprintf("http://localhost:%s%s", port, buildPathWithPrefixFlag(cr.Spec.ExtraArgs, reloadPath))
+}
+
+func init() {
+	SchemeBuilder.Register(&VMAuth{}, &VMAuthList{})
+}
@@ -21,6 +21,18 @@ const (
 	SelectRollingUpdateFailed = "failed to perform rolling update on vmSelect"
 	SelectCreationFailed      = "failed to create vmSelect statefulset"
 	InsertCreationFailed      = "failed to create vmInsert deployment"
+
+	// MetaVMStorageScaleDownConfirmKey - confirms scale down of vmstorage to the given replica count,
+	// draining vmstorage nodes are removed without waiting for retention period.
+	MetaVMStorageScaleDownConfirmKey = "operator.victoriametrics.com/vmstorage-scale-down-confirmed"
+
+	// PVCPolicyRetain keeps persistent volume claims of removed vmstorage nodes.
+	PVCPolicyRetain = "Retain"
+	// PVCPolicyDelete deletes persistent volume claims of removed vmstorage nodes.
+	PVCPolicyDelete = "Delete"
+
+	// StorageGroupLabel - name of storage group, which vmstorage, zone local vminsert and vmselect pods belong to.
+	StorageGroupLabel = "operator.victoriametrics.com/storage-group"
 )
 
 // VMClusterSpec defines the desired state of VMCluster
@@ -45,6 +57,40 @@ type VMClusterSpec struct {
 	VMInsert *VMInsert `json:"vminsert,omitempty"`
 	// +optional
 	VMStorage *VMStorage `json:"vmstorage,omitempty"`
+	// StorageGroups splits vmstorage nodes into named groups, for instance by availability zones.
+	// Each group has its own statefulset and service, VMStorage is used as template for groups.
+	// Each group has its own zone local vminsert, which writes only to vmstorage nodes of group,
+	// VMInsert is used as template for it. Data is replicated across groups by writing it to vminsert of each group.
+	// Global vmselect uses vmstorage nodes of all groups.
+	// +optional
+	StorageGroups []VMStorageGroup `json:"storageGroups,omitempty"`
+}
+
+// VMStorageGroup defines group of vmstorage nodes, it overrides cluster VMStorage settings.
+type VMStorageGroup struct {
+	// Name of group, it's added as suffix to names of group statefulsets and services.
+	// +kubebuilder:validation:Pattern:="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	Name string `json:"name"`
+	// ReplicaCount number of vmstorage nodes at group
+	// +optional
+	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// Affinity of group pods, usually node affinity for availability zone
+	// +optional
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// Tolerations of group pods
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+	// Storage of group vmstorage nodes, it allows to use zone specific storage class
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+	// VMInsertReplicaCount number of zone local vminsert pods, which write only to vmstorage nodes of group.
+	// VMInsert ReplicaCount is used if not set.
+	// +optional
+	VMInsertReplicaCount *int32 `json:"vmInsertReplicaCount,omitempty"`
+	// VMSelectReplicaCount number of zone local vmselect pods, which query only vmstorage nodes of group.
+	// Zone local vmselect isn't created if not set.
+	// +optional
+	VMSelectReplicaCount *int32 `json:"vmSelectReplicaCount,omitempty"`
 }
 
 // VMCluster is fast, cost-effective and scalable time-series database.
@@ -85,10 +131,83 @@ func (c *VMCluster) AsOwner() []metav1.OwnerReference {
 
 // VMClusterStatus defines the observed state of VMCluster
 type VMClusterStatus struct {
-	UpdateFailCount int    `json:"updateFailCount"`
-	LastSync        string `json:"lastSync,omitempty"`
-	ClusterStatus   string `json:"clusterStatus"`
-	Reason          string `json:"reason,omitempty"`
+	UpdateFailCount int `json:"updateFailCount"`
+	// LastSync is the time of the last reconcile in RFC3339 format
+	LastSync      string `json:"lastSync,omitempty"`
+	ClusterStatus string `json:"clusterStatus"`
+	Reason        string `json:"reason,omitempty"`
+	// ObservedGeneration is the most recent generation of VMCluster observed by operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of VMCluster: Available, Progressing, ConfigValid, ReconcileFailed and Degraded.
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+	// VMStorage observed state of vmstorage component
+	// +optional
+	VMStorage *VMClusterComponentStatus `json:"vmstorage,omitempty"`
+	// VMSelect observed state of vmselect component
+	// +optional
+	VMSelect *VMClusterComponentStatus `json:"vmselect,omitempty"`
+	// VMInsert observed state of vminsert component
+	// +optional
+	VMInsert *VMClusterComponentStatus `json:"vminsert,omitempty"`
+	// DrainingStorageNodes vmstorage nodes removed from vminsert by scale down,
+	// which are still available for vmselect
+	// +optional
+	DrainingStorageNodes []VMStorageDrainingNode `json:"drainingStorageNodes,omitempty"`
+}
+
+// VMStorageDrainingNode describes vmstorage node, which is removed from cluster by scale down
+type VMStorageDrainingNode struct {
+	// Name of vmstorage pod
+	Name string `json:"name"`
+	// Group is the name of storage group of node
+	// +optional
+	Group string `json:"group,omitempty"`
+	// Index of vmstorage pod at statefulset
+	Index int32 `json:"index"`
+	// DrainStartTime is the time, when node was removed from vminsert
+	DrainStartTime metav1.Time `json:"drainStartTime"`
+}
+
+// VMClusterComponentStatus defines the observed state of VMCluster component
+type VMClusterComponentStatus struct {
+	// Replicas desired number of component pods
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas number of component pods with Ready condition
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// UpdatedReplicas number of component pods with the desired version spec
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+	// Version image tag of component
+	// +optional
+	Version string `json:"version,omitempty"`
+	// UpdateStatus rollout progress of component: expanding, operational or failed
+	// +optional
+	UpdateStatus string `json:"updateStatus,omitempty"`
+	// LastGoodRevision revision of component statefulset or deployment, which was fully updated and ready
+	// +optional
+	LastGoodRevision string `json:"lastGoodRevision,omitempty"`
+	// FailCount number of consecutive failed update attempts of component.
+	// Repeated failures of the same revision are counted once per failure count interval.
+	// +optional
+	FailCount int32 `json:"failCount,omitempty"`
+	// FailedRevision revision of component, which update attempt failed last time
+	// +optional
+	FailedRevision string `json:"failedRevision,omitempty"`
+	// LastFailureTime is the time of the last counted failed update attempt of component
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+	// RolloutRevision revision of component, which rollout isn't finished yet
+	// +optional
+	RolloutRevision string `json:"rolloutRevision,omitempty"`
+	// RolloutStartTime is the time, when rollout of RolloutRevision was observed first.
+	// Component is rolled back, if rollout isn't finished within rollout deadline.
+	// +optional
+	RolloutStartTime *metav1.Time `json:"rolloutStartTime,omitempty"`
+	// RollbackGeneration generation of VMCluster, which update was rolled back to LastGoodRevision.
+	// Component updates are paused until VMCluster spec changes.
+	// +optional
+	RollbackGeneration int64 `json:"rollbackGeneration,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -134,6 +253,22 @@ type VMSelect struct {
 	// size.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
 	ReplicaCount *int32 `json:"replicaCount"`
+	// HPA enables HorizontalPodAutoscaler for VMSelect, ReplicaCount is used only for initial creation then.
+	// +optional
+	HPA *EmbeddedHPA `json:"hpa,omitempty"`
+	// PodDisruptionBudget created by operator for VMSelect pods.
+	// +optional
+	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// ServiceSpec overrides service generated for VMSelect.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMSelect pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
+	// Ingress created by operator for VMSelect web endpoints.
+	// Zone local vmselects of storage groups aren't exposed with ingress.
+	// +optional
+	Ingress *EmbeddedIngress `json:"ingress,omitempty"`
 	// Volumes allows configuration of additional volumes on the output Deployment definition.
 	// Volumes specified will be appended to other volumes that are generated as a result of
 	// StorageSpec objects.
@@ -256,6 +391,18 @@ type VMInsert struct {
 	// size.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
 	ReplicaCount *int32 `json:"replicaCount"`
+	// HPA enables HorizontalPodAutoscaler for VMInsert, ReplicaCount is used only for initial creation then.
+	// +optional
+	HPA *EmbeddedHPA `json:"hpa,omitempty"`
+	// PodDisruptionBudget created by operator for VMInsert pods.
+	// +optional
+	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// ServiceSpec overrides service generated for VMInsert.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMInsert pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
 	// Volumes allows configuration of additional volumes on the output Deployment definition.
 	// Volumes specified will be appended to other volumes that are generated as a result of
 	// StorageSpec objects.
@@ -363,6 +510,15 @@ type VMStorage struct {
 	// size.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
 	ReplicaCount *int32 `json:"replicaCount"`
+	// PodDisruptionBudget created by operator for VMStorage pods.
+	// +optional
+	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// ServiceSpec overrides service generated for VMStorage.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMStorage pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
 	// Volumes allows configuration of additional volumes on the output Deployment definition.
 	// Volumes specified will be appended to other volumes that are generated as a result of
 	// StorageSpec objects.
@@ -421,6 +577,11 @@ type VMStorage struct {
 	// its useful for persistent cache
 	// +optional
 	Storage *StorageSpec `json:"storage,omitempty"`
+	// RemovedNodePVCPolicy defines what to do with persistent volume claims of vmstorage nodes removed by scale down.
+	// Retain keeps claims, Delete removes them after node was drained. Default is Retain.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +optional
+	RemovedNodePVCPolicy string `json:"removedNodePVCPolicy,omitempty"`
 
 	// +optional
 	TerminationGracePeriodSeconds int64 `json:"terminationGracePeriodSeconds,omitempty"`
@@ -592,6 +753,54 @@ func (cr VMCluster) VMStoragePodLabels() map[string]string {
 	return labels
 }
 
+// VMStorageGroupSelectorLabels returns selector labels for vmstorage nodes of storage group.
+// Empty group means cluster vmstorage without groups.
+func (cr VMCluster) VMStorageGroupSelectorLabels(group string) map[string]string {
+	return cr.withStorageGroupLabels(cr.VMStorageSelectorLabels(), group)
+}
+
+// VMStorageGroupPodLabels returns labels for vmstorage pods of storage group.
+func (cr VMCluster) VMStorageGroupPodLabels(group string) map[string]string {
+	return cr.withStorageGroupLabels(cr.VMStoragePodLabels(), group)
+}
+
+// VMSelectGroupSelectorLabels returns selector labels for zone local vmselect of storage group.
+// Empty group means global vmselect.
+func (cr VMCluster) VMSelectGroupSelectorLabels(group string) map[string]string {
+	return cr.withStorageGroupLabels(cr.VMSelectSelectorLabels(), group)
+}
+
+// VMSelectGroupPodLabels returns labels for zone local vmselect pods of storage group.
+func (cr VMCluster) VMSelectGroupPodLabels(group string) map[string]string {
+	return cr.withStorageGroupLabels(cr.VMSelectPodLabels(), group)
+}
+
+// VMInsertGroupSelectorLabels returns selector labels for zone local vminsert of storage group.
+// Empty group means cluster vminsert.
+func (cr VMCluster) VMInsertGroupSelectorLabels(group string) map[string]string {
+	return cr.withStorageGroupLabels(cr.VMInsertSelectorLabels(), group)
+}
+
+// VMInsertGroupPodLabels returns labels for zone local vminsert pods of storage group.
+func (cr VMCluster) VMInsertGroupPodLabels(group string) map[string]string {
+	return cr.withStorageGroupLabels(cr.VMInsertPodLabels(), group)
+}
+
+// withStorageGroupLabels adds group label and changes instance label,
+// so selectors of group and cluster components don't overlap.
+func (cr VMCluster) withStorageGroupLabels(labels map[string]string, group string) map[string]string {
+	if group == "" {
+		return labels
+	}
+	withGroup := make(map[string]string, len(labels)+1)
+	for label, value := range labels {
+		withGroup[label] = value
+	}
+	withGroup["app.kubernetes.io/instance"] = cr.Name + "-" + group
+	withGroup[StorageGroupLabel] = group
+	return withGroup
+}
+
 func (cr VMCluster) FinalLabels(baseLabels map[string]string) map[string]string {
 	labels := map[string]string{}
 	if cr.ObjectMeta.Labels != nil {
@@ -639,7 +848,7 @@ func (cr VMCluster) HealthPathSelect() string {
 	if cr.Spec.VMSelect == nil {
 		return healthPath
 	}
-	return buildPathWithPrefixFlag(cr.Spec.VMSelect.ExtraArgs, healthPath)
+	return buildPathWithPrefixFlag(ExtraArgsWithIngressPrefix(cr.Spec.VMSelect.ExtraArgs, cr.Spec.VMSelect.Ingress), healthPath)
 }
 
 func (cr VMCluster) HealthPathInsert() string {
@@ -660,7 +869,7 @@ func (cr VMCluster) MetricPathSelect() string {
 	if cr.Spec.VMSelect == nil {
 		return healthPath
 	}
-	return buildPathWithPrefixFlag(cr.Spec.VMSelect.ExtraArgs, metricPath)
+	return buildPathWithPrefixFlag(ExtraArgsWithIngressPrefix(cr.Spec.VMSelect.ExtraArgs, cr.Spec.VMSelect.Ingress), metricPath)
 }
 
 func (cr VMCluster) MetricPathInsert() string {
@@ -0,0 +1,123 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMCluster.
+func (cr *VMCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmcluster,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmclusters,verbs=create;update,versions=v1beta1,name=vvmcluster.kb.io
+
+var _ webhook.Validator = &VMCluster{}
+
+func (cr *VMCluster) validate() field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	errs = append(errs, validateRetentionPeriod(specPath.Child("retentionPeriod"), cr.Spec.RetentionPeriod)...)
+	if cr.Spec.VMSelect != nil {
+		errs = append(errs, validateComponentReplicaCount(specPath.Child("vmselect", "replicaCount"), cr.Spec.VMSelect.ReplicaCount)...)
+		errs = append(errs, validatePDB(specPath.Child("vmselect", "podDisruptionBudget"), cr.Spec.VMSelect.PodDisruptionBudget)...)
+		errs = append(errs, validateServices(specPath.Child("vmselect"), cr.Spec.VMSelect.ServiceSpec, cr.Spec.VMSelect.ExtraServices, true)...)
+		errs = append(errs, validateIngress(specPath.Child("vmselect", "ingress"), cr.Spec.VMSelect.Ingress, cr.Spec.VMSelect.ExtraArgs[vmPathPrefixFlagName])...)
+		errs = append(errs, validateHPA(specPath.Child("vmselect", "hpa"), cr.Spec.VMSelect.HPA)...)
+	}
+	if cr.Spec.VMInsert != nil {
+		errs = append(errs, validateComponentReplicaCount(specPath.Child("vminsert", "replicaCount"), cr.Spec.VMInsert.ReplicaCount)...)
+		errs = append(errs, validatePDB(specPath.Child("vminsert", "podDisruptionBudget"), cr.Spec.VMInsert.PodDisruptionBudget)...)
+		errs = append(errs, validateServices(specPath.Child("vminsert"), cr.Spec.VMInsert.ServiceSpec, cr.Spec.VMInsert.ExtraServices, false)...)
+		errs = append(errs, validateHPA(specPath.Child("vminsert", "hpa"), cr.Spec.VMInsert.HPA)...)
+	}
+	if cr.Spec.VMStorage != nil {
+		errs = append(errs, validateComponentReplicaCount(specPath.Child("vmstorage", "replicaCount"), cr.Spec.VMStorage.ReplicaCount)...)
+		errs = append(errs, validatePDB(specPath.Child("vmstorage", "podDisruptionBudget"), cr.Spec.VMStorage.PodDisruptionBudget)...)
+		errs = append(errs, validateServices(specPath.Child("vmstorage"), cr.Spec.VMStorage.ServiceSpec, cr.Spec.VMStorage.ExtraServices, true)...)
+	}
+	errs = append(errs, cr.validateStorageGroups(specPath.Child("storageGroups"))...)
+	return errs
+}
+
+func (cr *VMCluster) validateStorageGroups(fldPath *field.Path) field.ErrorList {
+	if len(cr.Spec.StorageGroups) == 0 {
+		return nil
+	}
+	var errs field.ErrorList
+	if cr.Spec.VMStorage == nil {
+		errs = append(errs, field.Required(field.NewPath("spec", "vmstorage"), "vmstorage must be set for storage groups"))
+	}
+	names := make(map[string]struct{}, len(cr.Spec.StorageGroups))
+	for i, group := range cr.Spec.StorageGroups {
+		groupPath := fldPath.Index(i)
+		if group.Name == "" {
+			errs = append(errs, field.Required(groupPath.Child("name"), "name must be set"))
+		}
+		if _, ok := names[group.Name]; ok {
+			errs = append(errs, field.Duplicate(groupPath.Child("name"), group.Name))
+		}
+		names[group.Name] = struct{}{}
+		errs = append(errs, validateReplicaCount(groupPath.Child("replicaCount"), group.ReplicaCount)...)
+		errs = append(errs, validateReplicaCount(groupPath.Child("vmSelectReplicaCount"), group.VMSelectReplicaCount)...)
+		errs = append(errs, validateReplicaCount(groupPath.Child("vmInsertReplicaCount"), group.VMInsertReplicaCount)...)
+	}
+	return errs
+}
+
+// validateStorageGroupsUpdate refuses switching between cluster vmstorage and storage groups,
+// since vmstorage nodes and their data cannot be moved between statefulsets.
+func validateStorageGroupsUpdate(fldPath *field.Path, oldCR, cr *VMCluster) field.ErrorList {
+	if (len(oldCR.Spec.StorageGroups) == 0) != (len(cr.Spec.StorageGroups) == 0) {
+		return field.ErrorList{field.Forbidden(fldPath, "storage groups cannot be added to or removed from existing cluster")}
+	}
+	var errs field.ErrorList
+	oldGroups := make(map[string]*VMStorageGroup, len(oldCR.Spec.StorageGroups))
+	for i := range oldCR.Spec.StorageGroups {
+		oldGroups[oldCR.Spec.StorageGroups[i].Name] = &oldCR.Spec.StorageGroups[i]
+	}
+	for i, group := range cr.Spec.StorageGroups {
+		if oldGroup, ok := oldGroups[group.Name]; ok {
+			errs = append(errs, validateStorageSpecUpdate(fldPath.Index(i).Child("storage"), oldGroup.Storage, group.Storage)...)
+		}
+	}
+	return errs
+}
+
+func validateComponentReplicaCount(fldPath *field.Path, replicas *int32) field.ErrorList {
+	if replicas == nil {
+		return field.ErrorList{field.Required(fldPath, "replicaCount must be set")}
+	}
+	return validateReplicaCount(fldPath, replicas)
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMCluster) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmcluster", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMCluster", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMCluster) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmcluster", cr.Name, "namespace", cr.Namespace)
+	errs := cr.validate()
+	if oldCR, ok := old.(*VMCluster); ok {
+		if oldCR.Spec.VMStorage != nil && cr.Spec.VMStorage != nil {
+			errs = append(errs, validateStorageSpecUpdate(field.NewPath("spec", "vmstorage", "storage"), oldCR.Spec.VMStorage.Storage, cr.Spec.VMStorage.Storage)...)
+		}
+		if oldCR.Spec.VMSelect != nil && cr.Spec.VMSelect != nil {
+			errs = append(errs, validateStorageSpecUpdate(field.NewPath("spec", "vmselect", "persistentVolume"), oldCR.Spec.VMSelect.Storage, cr.Spec.VMSelect.Storage)...)
+		}
+		errs = append(errs, validateStorageGroupsUpdate(field.NewPath("spec", "storageGroups"), oldCR, cr)...)
+	}
+	return toValidationError("VMCluster", cr.Name, errs)
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMCluster) ValidateDelete() error {
+	return nil
+}
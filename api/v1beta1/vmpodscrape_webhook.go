@@ -0,0 +1,57 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMPodScrape.
+func (cr *VMPodScrape) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmpodscrape,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmpodscrapes,verbs=create;update,versions=v1beta1,name=vvmpodscrape.kb.io
+
+var _ webhook.Validator = &VMPodScrape{}
+
+func (cr *VMPodScrape) validate() field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	if _, err := metav1.LabelSelectorAsSelector(&cr.Spec.Selector); err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("selector"), cr.Spec.Selector.String(), err.Error()))
+	}
+	for i, ep := range cr.Spec.PodMetricsEndpoints {
+		epPath := specPath.Child("podMetricsEndpoints").Index(i)
+		if ep.Port != "" && ep.TargetPort != nil {
+			errs = append(errs, field.Forbidden(epPath.Child("targetPort"), "port and targetPort are mutually exclusive"))
+		}
+		errs = append(errs, validateDuration(epPath.Child("interval"), ep.Interval)...)
+		errs = append(errs, validateDuration(epPath.Child("scrapeTimeout"), ep.ScrapeTimeout)...)
+		errs = append(errs, validateOptionalURL(epPath.Child("proxyURL"), ep.ProxyURL)...)
+		errs = append(errs, validateRelabelConfigs(epPath.Child("relabelConfigs"), ep.RelabelConfigs)...)
+		errs = append(errs, validateRelabelConfigs(epPath.Child("metricRelabelConfigs"), ep.MetricRelabelConfigs)...)
+	}
+	return errs
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMPodScrape) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmpodscrape", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMPodScrape", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMPodScrape) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmpodscrape", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMPodScrape", cr.Name, cr.validate())
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMPodScrape) ValidateDelete() error {
+	return nil
+}
@@ -0,0 +1,65 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMProbe.
+func (cr *VMProbe) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmprobe,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmprobes,verbs=create;update,versions=v1beta1,name=vvmprobe.kb.io
+
+var _ webhook.Validator = &VMProbe{}
+
+func (cr *VMProbe) validate() field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	if cr.Spec.VMProberSpec.URL == "" {
+		errs = append(errs, field.Required(specPath.Child("vmProberSpec", "url"), "prober url must be set"))
+	}
+	errs = append(errs, validateDuration(specPath.Child("interval"), cr.Spec.Interval)...)
+	errs = append(errs, validateDuration(specPath.Child("scrapeTimeout"), cr.Spec.ScrapeTimeout)...)
+	targetsPath := specPath.Child("targets")
+	switch {
+	case cr.Spec.Targets.StaticConfig != nil && cr.Spec.Targets.Ingress != nil:
+		errs = append(errs, field.Forbidden(targetsPath, "staticConfig and ingress are mutually exclusive"))
+	case cr.Spec.Targets.StaticConfig == nil && cr.Spec.Targets.Ingress == nil:
+		errs = append(errs, field.Required(targetsPath, "one of staticConfig or ingress must be set"))
+	case cr.Spec.Targets.StaticConfig != nil:
+		if len(cr.Spec.Targets.StaticConfig.Targets) == 0 {
+			errs = append(errs, field.Required(targetsPath.Child("staticConfig", "targets"), "at least one target must be set"))
+		}
+	case cr.Spec.Targets.Ingress != nil:
+		ingressPath := targetsPath.Child("ingress")
+		if _, err := metav1.LabelSelectorAsSelector(&cr.Spec.Targets.Ingress.Selector); err != nil {
+			errs = append(errs, field.Invalid(ingressPath.Child("selector"), cr.Spec.Targets.Ingress.Selector.String(), err.Error()))
+		}
+		errs = append(errs, validateRelabelConfigs(ingressPath.Child("relabelingConfigs"), cr.Spec.Targets.Ingress.RelabelConfigs)...)
+	}
+	return errs
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMProbe) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmprobe", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMProbe", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMProbe) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmprobe", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMProbe", cr.Name, cr.validate())
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMProbe) ValidateDelete() error {
+	return nil
+}
@@ -49,6 +49,30 @@ type Rule struct {
 
 // VMRuleStatus defines the observed state of VMRule
 type VMRuleStatus struct {
+	// LastSyncTime is the last time, when rule was processed by operator
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// VMAlerts is a list of VMAlerts in namespace/name format, that include rule
+	// +optional
+	VMAlerts []string `json:"vmalerts,omitempty"`
+	// Errors lists invalid groups and rules, they are excluded from vmalert configuration
+	// +optional
+	Errors []RuleError `json:"errors,omitempty"`
+}
+
+// RuleError describes invalid group or rule of VMRule
+// +k8s:openapi-gen=true
+type RuleError struct {
+	// VMAlert is a VMAlert in namespace/name format, which excluded group or rule
+	// +optional
+	VMAlert string `json:"vmalert,omitempty"`
+	// Group is a name of rule group
+	Group string `json:"group"`
+	// Rule is a name of alerting or recording rule, empty for group errors
+	// +optional
+	Rule string `json:"rule,omitempty"`
+	// Message describes error
+	Message string `json:"message"`
 }
 
 // VMRule defines rule records for vmalert application
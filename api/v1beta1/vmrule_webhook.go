@@ -0,0 +1,85 @@
+package v1beta1
+
+import (
+	"github.com/VictoriaMetrics/metricsql"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMRule.
+func (cr *VMRule) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmrule,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmrules,verbs=create;update,versions=v1beta1,name=vvmrule.kb.io
+
+var _ webhook.Validator = &VMRule{}
+
+func (cr *VMRule) validate() field.ErrorList {
+	var errs field.ErrorList
+	groupsPath := field.NewPath("spec", "groups")
+	if len(cr.Spec.Groups) == 0 {
+		errs = append(errs, field.Required(groupsPath, "at least one group must be set"))
+	}
+	uniqGroups := make(map[string]struct{}, len(cr.Spec.Groups))
+	for i, group := range cr.Spec.Groups {
+		groupPath := groupsPath.Index(i)
+		if group.Name == "" {
+			errs = append(errs, field.Required(groupPath.Child("name"), "group name must be set"))
+		} else if _, ok := uniqGroups[group.Name]; ok {
+			errs = append(errs, field.Duplicate(groupPath.Child("name"), group.Name))
+		}
+		uniqGroups[group.Name] = struct{}{}
+		errs = append(errs, validateDuration(groupPath.Child("interval"), group.Interval)...)
+		for j, rule := range group.Rules {
+			errs = append(errs, validateRule(groupPath.Child("rules").Index(j), rule)...)
+		}
+	}
+	return errs
+}
+
+func validateRule(fldPath *field.Path, rule Rule) field.ErrorList {
+	var errs field.ErrorList
+	switch {
+	case rule.Alert != "" && rule.Record != "":
+		errs = append(errs, field.Forbidden(fldPath.Child("record"), "only one of alert and record can be set"))
+	case rule.Alert == "" && rule.Record == "":
+		errs = append(errs, field.Required(fldPath.Child("alert"), "one of alert or record must be set"))
+	case rule.Record != "":
+		if !metricNameRe.MatchString(rule.Record) {
+			errs = append(errs, field.Invalid(fldPath.Child("record"), rule.Record, "must be valid metric name"))
+		}
+		if rule.For != "" {
+			errs = append(errs, field.Forbidden(fldPath.Child("for"), "for cannot be set for recording rule"))
+		}
+	}
+	if rule.Expr == (intstr.IntOrString{}) || rule.Expr.String() == "" {
+		errs = append(errs, field.Required(fldPath.Child("expr"), "expr must be set"))
+	} else if _, err := metricsql.Parse(rule.Expr.String()); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("expr"), rule.Expr.String(), err.Error()))
+	}
+	errs = append(errs, validateDuration(fldPath.Child("for"), rule.For)...)
+	return errs
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMRule) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmrule", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMRule", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMRule) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmrule", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMRule", cr.Name, cr.validate())
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMRule) ValidateDelete() error {
+	return nil
+}
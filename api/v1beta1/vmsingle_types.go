@@ -48,6 +48,18 @@ type VMSingleSpec struct {
 	// if you need more - use vm cluster
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Number of pods",xDescriptors="urn:alm:descriptor:com.tectonic.ui:podCount,urn:alm:descriptor:io.kubernetes:custom"
 	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// PodDisruptionBudget created by operator for VMSingle pods.
+	// +optional
+	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// ServiceSpec overrides service generated for VMSingle.
+	// +optional
+	ServiceSpec *ServiceSpec `json:"serviceSpec,omitempty"`
+	// ExtraServices are additional named services, which select VMSingle pods.
+	// +optional
+	ExtraServices []ServiceSpec `json:"extraServices,omitempty"`
+	// Ingress created by operator for VMSingle web endpoints.
+	// +optional
+	Ingress *EmbeddedIngress `json:"ingress,omitempty"`
 
 	// Storage is the definition of how storage will be used by the VMSingle
 	// by default it`s empty dir
@@ -143,6 +155,15 @@ type VMSingleStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas"`
 	// UnavailableReplicas Total number of unavailable pods targeted by this VMAlert cluster.
 	UnavailableReplicas int32 `json:"unavailableReplicas"`
+	// ObservedGeneration is the most recent generation of VMSingle observed by operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of VMSingle: Available, Progressing, ConfigValid and ReconcileFailed.
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+	// LastError is the error message of the last failed reconcile.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
 // VMSingle  is fast, cost-effective and scalable time-series database.
@@ -238,11 +259,11 @@ func (cr VMSingle) PrefixedName() string {
 }
 
 func (cr VMSingle) HealthPath() string {
-	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, healthPath)
+	return buildPathWithPrefixFlag(ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress), healthPath)
 }
 
 func (cr VMSingle) MetricPath() string {
-	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, metricPath)
+	return buildPathWithPrefixFlag(ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress), metricPath)
 }
 
 func init() {
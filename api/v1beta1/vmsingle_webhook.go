@@ -0,0 +1,51 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers validating webhook for VMSingle.
+func (cr *VMSingle) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(cr).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmsingle,mutating=false,failurePolicy=fail,groups=operator.victoriametrics.com,resources=vmsingles,verbs=create;update,versions=v1beta1,name=vvmsingle.kb.io
+
+var _ webhook.Validator = &VMSingle{}
+
+func (cr *VMSingle) validate() field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	errs = append(errs, validateReplicaCount(specPath.Child("replicaCount"), cr.Spec.ReplicaCount)...)
+	errs = append(errs, validatePDB(specPath.Child("podDisruptionBudget"), cr.Spec.PodDisruptionBudget)...)
+	errs = append(errs, validateServices(specPath, cr.Spec.ServiceSpec, cr.Spec.ExtraServices, false)...)
+	errs = append(errs, validateIngress(specPath.Child("ingress"), cr.Spec.Ingress, cr.Spec.ExtraArgs[vmPathPrefixFlagName])...)
+	errs = append(errs, validateRetentionPeriod(specPath.Child("retentionPeriod"), cr.Spec.RetentionPeriod)...)
+	return errs
+}
+
+// ValidateCreate implements webhook.Validator
+func (cr *VMSingle) ValidateCreate() error {
+	webhookLog.Info("validate create", "vmsingle", cr.Name, "namespace", cr.Namespace)
+	return toValidationError("VMSingle", cr.Name, cr.validate())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (cr *VMSingle) ValidateUpdate(old runtime.Object) error {
+	webhookLog.Info("validate update", "vmsingle", cr.Name, "namespace", cr.Namespace)
+	errs := cr.validate()
+	if oldCR, ok := old.(*VMSingle); ok {
+		errs = append(errs, validatePVCSpecUpdate(field.NewPath("spec", "storage"), oldCR.Spec.Storage, cr.Spec.Storage)...)
+	}
+	return toValidationError("VMSingle", cr.Name, errs)
+}
+
+// ValidateDelete implements webhook.Validator
+func (cr *VMSingle) ValidateDelete() error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package v1beta1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VMUserSpec defines the desired state of VMUser
+// +k8s:openapi-gen=true
+type VMUserSpec struct {
+	// UserName basic auth user name for accessing protected endpoint,
+	// metadata.name if missing.
+	// +optional
+	UserName *string `json:"username,omitempty"`
+	// PasswordRef allows to fetch password from user-supplied secret by its name and key.
+	// +optional
+	PasswordRef *v1.SecretKeySelector `json:"passwordRef,omitempty"`
+	// BearerTokenRef allows to fetch bearer token from user-supplied secret by its name and key.
+	// it cannot be combined with passwordRef.
+	// +optional
+	BearerTokenRef *v1.SecretKeySelector `json:"bearerTokenRef,omitempty"`
+	// TargetRefs - reference to endpoints, which user may access.
+	TargetRefs []TargetRef `json:"targetRefs"`
+}
+
+// TargetRef describes target for user traffic forwarding.
+// one of target types can be chosen:
+// crd or static per targetRef.
+// user can define multiple targetRefs with different paths.
+// +k8s:openapi-gen=true
+type TargetRef struct {
+	// CRD describes exist operator's CRD object,
+	// operator generates access url based on CRD params.
+	// +optional
+	CRD *CRDRef `json:"crd,omitempty"`
+	// Static - user defined url for traffic forward.
+	// +optional
+	Static *StaticRef `json:"static,omitempty"`
+	// Paths - matched path to route.
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+	// TargetPathSuffix allows to add some suffix to the target path
+	// for example /select/0/prometheus for vmselect.
+	// +optional
+	TargetPathSuffix string `json:"targetPathSuffix,omitempty"`
+}
+
+// CRDRef describe CRD target reference.
+// +k8s:openapi-gen=true
+type CRDRef struct {
+	// Kind one of:
+	// VMSingle VMCluster/vmselect or VMCluster/vminsert
+	// +kubebuilder:validation:Enum=VMSingle;VMCluster/vmselect;VMCluster/vminsert
+	Kind string `json:"kind"`
+	// Name target CRD object name
+	Name string `json:"name"`
+	// Namespace target CRD object namespace, VMUser namespace if missing.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// StaticRef - user-defined routing host address.
+// +k8s:openapi-gen=true
+type StaticRef struct {
+	// URL http url for given staticRef.
+	URL string `json:"url"`
+}
+
+// VMUserStatus defines the observed state of VMUser
+// +k8s:openapi-gen=true
+type VMUserStatus struct {
+}
+
+// VMUser is the Schema for the vmusers API
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=vmusers,scope=Namespaced
+type VMUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VMUserSpec   `json:"spec,omitempty"`
+	Status VMUserStatus `json:"status,omitempty"`
+}
+
+// VMUserList contains a list of VMUser
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type VMUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VMUser `json:"items"`
+}
+
+// UserName returns name of user for basic auth.
+func (cr VMUser) UserName() string {
+	if cr.Spec.UserName != nil {
+		return *cr.Spec.UserName
+	}
+	return cr.Name
+}
+
+func init() {
+	SchemeBuilder.Register(&VMUser{}, &VMUserList{})
+}
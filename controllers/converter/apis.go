@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"fmt"
 	"strings"
 
 	v1beta1vm "github.com/VictoriaMetrics/operator/api/v1beta1"
@@ -17,10 +18,12 @@ const (
 
 var log = ctrl.Log.WithValues("controller", "prometheus.converter")
 
-func ConvertPromRule(prom *v1.PrometheusRule) *v1beta1vm.VMRule {
-
+// ConvertPromRule converts PrometheusRule into VMRule and returns fields, which were dropped or changed.
+func ConvertPromRule(prom *v1.PrometheusRule) (*v1beta1vm.VMRule, ConversionIssues) {
+	var issues ConversionIssues
 	ruleGroups := []v1beta1vm.RuleGroup{}
-	for _, promGroup := range prom.Spec.Groups {
+	for i, promGroup := range prom.Spec.Groups {
+		issues.dropIf(promGroup.PartialResponseStrategy != "", fmt.Sprintf("spec.groups[%d].partial_response_strategy", i), "thanos partial response isn't supported by VMRule")
 		ruleItems := []v1beta1vm.Rule{}
 		for _, promRuleItem := range promGroup.Rules {
 			ruleItems = append(ruleItems, v1beta1vm.Rule{
@@ -44,21 +47,24 @@ func ConvertPromRule(prom *v1.PrometheusRule) *v1beta1vm.VMRule {
 			Namespace:   prom.Namespace,
 			Name:        prom.Name,
 			Labels:      prom.Labels,
-			Annotations: prom.Annotations,
+			Annotations: issues.annotate(prom.Annotations),
 		},
 		Spec: v1beta1vm.VMRuleSpec{
 			Groups: ruleGroups,
 		},
 	}
-	return cr
+	return cr, issues
 }
 
-func ConvertServiceMonitor(serviceMon *v1.ServiceMonitor) *v1beta1vm.VMServiceScrape {
+// ConvertServiceMonitor converts ServiceMonitor into VMServiceScrape and returns fields, which were dropped or changed.
+func ConvertServiceMonitor(serviceMon *v1.ServiceMonitor) (*v1beta1vm.VMServiceScrape, ConversionIssues) {
+	var issues ConversionIssues
+	endpoints := ConvertEndpoint(serviceMon.Spec.Endpoints, "spec.endpoints", &issues)
 	return &v1beta1vm.VMServiceScrape{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        serviceMon.Name,
 			Namespace:   serviceMon.Namespace,
-			Annotations: serviceMon.Annotations,
+			Annotations: issues.annotate(serviceMon.Annotations),
 			Labels:      serviceMon.Labels,
 		},
 		Spec: v1beta1vm.VMServiceScrapeSpec{
@@ -67,28 +73,31 @@ func ConvertServiceMonitor(serviceMon *v1.ServiceMonitor) *v1beta1vm.VMServiceSc
 			PodTargetLabels: serviceMon.Spec.PodTargetLabels,
 			SampleLimit:     serviceMon.Spec.SampleLimit,
 			Selector:        serviceMon.Spec.Selector,
-			Endpoints:       ConvertEndpoint(serviceMon.Spec.Endpoints),
+			Endpoints:       endpoints,
 			NamespaceSelector: v1beta1vm.NamespaceSelector{
 				Any:        serviceMon.Spec.NamespaceSelector.Any,
 				MatchNames: serviceMon.Spec.NamespaceSelector.MatchNames,
 			},
 		},
-	}
+	}, issues
 }
 
-func replacePromDirPath(origin string) string {
-	if strings.HasPrefix(origin, prometheusSecretDir) {
-		return strings.Replace(origin, prometheusSecretDir, factory.SecretsDir, 1)
-	}
-	if strings.HasPrefix(origin, prometheusConfigmapDir) {
-		return strings.Replace(origin, prometheusConfigmapDir, factory.ConfigMapsDir, 1)
+// replacePromDirPath replaces directories of prometheus-operator secrets and configmaps with VictoriaMetrics ones.
+func replacePromDirPath(origin, path string, issues *ConversionIssues) string {
+	for promDir, vmDir := range map[string]string{prometheusSecretDir: factory.SecretsDir, prometheusConfigmapDir: factory.ConfigMapsDir} {
+		if strings.HasPrefix(origin, promDir) {
+			issues.change(path, fmt.Sprintf("directory %s is replaced with %s", promDir, vmDir))
+			return strings.Replace(origin, promDir, vmDir, 1)
+		}
 	}
 	return origin
 }
 
-func ConvertEndpoint(promEndpoint []v1.Endpoint) []v1beta1vm.Endpoint {
+// ConvertEndpoint converts endpoints of ServiceMonitor, issues are recorded for fields under given path.
+func ConvertEndpoint(promEndpoint []v1.Endpoint, path string, issues *ConversionIssues) []v1beta1vm.Endpoint {
 	endpoints := []v1beta1vm.Endpoint{}
-	for _, endpoint := range promEndpoint {
+	for i, endpoint := range promEndpoint {
+		endpointPath := fmt.Sprintf("%s[%d]", path, i)
 		endpoints = append(endpoints, v1beta1vm.Endpoint{
 			Port:                 endpoint.Port,
 			TargetPort:           endpoint.TargetPort,
@@ -97,13 +106,14 @@ func ConvertEndpoint(promEndpoint []v1.Endpoint) []v1beta1vm.Endpoint {
 			Params:               endpoint.Params,
 			Interval:             endpoint.Interval,
 			ScrapeTimeout:        endpoint.ScrapeTimeout,
-			BearerTokenFile:      replacePromDirPath(endpoint.BearerTokenFile),
+			BearerTokenFile:      replacePromDirPath(endpoint.BearerTokenFile, endpointPath+".bearerTokenFile", issues),
 			BearerTokenSecret:    endpoint.BearerTokenSecret,
 			HonorLabels:          endpoint.HonorLabels,
+			HonorTimestamps:      endpoint.HonorTimestamps,
 			BasicAuth:            ConvertBasicAuth(endpoint.BasicAuth),
-			TLSConfig:            ConvertTlsConfig(endpoint.TLSConfig),
-			MetricRelabelConfigs: ConvertRelabelConfig(endpoint.MetricRelabelConfigs),
-			RelabelConfigs:       ConvertRelabelConfig(endpoint.RelabelConfigs),
+			TLSConfig:            ConvertTlsConfig(endpoint.TLSConfig, endpointPath+".tlsConfig", issues),
+			MetricRelabelConfigs: ConvertRelabelConfig(endpoint.MetricRelabelConfigs, endpointPath+".metricRelabelings", issues),
+			RelabelConfigs:       ConvertRelabelConfig(endpoint.RelabelConfigs, endpointPath+".relabelings", issues),
 			ProxyURL:             endpoint.ProxyURL,
 		})
 	}
@@ -121,17 +131,19 @@ func ConvertBasicAuth(bAuth *v1.BasicAuth) *v1beta1vm.BasicAuth {
 	}
 }
 
-func ConvertTlsConfig(tlsConf *v1.TLSConfig) *v1beta1vm.TLSConfig {
+// ConvertTlsConfig converts tls config, issues are recorded for fields under given path.
+func ConvertTlsConfig(tlsConf *v1.TLSConfig, path string, issues *ConversionIssues) *v1beta1vm.TLSConfig {
 	if tlsConf == nil {
 		return nil
 	}
 	return &v1beta1vm.TLSConfig{
-		CAFile:             replacePromDirPath(tlsConf.CAFile),
+		CAFile:             replacePromDirPath(tlsConf.CAFile, path+".caFile", issues),
 		CA:                 ConvertSecretOrConfigmap(tlsConf.CA),
-		CertFile:           replacePromDirPath(tlsConf.CertFile),
+		CertFile:           replacePromDirPath(tlsConf.CertFile, path+".certFile", issues),
 		Cert:               ConvertSecretOrConfigmap(tlsConf.Cert),
-		KeyFile:            replacePromDirPath(tlsConf.KeyFile),
+		KeyFile:            replacePromDirPath(tlsConf.KeyFile, path+".keyFile", issues),
 		KeySecret:          tlsConf.KeySecret,
+		ServerName:         tlsConf.ServerName,
 		InsecureSkipVerify: tlsConf.InsecureSkipVerify,
 	}
 }
@@ -143,7 +155,8 @@ func ConvertSecretOrConfigmap(promSCM v1.SecretOrConfigMap) v1beta1vm.SecretOrCo
 	}
 }
 
-func ConvertRelabelConfig(promRelabelConfig []*v1.RelabelConfig) []*v1beta1vm.RelabelConfig {
+// ConvertRelabelConfig converts relabel configs, issues are recorded for configs under given path.
+func ConvertRelabelConfig(promRelabelConfig []*v1.RelabelConfig, path string, issues *ConversionIssues) []*v1beta1vm.RelabelConfig {
 	if promRelabelConfig == nil {
 		return nil
 	}
@@ -159,18 +172,21 @@ func ConvertRelabelConfig(promRelabelConfig []*v1.RelabelConfig) []*v1beta1vm.Re
 			Action:       relabel.Action,
 		})
 	}
-	return filterUnsupportedRelabelCfg(relabelCfg)
+	return filterUnsupportedRelabelCfg(relabelCfg, path, issues)
 
 }
 
-func ConvertPodEndpoints(promPodEnpoints []v1.PodMetricsEndpoint) []v1beta1vm.PodMetricsEndpoint {
+// ConvertPodEndpoints converts endpoints of PodMonitor, issues are recorded for fields under given path.
+func ConvertPodEndpoints(promPodEnpoints []v1.PodMetricsEndpoint, path string, issues *ConversionIssues) []v1beta1vm.PodMetricsEndpoint {
 	if promPodEnpoints == nil {
 		return nil
 	}
 	endPoints := []v1beta1vm.PodMetricsEndpoint{}
-	for _, promEndPoint := range promPodEnpoints {
+	for i, promEndPoint := range promPodEnpoints {
+		endpointPath := fmt.Sprintf("%s[%d]", path, i)
 		endPoints = append(endPoints, v1beta1vm.PodMetricsEndpoint{
 			Port:                 promEndPoint.Port,
+			TargetPort:           promEndPoint.TargetPort,
 			Interval:             promEndPoint.Interval,
 			Path:                 promEndPoint.Path,
 			Scheme:               promEndPoint.Scheme,
@@ -179,21 +195,23 @@ func ConvertPodEndpoints(promPodEnpoints []v1.PodMetricsEndpoint) []v1beta1vm.Po
 			HonorLabels:          promEndPoint.HonorLabels,
 			HonorTimestamps:      promEndPoint.HonorTimestamps,
 			ProxyURL:             promEndPoint.ProxyURL,
-			RelabelConfigs:       ConvertRelabelConfig(promEndPoint.RelabelConfigs),
-			MetricRelabelConfigs: ConvertRelabelConfig(promEndPoint.MetricRelabelConfigs),
+			RelabelConfigs:       ConvertRelabelConfig(promEndPoint.RelabelConfigs, endpointPath+".relabelings", issues),
+			MetricRelabelConfigs: ConvertRelabelConfig(promEndPoint.MetricRelabelConfigs, endpointPath+".metricRelabelings", issues),
 		})
 	}
 	return endPoints
 }
 
-func ConvertPodMonitor(podMon *v1.PodMonitor) *v1beta1vm.VMPodScrape {
-
+// ConvertPodMonitor converts PodMonitor into VMPodScrape and returns fields, which were dropped or changed.
+func ConvertPodMonitor(podMon *v1.PodMonitor) (*v1beta1vm.VMPodScrape, ConversionIssues) {
+	var issues ConversionIssues
+	endpoints := ConvertPodEndpoints(podMon.Spec.PodMetricsEndpoints, "spec.podMetricsEndpoints", &issues)
 	return &v1beta1vm.VMPodScrape{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        podMon.Name,
 			Namespace:   podMon.Namespace,
 			Labels:      podMon.Labels,
-			Annotations: podMon.Annotations,
+			Annotations: issues.annotate(podMon.Annotations),
 		},
 		Spec: v1beta1vm.VMPodScrapeSpec{
 			JobLabel:        podMon.Spec.JobLabel,
@@ -204,15 +222,17 @@ func ConvertPodMonitor(podMon *v1.PodMonitor) *v1beta1vm.VMPodScrape {
 				MatchNames: podMon.Spec.NamespaceSelector.MatchNames,
 			},
 			SampleLimit:         podMon.Spec.SampleLimit,
-			PodMetricsEndpoints: ConvertPodEndpoints(podMon.Spec.PodMetricsEndpoints),
+			PodMetricsEndpoints: endpoints,
 		},
-	}
+	}, issues
 }
 
-func ConvertProbe(probe *v1.Probe) *v1beta1vm.VMProbe {
+// ConvertProbe converts Probe into VMProbe and returns fields, which were dropped or changed.
+func ConvertProbe(probe *v1.Probe) (*v1beta1vm.VMProbe, ConversionIssues) {
 	var (
 		ingressTarget *v1beta1vm.ProbeTargetIngress
 		staticTargets *v1beta1vm.VMProbeTargetStaticConfig
+		issues        ConversionIssues
 	)
 	if probe.Spec.Targets.Ingress != nil {
 		ingressTarget = &v1beta1vm.ProbeTargetIngress{
@@ -221,7 +241,7 @@ func ConvertProbe(probe *v1.Probe) *v1beta1vm.VMProbe {
 				Any:        probe.Spec.Targets.Ingress.NamespaceSelector.Any,
 				MatchNames: probe.Spec.Targets.Ingress.NamespaceSelector.MatchNames,
 			},
-			RelabelConfigs: ConvertRelabelConfig(probe.Spec.Targets.Ingress.RelabelConfigs),
+			RelabelConfigs: ConvertRelabelConfig(probe.Spec.Targets.Ingress.RelabelConfigs, "spec.targets.ingress.relabelingConfigs", &issues),
 		}
 	}
 	if probe.Spec.Targets.StaticConfig != nil {
@@ -232,8 +252,10 @@ func ConvertProbe(probe *v1.Probe) *v1beta1vm.VMProbe {
 	}
 	return &v1beta1vm.VMProbe{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      probe.Name,
-			Namespace: probe.Namespace,
+			Name:        probe.Name,
+			Namespace:   probe.Namespace,
+			Labels:      probe.Labels,
+			Annotations: issues.annotate(probe.Annotations),
 		},
 		Spec: v1beta1vm.VMProbeSpec{
 			JobName: probe.Spec.JobName,
@@ -250,16 +272,16 @@ func ConvertProbe(probe *v1.Probe) *v1beta1vm.VMProbe {
 			Interval:      probe.Spec.Interval,
 			ScrapeTimeout: probe.Spec.ScrapeTimeout,
 		},
-	}
+	}, issues
 }
 
-func filterUnsupportedRelabelCfg(relabelCfgs []*v1beta1vm.RelabelConfig) []*v1beta1vm.RelabelConfig {
+func filterUnsupportedRelabelCfg(relabelCfgs []*v1beta1vm.RelabelConfig, path string, issues *ConversionIssues) []*v1beta1vm.RelabelConfig {
 	newRelabelCfg := make([]*v1beta1vm.RelabelConfig, 0, len(relabelCfgs))
-	for _, r := range relabelCfgs {
+	for i, r := range relabelCfgs {
 		switch r.Action {
 		case "keep", "hashmod", "drop":
 			if len(r.SourceLabels) == 0 {
-				log.Info("filtering unsupported relabelConfig", "action", r.Action, "reason", "source labels are empty")
+				issues.drop(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("source labels are empty for action %s", r.Action))
 				continue
 			}
 		}
@@ -6,6 +6,9 @@ import (
 
 	v1beta1vm "github.com/VictoriaMetrics/operator/api/v1beta1"
 	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 )
 
 func TestConvertTlsConfig(t *testing.T) {
@@ -13,9 +16,10 @@ func TestConvertTlsConfig(t *testing.T) {
 		tlsConf *v1.TLSConfig
 	}
 	tests := []struct {
-		name string
-		args args
-		want *v1beta1vm.TLSConfig
+		name       string
+		args       args
+		want       *v1beta1vm.TLSConfig
+		wantIssues ConversionIssues
 	}{
 		{
 			name: "replace prom secret path",
@@ -31,14 +35,22 @@ func TestConvertTlsConfig(t *testing.T) {
 				CertFile: "/etc/vm/secrets/cert.crt",
 				KeyFile:  "/etc/vm/configs/key.pem",
 			},
+			wantIssues: ConversionIssues{
+				{Field: "tlsConfig.certFile", Action: IssueChanged, Reason: "directory /etc/prometheus/secrets is replaced with /etc/vm/secrets"},
+				{Field: "tlsConfig.keyFile", Action: IssueChanged, Reason: "directory /etc/prometheus/configmaps is replaced with /etc/vm/configs"},
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ConvertTlsConfig(tt.args.tlsConf)
+			var issues ConversionIssues
+			got := ConvertTlsConfig(tt.args.tlsConf, "tlsConfig", &issues)
 			if got.KeyFile != tt.want.KeyFile || got.CertFile != tt.want.CertFile || got.CAFile != tt.want.CAFile {
 				t.Errorf("ConvertTlsConfig() = \n%v, \nwant \n%v", got, tt.want)
 			}
+			if !reflect.DeepEqual(issues, tt.wantIssues) {
+				t.Errorf("ConvertTlsConfig() issues = %v, want %v", issues, tt.wantIssues)
+			}
 		})
 	}
 }
@@ -48,9 +60,10 @@ func TestConvertRelabelConfig(t *testing.T) {
 		promRelabelConfig []*v1.RelabelConfig
 	}
 	tests := []struct {
-		name string
-		args args
-		want []*v1beta1vm.RelabelConfig
+		name       string
+		args       args
+		want       []*v1beta1vm.RelabelConfig
+		wantIssues ConversionIssues
 	}{
 		{
 			name: "test empty cfg",
@@ -93,11 +106,18 @@ func TestConvertRelabelConfig(t *testing.T) {
 					SourceLabels: []string{"__address__"},
 				},
 			},
+			wantIssues: ConversionIssues{
+				{Field: "relabelings[0]", Action: IssueDropped, Reason: "source labels are empty for action drop"},
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ConvertRelabelConfig(tt.args.promRelabelConfig)
+			var issues ConversionIssues
+			got := ConvertRelabelConfig(tt.args.promRelabelConfig, "relabelings", &issues)
+			if !reflect.DeepEqual(issues, tt.wantIssues) {
+				t.Errorf("ConvertRelabelConfig() issues = %v, want %v", issues, tt.wantIssues)
+			}
 			if len(got) != len(tt.want) {
 				t.Fatalf("len of relabelConfigs mismatch, want: %d, got %d", len(tt.want), len(got))
 			}
@@ -115,9 +135,10 @@ func TestConvertEndpoint(t *testing.T) {
 		promEndpoint []v1.Endpoint
 	}
 	tests := []struct {
-		name string
-		args args
-		want []v1beta1vm.Endpoint
+		name       string
+		args       args
+		want       []v1beta1vm.Endpoint
+		wantIssues ConversionIssues
 	}{
 		{
 			name: "convert endpoint with relabel config",
@@ -150,13 +171,42 @@ func TestConvertEndpoint(t *testing.T) {
 					},
 				},
 			},
+			wantIssues: ConversionIssues{
+				{Field: "spec.endpoints[0].relabelings[1]", Action: IssueDropped, Reason: "source labels are empty for action keep"},
+			},
+		},
+		{
+			name: "convert endpoint with bearer token file",
+			args: args{
+				promEndpoint: []v1.Endpoint{
+					{
+						Port:            "9100",
+						BearerTokenFile: "/etc/prometheus/secrets/token/value",
+						HonorTimestamps: pointer.BoolPtr(false),
+					},
+				},
+			},
+			want: []v1beta1vm.Endpoint{
+				{
+					Port:            "9100",
+					BearerTokenFile: "/etc/vm/secrets/token/value",
+					HonorTimestamps: pointer.BoolPtr(false),
+				},
+			},
+			wantIssues: ConversionIssues{
+				{Field: "spec.endpoints[0].bearerTokenFile", Action: IssueChanged, Reason: "directory /etc/prometheus/secrets is replaced with /etc/vm/secrets"},
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ConvertEndpoint(tt.args.promEndpoint); !reflect.DeepEqual(got, tt.want) {
+			var issues ConversionIssues
+			if got := ConvertEndpoint(tt.args.promEndpoint, "spec.endpoints", &issues); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("ConvertEndpoint() = %v, want %v", got, tt.want)
 			}
+			if !reflect.DeepEqual(issues, tt.wantIssues) {
+				t.Errorf("ConvertEndpoint() issues = %v, want %v", issues, tt.wantIssues)
+			}
 		})
 	}
 }
@@ -166,9 +216,10 @@ func TestConvertServiceMonitor(t *testing.T) {
 		serviceMon *v1.ServiceMonitor
 	}
 	tests := []struct {
-		name string
-		args args
-		want v1beta1vm.VMServiceScrape
+		name       string
+		args       args
+		want       v1beta1vm.VMServiceScrape
+		wantIssues ConversionIssues
 	}{
 		{
 			name: "with metricsRelabelConfig",
@@ -203,12 +254,148 @@ func TestConvertServiceMonitor(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "with unsupported metricsRelabelConfig",
+			args: args{
+				serviceMon: &v1.ServiceMonitor{
+					ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"team": "infra"}},
+					Spec: v1.ServiceMonitorSpec{
+						Endpoints: []v1.Endpoint{
+							{Port: "http"},
+							{
+								Port: "metrics",
+								MetricRelabelConfigs: []*v1.RelabelConfig{
+									{Action: "hashmod"},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: v1beta1vm.VMServiceScrape{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					"team":                     "infra",
+					ConversionIssuesAnnotation: `[{"field":"spec.endpoints[1].metricRelabelings[0]","action":"dropped","reason":"source labels are empty for action hashmod"}]`,
+				}},
+				Spec: v1beta1vm.VMServiceScrapeSpec{
+					Endpoints: []v1beta1vm.Endpoint{
+						{Port: "http"},
+						{Port: "metrics", MetricRelabelConfigs: []*v1beta1vm.RelabelConfig{}},
+					},
+				},
+			},
+			wantIssues: ConversionIssues{
+				{Field: "spec.endpoints[1].metricRelabelings[0]", Action: IssueDropped, Reason: "source labels are empty for action hashmod"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ConvertServiceMonitor(tt.args.serviceMon); !reflect.DeepEqual(*got, tt.want) {
+			got, issues := ConvertServiceMonitor(tt.args.serviceMon)
+			if !reflect.DeepEqual(*got, tt.want) {
 				t.Errorf("ConvertServiceMonitor() = %v, want %v", got, tt.want)
 			}
+			if !reflect.DeepEqual(issues, tt.wantIssues) {
+				t.Errorf("ConvertServiceMonitor() issues = %v, want %v", issues, tt.wantIssues)
+			}
+			if _, ok := tt.args.serviceMon.Annotations[ConversionIssuesAnnotation]; ok {
+				t.Errorf("ConvertServiceMonitor() must not modify annotations of source")
+			}
+		})
+	}
+}
+
+func TestConvertPromRule(t *testing.T) {
+	prom := &v1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1.PrometheusRuleSpec{
+			Groups: []v1.RuleGroup{
+				{Name: "first", Rules: []v1.Rule{{Record: "up:sum", Expr: intstr.FromString("sum(up)")}}},
+				{Name: "second", PartialResponseStrategy: "warn", Rules: []v1.Rule{{Alert: "down", Expr: intstr.FromString("up == 0")}}},
+			},
+		},
+	}
+	got, issues := ConvertPromRule(prom)
+	if len(got.Spec.Groups) != 2 || got.Spec.Groups[1].Rules[0].Expr.String() != "up == 0" {
+		t.Fatalf("ConvertPromRule() unexpected groups: %v", got.Spec.Groups)
+	}
+	wantIssues := ConversionIssues{
+		{Field: "spec.groups[1].partial_response_strategy", Action: IssueDropped, Reason: "thanos partial response isn't supported by VMRule"},
+	}
+	if !reflect.DeepEqual(issues, wantIssues) {
+		t.Errorf("ConvertPromRule() issues = %v, want %v", issues, wantIssues)
+	}
+	if got.Annotations[ConversionIssuesAnnotation] == "" {
+		t.Errorf("ConvertPromRule() issues annotation must be set: %v", got.Annotations)
+	}
+}
+
+func TestConvertPodMonitor(t *testing.T) {
+	podMon := &v1.PodMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1.PodMonitorSpec{
+			PodMetricsEndpoints: []v1.PodMetricsEndpoint{
+				{
+					TargetPort:     &intstr.IntOrString{Type: intstr.Int, IntVal: 8080},
+					RelabelConfigs: []*v1.RelabelConfig{{Action: "drop"}},
+				},
+			},
+		},
+	}
+	got, issues := ConvertPodMonitor(podMon)
+	if len(got.Spec.PodMetricsEndpoints) != 1 || got.Spec.PodMetricsEndpoints[0].TargetPort.IntValue() != 8080 {
+		t.Fatalf("ConvertPodMonitor() unexpected endpoints: %v", got.Spec.PodMetricsEndpoints)
+	}
+	wantIssues := ConversionIssues{
+		{Field: "spec.podMetricsEndpoints[0].relabelings[0]", Action: IssueDropped, Reason: "source labels are empty for action drop"},
+	}
+	if !reflect.DeepEqual(issues, wantIssues) {
+		t.Errorf("ConvertPodMonitor() issues = %v, want %v", issues, wantIssues)
+	}
+}
+
+func TestConvertProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		probe      *v1.Probe
+		wantIssues ConversionIssues
+	}{
+		{
+			name: "static targets",
+			probe: &v1.Probe{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+				Spec: v1.ProbeSpec{
+					Targets: v1.ProbeTargets{StaticConfig: &v1.ProbeTargetStaticConfig{Targets: []string{"example.com"}}},
+				},
+			},
+		},
+		{
+			name: "ingress targets with unsupported relabeling",
+			probe: &v1.Probe{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+				Spec: v1.ProbeSpec{
+					Targets: v1.ProbeTargets{Ingress: &v1.ProbeTargetIngress{
+						RelabelConfigs: []*v1.RelabelConfig{
+							{Action: "keep", SourceLabels: []string{"__address__"}},
+							{Action: "keep"},
+						},
+					}},
+				},
+			},
+			wantIssues: ConversionIssues{
+				{Field: "spec.targets.ingress.relabelingConfigs[1]", Action: IssueDropped, Reason: "source labels are empty for action keep"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, issues := ConvertProbe(tt.probe)
+			if !reflect.DeepEqual(issues, tt.wantIssues) {
+				t.Errorf("ConvertProbe() issues = %v, want %v", issues, tt.wantIssues)
+			}
+			if _, ok := got.Annotations[ConversionIssuesAnnotation]; ok != (len(tt.wantIssues) > 0) {
+				t.Errorf("ConvertProbe() unexpected annotations: %v", got.Annotations)
+			}
 		})
 	}
 }
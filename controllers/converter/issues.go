@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConversionIssuesAnnotation contains fields of prometheus-operator object,
+// which were dropped or changed during conversion into VictoriaMetrics object.
+// Value is json encoded ConversionIssues.
+const ConversionIssuesAnnotation = "operator.victoriametrics.com/conversion-issues"
+
+const (
+	// IssueDropped - field cannot be expressed by VictoriaMetrics object and was dropped.
+	IssueDropped = "dropped"
+	// IssueChanged - field was converted with different value.
+	IssueChanged = "changed"
+)
+
+// ConversionIssue describes field of prometheus-operator object, which was dropped or changed during conversion.
+type ConversionIssue struct {
+	// Field is path of field at prometheus-operator object, e.g. spec.endpoints[0].relabelConfigs[1]
+	Field string `json:"field"`
+	// Action is dropped or changed
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// ConversionIssues is a list of dropped or changed fields.
+type ConversionIssues []ConversionIssue
+
+func (ci *ConversionIssues) drop(field, reason string) {
+	if ci == nil {
+		return
+	}
+	*ci = append(*ci, ConversionIssue{Field: field, Action: IssueDropped, Reason: reason})
+}
+
+func (ci *ConversionIssues) dropIf(set bool, field, reason string) {
+	if set {
+		ci.drop(field, reason)
+	}
+}
+
+func (ci *ConversionIssues) change(field, reason string) {
+	if ci == nil {
+		return
+	}
+	*ci = append(*ci, ConversionIssue{Field: field, Action: IssueChanged, Reason: reason})
+}
+
+// String returns human readable issues, it's used for events.
+func (ci ConversionIssues) String() string {
+	messages := make([]string, 0, len(ci))
+	for _, issue := range ci {
+		messages = append(messages, fmt.Sprintf("%s %s: %s", issue.Field, issue.Action, issue.Reason))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// annotate adds issues to annotations of converted object,
+// annotations are copied, since they're shared with prometheus-operator object.
+func (ci ConversionIssues) annotate(annotations map[string]string) map[string]string {
+	if len(ci) == 0 {
+		return annotations
+	}
+	sort.SliceStable(ci, func(i, j int) bool {
+		return ci[i].Field < ci[j].Field
+	})
+	merged := make(map[string]string, len(annotations)+1)
+	for annotation, value := range annotations {
+		merged[annotation] = value
+	}
+	// issues contain only strings, so marshaling cannot fail
+	data, _ := json.Marshal(ci)
+	merged[ConversionIssuesAnnotation] = string(data)
+	return merged
+}
@@ -0,0 +1,433 @@
+package converter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	v1beta1vm "github.com/VictoriaMetrics/operator/api/v1beta1"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// selectNothingLabel is used for label selector, which doesn't match any object.
+	// prometheus-operator doesn't select objects with nil selector, but VictoriaMetrics objects select all of them.
+	selectNothingLabel = "operator.victoriametrics.com/prometheus-selector-not-set"
+
+	// defaultAlertmanagerPort is the port of prometheus-operator alertmanager service with name web.
+	defaultAlertmanagerPort = 9093
+)
+
+// prometheus-operator manages these containers by itself,
+// containers with the same name at spec patch generated containers.
+var (
+	prometheusContainers   = map[string]struct{}{"prometheus": {}, "config-reloader": {}, "prometheus-config-reloader": {}, "rules-configmap-reloader": {}, "thanos-sidecar": {}}
+	alertmanagerContainers = map[string]struct{}{"alertmanager": {}, "config-reloader": {}}
+)
+
+// ConvertPrometheus converts Prometheus into VMAgent, which scrapes targets and writes metrics to remote storage,
+// and returns fields, which were dropped or changed.
+// VMAgent is nil, if Prometheus stores metrics only locally, since VMAgent requires remote write.
+// Rules evaluation is converted into VMAlert by ConvertPrometheusRules.
+func ConvertPrometheus(prom *v1.Prometheus) (*v1beta1vm.VMAgent, ConversionIssues) {
+	var issues ConversionIssues
+	spec := &prom.Spec
+	if len(spec.RemoteWrite) == 0 {
+		issues.drop("spec.remoteWrite", "VMAgent requires remote write, Prometheus with local storage only cannot be converted")
+		return nil, issues
+	}
+
+	remoteWrite := make([]v1beta1vm.VMAgentRemoteWriteSpec, 0, len(spec.RemoteWrite))
+	for i, rw := range spec.RemoteWrite {
+		rwPath := fmt.Sprintf("spec.remoteWrite[%d]", i)
+		vmrw := v1beta1vm.VMAgentRemoteWriteSpec{
+			URL:       rw.URL,
+			BasicAuth: ConvertBasicAuth(rw.BasicAuth),
+			TLSConfig: ConvertTlsConfig(rw.TLSConfig, rwPath+".tlsConfig", &issues),
+		}
+		if rw.RemoteTimeout != "" {
+			timeout := rw.RemoteTimeout
+			vmrw.SendTimeout = &timeout
+		}
+		if rw.QueueConfig != nil && rw.QueueConfig.MaxShards > 0 {
+			queues := int32(rw.QueueConfig.MaxShards)
+			vmrw.Queues = &queues
+		}
+		issues.dropIf(rw.BearerToken != "", rwPath+".bearerToken", "bearer token isn't supported by VMAgent remote write")
+		issues.dropIf(rw.BearerTokenFile != "", rwPath+".bearerTokenFile", "bearer token isn't supported by VMAgent remote write")
+		issues.dropIf(len(rw.WriteRelabelConfigs) > 0, rwPath+".writeRelabelConfigs", "relabeling per remote write isn't supported by VMAgent")
+		issues.dropIf(rw.ProxyURL != "", rwPath+".proxyUrl", "proxy isn't supported by VMAgent remote write")
+		remoteWrite = append(remoteWrite, vmrw)
+	}
+
+	containers, skipped := filterContainers(spec.Containers, prometheusContainers)
+	for _, name := range skipped {
+		issues.drop(fmt.Sprintf("spec.containers[%s]", name), "container is managed by prometheus-operator")
+	}
+	if spec.LogLevel == "debug" {
+		issues.change("spec.logLevel", "debug level isn't supported, INFO is used")
+	}
+
+	vmAgent := &v1beta1vm.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      prom.Name,
+			Namespace: prom.Namespace,
+			Labels:    prom.Labels,
+		},
+		Spec: v1beta1vm.VMAgentSpec{
+			PodMetadata:                    convertPodMetadata(spec.PodMetadata),
+			ImagePullSecrets:               spec.ImagePullSecrets,
+			Secrets:                        spec.Secrets,
+			ConfigMaps:                     spec.ConfigMaps,
+			LogLevel:                       convertLogLevel(spec.LogLevel),
+			LogFormat:                      convertLogFormat(spec.LogFormat),
+			ReplicaCount:                   spec.Replicas,
+			Volumes:                        spec.Volumes,
+			VolumeMounts:                   spec.VolumeMounts,
+			Resources:                      spec.Resources,
+			Affinity:                       spec.Affinity,
+			Tolerations:                    spec.Tolerations,
+			SecurityContext:                spec.SecurityContext,
+			ServiceAccountName:             spec.ServiceAccountName,
+			Containers:                     containers,
+			InitContainers:                 spec.InitContainers,
+			PriorityClassName:              spec.PriorityClassName,
+			ScrapeInterval:                 spec.ScrapeInterval,
+			APIServerConfig:                convertAPIServerConfig(spec.APIServerConfig, &issues),
+			OverrideHonorLabels:            spec.OverrideHonorLabels,
+			OverrideHonorTimestamps:        spec.OverrideHonorTimestamps,
+			IgnoreNamespaceSelectors:       spec.IgnoreNamespaceSelectors,
+			EnforcedNamespaceLabel:         spec.EnforcedNamespaceLabel,
+			VMAgentExternalLabelName:       spec.PrometheusExternalLabelName,
+			ExternalLabels:                 spec.ExternalLabels,
+			RemoteWrite:                    remoteWrite,
+			ServiceScrapeSelector:          convertSelector(spec.ServiceMonitorSelector),
+			ServiceScrapeNamespaceSelector: spec.ServiceMonitorNamespaceSelector,
+			PodScrapeSelector:              convertSelector(spec.PodMonitorSelector),
+			PodScrapeNamespaceSelector:     spec.PodMonitorNamespaceSelector,
+			ProbeSelector:                  convertSelector(spec.ProbeSelector),
+			ProbeNamespaceSelector:         spec.ProbeNamespaceSelector,
+			AdditionalScrapeConfigs:        spec.AdditionalScrapeConfigs,
+			ArbitraryFSAccessThroughSMs:    v1beta1vm.ArbitraryFSAccessThroughSMsConfig{Deny: spec.ArbitraryFSAccessThroughSMs.Deny},
+		},
+	}
+	issues.dropIf(spec.ScrapeTimeout != "", "spec.scrapeTimeout", "global scrape timeout isn't supported by VMAgent")
+	issues.dropIf(len(spec.NodeSelector) > 0, "spec.nodeSelector", "not supported by VMAgent")
+	issues.dropIf(spec.Storage != nil, "spec.storage", "VMAgent doesn't store metrics locally")
+	issues.dropIf(spec.Retention != "", "spec.retention", "VMAgent doesn't store metrics locally")
+	issues.dropIf(spec.RetentionSize != "", "spec.retentionSize", "VMAgent doesn't store metrics locally")
+	issues.dropIf(spec.WALCompression != nil, "spec.walCompression", "VMAgent doesn't store metrics locally")
+	issues.dropIf(spec.DisableCompaction, "spec.disableCompaction", "VMAgent doesn't store metrics locally")
+	issues.dropIf(spec.AllowOverlappingBlocks, "spec.allowOverlappingBlocks", "VMAgent doesn't store metrics locally")
+	issues.dropIf(spec.Query != nil, "spec.query", "VMAgent doesn't serve queries")
+	issues.dropIf(spec.QueryLogFile != "", "spec.queryLogFile", "VMAgent doesn't serve queries")
+	issues.dropIf(spec.EnableAdminAPI, "spec.enableAdminAPI", "VMAgent doesn't have admin api")
+	issues.dropIf(len(spec.RemoteRead) > 0, "spec.remoteRead", "VMAgent doesn't serve queries")
+	issues.dropIf(spec.Thanos != nil, "spec.thanos", "thanos sidecar isn't supported")
+	issues.dropIf(spec.ListenLocal, "spec.listenLocal", "not supported by VMAgent")
+	issues.dropIf(spec.RoutePrefix != "", "spec.routePrefix", "not supported by VMAgent")
+	issues.dropIf(spec.ReplicaExternalLabelName != nil, "spec.replicaExternalLabelName", "not supported by VMAgent")
+	issues.dropIf(spec.EnforcedSampleLimit != nil, "spec.enforcedSampleLimit", "not supported by VMAgent")
+	issues.dropIf(spec.Paused, "spec.paused", "not supported by VMAgent")
+	if !prometheusRulesConvertible(prom) {
+		issues.dropIf(spec.RuleSelector != nil, "spec.ruleSelector", "VMAlert requires remoteWrite with VictoriaMetrics single node url and alertmanager endpoint")
+	}
+	vmAgent.ObjectMeta.Annotations = issues.annotate(prom.Annotations)
+	return vmAgent, issues
+}
+
+// prometheusRulesConvertible checks if rules evaluation of Prometheus can be converted into VMAlert.
+func prometheusRulesConvertible(prom *v1.Prometheus) bool {
+	spec := &prom.Spec
+	if spec.RuleSelector == nil || len(spec.RemoteWrite) == 0 || spec.Alerting == nil || len(spec.Alerting.Alertmanagers) == 0 {
+		return false
+	}
+	return datasourceURLFromRemoteWrite(spec.RemoteWrite[0].URL) != "" && notifierURLFromAlertmanager(spec.Alerting.Alertmanagers[0], prom.Namespace) != ""
+}
+
+// ConvertPrometheusRules converts rules evaluation of Prometheus into VMAlert and returns fields, which were dropped or changed.
+// Datasource of VMAlert is built from the first remoteWrite of Prometheus,
+// notifier is built from the first alertmanager endpoint.
+// VMAlert is nil, if Prometheus doesn't select rules or its datasource and notifier cannot be built.
+func ConvertPrometheusRules(prom *v1.Prometheus) (*v1beta1vm.VMAlert, ConversionIssues) {
+	if !prometheusRulesConvertible(prom) {
+		return nil, nil
+	}
+	var issues ConversionIssues
+	spec := &prom.Spec
+	notifier := spec.Alerting.Alertmanagers[0]
+	for i := range spec.Alerting.Alertmanagers[1:] {
+		issues.drop(fmt.Sprintf("spec.alerting.alertmanagers[%d]", i+1), "VMAlert supports single notifier")
+	}
+	issues.dropIf(notifier.BearerTokenFile != "", "spec.alerting.alertmanagers[0].bearerTokenFile", "bearer token isn't supported by VMAlert notifier")
+	issues.dropIf(spec.Rules.Alert.ForOutageTolerance != "" || spec.Rules.Alert.ForGracePeriod != "" || spec.Rules.Alert.ResendDelay != "", "spec.rules", "not supported by VMAlert")
+	issues.dropIf(len(spec.ExternalLabels) > 0, "spec.externalLabels", "not supported by VMAlert")
+	issues.dropIf(spec.AdditionalAlertRelabelConfigs != nil, "spec.additionalAlertRelabelConfigs", "not supported by VMAlert")
+	issues.dropIf(spec.AdditionalAlertManagerConfigs != nil, "spec.additionalAlertManagerConfigs", "not supported by VMAlert")
+	issues.dropIf(len(spec.PrometheusRulesExcludedFromEnforce) > 0, "spec.prometheusRulesExcludedFromEnforce", "not supported by VMAlert")
+	if spec.LogLevel == "debug" {
+		issues.change("spec.logLevel", "debug level isn't supported, INFO is used")
+	}
+
+	rw := spec.RemoteWrite[0]
+	datasourceURL := datasourceURLFromRemoteWrite(rw.URL)
+	rwTLSPath := "spec.remoteWrite[0].tlsConfig"
+	vmAlert := &v1beta1vm.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      prom.Name,
+			Namespace: prom.Namespace,
+			Labels:    prom.Labels,
+		},
+		Spec: v1beta1vm.VMAlertSpec{
+			PodMetadata:            convertPodMetadata(spec.PodMetadata),
+			ImagePullSecrets:       spec.ImagePullSecrets,
+			LogLevel:               convertLogLevel(spec.LogLevel),
+			LogFormat:              convertLogFormat(spec.LogFormat),
+			Affinity:               spec.Affinity,
+			Tolerations:            spec.Tolerations,
+			SecurityContext:        spec.SecurityContext,
+			ServiceAccountName:     spec.ServiceAccountName,
+			PriorityClassName:      spec.PriorityClassName,
+			EvaluationInterval:     spec.EvaluationInterval,
+			EnforcedNamespaceLabel: spec.EnforcedNamespaceLabel,
+			RuleSelector:           convertSelector(spec.RuleSelector),
+			RuleNamespaceSelector:  spec.RuleNamespaceSelector,
+			Datasource: v1beta1vm.VMAlertDatasourceSpec{
+				URL:       datasourceURL,
+				BasicAuth: ConvertBasicAuth(rw.BasicAuth),
+				TLSConfig: ConvertTlsConfig(rw.TLSConfig, rwTLSPath, &issues),
+			},
+			// results of recording rules and alerts state are written into the same storage,
+			// issues of tls config are already recorded for datasource
+			RemoteWrite: &v1beta1vm.VMAlertRemoteWriteSpec{
+				URL:       datasourceURL,
+				BasicAuth: ConvertBasicAuth(rw.BasicAuth),
+				TLSConfig: ConvertTlsConfig(rw.TLSConfig, rwTLSPath, nil),
+			},
+			Notifier: v1beta1vm.VMAlertNotifierSpec{
+				URL:       notifierURLFromAlertmanager(notifier, prom.Namespace),
+				TLSConfig: ConvertTlsConfig(notifier.TLSConfig, "spec.alerting.alertmanagers[0].tlsConfig", &issues),
+			},
+		},
+	}
+	if spec.ExternalURL != "" {
+		vmAlert.Spec.ExtraArgs = map[string]string{"external.url": spec.ExternalURL}
+	}
+	vmAlert.ObjectMeta.Annotations = issues.annotate(prom.Annotations)
+	return vmAlert, issues
+}
+
+// datasourceURLFromRemoteWrite returns url of single node VictoriaMetrics, which accepts remote write.
+// Cluster version accepts writes at vminsert and serves queries at vmselect, so it cannot be used as datasource.
+func datasourceURLFromRemoteWrite(remoteWriteURL string) string {
+	u, err := url.Parse(remoteWriteURL)
+	if err != nil || !strings.HasSuffix(u.Path, "/api/v1/write") || strings.Contains(u.Path, "/insert/") {
+		return ""
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/api/v1/write")
+	return u.String()
+}
+
+// notifierURLFromAlertmanager returns url of alertmanager service.
+// Named port is supported only for default alertmanager port web.
+func notifierURLFromAlertmanager(am v1.AlertmanagerEndpoints, namespace string) string {
+	var port int
+	switch {
+	case am.Port.Type == intstr.Int:
+		port = am.Port.IntValue()
+	case am.Port.StrVal == "web":
+		port = defaultAlertmanagerPort
+	default:
+		p, err := strconv.Atoi(am.Port.StrVal)
+		if err != nil {
+			return ""
+		}
+		port = p
+	}
+	if am.Namespace != "" {
+		namespace = am.Namespace
+	}
+	scheme := am.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s.%s.svc:%d%s", scheme, am.Name, namespace, port, am.PathPrefix)
+}
+
+// ConvertAlertmanager converts Alertmanager into VMAlertmanager and returns fields, which were dropped or changed.
+// VMAlertmanager uses config secret of Alertmanager, if it isn't set explicitly.
+func ConvertAlertmanager(am *v1.Alertmanager) (*v1beta1vm.VMAlertmanager, ConversionIssues) {
+	var issues ConversionIssues
+	spec := &am.Spec
+	containers, skipped := filterContainers(spec.Containers, alertmanagerContainers)
+	for _, name := range skipped {
+		issues.drop(fmt.Sprintf("spec.containers[%s]", name), "container is managed by prometheus-operator")
+	}
+	configSecret := spec.ConfigSecret
+	if configSecret == "" {
+		// default config secret of prometheus-operator
+		configSecret = "alertmanager-" + am.Name
+	}
+	issues.dropIf(spec.SHA != "", "spec.sha", "image digest isn't supported by VMAlertmanager")
+	if spec.BaseImage != "" {
+		issues.change("spec.baseImage", "base image is ignored, image and tag are used")
+	}
+
+	return &v1beta1vm.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        am.Name,
+			Namespace:   am.Namespace,
+			Labels:      am.Labels,
+			Annotations: issues.annotate(am.Annotations),
+		},
+		Spec: v1beta1vm.VMAlertmanagerSpec{
+			PodMetadata:             convertPodMetadata(spec.PodMetadata),
+			Image:                   convertAlertmanagerImage(spec),
+			ImagePullSecrets:        spec.ImagePullSecrets,
+			Secrets:                 spec.Secrets,
+			ConfigMaps:              spec.ConfigMaps,
+			ConfigSecret:            configSecret,
+			LogLevel:                spec.LogLevel,
+			LogFormat:               spec.LogFormat,
+			ReplicaCount:            spec.Replicas,
+			Retention:               spec.Retention,
+			Storage:                 convertStorage(spec.Storage),
+			Volumes:                 spec.Volumes,
+			VolumeMounts:            spec.VolumeMounts,
+			ExternalURL:             spec.ExternalURL,
+			RoutePrefix:             spec.RoutePrefix,
+			Paused:                  spec.Paused,
+			NodeSelector:            spec.NodeSelector,
+			Resources:               spec.Resources,
+			Affinity:                spec.Affinity,
+			Tolerations:             spec.Tolerations,
+			SecurityContext:         spec.SecurityContext,
+			ServiceAccountName:      spec.ServiceAccountName,
+			ListenLocal:             spec.ListenLocal,
+			Containers:              containers,
+			InitContainers:          spec.InitContainers,
+			PriorityClassName:       spec.PriorityClassName,
+			AdditionalPeers:         spec.AdditionalPeers,
+			ClusterAdvertiseAddress: spec.ClusterAdvertiseAddress,
+			PortName:                spec.PortName,
+		},
+	}, issues
+}
+
+// convertAlertmanagerImage converts image of alertmanager,
+// default VMAlertmanager image is used, if it isn't set.
+func convertAlertmanagerImage(spec *v1.AlertmanagerSpec) v1beta1vm.Image {
+	var image v1beta1vm.Image
+	if spec.Image != nil && *spec.Image != "" {
+		image.Repository = *spec.Image
+		// tag is separated by the last colon after registry host and port
+		if i := strings.LastIndex(*spec.Image, ":"); i > strings.LastIndex(*spec.Image, "/") {
+			image.Repository, image.Tag = (*spec.Image)[:i], (*spec.Image)[i+1:]
+		}
+	}
+	if image.Tag == "" {
+		image.Tag = spec.Tag
+	}
+	if image.Tag == "" {
+		image.Tag = spec.Version
+	}
+	return image
+}
+
+func convertPodMetadata(meta *v1.EmbeddedObjectMetadata) *v1beta1vm.EmbeddedObjectMetadata {
+	if meta == nil {
+		return nil
+	}
+	return &v1beta1vm.EmbeddedObjectMetadata{
+		Name:        meta.Name,
+		Labels:      meta.Labels,
+		Annotations: meta.Annotations,
+	}
+}
+
+func convertStorage(storage *v1.StorageSpec) *v1beta1vm.StorageSpec {
+	if storage == nil {
+		return nil
+	}
+	return &v1beta1vm.StorageSpec{
+		DisableMountSubPath: storage.DisableMountSubPath,
+		EmptyDir:            storage.EmptyDir,
+		VolumeClaimTemplate: v1beta1vm.EmbeddedPersistentVolumeClaim{
+			TypeMeta: storage.VolumeClaimTemplate.TypeMeta,
+			EmbeddedObjectMetadata: v1beta1vm.EmbeddedObjectMetadata{
+				Name:        storage.VolumeClaimTemplate.Name,
+				Labels:      storage.VolumeClaimTemplate.Labels,
+				Annotations: storage.VolumeClaimTemplate.Annotations,
+			},
+			Spec:   storage.VolumeClaimTemplate.Spec,
+			Status: storage.VolumeClaimTemplate.Status,
+		},
+	}
+}
+
+func convertAPIServerConfig(apiConfig *v1.APIServerConfig, issues *ConversionIssues) *v1beta1vm.APIServerConfig {
+	if apiConfig == nil {
+		return nil
+	}
+	return &v1beta1vm.APIServerConfig{
+		Host:            apiConfig.Host,
+		BasicAuth:       ConvertBasicAuth(apiConfig.BasicAuth),
+		BearerToken:     apiConfig.BearerToken,
+		BearerTokenFile: replacePromDirPath(apiConfig.BearerTokenFile, "spec.apiserverConfig.bearerTokenFile", issues),
+		TLSConfig:       ConvertTlsConfig(apiConfig.TLSConfig, "spec.apiserverConfig.tlsConfig", issues),
+	}
+}
+
+// convertSelector keeps semantic of prometheus-operator nil selector, which doesn't select any object.
+func convertSelector(selector *metav1.LabelSelector) *metav1.LabelSelector {
+	if selector != nil {
+		return selector
+	}
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: selectNothingLabel, Operator: metav1.LabelSelectorOpExists},
+		},
+	}
+}
+
+// convertLogLevel converts prometheus log level into VictoriaMetrics one,
+// VictoriaMetrics doesn't have debug level, so info level is used instead.
+func convertLogLevel(level string) string {
+	switch level {
+	case "debug", "info":
+		return "INFO"
+	case "warn":
+		return "WARN"
+	case "error":
+		return "ERROR"
+	}
+	return ""
+}
+
+func convertLogFormat(format string) string {
+	switch format {
+	case "json":
+		return "json"
+	case "logfmt":
+		return "default"
+	}
+	return ""
+}
+
+// filterContainers returns containers, which aren't managed by prometheus-operator, and names of skipped containers.
+func filterContainers(containers []corev1.Container, managed map[string]struct{}) ([]corev1.Container, []string) {
+	var filtered []corev1.Container
+	var skipped []string
+	for _, container := range containers {
+		if _, ok := managed[container.Name]; ok {
+			skipped = append(skipped, container.Name)
+			continue
+		}
+		filtered = append(filtered, container)
+	}
+	return filtered, skipped
+}
@@ -0,0 +1,271 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1beta1vm "github.com/VictoriaMetrics/operator/api/v1beta1"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func TestConvertPrometheus(t *testing.T) {
+	ruleSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"role": "rules"}}
+	tests := []struct {
+		name            string
+		prom            *v1.Prometheus
+		wantAgent       v1beta1vm.VMAgentSpec
+		wantAgentFields string
+		wantAlert       *v1beta1vm.VMAlertSpec
+		wantAlertFields string
+		wantNoAgent     bool
+	}{
+		{
+			name: "prometheus with local storage only",
+			prom: &v1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+				Spec: v1.PrometheusSpec{
+					ServiceMonitorSelector: &metav1.LabelSelector{},
+					RuleSelector:           ruleSelector,
+					Retention:              "30d",
+				},
+			},
+			wantAgentFields: "spec.remoteWrite",
+			wantNoAgent:     true,
+		},
+		{
+			name: "agent with remote write",
+			prom: &v1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+				Spec: v1.PrometheusSpec{
+					Replicas:               pointer.Int32Ptr(2),
+					ServiceMonitorSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "infra"}},
+					ExternalLabels:         map[string]string{"cluster": "main"},
+					LogLevel:               "debug",
+					LogFormat:              "logfmt",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+					RemoteWrite: []v1.RemoteWriteSpec{
+						{
+							URL:           "http://vmsingle.monitoring.svc:8429/api/v1/write",
+							RemoteTimeout: "30s",
+							BearerToken:   "secret",
+							QueueConfig:   &v1.QueueConfig{MaxShards: 4},
+						},
+					},
+					Retention: "30d",
+					Storage:   &v1.StorageSpec{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					Containers: []corev1.Container{
+						{Name: "prometheus", Args: []string{"--log.level=debug"}},
+						{Name: "oauth-proxy"},
+					},
+				},
+			},
+			wantAgent: v1beta1vm.VMAgentSpec{
+				ReplicaCount:          pointer.Int32Ptr(2),
+				LogLevel:              "INFO",
+				LogFormat:             "default",
+				ServiceScrapeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "infra"}},
+				ExternalLabels:        map[string]string{"cluster": "main"},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+				RemoteWrite: []v1beta1vm.VMAgentRemoteWriteSpec{
+					{
+						URL:         "http://vmsingle.monitoring.svc:8429/api/v1/write",
+						SendTimeout: pointer.StringPtr("30s"),
+						Queues:      pointer.Int32Ptr(4),
+					},
+				},
+				Containers: []corev1.Container{{Name: "oauth-proxy"}},
+			},
+			wantAgentFields: "spec.containers[prometheus],spec.logLevel,spec.remoteWrite[0].bearerToken,spec.retention,spec.storage",
+		},
+		{
+			name: "agent with alert",
+			prom: &v1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+				Spec: v1.PrometheusSpec{
+					EvaluationInterval: "15s",
+					RuleSelector:       ruleSelector,
+					ExternalURL:        "http://prometheus.example.com",
+					RemoteWrite: []v1.RemoteWriteSpec{
+						{URL: "http://vmsingle.monitoring.svc:8429/api/v1/write"},
+					},
+					Alerting: &v1.AlertingSpec{
+						Alertmanagers: []v1.AlertmanagerEndpoints{
+							{Name: "alertmanager-main", Namespace: "monitoring", Port: intstr.FromString("web")},
+							{Name: "alertmanager-backup", Namespace: "monitoring", Port: intstr.FromInt(9093)},
+						},
+					},
+				},
+			},
+			wantAgent: v1beta1vm.VMAgentSpec{
+				RemoteWrite: []v1beta1vm.VMAgentRemoteWriteSpec{
+					{URL: "http://vmsingle.monitoring.svc:8429/api/v1/write"},
+				},
+			},
+			wantAlert: &v1beta1vm.VMAlertSpec{
+				EvaluationInterval: "15s",
+				RuleSelector:       ruleSelector,
+				ExtraArgs:          map[string]string{"external.url": "http://prometheus.example.com"},
+				Datasource:         v1beta1vm.VMAlertDatasourceSpec{URL: "http://vmsingle.monitoring.svc:8429"},
+				RemoteWrite:        &v1beta1vm.VMAlertRemoteWriteSpec{URL: "http://vmsingle.monitoring.svc:8429"},
+				Notifier:           v1beta1vm.VMAlertNotifierSpec{URL: "http://alertmanager-main.monitoring.svc:9093"},
+			},
+			wantAlertFields: "spec.alerting.alertmanagers[1]",
+		},
+		{
+			name: "rules of cluster remote write cannot be converted",
+			prom: &v1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+				Spec: v1.PrometheusSpec{
+					RuleSelector: ruleSelector,
+					RemoteWrite: []v1.RemoteWriteSpec{
+						{URL: "http://vminsert.monitoring.svc:8480/insert/0/prometheus/api/v1/write"},
+					},
+					Alerting: &v1.AlertingSpec{
+						Alertmanagers: []v1.AlertmanagerEndpoints{
+							{Name: "alertmanager-main", Namespace: "monitoring", Port: intstr.FromString("web")},
+						},
+					},
+				},
+			},
+			wantAgent: v1beta1vm.VMAgentSpec{
+				RemoteWrite: []v1beta1vm.VMAgentRemoteWriteSpec{
+					{URL: "http://vminsert.monitoring.svc:8480/insert/0/prometheus/api/v1/write"},
+				},
+			},
+			wantAgentFields: "spec.ruleSelector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAgent, agentIssues := ConvertPrometheus(tt.prom)
+			gotAlert, alertIssues := ConvertPrometheusRules(tt.prom)
+			if tt.wantNoAgent {
+				if gotAgent != nil || gotAlert != nil {
+					t.Fatalf("ConvertPrometheus() vmagent and vmalert must be nil, got: %v, %v", gotAgent, gotAlert)
+				}
+				if got := issueFields(agentIssues); got != tt.wantAgentFields {
+					t.Errorf("ConvertPrometheus() vmagent issues = %s, want %s", got, tt.wantAgentFields)
+				}
+				return
+			}
+			if gotAgent.Name != tt.prom.Name || gotAgent.Namespace != tt.prom.Namespace {
+				t.Errorf("unexpected vmagent meta: %v", gotAgent.ObjectMeta)
+			}
+			if tt.wantAgent.ServiceScrapeSelector == nil {
+				tt.wantAgent.ServiceScrapeSelector = convertSelector(nil)
+			}
+			tt.wantAgent.PodScrapeSelector = convertSelector(nil)
+			tt.wantAgent.ProbeSelector = convertSelector(nil)
+			if !reflect.DeepEqual(gotAgent.Spec, tt.wantAgent) {
+				t.Errorf("ConvertPrometheus() vmagent spec = \n%v, want \n%v", gotAgent.Spec, tt.wantAgent)
+			}
+			if got := issueFields(agentIssues); got != tt.wantAgentFields {
+				t.Errorf("ConvertPrometheus() vmagent issues = %s, want %s", got, tt.wantAgentFields)
+			}
+			if _, ok := gotAgent.ObjectMeta.Annotations[ConversionIssuesAnnotation]; ok != (tt.wantAgentFields != "") {
+				t.Errorf("ConvertPrometheus() unexpected vmagent annotations: %v", gotAgent.ObjectMeta.Annotations)
+			}
+			if tt.wantAlert == nil {
+				if gotAlert != nil {
+					t.Fatalf("ConvertPrometheusRules() vmalert must be nil, got: %v", gotAlert)
+				}
+				return
+			}
+			if gotAlert == nil {
+				t.Fatalf("ConvertPrometheusRules() vmalert must not be nil")
+			}
+			if !reflect.DeepEqual(gotAlert.Spec, *tt.wantAlert) {
+				t.Errorf("ConvertPrometheusRules() vmalert spec = \n%v, want \n%v", gotAlert.Spec, *tt.wantAlert)
+			}
+			if got := issueFields(alertIssues); got != tt.wantAlertFields {
+				t.Errorf("ConvertPrometheusRules() vmalert issues = %s, want %s", got, tt.wantAlertFields)
+			}
+		})
+	}
+}
+
+func TestConvertAlertmanager(t *testing.T) {
+	tests := []struct {
+		name       string
+		am         *v1.Alertmanager
+		want       v1beta1vm.VMAlertmanagerSpec
+		wantFields string
+	}{
+		{
+			name: "default config secret",
+			am: &v1.Alertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+				Spec: v1.AlertmanagerSpec{
+					Replicas: pointer.Int32Ptr(3),
+					Version:  "v0.21.0",
+					Storage: &v1.StorageSpec{
+						VolumeClaimTemplate: v1.EmbeddedPersistentVolumeClaim{
+							EmbeddedObjectMetadata: v1.EmbeddedObjectMetadata{Name: "data"},
+						},
+					},
+				},
+			},
+			want: v1beta1vm.VMAlertmanagerSpec{
+				ReplicaCount: pointer.Int32Ptr(3),
+				Image:        v1beta1vm.Image{Tag: "v0.21.0"},
+				ConfigSecret: "alertmanager-main",
+				Storage: &v1beta1vm.StorageSpec{
+					VolumeClaimTemplate: v1beta1vm.EmbeddedPersistentVolumeClaim{
+						EmbeddedObjectMetadata: v1beta1vm.EmbeddedObjectMetadata{Name: "data"},
+					},
+				},
+			},
+		},
+		{
+			name: "image and custom containers",
+			am: &v1.Alertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+				Spec: v1.AlertmanagerSpec{
+					Image:        pointer.StringPtr("registry.example.com:5000/prom/alertmanager:v0.20.0"),
+					ConfigSecret: "am-config",
+					SHA:          "7c85ec2b",
+					Containers: []corev1.Container{
+						{Name: "config-reloader"},
+						{Name: "proxy"},
+					},
+				},
+			},
+			want: v1beta1vm.VMAlertmanagerSpec{
+				Image:        v1beta1vm.Image{Repository: "registry.example.com:5000/prom/alertmanager", Tag: "v0.20.0"},
+				ConfigSecret: "am-config",
+				Containers:   []corev1.Container{{Name: "proxy"}},
+			},
+			wantFields: "spec.containers[config-reloader],spec.sha",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, issues := ConvertAlertmanager(tt.am)
+			if !reflect.DeepEqual(got.Spec, tt.want) {
+				t.Errorf("ConvertAlertmanager() = \n%v, want \n%v", got.Spec, tt.want)
+			}
+			if fields := issueFields(issues); fields != tt.wantFields {
+				t.Errorf("ConvertAlertmanager() issues = %s, want %s", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+// issueFields returns comma separated fields of issues.
+func issueFields(issues ConversionIssues) string {
+	fields := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		fields = append(fields, issue.Field)
+	}
+	return strings.Join(fields, ",")
+}
@@ -56,7 +56,11 @@ func CreateOrUpdateAlertManager(ctx context.Context, cr *victoriametricsv1beta1.
 		return nil, fmt.Errorf("cannot generate alertmanager sts, name: %s,err: %w", cr.Name, err)
 	}
 	// check secret with config
-	if err := createDefaultAMConfig(ctx, cr, rclient); err != nil {
+	if cr.HasConfigSelectors() {
+		if err := createOrUpdateAlertManagerConfig(ctx, cr, rclient, c); err != nil {
+			return nil, fmt.Errorf("failed to build Alertmanager config with vmalertmanagerconfigs: %w", err)
+		}
+	} else if err := createDefaultAMConfig(ctx, cr, rclient); err != nil {
 		return nil, fmt.Errorf("failed to check default Alertmanager config: %w", err)
 	}
 	currentSts := &appsv1.StatefulSet{}
@@ -73,7 +77,13 @@ func CreateOrUpdateAlertManager(ctx context.Context, cr *victoriametricsv1beta1.
 			return nil, fmt.Errorf("cannot get alertmanager sts: %w", err)
 		}
 	}
-	return newSts, updateStsForAlertManager(ctx, rclient, currentSts, newSts)
+	if err := updateStsForAlertManager(ctx, rclient, currentSts, newSts); err != nil {
+		return nil, fmt.Errorf("cannot update alertmanager sts: %w", err)
+	}
+	if err := reconcilePDB(ctx, rclient, cr.Spec.PodDisruptionBudget, cr.SelectorLabels(), newSts.ObjectMeta); err != nil {
+		return nil, fmt.Errorf("cannot reconcile pdb for alertmanager: %w", err)
+	}
+	return newSts, nil
 }
 
 func updateStsForAlertManager(ctx context.Context, rclient client.Client, oldSts, newSts *appsv1.StatefulSet) error {
@@ -176,37 +186,17 @@ func newStsForAlertManager(cr *victoriametricsv1beta1.VMAlertmanager, c *config.
 }
 
 func CreateOrUpdateAlertManagerService(ctx context.Context, cr *victoriametricsv1beta1.VMAlertmanager, rclient client.Client, c *config.BaseOperatorConf) (*v1.Service, error) {
-
-	l := log.WithValues("recon.alertmanager.service", cr.Name)
-
 	newService := newAlertManagerService(cr, c)
-	oldService := &v1.Service{}
-	err := rclient.Get(ctx, types.NamespacedName{Name: newService.Name, Namespace: newService.Namespace}, oldService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			l.Info("creating new service for sts")
-			err := rclient.Create(ctx, newService)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create service for vmalertmanager sts: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("cannot get service for vmalertmanager sts: %w", err)
-		}
-	}
-	for annotation, value := range oldService.Annotations {
-		newService.Annotations[annotation] = value
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vmalertmanager: %w", err)
 	}
-	if oldService.Spec.ClusterIP != "" {
-		newService.Spec.ClusterIP = oldService.Spec.ClusterIP
+	applyServiceSpec(newService, cr.Spec.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vmalertmanager: %w", err)
 	}
-	if oldService.ResourceVersion != "" {
-		newService.ResourceVersion = oldService.ResourceVersion
+	if err := reconcileIngress(ctx, rclient, cr.Spec.Ingress, newService, cr.Spec.PortName); err != nil {
+		return nil, fmt.Errorf("cannot reconcile ingress for vmalertmanager: %w", err)
 	}
-	err = rclient.Update(ctx, newService)
-	if err != nil {
-		return nil, fmt.Errorf("cannot update vmalert server: %w", err)
-	}
-
 	return newService, nil
 }
 
@@ -278,6 +268,8 @@ func makeStatefulSetSpec(cr *victoriametricsv1beta1.VMAlertmanager, config *conf
 	webRoutePrefix := "/"
 	if cr.Spec.RoutePrefix != "" {
 		webRoutePrefix = cr.Spec.RoutePrefix
+	} else if cr.Spec.Ingress != nil && cr.Spec.Ingress.PathPrefix != "" {
+		webRoutePrefix = cr.Spec.Ingress.PathPrefix
 	}
 	amArgs = append(amArgs, fmt.Sprintf("--web.route-prefix=%s", webRoutePrefix))
 
@@ -406,7 +398,7 @@ func makeStatefulSetSpec(cr *victoriametricsv1beta1.VMAlertmanager, config *conf
 			Name: "config-volume",
 			VolumeSource: v1.VolumeSource{
 				Secret: &v1.SecretVolumeSource{
-					SecretName: cr.Spec.ConfigSecret,
+					SecretName: cr.ConfigSecretName(),
 				},
 			},
 		},
@@ -596,7 +588,7 @@ func createDefaultAMConfig(ctx context.Context, cr *victoriametricsv1beta1.VMAle
 			Annotations:     cr.Annotations(),
 			OwnerReferences: cr.AsOwner(),
 		},
-		StringData: map[string]string{"alertmanager.yaml": cr.Spec.ConfigRawYaml},
+		StringData: map[string]string{alertmanagerSecretConfigKey: cr.Spec.ConfigRawYaml},
 	}
 	var existAMSecretConfig v1.Secret
 
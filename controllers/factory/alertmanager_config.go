@@ -0,0 +1,633 @@
+package factory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const alertmanagerSecretConfigKey = "alertmanager.yaml"
+
+// createOrUpdateAlertManagerConfig builds alertmanager configuration from
+// base config and selected VMAlertmanagerConfigs and writes it into managed secret.
+func createOrUpdateAlertManagerConfig(ctx context.Context, cr *victoriametricsv1beta1.VMAlertmanager, rclient client.Client, c *config.BaseOperatorConf) error {
+	baseConfig, err := loadBaseAMConfig(ctx, cr, rclient)
+	if err != nil {
+		return fmt.Errorf("cannot load base config for alertmanager: %w", err)
+	}
+	amConfigs, err := selectVMAlertmanagerConfigs(ctx, cr, rclient)
+	if err != nil {
+		return fmt.Errorf("cannot select vmalertmanagerconfigs: %w", err)
+	}
+	generatedConfig, err := buildAlertmanagerConfigWithCRDs(ctx, rclient, baseConfig, amConfigs)
+	if err != nil {
+		return fmt.Errorf("cannot build alertmanager config: %w", err)
+	}
+	s := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cr.ConfigSecretName(),
+			Namespace:       cr.Namespace,
+			Labels:          c.Labels.Merge(cr.FinalLabels()),
+			Annotations:     cr.Annotations(),
+			OwnerReferences: cr.AsOwner(),
+		},
+		Data: map[string][]byte{
+			alertmanagerSecretConfigKey: generatedConfig,
+		},
+	}
+
+	curSecret := &v1.Secret{}
+	err = rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: s.Name}, curSecret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("creating new configuration secret for vmalertmanager", "vmalertmanager", cr.Name)
+			return rclient.Create(ctx, s)
+		}
+		return fmt.Errorf("cannot get vmalertmanager config secret: %w", err)
+	}
+	if bytes.Equal(curSecret.Data[alertmanagerSecretConfigKey], s.Data[alertmanagerSecretConfigKey]) {
+		log.Info("updating vmalertmanager configuration secret skipped, no configuration change", "vmalertmanager", cr.Name)
+		return nil
+	}
+	for annotation, value := range curSecret.Annotations {
+		if _, ok := s.Annotations[annotation]; !ok {
+			s.Annotations[annotation] = value
+		}
+	}
+	log.Info("updating vmalertmanager configuration secret", "vmalertmanager", cr.Name)
+	return rclient.Update(ctx, s)
+}
+
+// loadBaseAMConfig returns configuration, which is used as base for merge.
+// priority -> ConfigRaw, provided by user -> user-defined ConfigSecret -> hardcoded ConfigRaw.
+func loadBaseAMConfig(ctx context.Context, cr *victoriametricsv1beta1.VMAlertmanager, rclient client.Client) ([]byte, error) {
+	if cr.Spec.ConfigRawYaml != "" {
+		return []byte(cr.Spec.ConfigRawYaml), nil
+	}
+	// secret with operator's name is managed by operator, it cannot be used as base.
+	if cr.Spec.ConfigSecret != "" && cr.Spec.ConfigSecret != cr.PrefixedName() {
+		s := &v1.Secret{}
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Spec.ConfigSecret}, s); err != nil {
+			return nil, fmt.Errorf("cannot get secret with alertmanager config: %s, err: %w", cr.Spec.ConfigSecret, err)
+		}
+		data, ok := s.Data[alertmanagerSecretConfigKey]
+		if !ok {
+			return nil, fmt.Errorf("secret %s has no key: %s", cr.Spec.ConfigSecret, alertmanagerSecretConfigKey)
+		}
+		return data, nil
+	}
+	return []byte(defaultAMConfig), nil
+}
+
+func selectVMAlertmanagerConfigs(ctx context.Context, cr *victoriametricsv1beta1.VMAlertmanager, rclient client.Client) ([]*victoriametricsv1beta1.VMAlertmanagerConfig, error) {
+	var namespaces []string
+
+	if cr.Spec.ConfigNamespaceSelector == nil {
+		namespaces = append(namespaces, cr.Namespace)
+	} else if cr.Spec.ConfigNamespaceSelector.MatchExpressions == nil && cr.Spec.ConfigNamespaceSelector.MatchLabels == nil {
+		// all namespaces watched by operator
+		var err error
+		namespaces, err = watchedNamespaces(ctx, rclient)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select watched namespaces: %w", err)
+		}
+	} else {
+		nsSelector, err := metav1.LabelSelectorAsSelector(cr.Spec.ConfigNamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert configNamespaceSelector: %w", err)
+		}
+		namespaces, err = selectNamespaces(ctx, rclient, nsSelector)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select namespaces for vmalertmanagerconfigs: %w", err)
+		}
+	}
+
+	configSelector := cr.Spec.ConfigSelector
+	if configSelector == nil {
+		configSelector = &metav1.LabelSelector{}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(configSelector)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert configSelector to labelSelector: %w", err)
+	}
+
+	var configsCombined []victoriametricsv1beta1.VMAlertmanagerConfig
+	if namespaces == nil {
+		configs := &victoriametricsv1beta1.VMAlertmanagerConfigList{}
+		if err := rclient.List(ctx, configs, &client.ListOptions{LabelSelector: selector}); err != nil {
+			return nil, fmt.Errorf("cannot list vmalertmanagerconfigs from all namespaces: %w", err)
+		}
+		configsCombined = append(configsCombined, configs.Items...)
+	} else {
+		for _, ns := range namespaces {
+			configs := &victoriametricsv1beta1.VMAlertmanagerConfigList{}
+			if err := rclient.List(ctx, configs, &client.ListOptions{Namespace: ns, LabelSelector: selector}); err != nil {
+				return nil, fmt.Errorf("cannot list vmalertmanagerconfigs at namespace: %s, err: %w", ns, err)
+			}
+			configsCombined = append(configsCombined, configs.Items...)
+		}
+	}
+
+	res := make([]*victoriametricsv1beta1.VMAlertmanagerConfig, 0, len(configsCombined))
+	selected := make([]string, 0, len(configsCombined))
+	for i := range configsCombined {
+		res = append(res, configsCombined[i].DeepCopy())
+	}
+	// keep generated config stable between reconciles.
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+	for _, amcfg := range res {
+		selected = append(selected, amcfg.Namespace+"/"+amcfg.Name)
+	}
+	log.Info("selected vmalertmanagerconfigs", "vmalertmanager", cr.Name, "configs", strings.Join(selected, ","))
+
+	return res, nil
+}
+
+// buildAlertmanagerConfigWithCRDs merges given VMAlertmanagerConfigs into base config.
+// Generated routes are prepended to the base route, receivers, inhibit rules
+// and mute time intervals are appended to the base lists.
+// Broken VMAlertmanagerConfigs are skipped.
+func buildAlertmanagerConfigWithCRDs(ctx context.Context, rclient client.Client, baseCfg []byte, amcfgs []*victoriametricsv1beta1.VMAlertmanagerConfig) ([]byte, error) {
+	var baseYAMLCfg yaml.MapSlice
+	if err := yaml.Unmarshal(baseCfg, &baseYAMLCfg); err != nil {
+		return nil, fmt.Errorf("cannot parse base alertmanager config: %w", err)
+	}
+	baseRoute, ok := mapSliceValue(baseYAMLCfg, "route").(yaml.MapSlice)
+	if !ok {
+		return nil, fmt.Errorf("base alertmanager config must have route section")
+	}
+	var routes, receivers, inhibitRules, muteTimeIntervals []interface{}
+	secretCache := make(map[string]*v1.Secret)
+	for _, amcfg := range amcfgs {
+		if err := validateAMConfigRefs(amcfg); err != nil {
+			log.Error(err, "skipping broken vmalertmanagerconfig", "vmalertmanagerconfig", amcfg.Name, "namespace", amcfg.Namespace)
+			continue
+		}
+		cb := &amConfigBuilder{ctx: ctx, rclient: rclient, amcfg: amcfg, secretCache: secretCache}
+		cfgReceivers := make([]interface{}, 0, len(amcfg.Spec.Receivers))
+		var err error
+		for _, receiver := range amcfg.Spec.Receivers {
+			var r yaml.MapSlice
+			r, err = cb.buildReceiver(receiver)
+			if err != nil {
+				break
+			}
+			cfgReceivers = append(cfgReceivers, r)
+		}
+		if err != nil {
+			log.Error(err, "skipping vmalertmanagerconfig with broken receiver", "vmalertmanagerconfig", amcfg.Name, "namespace", amcfg.Namespace)
+			continue
+		}
+		receivers = append(receivers, cfgReceivers...)
+		if amcfg.Spec.Route != nil {
+			routes = append(routes, cb.buildRoute())
+		}
+		for _, rule := range amcfg.Spec.InhibitRules {
+			inhibitRules = append(inhibitRules, cb.buildInhibitRule(rule))
+		}
+		for _, interval := range amcfg.Spec.MuteTimeIntervals {
+			muteTimeIntervals = append(muteTimeIntervals, cb.buildMuteTimeInterval(interval))
+		}
+	}
+	if len(routes) > 0 {
+		baseRoutes, _ := mapSliceValue(baseRoute, "routes").([]interface{})
+		baseRoute = setMapSliceValue(baseRoute, "routes", append(routes, baseRoutes...))
+		baseYAMLCfg = setMapSliceValue(baseYAMLCfg, "route", baseRoute)
+	}
+	baseYAMLCfg = appendMapSliceList(baseYAMLCfg, "receivers", receivers)
+	baseYAMLCfg = appendMapSliceList(baseYAMLCfg, "inhibit_rules", inhibitRules)
+	baseYAMLCfg = appendMapSliceList(baseYAMLCfg, "mute_time_intervals", muteTimeIntervals)
+	return yaml.Marshal(baseYAMLCfg)
+}
+
+// validateAMConfigRefs checks that route references only receivers
+// and mute time intervals defined at the same VMAlertmanagerConfig.
+func validateAMConfigRefs(amcfg *victoriametricsv1beta1.VMAlertmanagerConfig) error {
+	receivers := make(map[string]struct{}, len(amcfg.Spec.Receivers))
+	for _, r := range amcfg.Spec.Receivers {
+		if _, ok := receivers[r.Name]; ok {
+			return fmt.Errorf("duplicate receiver name: %s", r.Name)
+		}
+		receivers[r.Name] = struct{}{}
+	}
+	intervals := make(map[string]struct{}, len(amcfg.Spec.MuteTimeIntervals))
+	for _, mti := range amcfg.Spec.MuteTimeIntervals {
+		intervals[mti.Name] = struct{}{}
+	}
+	route := amcfg.Spec.Route
+	if route == nil {
+		return nil
+	}
+	checkRefs := func(receiver string, muteIntervals []string) error {
+		if _, ok := receivers[receiver]; receiver != "" && !ok {
+			return fmt.Errorf("receiver: %s not found at spec.receivers", receiver)
+		}
+		for _, name := range muteIntervals {
+			if _, ok := intervals[name]; !ok {
+				return fmt.Errorf("mute time interval: %s not found at spec.muteTimeIntervals", name)
+			}
+		}
+		return nil
+	}
+	if route.Receiver == "" {
+		return fmt.Errorf("route receiver cannot be empty")
+	}
+	if err := checkRefs(route.Receiver, route.MuteTimeIntervals); err != nil {
+		return err
+	}
+	for _, sr := range route.Routes {
+		if err := checkRefs(sr.Receiver, sr.MuteTimeIntervals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type amConfigBuilder struct {
+	ctx         context.Context
+	rclient     client.Client
+	amcfg       *victoriametricsv1beta1.VMAlertmanagerConfig
+	secretCache map[string]*v1.Secret
+}
+
+// prefixedName makes name unique across all VMAlertmanagerConfigs.
+func (cb *amConfigBuilder) prefixedName(name string) string {
+	return fmt.Sprintf("%s-%s-%s", cb.amcfg.Namespace, cb.amcfg.Name, name)
+}
+
+func (cb *amConfigBuilder) fetchSecretValue(sel *v1.SecretKeySelector) (string, error) {
+	return getCredFromSecret(cb.ctx, cb.rclient, cb.amcfg.Namespace, *sel, cb.amcfg.Namespace+"/"+sel.Name, cb.secretCache)
+}
+
+func (cb *amConfigBuilder) buildRoute() yaml.MapSlice {
+	r := cb.amcfg.Spec.Route
+	// namespace matcher must be enforced, user-defined one is ignored.
+	var matchers []victoriametricsv1beta1.Matcher
+	for _, m := range r.Matchers {
+		if m.Name == "namespace" {
+			continue
+		}
+		matchers = append(matchers, m)
+	}
+	matchers = append(matchers, victoriametricsv1beta1.Matcher{Name: "namespace", Value: cb.amcfg.Namespace})
+	route := cb.buildSubRoute(victoriametricsv1beta1.SubRoute{
+		Receiver:          r.Receiver,
+		GroupBy:           r.GroupBy,
+		GroupWait:         r.GroupWait,
+		GroupInterval:     r.GroupInterval,
+		RepeatInterval:    r.RepeatInterval,
+		Matchers:          matchers,
+		Continue:          r.Continue,
+		MuteTimeIntervals: r.MuteTimeIntervals,
+	})
+	if len(r.Routes) > 0 {
+		subRoutes := make([]yaml.MapSlice, 0, len(r.Routes))
+		for _, sr := range r.Routes {
+			subRoutes = append(subRoutes, cb.buildSubRoute(sr))
+		}
+		route = append(route, yaml.MapItem{Key: "routes", Value: subRoutes})
+	}
+	return route
+}
+
+func (cb *amConfigBuilder) buildSubRoute(r victoriametricsv1beta1.SubRoute) yaml.MapSlice {
+	var route yaml.MapSlice
+	if r.Receiver != "" {
+		route = append(route, yaml.MapItem{Key: "receiver", Value: cb.prefixedName(r.Receiver)})
+	}
+	if len(r.GroupBy) > 0 {
+		route = append(route, yaml.MapItem{Key: "group_by", Value: r.GroupBy})
+	}
+	route = appendIfNotEmpty(route, "group_wait", r.GroupWait)
+	route = appendIfNotEmpty(route, "group_interval", r.GroupInterval)
+	route = appendIfNotEmpty(route, "repeat_interval", r.RepeatInterval)
+	route = appendMatchers(route, "match", "match_re", r.Matchers)
+	if r.Continue {
+		route = append(route, yaml.MapItem{Key: "continue", Value: true})
+	}
+	if len(r.MuteTimeIntervals) > 0 {
+		names := make([]string, 0, len(r.MuteTimeIntervals))
+		for _, name := range r.MuteTimeIntervals {
+			names = append(names, cb.prefixedName(name))
+		}
+		route = append(route, yaml.MapItem{Key: "mute_time_intervals", Value: names})
+	}
+	return route
+}
+
+func (cb *amConfigBuilder) buildInhibitRule(rule victoriametricsv1beta1.InhibitRule) yaml.MapSlice {
+	namespaceMatcher := victoriametricsv1beta1.Matcher{Name: "namespace", Value: cb.amcfg.Namespace}
+	var ir yaml.MapSlice
+	ir = appendMatchers(ir, "source_match", "source_match_re", append(rule.SourceMatchers, namespaceMatcher))
+	ir = appendMatchers(ir, "target_match", "target_match_re", append(rule.TargetMatchers, namespaceMatcher))
+	if len(rule.Equal) > 0 {
+		ir = append(ir, yaml.MapItem{Key: "equal", Value: rule.Equal})
+	}
+	return ir
+}
+
+func (cb *amConfigBuilder) buildMuteTimeInterval(mti victoriametricsv1beta1.MuteTimeInterval) yaml.MapSlice {
+	intervals := make([]yaml.MapSlice, 0, len(mti.TimeIntervals))
+	for _, ti := range mti.TimeIntervals {
+		var interval yaml.MapSlice
+		if len(ti.Times) > 0 {
+			times := make([]yaml.MapSlice, 0, len(ti.Times))
+			for _, t := range ti.Times {
+				times = append(times, yaml.MapSlice{
+					{Key: "start_time", Value: t.StartTime},
+					{Key: "end_time", Value: t.EndTime},
+				})
+			}
+			interval = append(interval, yaml.MapItem{Key: "times", Value: times})
+		}
+		for _, item := range []struct {
+			key   string
+			value []string
+		}{
+			{key: "weekdays", value: ti.Weekdays},
+			{key: "days_of_month", value: ti.DaysOfMonth},
+			{key: "months", value: ti.Months},
+			{key: "years", value: ti.Years},
+		} {
+			if len(item.value) > 0 {
+				interval = append(interval, yaml.MapItem{Key: item.key, Value: item.value})
+			}
+		}
+		intervals = append(intervals, interval)
+	}
+	return yaml.MapSlice{
+		{Key: "name", Value: cb.prefixedName(mti.Name)},
+		{Key: "time_intervals", Value: intervals},
+	}
+}
+
+func (cb *amConfigBuilder) buildReceiver(receiver victoriametricsv1beta1.Receiver) (yaml.MapSlice, error) {
+	r := yaml.MapSlice{{Key: "name", Value: cb.prefixedName(receiver.Name)}}
+	if len(receiver.EmailConfigs) > 0 {
+		configs := make([]yaml.MapSlice, 0, len(receiver.EmailConfigs))
+		for _, ec := range receiver.EmailConfigs {
+			cfg, err := cb.buildEmail(ec)
+			if err != nil {
+				return nil, fmt.Errorf("cannot build email config for receiver: %s, err: %w", receiver.Name, err)
+			}
+			configs = append(configs, cfg)
+		}
+		r = append(r, yaml.MapItem{Key: "email_configs", Value: configs})
+	}
+	if len(receiver.SlackConfigs) > 0 {
+		configs := make([]yaml.MapSlice, 0, len(receiver.SlackConfigs))
+		for _, sc := range receiver.SlackConfigs {
+			cfg, err := cb.buildSlack(sc)
+			if err != nil {
+				return nil, fmt.Errorf("cannot build slack config for receiver: %s, err: %w", receiver.Name, err)
+			}
+			configs = append(configs, cfg)
+		}
+		r = append(r, yaml.MapItem{Key: "slack_configs", Value: configs})
+	}
+	if len(receiver.PagerDutyConfigs) > 0 {
+		configs := make([]yaml.MapSlice, 0, len(receiver.PagerDutyConfigs))
+		for _, pd := range receiver.PagerDutyConfigs {
+			cfg, err := cb.buildPagerDuty(pd)
+			if err != nil {
+				return nil, fmt.Errorf("cannot build pagerduty config for receiver: %s, err: %w", receiver.Name, err)
+			}
+			configs = append(configs, cfg)
+		}
+		r = append(r, yaml.MapItem{Key: "pagerduty_configs", Value: configs})
+	}
+	if len(receiver.WebhookConfigs) > 0 {
+		configs := make([]yaml.MapSlice, 0, len(receiver.WebhookConfigs))
+		for _, wh := range receiver.WebhookConfigs {
+			cfg, err := cb.buildWebhook(wh)
+			if err != nil {
+				return nil, fmt.Errorf("cannot build webhook config for receiver: %s, err: %w", receiver.Name, err)
+			}
+			configs = append(configs, cfg)
+		}
+		r = append(r, yaml.MapItem{Key: "webhook_configs", Value: configs})
+	}
+	return r, nil
+}
+
+func (cb *amConfigBuilder) buildEmail(email victoriametricsv1beta1.EmailConfig) (yaml.MapSlice, error) {
+	var cfg yaml.MapSlice
+	if email.SendResolved != nil {
+		cfg = append(cfg, yaml.MapItem{Key: "send_resolved", Value: *email.SendResolved})
+	}
+	cfg = appendIfNotEmpty(cfg, "to", email.To)
+	cfg = appendIfNotEmpty(cfg, "from", email.From)
+	cfg = appendIfNotEmpty(cfg, "hello", email.Hello)
+	cfg = appendIfNotEmpty(cfg, "smarthost", email.Smarthost)
+	cfg = appendIfNotEmpty(cfg, "auth_username", email.AuthUsername)
+	if email.AuthPassword != nil {
+		s, err := cb.fetchSecretValue(email.AuthPassword)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "auth_password", Value: s})
+	}
+	if email.AuthSecret != nil {
+		s, err := cb.fetchSecretValue(email.AuthSecret)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "auth_secret", Value: s})
+	}
+	cfg = appendIfNotEmpty(cfg, "auth_identity", email.AuthIdentity)
+	if len(email.Headers) > 0 {
+		cfg = append(cfg, yaml.MapItem{Key: "headers", Value: email.Headers})
+	}
+	cfg = appendIfNotEmpty(cfg, "html", email.HTML)
+	cfg = appendIfNotEmpty(cfg, "text", email.Text)
+	if email.RequireTLS != nil {
+		cfg = append(cfg, yaml.MapItem{Key: "require_tls", Value: *email.RequireTLS})
+	}
+	return cfg, nil
+}
+
+func (cb *amConfigBuilder) buildSlack(slack victoriametricsv1beta1.SlackConfig) (yaml.MapSlice, error) {
+	var cfg yaml.MapSlice
+	if slack.SendResolved != nil {
+		cfg = append(cfg, yaml.MapItem{Key: "send_resolved", Value: *slack.SendResolved})
+	}
+	if slack.APIURL != nil {
+		s, err := cb.fetchSecretValue(slack.APIURL)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "api_url", Value: s})
+	}
+	cfg = appendIfNotEmpty(cfg, "channel", slack.Channel)
+	cfg = appendIfNotEmpty(cfg, "username", slack.Username)
+	cfg = appendIfNotEmpty(cfg, "color", slack.Color)
+	cfg = appendIfNotEmpty(cfg, "title", slack.Title)
+	cfg = appendIfNotEmpty(cfg, "title_link", slack.TitleLink)
+	cfg = appendIfNotEmpty(cfg, "text", slack.Text)
+	cfg = appendIfNotEmpty(cfg, "fallback", slack.Fallback)
+	cfg = appendIfNotEmpty(cfg, "icon_emoji", slack.IconEmoji)
+	cfg = appendIfNotEmpty(cfg, "icon_url", slack.IconURL)
+	return cfg, nil
+}
+
+func (cb *amConfigBuilder) buildPagerDuty(pd victoriametricsv1beta1.PagerDutyConfig) (yaml.MapSlice, error) {
+	var cfg yaml.MapSlice
+	if pd.SendResolved != nil {
+		cfg = append(cfg, yaml.MapItem{Key: "send_resolved", Value: *pd.SendResolved})
+	}
+	if pd.RoutingKey != nil {
+		s, err := cb.fetchSecretValue(pd.RoutingKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "routing_key", Value: s})
+	}
+	if pd.ServiceKey != nil {
+		s, err := cb.fetchSecretValue(pd.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "service_key", Value: s})
+	}
+	cfg = appendIfNotEmpty(cfg, "url", pd.URL)
+	cfg = appendIfNotEmpty(cfg, "client", pd.Client)
+	cfg = appendIfNotEmpty(cfg, "client_url", pd.ClientURL)
+	cfg = appendIfNotEmpty(cfg, "description", pd.Description)
+	cfg = appendIfNotEmpty(cfg, "severity", pd.Severity)
+	cfg = appendIfNotEmpty(cfg, "class", pd.Class)
+	cfg = appendIfNotEmpty(cfg, "group", pd.Group)
+	cfg = appendIfNotEmpty(cfg, "component", pd.Component)
+	if len(pd.Details) > 0 {
+		cfg = append(cfg, yaml.MapItem{Key: "details", Value: pd.Details})
+	}
+	return cfg, nil
+}
+
+func (cb *amConfigBuilder) buildWebhook(wh victoriametricsv1beta1.WebhookConfig) (yaml.MapSlice, error) {
+	var cfg yaml.MapSlice
+	if wh.SendResolved != nil {
+		cfg = append(cfg, yaml.MapItem{Key: "send_resolved", Value: *wh.SendResolved})
+	}
+	switch {
+	case wh.URLSecret != nil:
+		s, err := cb.fetchSecretValue(wh.URLSecret)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "url", Value: s})
+	case wh.URL != nil:
+		cfg = append(cfg, yaml.MapItem{Key: "url", Value: *wh.URL})
+	default:
+		return nil, fmt.Errorf("one of url or urlSecret must be defined for webhook")
+	}
+	if wh.HTTPConfig != nil {
+		httpCfg, err := cb.buildHTTPConfig(wh.HTTPConfig)
+		if err != nil {
+			return nil, err
+		}
+		if len(httpCfg) > 0 {
+			cfg = append(cfg, yaml.MapItem{Key: "http_config", Value: httpCfg})
+		}
+	}
+	if wh.MaxAlerts > 0 {
+		cfg = append(cfg, yaml.MapItem{Key: "max_alerts", Value: wh.MaxAlerts})
+	}
+	return cfg, nil
+}
+
+func (cb *amConfigBuilder) buildHTTPConfig(httpCfg *victoriametricsv1beta1.HTTPConfig) (yaml.MapSlice, error) {
+	var cfg yaml.MapSlice
+	if httpCfg.BasicAuth != nil {
+		username, err := cb.fetchSecretValue(&httpCfg.BasicAuth.Username)
+		if err != nil {
+			return nil, err
+		}
+		password, err := cb.fetchSecretValue(&httpCfg.BasicAuth.Password)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "basic_auth", Value: yaml.MapSlice{
+			{Key: "username", Value: username},
+			{Key: "password", Value: password},
+		}})
+	}
+	if httpCfg.BearerTokenSecret != nil {
+		token, err := cb.fetchSecretValue(httpCfg.BearerTokenSecret)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "bearer_token", Value: token})
+	}
+	cfg = appendIfNotEmpty(cfg, "proxy_url", httpCfg.ProxyURL)
+	return cfg, nil
+}
+
+// appendMatchers converts matchers into alertmanager match and match_re sections.
+func appendMatchers(dst yaml.MapSlice, matchKey, matchREKey string, matchers []victoriametricsv1beta1.Matcher) yaml.MapSlice {
+	match := make(map[string]string)
+	matchRE := make(map[string]string)
+	for _, m := range matchers {
+		if m.Regex {
+			matchRE[m.Name] = m.Value
+			continue
+		}
+		match[m.Name] = m.Value
+	}
+	if len(match) > 0 {
+		dst = append(dst, yaml.MapItem{Key: matchKey, Value: match})
+	}
+	if len(matchRE) > 0 {
+		dst = append(dst, yaml.MapItem{Key: matchREKey, Value: matchRE})
+	}
+	return dst
+}
+
+func appendIfNotEmpty(dst yaml.MapSlice, key, value string) yaml.MapSlice {
+	if value == "" {
+		return dst
+	}
+	return append(dst, yaml.MapItem{Key: key, Value: value})
+}
+
+func mapSliceValue(ms yaml.MapSlice, key string) interface{} {
+	for _, item := range ms {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func setMapSliceValue(ms yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range ms {
+		if ms[i].Key == key {
+			ms[i].Value = value
+			return ms
+		}
+	}
+	return append(ms, yaml.MapItem{Key: key, Value: value})
+}
+
+func appendMapSliceList(ms yaml.MapSlice, key string, items []interface{}) yaml.MapSlice {
+	if len(items) == 0 {
+		return ms
+	}
+	current, _ := mapSliceValue(ms, key).([]interface{})
+	return setMapSliceValue(ms, key, append(current, items...))
+}
@@ -0,0 +1,370 @@
+package factory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_buildAlertmanagerConfigWithCRDs(t *testing.T) {
+	baseCfg := `global:
+  resolve_timeout: 5m
+route:
+  receiver: default
+  routes:
+  - receiver: default
+    match:
+      team: ops
+receivers:
+- name: default
+  webhook_configs:
+  - url: http://default-webhook
+`
+	tests := []struct {
+		name              string
+		baseCfg           string
+		amcfgs            []*victoriametricsv1beta1.VMAlertmanagerConfig
+		want              string
+		wantErr           bool
+		predefinedObjects []runtime.Object
+	}{
+		{
+			name:    "base config without route",
+			baseCfg: "global: {}",
+			wantErr: true,
+		},
+		{
+			name:    "without configs",
+			baseCfg: baseCfg,
+			want:    baseCfg,
+		},
+		{
+			name:    "with config and secrets",
+			baseCfg: baseCfg,
+			amcfgs: []*victoriametricsv1beta1.VMAlertmanagerConfig{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "team-a", Namespace: "team-a"},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Route: &victoriametricsv1beta1.Route{
+							Receiver: "slack",
+							GroupBy:  []string{"alertname"},
+							Matchers: []victoriametricsv1beta1.Matcher{
+								{Name: "namespace", Value: "team-b"},
+								{Name: "job", Value: "api.*", Regex: true},
+							},
+							Routes: []victoriametricsv1beta1.SubRoute{
+								{
+									Receiver:          "webhook",
+									Matchers:          []victoriametricsv1beta1.Matcher{{Name: "severity", Value: "critical"}},
+									MuteTimeIntervals: []string{"weekends"},
+								},
+							},
+						},
+						Receivers: []victoriametricsv1beta1.Receiver{
+							{
+								Name: "slack",
+								SlackConfigs: []victoriametricsv1beta1.SlackConfig{
+									{
+										SendResolved: pointer.BoolPtr(true),
+										APIURL:       &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "slack"}, Key: "url"},
+										Channel:      "#team-a",
+									},
+								},
+							},
+							{
+								Name: "webhook",
+								WebhookConfigs: []victoriametricsv1beta1.WebhookConfig{
+									{
+										URL: pointer.StringPtr("http://team-a-webhook"),
+										HTTPConfig: &victoriametricsv1beta1.HTTPConfig{
+											BearerTokenSecret: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "slack"}, Key: "token"},
+										},
+									},
+								},
+							},
+						},
+						InhibitRules: []victoriametricsv1beta1.InhibitRule{
+							{
+								SourceMatchers: []victoriametricsv1beta1.Matcher{{Name: "severity", Value: "critical"}},
+								TargetMatchers: []victoriametricsv1beta1.Matcher{{Name: "severity", Value: "warning"}},
+								Equal:          []string{"alertname"},
+							},
+						},
+						MuteTimeIntervals: []victoriametricsv1beta1.MuteTimeInterval{
+							{
+								Name: "weekends",
+								TimeIntervals: []victoriametricsv1beta1.TimeInterval{
+									{Weekdays: []string{"saturday", "sunday"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "team-a"},
+					Data: map[string][]byte{
+						"url":   []byte("http://slack-url"),
+						"token": []byte("some-token"),
+					},
+				},
+			},
+			want: `global:
+  resolve_timeout: 5m
+route:
+  receiver: default
+  routes:
+  - receiver: team-a-team-a-slack
+    group_by:
+    - alertname
+    match:
+      namespace: team-a
+    match_re:
+      job: api.*
+    routes:
+    - receiver: team-a-team-a-webhook
+      match:
+        severity: critical
+      mute_time_intervals:
+      - team-a-team-a-weekends
+  - receiver: default
+    match:
+      team: ops
+receivers:
+- name: default
+  webhook_configs:
+  - url: http://default-webhook
+- name: team-a-team-a-slack
+  slack_configs:
+  - send_resolved: true
+    api_url: http://slack-url
+    channel: '#team-a'
+- name: team-a-team-a-webhook
+  webhook_configs:
+  - url: http://team-a-webhook
+    http_config:
+      bearer_token: some-token
+inhibit_rules:
+- source_match:
+    namespace: team-a
+    severity: critical
+  target_match:
+    namespace: team-a
+    severity: warning
+  equal:
+  - alertname
+mute_time_intervals:
+- name: team-a-team-a-weekends
+  time_intervals:
+  - weekdays:
+    - saturday
+    - sunday
+`,
+		},
+		{
+			name:    "skip broken configs",
+			baseCfg: baseCfg,
+			amcfgs: []*victoriametricsv1beta1.VMAlertmanagerConfig{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "missing-receiver", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Route: &victoriametricsv1beta1.Route{Receiver: "missing"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "missing-secret", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Route: &victoriametricsv1beta1.Route{Receiver: "pd"},
+						Receivers: []victoriametricsv1beta1.Receiver{
+							{
+								Name: "pd",
+								PagerDutyConfigs: []victoriametricsv1beta1.PagerDutyConfig{
+									{RoutingKey: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "pd"}, Key: "key"}},
+								},
+							},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "email", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Route: &victoriametricsv1beta1.Route{Receiver: "email"},
+						Receivers: []victoriametricsv1beta1.Receiver{
+							{
+								Name: "email",
+								EmailConfigs: []victoriametricsv1beta1.EmailConfig{
+									{
+										To:           "team@example.com",
+										AuthUsername: "user",
+										AuthPassword: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "email"}, Key: "password"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "email", Namespace: "default"},
+					Data:       map[string][]byte{"password": []byte("email-password")},
+				},
+			},
+			want: `global:
+  resolve_timeout: 5m
+route:
+  receiver: default
+  routes:
+  - receiver: default-email-email
+    match:
+      namespace: default
+  - receiver: default
+    match:
+      team: ops
+receivers:
+- name: default
+  webhook_configs:
+  - url: http://default-webhook
+- name: default-email-email
+  email_configs:
+  - to: team@example.com
+    auth_username: user
+    auth_password: email-password
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			got, err := buildAlertmanagerConfigWithCRDs(context.TODO(), fclient, []byte(tt.baseCfg), tt.amcfgs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildAlertmanagerConfigWithCRDs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("buildAlertmanagerConfigWithCRDs() got = \n%s, want \n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createOrUpdateAlertManagerConfig(t *testing.T) {
+	tests := []struct {
+		name              string
+		cr                *victoriametricsv1beta1.VMAlertmanager
+		wantReceivers     []string
+		wantErr           bool
+		predefinedObjects []runtime.Object
+	}{
+		{
+			name: "select configs from own namespace",
+			cr: &victoriametricsv1beta1.VMAlertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-am", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMAlertmanagerSpec{
+					ConfigSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&victoriametricsv1beta1.VMAlertmanagerConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "selected", Namespace: "default", Labels: map[string]string{"team": "a"}},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Receivers: []victoriametricsv1beta1.Receiver{{Name: "webhook", WebhookConfigs: []victoriametricsv1beta1.WebhookConfig{{URL: pointer.StringPtr("http://webhook")}}}},
+					},
+				},
+				&victoriametricsv1beta1.VMAlertmanagerConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-team", Namespace: "default", Labels: map[string]string{"team": "b"}},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Receivers: []victoriametricsv1beta1.Receiver{{Name: "webhook", WebhookConfigs: []victoriametricsv1beta1.WebhookConfig{{URL: pointer.StringPtr("http://webhook")}}}},
+					},
+				},
+				&victoriametricsv1beta1.VMAlertmanagerConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-ns", Namespace: "monitoring", Labels: map[string]string{"team": "a"}},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Receivers: []victoriametricsv1beta1.Receiver{{Name: "webhook", WebhookConfigs: []victoriametricsv1beta1.WebhookConfig{{URL: pointer.StringPtr("http://webhook")}}}},
+					},
+				},
+			},
+			wantReceivers: []string{"webhook", "default-selected-webhook"},
+		},
+		{
+			name: "merge with user secret",
+			cr: &victoriametricsv1beta1.VMAlertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-am", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMAlertmanagerSpec{
+					ConfigSecret:            "user-config",
+					ConfigNamespaceSelector: &metav1.LabelSelector{},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "user-config", Namespace: "default"},
+					Data: map[string][]byte{alertmanagerSecretConfigKey: []byte(`route:
+  receiver: user
+receivers:
+- name: user
+`)},
+				},
+				&victoriametricsv1beta1.VMAlertmanagerConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-ns", Namespace: "monitoring"},
+					Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+						Receivers: []victoriametricsv1beta1.Receiver{{Name: "webhook", WebhookConfigs: []victoriametricsv1beta1.WebhookConfig{{URL: pointer.StringPtr("http://webhook")}}}},
+					},
+				},
+			},
+			wantReceivers: []string{"user", "monitoring-other-ns-webhook"},
+		},
+		{
+			name: "missing user secret",
+			cr: &victoriametricsv1beta1.VMAlertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-am", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMAlertmanagerSpec{
+					ConfigSecret:   "user-config",
+					ConfigSelector: &metav1.LabelSelector{},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			err := createOrUpdateAlertManagerConfig(context.TODO(), tt.cr, fclient, config.MustGetBaseConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createOrUpdateAlertManagerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var createdSecret v1.Secret
+			if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: tt.cr.Namespace, Name: tt.cr.PrefixedName()}, &createdSecret); err != nil {
+				t.Fatalf("config for alertmanager not exist, err: %v", err)
+			}
+			var amCfg struct {
+				Receivers []struct {
+					Name string `yaml:"name"`
+				} `yaml:"receivers"`
+			}
+			if err := yaml.Unmarshal(createdSecret.Data[alertmanagerSecretConfigKey], &amCfg); err != nil {
+				t.Fatalf("cannot parse generated config: %v", err)
+			}
+			var gotReceivers []string
+			for _, r := range amCfg.Receivers {
+				gotReceivers = append(gotReceivers, r.Name)
+			}
+			if !reflect.DeepEqual(gotReceivers, tt.wantReceivers) {
+				t.Errorf("createOrUpdateAlertManagerConfig() got receivers = %v, want %v", gotReceivers, tt.wantReceivers)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package factory
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// credentialsChecksumAnnotation is added to pod template,
+// it triggers pods rollout on credentials change.
+const credentialsChecksumAnnotation = "operator.victoriametrics.com/credentials-checksum"
+
+// envFlagName converts flag name into env var name,
+// which is read by VictoriaMetrics components with -envflag.enable.
+func envFlagName(flagName string) string {
+	return strings.Replace(flagName, ".", "_", -1)
+}
+
+// credentialsChecksum returns stable checksum for given credentials.
+func credentialsChecksum(creds map[string]string) string {
+	keys := make([]string, 0, len(creds))
+	for k := range creds {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))        //nolint:errcheck
+		h.Write([]byte{0})        //nolint:errcheck
+		h.Write([]byte(creds[k])) //nolint:errcheck
+		h.Write([]byte("\xff"))   //nolint:errcheck
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// credentialsEnvFrom returns env source for credentials secret.
+func credentialsEnvFrom(secretName string) corev1.EnvFromSource {
+	return corev1.EnvFromSource{
+		SecretRef: &corev1.SecretEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+		},
+	}
+}
+
+// createOrUpdateCredentialsSecret stores credentials flags as env vars at secret,
+// so they are not exposed at pod command line.
+func createOrUpdateCredentialsSecret(ctx context.Context, rclient client.Client, newSecret *corev1.Secret) error {
+	currentSecret := &corev1.Secret{}
+	err := rclient.Get(ctx, types.NamespacedName{Namespace: newSecret.Namespace, Name: newSecret.Name}, currentSecret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("creating new credentials secret", "secret", newSecret.Name, "namespace", newSecret.Namespace)
+			if err := rclient.Create(ctx, newSecret); err != nil {
+				return fmt.Errorf("cannot create credentials secret: %s, err: %w", newSecret.Name, err)
+			}
+			return nil
+		}
+		return fmt.Errorf("cannot get credentials secret: %s, err: %w", newSecret.Name, err)
+	}
+	for annotation, value := range currentSecret.Annotations {
+		newSecret.Annotations[annotation] = value
+	}
+	if err := rclient.Update(ctx, newSecret); err != nil {
+		return fmt.Errorf("cannot update credentials secret: %s, err: %w", newSecret.Name, err)
+	}
+	return nil
+}
+
+// deleteCredentialsSecret removes credentials secret owned by component,
+// it's called after credentials were removed from pod template, so stale credentials aren't kept at cluster.
+func deleteCredentialsSecret(ctx context.Context, rclient client.Client, name, namespace string, owners []metav1.OwnerReference) error {
+	currentSecret := &corev1.Secret{}
+	err := rclient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, currentSecret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot get credentials secret: %s, err: %w", name, err)
+	}
+	if !isOwnedBy(currentSecret.OwnerReferences, owners) {
+		return nil
+	}
+	log.Info("credentials were removed, deleting credentials secret", "secret", name, "namespace", namespace)
+	if err := rclient.Delete(ctx, currentSecret); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete credentials secret: %s, err: %w", name, err)
+	}
+	return nil
+}
+
+func makeCredentialsSecret(name, namespace string, labels map[string]string, owners []metav1.OwnerReference, creds map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			Labels:          labels,
+			Annotations:     map[string]string{},
+			OwnerReferences: owners,
+		},
+		Data: make(map[string][]byte, len(creds)),
+	}
+	for k, v := range creds {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
@@ -0,0 +1,132 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultHPACPUUtilization is used, if metrics aren't set at hpa spec.
+const defaultHPACPUUtilization = 80
+
+// buildHPA returns HorizontalPodAutoscaler for target workload,
+// hpa has the same name, labels and owner as its target.
+func buildHPA(spec *v1beta1.EmbeddedHPA, targetRef v2beta2.CrossVersionObjectReference, meta metav1.ObjectMeta) *v2beta2.HorizontalPodAutoscaler {
+	metrics := spec.Metrics
+	if len(metrics) == 0 {
+		metrics = []v2beta2.MetricSpec{
+			{
+				Type: v2beta2.ResourceMetricSourceType,
+				Resource: &v2beta2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: v2beta2.MetricTarget{
+						Type:               v2beta2.UtilizationMetricType,
+						AverageUtilization: pointer.Int32Ptr(defaultHPACPUUtilization),
+					},
+				},
+			},
+		}
+	}
+	return &v2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            meta.Name,
+			Namespace:       meta.Namespace,
+			Labels:          meta.Labels,
+			Annotations:     map[string]string{},
+			OwnerReferences: meta.OwnerReferences,
+		},
+		Spec: v2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: targetRef,
+			MinReplicas:    spec.MinReplicas,
+			MaxReplicas:    spec.MaxReplicas,
+			Metrics:        metrics,
+			Behavior:       spec.Behavior,
+		},
+	}
+}
+
+// reconcileHPA creates or updates HorizontalPodAutoscaler for target workload,
+// if hpa is set at component spec, otherwise it removes hpa created by operator before.
+// meta is object metadata of target workload.
+func reconcileHPA(ctx context.Context, rclient client.Client, spec *v1beta1.EmbeddedHPA, targetRef v2beta2.CrossVersionObjectReference, meta metav1.ObjectMeta) error {
+	l := log.WithValues("controller", "hpa", "name", meta.Name, "namespace", meta.Namespace)
+	currentHPA := &v2beta2.HorizontalPodAutoscaler{}
+	err := rclient.Get(ctx, types.NamespacedName{Name: meta.Name, Namespace: meta.Namespace}, currentHPA)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot get hpa: %s, err: %w", meta.Name, err)
+	}
+	exists := err == nil
+	if spec == nil {
+		if !exists || !isOwnedBy(currentHPA.OwnerReferences, meta.OwnerReferences) {
+			return nil
+		}
+		l.Info("hpa was disabled, removing it")
+		if err := rclient.Delete(ctx, currentHPA); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete hpa: %s, err: %w", meta.Name, err)
+		}
+		return nil
+	}
+	newHPA := buildHPA(spec, targetRef, meta)
+	if !exists {
+		l.Info("creating new hpa")
+		if err := rclient.Create(ctx, newHPA); err != nil {
+			return fmt.Errorf("cannot create hpa: %s, err: %w", meta.Name, err)
+		}
+		return nil
+	}
+	for annotation, value := range currentHPA.Annotations {
+		newHPA.Annotations[annotation] = value
+	}
+	newHPA.ResourceVersion = currentHPA.ResourceVersion
+	if err := rclient.Update(ctx, newHPA); err != nil {
+		return fmt.Errorf("cannot update hpa: %s, err: %w", meta.Name, err)
+	}
+	l.Info("hpa was reconciled")
+	return nil
+}
+
+// isOwnedBy checks if object with given owner references is owned by one of owners.
+func isOwnedBy(refs, owners []metav1.OwnerReference) bool {
+	for _, ref := range refs {
+		for _, owner := range owners {
+			if ref.UID == owner.UID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// componentReplicas returns number of pods, which component is expected to have.
+// Replicas of component with hpa are managed by hpa, so replicas of workload are used instead of spec.
+// obj must be empty statefulset or deployment.
+func componentReplicas(ctx context.Context, rclient client.Client, name, namespace string, obj runtime.Object, replicaCount *int32, hpa *v1beta1.EmbeddedHPA) (int32, error) {
+	if hpa == nil {
+		return *replicaCount, nil
+	}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj); err != nil {
+		return 0, fmt.Errorf("cannot get workload: %s for replicas check, err: %w", name, err)
+	}
+	var replicas *int32
+	switch w := obj.(type) {
+	case *appsv1.StatefulSet:
+		replicas = w.Spec.Replicas
+	case *appsv1.Deployment:
+		replicas = w.Spec.Replicas
+	}
+	if replicas == nil {
+		// kubernetes default
+		return 1, nil
+	}
+	return *replicas, nil
+}
@@ -0,0 +1,167 @@
+package factory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_reconcileHPA(t *testing.T) {
+	owner := []metav1.OwnerReference{{APIVersion: "operator.victoriametrics.com/v1beta1", Kind: "VMCluster", Name: "example", UID: "uid-1"}}
+	meta := metav1.ObjectMeta{Name: "vminsert-example", Namespace: "default", OwnerReferences: owner}
+	target := v2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "vminsert-example"}
+	existingHPA := func(owners []metav1.OwnerReference) *v2beta2.HorizontalPodAutoscaler {
+		return &v2beta2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "vminsert-example", Namespace: "default", OwnerReferences: owners},
+			Spec:       v2beta2.HorizontalPodAutoscalerSpec{ScaleTargetRef: target, MaxReplicas: 3},
+		}
+	}
+	tests := []struct {
+		name              string
+		spec              *v1beta1.EmbeddedHPA
+		predefinedObjects []runtime.Object
+		wantHPA           bool
+		wantMaxReplicas   int32
+		wantMetric        corev1.ResourceName
+	}{
+		{
+			name:            "create with default metric",
+			spec:            &v1beta1.EmbeddedHPA{MinReplicas: pointer.Int32Ptr(2), MaxReplicas: 5},
+			wantHPA:         true,
+			wantMaxReplicas: 5,
+			wantMetric:      corev1.ResourceCPU,
+		},
+		{
+			name: "update with custom metric",
+			spec: &v1beta1.EmbeddedHPA{
+				MaxReplicas: 10,
+				Metrics: []v2beta2.MetricSpec{{
+					Type: v2beta2.ResourceMetricSourceType,
+					Resource: &v2beta2.ResourceMetricSource{
+						Name:   corev1.ResourceMemory,
+						Target: v2beta2.MetricTarget{Type: v2beta2.UtilizationMetricType, AverageUtilization: pointer.Int32Ptr(60)},
+					},
+				}},
+			},
+			predefinedObjects: []runtime.Object{existingHPA(owner)},
+			wantHPA:           true,
+			wantMaxReplicas:   10,
+			wantMetric:        corev1.ResourceMemory,
+		},
+		{
+			name:              "disabled hpa is removed",
+			predefinedObjects: []runtime.Object{existingHPA(owner)},
+		},
+		{
+			name:              "disabled hpa of other owner is kept",
+			predefinedObjects: []runtime.Object{existingHPA(nil)},
+			wantHPA:           true,
+			wantMaxReplicas:   3,
+		},
+		{
+			name: "nothing to do",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			if err := reconcileHPA(context.TODO(), fclient, tt.spec, target, meta); err != nil {
+				t.Fatalf("reconcileHPA() error = %v", err)
+			}
+			got := &v2beta2.HorizontalPodAutoscaler{}
+			err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vminsert-example", Namespace: "default"}, got)
+			if !tt.wantHPA {
+				if !errors.IsNotFound(err) {
+					t.Fatalf("hpa must not exist, got err: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cannot get hpa: %v", err)
+			}
+			if got.Spec.MaxReplicas != tt.wantMaxReplicas {
+				t.Errorf("reconcileHPA() maxReplicas = %d, want %d", got.Spec.MaxReplicas, tt.wantMaxReplicas)
+			}
+			if tt.wantMetric != "" && (len(got.Spec.Metrics) != 1 || got.Spec.Metrics[0].Resource.Name != tt.wantMetric) {
+				t.Errorf("reconcileHPA() unexpected metrics: %v", got.Spec.Metrics)
+			}
+		})
+	}
+}
+
+func Test_componentReplicas(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "vminsert-example", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(4)},
+	}
+	tests := []struct {
+		name              string
+		hpa               *v1beta1.EmbeddedHPA
+		predefinedObjects []runtime.Object
+		want              int32
+		wantErr           bool
+	}{
+		{
+			name: "without hpa",
+			want: 2,
+		},
+		{
+			name:              "with hpa",
+			hpa:               &v1beta1.EmbeddedHPA{MaxReplicas: 5},
+			predefinedObjects: []runtime.Object{deploy},
+			want:              4,
+		},
+		{
+			name:    "with hpa and missing workload",
+			hpa:     &v1beta1.EmbeddedHPA{MaxReplicas: 5},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			got, err := componentReplicas(context.TODO(), fclient, "vminsert-example", "default", &appsv1.Deployment{}, pointer.Int32Ptr(2), tt.hpa)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("componentReplicas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("componentReplicas() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createOrUpdateVMInsert_keepsHPAReplicas(t *testing.T) {
+	cr := &v1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1beta1.VMClusterSpec{
+			RetentionPeriod: "1",
+			VMInsert: &v1beta1.VMInsert{
+				ReplicaCount: pointer.Int32Ptr(2),
+				HPA:          &v1beta1.EmbeddedHPA{MaxReplicas: 10},
+			},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "vminsert-example", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(7)},
+	})
+	got, err := createOrUpdateVMInsert(context.TODO(), cr, "", fclient, config.MustGetBaseConfig())
+	if err != nil {
+		t.Fatalf("createOrUpdateVMInsert() error = %v", err)
+	}
+	if *got.Spec.Replicas != 7 {
+		t.Errorf("createOrUpdateVMInsert() replicas = %d, want replicas set by hpa: 7", *got.Spec.Replicas)
+	}
+}
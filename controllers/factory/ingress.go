@@ -0,0 +1,107 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// buildIngress returns Ingress, which routes requests of ingress path to given port of component service,
+// ingress has the same name, labels and owner as service.
+// networking.k8s.io/v1beta1 is used instead of v1, since k8s.io/api v0.18 used by operator has no v1 Ingress,
+// pathType and ingressClassName of v1beta1 require kubernetes 1.18, v1beta1 is removed at kubernetes 1.22.
+func buildIngress(spec *v1beta1.EmbeddedIngress, svc *corev1.Service, portName string) *networkingv1beta1.Ingress {
+	annotations := make(map[string]string, len(spec.Annotations))
+	for annotation, value := range spec.Annotations {
+		annotations[annotation] = value
+	}
+	pathType := networkingv1beta1.PathTypePrefix
+	ingress := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            svc.Name,
+			Namespace:       svc.Namespace,
+			Labels:          svc.Labels,
+			Annotations:     annotations,
+			OwnerReferences: svc.OwnerReferences,
+		},
+		Spec: networkingv1beta1.IngressSpec{
+			IngressClassName: spec.ClassName,
+			Rules: []networkingv1beta1.IngressRule{
+				{
+					Host: spec.Host,
+					IngressRuleValue: networkingv1beta1.IngressRuleValue{
+						HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+							Paths: []networkingv1beta1.HTTPIngressPath{
+								{
+									Path:     spec.IngressPath(),
+									PathType: &pathType,
+									Backend: networkingv1beta1.IngressBackend{
+										ServiceName: svc.Name,
+										ServicePort: intstr.FromString(portName),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if spec.TLSSecretName != "" {
+		tls := networkingv1beta1.IngressTLS{SecretName: spec.TLSSecretName}
+		if spec.Host != "" {
+			tls.Hosts = []string{spec.Host}
+		}
+		ingress.Spec.TLS = []networkingv1beta1.IngressTLS{tls}
+	}
+	return ingress
+}
+
+// reconcileIngress creates or updates Ingress for component service,
+// if ingress is set at component spec, otherwise it removes ingress created by operator before.
+func reconcileIngress(ctx context.Context, rclient client.Client, spec *v1beta1.EmbeddedIngress, svc *corev1.Service, portName string) error {
+	l := log.WithValues("controller", "ingress", "name", svc.Name, "namespace", svc.Namespace)
+	currentIngress := &networkingv1beta1.Ingress{}
+	err := rclient.Get(ctx, types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, currentIngress)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot get ingress: %s, err: %w", svc.Name, err)
+	}
+	exists := err == nil
+	if spec == nil {
+		if !exists || !isOwnedBy(currentIngress.OwnerReferences, svc.OwnerReferences) {
+			return nil
+		}
+		l.Info("ingress was disabled, removing it")
+		if err := rclient.Delete(ctx, currentIngress); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete ingress: %s, err: %w", svc.Name, err)
+		}
+		return nil
+	}
+	newIngress := buildIngress(spec, svc, portName)
+	if !exists {
+		l.Info("creating new ingress")
+		if err := rclient.Create(ctx, newIngress); err != nil {
+			return fmt.Errorf("cannot create ingress: %s, err: %w", svc.Name, err)
+		}
+		return nil
+	}
+	for annotation, value := range currentIngress.Annotations {
+		if _, ok := newIngress.Annotations[annotation]; !ok {
+			newIngress.Annotations[annotation] = value
+		}
+	}
+	newIngress.ResourceVersion = currentIngress.ResourceVersion
+	if err := rclient.Update(ctx, newIngress); err != nil {
+		return fmt.Errorf("cannot update ingress: %s, err: %w", svc.Name, err)
+	}
+	l.Info("ingress was reconciled")
+	return nil
+}
@@ -0,0 +1,149 @@
+package factory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_reconcileIngress(t *testing.T) {
+	svc := newTestService("")
+	existingIngress := func(owners []metav1.OwnerReference) *networkingv1beta1.Ingress {
+		return &networkingv1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: svc.Name, Namespace: "default", OwnerReferences: owners},
+			Spec: networkingv1beta1.IngressSpec{
+				Backend: &networkingv1beta1.IngressBackend{ServiceName: svc.Name},
+			},
+		}
+	}
+	tests := []struct {
+		name              string
+		spec              *v1beta1.EmbeddedIngress
+		predefinedObjects []runtime.Object
+		wantIngress       bool
+		wantPath          string
+		wantTLS           bool
+	}{
+		{
+			name: "create ingress with tls",
+			spec: &v1beta1.EmbeddedIngress{
+				Host:          "vmselect.example.com",
+				TLSSecretName: "vmselect-tls",
+				ClassName:     pointer.StringPtr("nginx"),
+				PathPrefix:    "/vmselect",
+			},
+			wantIngress: true,
+			wantPath:    "/vmselect",
+			wantTLS:     true,
+		},
+		{
+			name:              "update ingress",
+			spec:              &v1beta1.EmbeddedIngress{Host: "vmselect.example.com"},
+			predefinedObjects: []runtime.Object{existingIngress(svc.OwnerReferences)},
+			wantIngress:       true,
+			wantPath:          "/",
+		},
+		{
+			name:              "disabled ingress is removed",
+			predefinedObjects: []runtime.Object{existingIngress(svc.OwnerReferences)},
+		},
+		{
+			name:              "disabled ingress of other owner is kept",
+			predefinedObjects: []runtime.Object{existingIngress(nil)},
+			wantIngress:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			if err := reconcileIngress(context.TODO(), fclient, tt.spec, svc, "http"); err != nil {
+				t.Fatalf("reconcileIngress() error = %v", err)
+			}
+			got := &networkingv1beta1.Ingress{}
+			err := fclient.Get(context.TODO(), types.NamespacedName{Name: svc.Name, Namespace: "default"}, got)
+			if !tt.wantIngress {
+				if !errors.IsNotFound(err) {
+					t.Fatalf("ingress must not exist, got err: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cannot get ingress: %v", err)
+			}
+			if tt.spec == nil {
+				return
+			}
+			if len(got.Spec.Rules) != 1 || got.Spec.Rules[0].Host != tt.spec.Host {
+				t.Fatalf("unexpected ingress rules: %v", got.Spec.Rules)
+			}
+			paths := got.Spec.Rules[0].HTTP.Paths
+			if len(paths) != 1 || paths[0].Path != tt.wantPath || paths[0].Backend.ServiceName != svc.Name || paths[0].Backend.ServicePort.StrVal != "http" {
+				t.Errorf("unexpected ingress paths: %v", paths)
+			}
+			if (len(got.Spec.TLS) > 0) != tt.wantTLS {
+				t.Errorf("unexpected ingress tls: %v", got.Spec.TLS)
+			}
+			if tt.wantTLS && (got.Spec.TLS[0].SecretName != tt.spec.TLSSecretName || got.Spec.TLS[0].Hosts[0] != tt.spec.Host) {
+				t.Errorf("unexpected ingress tls: %v", got.Spec.TLS)
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdateVMSingle_ingressPathPrefix(t *testing.T) {
+	cr := &v1beta1.VMSingle{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1beta1.VMSingleSpec{
+			RetentionPeriod: "1",
+			Ingress:         &v1beta1.EmbeddedIngress{Host: "vm.example.com", PathPrefix: "/vmsingle"},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme())
+	c := config.MustGetBaseConfig()
+	if _, err := CreateOrUpdateVMSingle(context.TODO(), cr, fclient, c); err != nil {
+		t.Fatalf("CreateOrUpdateVMSingle() error = %v", err)
+	}
+	svc, err := CreateOrUpdateVMSingleService(context.TODO(), cr, fclient, c)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateVMSingleService() error = %v", err)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: svc.Name, Namespace: "default"}, &networkingv1beta1.Ingress{}); err != nil {
+		t.Fatalf("cannot get ingress: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: cr.PrefixedName(), Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("cannot get deployment: %v", err)
+	}
+	var container *corev1.Container
+	for i := range deploy.Spec.Template.Spec.Containers {
+		if deploy.Spec.Template.Spec.Containers[i].Name == "vmsingle" {
+			container = &deploy.Spec.Template.Spec.Containers[i]
+		}
+	}
+	if container == nil {
+		t.Fatalf("vmsingle container not found")
+	}
+	var hasPrefixArg bool
+	for _, arg := range container.Args {
+		if arg == "--http.pathPrefix=/vmsingle" {
+			hasPrefixArg = true
+		}
+	}
+	if !hasPrefixArg {
+		t.Errorf("vmsingle args must contain path prefix of ingress: %v", container.Args)
+	}
+	if path := container.ReadinessProbe.HTTPGet.Path; path != "/vmsingle/health" {
+		t.Errorf("readiness probe path = %s, want /vmsingle/health", path)
+	}
+}
@@ -0,0 +1,72 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// buildPDB returns PodDisruptionBudget for pods of component,
+// pdb has the same name, labels and owner as workload of component.
+func buildPDB(spec *v1beta1.EmbeddedPodDisruptionBudgetSpec, selectorLabels map[string]string, meta metav1.ObjectMeta) *policyv1beta1.PodDisruptionBudget {
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            meta.Name,
+			Namespace:       meta.Namespace,
+			Labels:          meta.Labels,
+			Annotations:     map[string]string{},
+			OwnerReferences: meta.OwnerReferences,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable:   spec.MinAvailable,
+			MaxUnavailable: spec.MaxUnavailable,
+			Selector:       &metav1.LabelSelector{MatchLabels: selectorLabels},
+		},
+	}
+}
+
+// reconcilePDB creates or updates PodDisruptionBudget for pods matching selector labels,
+// if pdb is set at component spec, otherwise it removes pdb created by operator before.
+// meta is object metadata of component workload.
+func reconcilePDB(ctx context.Context, rclient client.Client, spec *v1beta1.EmbeddedPodDisruptionBudgetSpec, selectorLabels map[string]string, meta metav1.ObjectMeta) error {
+	l := log.WithValues("controller", "pdb", "name", meta.Name, "namespace", meta.Namespace)
+	currentPDB := &policyv1beta1.PodDisruptionBudget{}
+	err := rclient.Get(ctx, types.NamespacedName{Name: meta.Name, Namespace: meta.Namespace}, currentPDB)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot get pdb: %s, err: %w", meta.Name, err)
+	}
+	exists := err == nil
+	if spec == nil {
+		if !exists || !isOwnedBy(currentPDB.OwnerReferences, meta.OwnerReferences) {
+			return nil
+		}
+		l.Info("pdb was disabled, removing it")
+		if err := rclient.Delete(ctx, currentPDB); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete pdb: %s, err: %w", meta.Name, err)
+		}
+		return nil
+	}
+	newPDB := buildPDB(spec, selectorLabels, meta)
+	if !exists {
+		l.Info("creating new pdb")
+		if err := rclient.Create(ctx, newPDB); err != nil {
+			return fmt.Errorf("cannot create pdb: %s, err: %w", meta.Name, err)
+		}
+		return nil
+	}
+	for annotation, value := range currentPDB.Annotations {
+		newPDB.Annotations[annotation] = value
+	}
+	newPDB.ResourceVersion = currentPDB.ResourceVersion
+	if err := rclient.Update(ctx, newPDB); err != nil {
+		return fmt.Errorf("cannot update pdb: %s, err: %w", meta.Name, err)
+	}
+	l.Info("pdb was reconciled")
+	return nil
+}
@@ -0,0 +1,119 @@
+package factory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_reconcilePDB(t *testing.T) {
+	owner := []metav1.OwnerReference{{APIVersion: "operator.victoriametrics.com/v1beta1", Kind: "VMCluster", Name: "example", UID: "uid-1"}}
+	meta := metav1.ObjectMeta{Name: "vmstorage-example", Namespace: "default", OwnerReferences: owner}
+	selector := map[string]string{"app.kubernetes.io/name": "vmstorage", "app.kubernetes.io/instance": "example"}
+	one := intstr.FromInt(1)
+	half := intstr.FromString("50%")
+	existingPDB := func(owners []metav1.OwnerReference) *policyv1beta1.PodDisruptionBudget {
+		return &policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-example", Namespace: "default", OwnerReferences: owners},
+			Spec:       policyv1beta1.PodDisruptionBudgetSpec{MinAvailable: &one},
+		}
+	}
+	tests := []struct {
+		name               string
+		spec               *v1beta1.EmbeddedPodDisruptionBudgetSpec
+		predefinedObjects  []runtime.Object
+		wantPDB            bool
+		wantMinAvailable   *intstr.IntOrString
+		wantMaxUnavailable *intstr.IntOrString
+	}{
+		{
+			name:               "create pdb",
+			spec:               &v1beta1.EmbeddedPodDisruptionBudgetSpec{MaxUnavailable: &one},
+			wantPDB:            true,
+			wantMaxUnavailable: &one,
+		},
+		{
+			name:              "update pdb",
+			spec:              &v1beta1.EmbeddedPodDisruptionBudgetSpec{MinAvailable: &half},
+			predefinedObjects: []runtime.Object{existingPDB(owner)},
+			wantPDB:           true,
+			wantMinAvailable:  &half,
+		},
+		{
+			name:              "disabled pdb is removed",
+			predefinedObjects: []runtime.Object{existingPDB(owner)},
+		},
+		{
+			name:              "disabled pdb of other owner is kept",
+			predefinedObjects: []runtime.Object{existingPDB(nil)},
+			wantPDB:           true,
+			wantMinAvailable:  &one,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			if err := reconcilePDB(context.TODO(), fclient, tt.spec, selector, meta); err != nil {
+				t.Fatalf("reconcilePDB() error = %v", err)
+			}
+			got := &policyv1beta1.PodDisruptionBudget{}
+			err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmstorage-example", Namespace: "default"}, got)
+			if !tt.wantPDB {
+				if !errors.IsNotFound(err) {
+					t.Fatalf("pdb must not exist, got err: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cannot get pdb: %v", err)
+			}
+			if !intOrStringEqual(got.Spec.MinAvailable, tt.wantMinAvailable) || !intOrStringEqual(got.Spec.MaxUnavailable, tt.wantMaxUnavailable) {
+				t.Errorf("reconcilePDB() unexpected spec: minAvailable %v, maxUnavailable %v", got.Spec.MinAvailable, got.Spec.MaxUnavailable)
+			}
+			if tt.spec != nil && (got.Spec.Selector == nil || got.Spec.Selector.MatchLabels["app.kubernetes.io/name"] != "vmstorage") {
+				t.Errorf("reconcilePDB() unexpected selector: %v", got.Spec.Selector)
+			}
+		})
+	}
+}
+
+func intOrStringEqual(a, b *intstr.IntOrString) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestCreateOrUpdateVMSingle_pdb(t *testing.T) {
+	one := intstr.FromInt(1)
+	cr := &v1beta1.VMSingle{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1beta1.VMSingleSpec{
+			RetentionPeriod:     "1",
+			PodDisruptionBudget: &v1beta1.EmbeddedPodDisruptionBudgetSpec{MinAvailable: &one},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme())
+	deploy, err := CreateOrUpdateVMSingle(context.TODO(), cr, fclient, config.MustGetBaseConfig())
+	if err != nil {
+		t.Fatalf("CreateOrUpdateVMSingle() error = %v", err)
+	}
+	got := &policyv1beta1.PodDisruptionBudget{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: deploy.Name, Namespace: "default"}, got); err != nil {
+		t.Fatalf("cannot get pdb: %v", err)
+	}
+	for k, v := range cr.SelectorLabels() {
+		if got.Spec.Selector.MatchLabels[k] != v {
+			t.Errorf("pdb selector must match vmsingle selector labels, got: %v", got.Spec.Selector.MatchLabels)
+		}
+	}
+}
@@ -9,11 +9,17 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/VictoriaMetrics/metricsql"
 	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/ghodss/yaml"
+	"github.com/prometheus/common/model"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -52,9 +58,16 @@ groups:
 
 func CreateOrUpdateRuleConfigMaps(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclient client.Client) ([]string, error) {
 	l := log.WithValues("reconcile", "rulesCm", "vmalert", cr.Name)
-	newRules, err := SelectRules(ctx, cr, rclient)
+	vmRules, namespaces, err := selectVMRules(ctx, cr, rclient)
 	if err != nil {
-		return nil, err
+		return nil, newConfigError(err)
+	}
+	newRules, ruleErrors, err := buildRulesContent(cr, vmRules)
+	if err != nil {
+		return nil, newConfigError(err)
+	}
+	if err := updateRulesStatus(ctx, rclient, cr, namespaces, vmRules, ruleErrors); err != nil {
+		l.Error(err, "cannot update vmrules status")
 	}
 
 	currentConfigMapList := &v1.ConfigMapList{}
@@ -142,7 +155,12 @@ func rulesConfigMapSelector(vmAlertName string, namespace string) client.ListOpt
 	}
 }
 
+// selectNamespaces returns watched namespaces matched by selector.
 func selectNamespaces(ctx context.Context, rclient client.Client, selector labels.Selector) ([]string, error) {
+	watchNamespaces := config.MustGetBaseConfig().Namespaces
+	if !watchNamespaces.IsAllNamespaces() {
+		return selectAllowedNamespaces(ctx, rclient, watchNamespaces.Allowed(), selector)
+	}
 	matchedNs := []string{}
 	ns := &v1.NamespaceList{}
 
@@ -151,6 +169,9 @@ func selectNamespaces(ctx context.Context, rclient client.Client, selector label
 	}
 
 	for _, n := range ns.Items {
+		if !watchNamespaces.IsAllowed(n.Name) {
+			continue
+		}
 		matchedNs = append(matchedNs, n.Name)
 	}
 	log.Info("namespaced matched by selector", "ns", strings.Join(matchedNs, ","))
@@ -158,25 +179,74 @@ func selectNamespaces(ctx context.Context, rclient client.Client, selector label
 	return matchedNs, nil
 }
 
+// selectAllowedNamespaces returns namespaces from allow list matched by selector.
+// Operator restricted to the list of namespaces may have no permissions to get namespace objects,
+// in this case selector matches any of allowed namespaces.
+func selectAllowedNamespaces(ctx context.Context, rclient client.Client, allowed []string, selector labels.Selector) ([]string, error) {
+	matchedNs := []string{}
+	for _, name := range allowed {
+		ns := &v1.Namespace{}
+		if err := rclient.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if errors.IsForbidden(err) {
+				log.Info("operator has no permissions to get namespaces, namespace selector matches all watched namespaces", "namespaces", strings.Join(allowed, ","))
+				return allowed, nil
+			}
+			return nil, fmt.Errorf("cannot get namespace: %s, err: %w", name, err)
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			matchedNs = append(matchedNs, name)
+		}
+	}
+	log.Info("namespaced matched by selector", "ns", strings.Join(matchedNs, ","))
+	return matchedNs, nil
+}
+
+// watchedNamespaces returns nil, if objects can be listed from all namespaces,
+// otherwise it returns namespaces, which are not denied.
+func watchedNamespaces(ctx context.Context, rclient client.Client) ([]string, error) {
+	if len(config.MustGetBaseConfig().Namespaces.DenyList) == 0 {
+		// cache is already restricted by allow list
+		return nil, nil
+	}
+	return selectNamespaces(ctx, rclient, labels.Everything())
+}
+
 func SelectRules(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclient client.Client) (map[string]string, error) {
-	rules := map[string]string{}
+	vmRules, _, err := selectVMRules(ctx, cr, rclient)
+	if err != nil {
+		return nil, err
+	}
+	rules, _, err := buildRulesContent(cr, vmRules)
+	return rules, err
+}
+
+// selectVMRules returns VMRules matched by VMAlert rule selectors and namespaces,
+// where they were selected. Namespaces are nil, if rules were selected from all namespaces.
+func selectVMRules(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclient client.Client) ([]*victoriametricsv1beta1.VMRule, []string, error) {
 	namespaces := []string{}
 
 	//use only object's namespace
 	if cr.Spec.RuleNamespaceSelector == nil {
 		namespaces = append(namespaces, cr.Namespace)
 	} else if cr.Spec.RuleNamespaceSelector.MatchExpressions == nil && cr.Spec.RuleNamespaceSelector.MatchLabels == nil {
-		// all namespaces matched
-		namespaces = nil
+		// all namespaces watched by operator
+		var err error
+		namespaces, err = watchedNamespaces(ctx, rclient)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot select watched namespaces: %w", err)
+		}
 	} else {
 		//filter for specific namespaces
 		nsSelector, err := metav1.LabelSelectorAsSelector(cr.Spec.RuleNamespaceSelector)
 		if err != nil {
-			return nil, fmt.Errorf("cannot convert ruleNamespace selector: %w", err)
+			return nil, nil, fmt.Errorf("cannot convert ruleNamespace selector: %w", err)
 		}
 		namespaces, err = selectNamespaces(ctx, rclient, nsSelector)
 		if err != nil {
-			return nil, fmt.Errorf("cannot select namespaces for rule match: %w", err)
+			return nil, nil, fmt.Errorf("cannot select namespaces for rule match: %w", err)
 		}
 	}
 
@@ -188,7 +258,7 @@ func SelectRules(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclien
 
 	ruleSelector, err := metav1.LabelSelectorAsSelector(cr.Spec.RuleSelector)
 	if err != nil {
-		return rules, fmt.Errorf("cannot convert rule label selector to selector: %w", err)
+		return nil, nil, fmt.Errorf("cannot convert rule label selector to selector: %w", err)
 	}
 	promRules := []*victoriametricsv1beta1.VMRule{}
 
@@ -198,7 +268,7 @@ func SelectRules(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclien
 		ruleNs := &victoriametricsv1beta1.VMRuleList{}
 		err = rclient.List(ctx, ruleNs, &client.ListOptions{LabelSelector: ruleSelector})
 		if err != nil {
-			return nil, fmt.Errorf("cannot list rules from all namespaces: %w", err)
+			return nil, nil, fmt.Errorf("cannot list rules from all namespaces: %w", err)
 		}
 		promRules = append(promRules, ruleNs.Items...)
 
@@ -208,21 +278,44 @@ func SelectRules(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclien
 			ruleNs := &victoriametricsv1beta1.VMRuleList{}
 			err = rclient.List(ctx, ruleNs, listOpts)
 			if err != nil {
-				return nil, fmt.Errorf("cannot list rules at namespace: %s, err: %w", ns, err)
+				return nil, nil, fmt.Errorf("cannot list rules at namespace: %s, err: %w", ns, err)
 			}
 			promRules = append(promRules, ruleNs.Items...)
 
 		}
 	}
 
+	return promRules, namespaces, nil
+}
+
+// buildRulesContent generates rule files for the given VMRules.
+// Invalid groups and rules are excluded from rule files,
+// returned errors are keyed by VMRule namespace/name.
+func buildRulesContent(cr *victoriametricsv1beta1.VMAlert, vmRules []*victoriametricsv1beta1.VMRule) (map[string]string, map[string][]victoriametricsv1beta1.RuleError, error) {
+	rules := map[string]string{}
+	ruleErrors := map[string][]victoriametricsv1beta1.RuleError{}
+
+	promRules := make([]*victoriametricsv1beta1.VMRule, 0, len(vmRules))
+	for _, vmRule := range vmRules {
+		promRules = append(promRules, vmRule.DeepCopy())
+	}
 	if cr.NeedDedupRules() {
 		log.Info("deduplicating vmalert rules", "vmalert", cr.ObjectMeta.Name)
 		promRules = deduplicateRules(promRules)
 	}
 	for _, pRule := range promRules {
-		content, err := generateContent(pRule.Spec, cr.Spec.EnforcedNamespaceLabel, pRule.Namespace)
+		spec, errs := filterInvalidRules(pRule.Spec)
+		if len(errs) > 0 {
+			log.Info("vmrule contains invalid rules, they are excluded from configuration",
+				"vmrule", pRule.Name, "namespace", pRule.Namespace, "errors", len(errs))
+			ruleErrors[pRule.Namespace+"/"+pRule.Name] = errs
+		}
+		if len(spec.Groups) == 0 {
+			continue
+		}
+		content, err := generateContent(spec, cr.Spec.EnforcedNamespaceLabel, pRule.Namespace)
 		if err != nil {
-			return nil, fmt.Errorf("cannot generate content for rule: %s, err :%w", pRule.Name, err)
+			return nil, nil, fmt.Errorf("cannot generate content for rule: %s, err :%w", pRule.Name, err)
 		}
 		rules[fmt.Sprintf("%v-%v.yaml", pRule.Namespace, pRule.Name)] = content
 	}
@@ -243,34 +336,221 @@ func SelectRules(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclien
 		"vmalert", cr.Name,
 	)
 
-	return rules, nil
+	return rules, ruleErrors, nil
+}
+
+// filterInvalidRules parses rule expressions with MetricsQL parser,
+// which is a superset of PromQL, and returns spec without invalid groups and rules.
+func filterInvalidRules(spec victoriametricsv1beta1.VMRuleSpec) (victoriametricsv1beta1.VMRuleSpec, []victoriametricsv1beta1.RuleError) {
+	var errs []victoriametricsv1beta1.RuleError
+	validSpec := victoriametricsv1beta1.VMRuleSpec{}
+	uniqGroups := make(map[string]struct{}, len(spec.Groups))
+	for _, group := range spec.Groups {
+		if err := validateRuleGroup(group, uniqGroups); err != nil {
+			errs = append(errs, victoriametricsv1beta1.RuleError{Group: group.Name, Message: err.Error()})
+			continue
+		}
+		uniqGroups[group.Name] = struct{}{}
+		rules := make([]victoriametricsv1beta1.Rule, 0, len(group.Rules))
+		for _, rule := range group.Rules {
+			if err := validateRule(rule); err != nil {
+				errs = append(errs, victoriametricsv1beta1.RuleError{Group: group.Name, Rule: ruleName(rule), Message: err.Error()})
+				continue
+			}
+			rules = append(rules, rule)
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		group.Rules = rules
+		validSpec.Groups = append(validSpec.Groups, group)
+	}
+	return validSpec, errs
+}
+
+func validateRuleGroup(group victoriametricsv1beta1.RuleGroup, uniqGroups map[string]struct{}) error {
+	if group.Name == "" {
+		return fmt.Errorf("group name cannot be empty")
+	}
+	if _, ok := uniqGroups[group.Name]; ok {
+		return fmt.Errorf("duplicate group name: %s", group.Name)
+	}
+	if group.Interval != "" {
+		if _, err := model.ParseDuration(group.Interval); err != nil {
+			return fmt.Errorf("cannot parse interval: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule victoriametricsv1beta1.Rule) error {
+	if rule.Alert == "" && rule.Record == "" {
+		return fmt.Errorf("one of alert or record must be set")
+	}
+	if rule.Alert != "" && rule.Record != "" {
+		return fmt.Errorf("only one of alert or record can be set")
+	}
+	if rule.Record != "" && rule.For != "" {
+		return fmt.Errorf("for cannot be set for recording rule")
+	}
+	if rule.For != "" {
+		if _, err := model.ParseDuration(rule.For); err != nil {
+			return fmt.Errorf("cannot parse for: %w", err)
+		}
+	}
+	expr := rule.Expr.String()
+	if expr == "" {
+		return fmt.Errorf("expr cannot be empty")
+	}
+	if _, err := metricsql.Parse(expr); err != nil {
+		return fmt.Errorf("cannot parse expr: %w", err)
+	}
+	return nil
+}
+
+func ruleName(rule victoriametricsv1beta1.Rule) string {
+	if rule.Record != "" {
+		return rule.Record
+	}
+	return rule.Alert
+}
+
+// updateRulesStatus updates status for VMRules selected by VMAlert
+// and removes VMAlert from status of VMRules, which are not selected anymore.
+// Only VMRules from namespaces, where rules were selected, are checked.
+// Status isn't updated, if only lastSyncTime would be changed.
+func updateRulesStatus(ctx context.Context, rclient client.Client, cr *victoriametricsv1beta1.VMAlert, namespaces []string, vmRules []*victoriametricsv1beta1.VMRule, ruleErrors map[string][]victoriametricsv1beta1.RuleError) error {
+	vmAlertName := cr.Namespace + "/" + cr.Name
+	selected := make(map[string]struct{}, len(vmRules))
+	for _, vmRule := range vmRules {
+		selected[vmRule.Namespace+"/"+vmRule.Name] = struct{}{}
+	}
+
+	var allRules []*victoriametricsv1beta1.VMRule
+	if namespaces == nil {
+		ruleList := &victoriametricsv1beta1.VMRuleList{}
+		if err := rclient.List(ctx, ruleList); err != nil {
+			return fmt.Errorf("cannot list vmrules: %w", err)
+		}
+		allRules = append(allRules, ruleList.Items...)
+	}
+	for _, ns := range namespaces {
+		ruleList := &victoriametricsv1beta1.VMRuleList{}
+		if err := rclient.List(ctx, ruleList, &client.ListOptions{Namespace: ns}); err != nil {
+			return fmt.Errorf("cannot list vmrules at namespace: %s, err: %w", ns, err)
+		}
+		allRules = append(allRules, ruleList.Items...)
+	}
+
+	syncTime := metav1.Now()
+	for _, vmRule := range allRules {
+		key := vmRule.Namespace + "/" + vmRule.Name
+		_, ok := selected[key]
+		alerts, errs := ruleStatusWithVMAlert(vmRule.Status, vmAlertName, ok, ruleErrors[key])
+		if stringSlicesEqual(alerts, vmRule.Status.VMAlerts) && ruleErrorsEqual(errs, vmRule.Status.Errors) &&
+			(!ok || vmRule.Status.LastSyncTime != nil) {
+			continue
+		}
+		vmRule.Status.VMAlerts = alerts
+		vmRule.Status.Errors = errs
+		if ok {
+			vmRule.Status.LastSyncTime = &syncTime
+		}
+		if err := rclient.Status().Update(ctx, vmRule); err != nil {
+			return fmt.Errorf("cannot update status for vmrule: %s, err: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// RemoveVMAlertFromRulesStatus removes deleted VMAlert and its errors from status of VMRules.
+func RemoveVMAlertFromRulesStatus(ctx context.Context, rclient client.Client, vmAlert types.NamespacedName) error {
+	vmAlertName := vmAlert.Namespace + "/" + vmAlert.Name
+	ruleList := &victoriametricsv1beta1.VMRuleList{}
+	if err := rclient.List(ctx, ruleList); err != nil {
+		return fmt.Errorf("cannot list vmrules: %w", err)
+	}
+	for _, vmRule := range ruleList.Items {
+		alerts, errs := ruleStatusWithVMAlert(vmRule.Status, vmAlertName, false, nil)
+		if stringSlicesEqual(alerts, vmRule.Status.VMAlerts) && ruleErrorsEqual(errs, vmRule.Status.Errors) {
+			continue
+		}
+		vmRule.Status.VMAlerts = alerts
+		vmRule.Status.Errors = errs
+		if err := rclient.Status().Update(ctx, vmRule); err != nil {
+			return fmt.Errorf("cannot update status for vmrule: %s/%s, err: %w", vmRule.Namespace, vmRule.Name, err)
+		}
+	}
+	return nil
+}
+
+// ruleStatusWithVMAlert returns VMAlerts and errors of VMRule status, where entries of given VMAlert
+// are replaced with errs, if rule is selected by it, or removed otherwise.
+// Errors of other VMAlerts are kept as is.
+func ruleStatusWithVMAlert(status victoriametricsv1beta1.VMRuleStatus, vmAlertName string, selected bool, errs []victoriametricsv1beta1.RuleError) ([]string, []victoriametricsv1beta1.RuleError) {
+	var alerts []string
+	for _, name := range status.VMAlerts {
+		if name != vmAlertName {
+			alerts = append(alerts, name)
+		}
+	}
+	var ruleErrs []victoriametricsv1beta1.RuleError
+	for _, ruleErr := range status.Errors {
+		if ruleErr.VMAlert != vmAlertName {
+			ruleErrs = append(ruleErrs, ruleErr)
+		}
+	}
+	if selected {
+		alerts = append(alerts, vmAlertName)
+		sort.Strings(alerts)
+		for _, ruleErr := range errs {
+			ruleErr.VMAlert = vmAlertName
+			ruleErrs = append(ruleErrs, ruleErr)
+		}
+		sort.SliceStable(ruleErrs, func(i, j int) bool {
+			return ruleErrs[i].VMAlert < ruleErrs[j].VMAlert
+		})
+	}
+	return alerts, ruleErrs
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func ruleErrorsEqual(a, b []victoriametricsv1beta1.RuleError) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 func generateContent(promRule victoriametricsv1beta1.VMRuleSpec, enforcedNsLabel, ns string) (string, error) {
 	if enforcedNsLabel != "" {
-		log.Info("enforce ns label is partly supported, create issue for it")
 		for gi, group := range promRule.Groups {
-			for ri := range group.Rules {
+			for ri, rule := range group.Rules {
 				if len(promRule.Groups[gi].Rules[ri].Labels) == 0 {
 					promRule.Groups[gi].Rules[ri].Labels = map[string]string{}
 				}
 				promRule.Groups[gi].Rules[ri].Labels[enforcedNsLabel] = ns
-				//TODO its required by openshift, add it later?
-				//expr := r.Expr.String()
-				//parsedExpr, err := promql.ParseExpr(expr)
-				//if err != nil {
-				//	return "", errors.Wrap(err, "failed to parse promql expression")
-				//}
-				//err = injectproxy.SetRecursive(parsedExpr, []*labels.Matcher{{
-				//	Name:  enforcedNsLabel,
-				//	Type:  labels.MatchEqual,
-				//	Value: ns,
-				//}})
-				//if err != nil {
-				//	return "", fmt.Errorf("failed to inject labels to expression: %w",err)
-				//}
-
-				//				promRule.Groups[gi].Rules[ri].Expr = intstr.FromString(parsedExpr.String())
+				expr, err := enforceNamespaceLabelAtExpr(rule.Expr.String(), enforcedNsLabel, ns)
+				if err != nil {
+					return "", fmt.Errorf("cannot enforce namespace label for rule: %s at group: %s, err: %w", ruleName(rule), group.Name, err)
+				}
+				promRule.Groups[gi].Rules[ri].Expr = intstr.FromString(expr)
 			}
 		}
 	}
@@ -281,6 +561,32 @@ func generateContent(promRule victoriametricsv1beta1.VMRuleSpec, enforcedNsLabel
 	return string(content), nil
 }
 
+// enforceNamespaceLabelAtExpr rewrites every series selector at the given expression
+// with label matcher enforcedNsLabel="ns".
+// Matchers for enforcedNsLabel defined by user are replaced,
+// so rule cannot query series from other namespaces.
+func enforceNamespaceLabelAtExpr(expr, enforcedNsLabel, ns string) (string, error) {
+	parsedExpr, err := metricsql.Parse(expr)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse expr: %w", err)
+	}
+	metricsql.VisitAll(parsedExpr, func(e metricsql.Expr) {
+		me, ok := e.(*metricsql.MetricExpr)
+		if !ok {
+			return
+		}
+		filters := make([]metricsql.LabelFilter, 0, len(me.LabelFilters)+1)
+		for _, lf := range me.LabelFilters {
+			if lf.Label == enforcedNsLabel {
+				continue
+			}
+			filters = append(filters, lf)
+		}
+		me.LabelFilters = append(filters, metricsql.LabelFilter{Label: enforcedNsLabel, Value: ns})
+	})
+	return string(parsedExpr.AppendString(nil)), nil
+}
+
 // makeRulesConfigMaps takes a VMAlert configuration and rule files and
 // returns a list of Kubernetes ConfigMaps to be later on mounted into the
 // Prometheus instance.
@@ -2,19 +2,24 @@ package factory
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
 	"github.com/go-logr/logr"
 	"github.com/go-test/deep"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -68,6 +73,57 @@ func Test_selectNamespaces(t *testing.T) {
 	}
 }
 
+// forbiddenNamespacesClient returns Forbidden error for namespace reads as api server does for namespaced Role.
+type forbiddenNamespacesClient struct {
+	client.Client
+}
+
+func (c *forbiddenNamespacesClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if _, ok := obj.(*v1.Namespace); ok {
+		return errors.NewForbidden(v1.Resource("namespaces"), key.Name, fmt.Errorf("namespaced role"))
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func Test_selectAllowedNamespaces(t *testing.T) {
+	predefinedNs := []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"monitoring": "enabled"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "not-watched", Labels: map[string]string{"monitoring": "enabled"}}},
+	}
+	allowed := []string{"tenant-a", "tenant-b", "deleted"}
+	tests := []struct {
+		name      string
+		forbidden bool
+		want      []string
+	}{
+		{
+			name: "match labels of allowed namespaces",
+			want: []string{"tenant-a"},
+		},
+		{
+			name:      "namespaces are forbidden",
+			forbidden: true,
+			want:      allowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rclient client.Client = fake.NewFakeClientWithScheme(scheme.Scheme, predefinedNs...)
+			if tt.forbidden {
+				rclient = &forbiddenNamespacesClient{Client: rclient}
+			}
+			got, err := selectAllowedNamespaces(context.TODO(), rclient, allowed, labels.SelectorFromSet(labels.Set{"monitoring": "enabled"}))
+			if err != nil {
+				t.Fatalf("selectAllowedNamespaces() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectAllowedNamespaces() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSelectRules(t *testing.T) {
 	type args struct {
 		p *victoriametricsv1beta1.VMAlert
@@ -100,7 +156,7 @@ func TestSelectRules(t *testing.T) {
 				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
 				&victoriametricsv1beta1.VMRule{ObjectMeta: metav1.ObjectMeta{Name: "error-alert", Namespace: "default"}, Spec: victoriametricsv1beta1.VMRuleSpec{
 					Groups: []victoriametricsv1beta1.RuleGroup{{Name: "error-alert", Interval: "10s", Rules: []victoriametricsv1beta1.Rule{
-						{Alert: "", Expr: intstr.IntOrString{IntVal: 10}, For: "10s", Labels: nil, Annotations: nil},
+						{Alert: "error", Expr: intstr.IntOrString{IntVal: 10}, For: "10s", Labels: nil, Annotations: nil},
 					}}},
 				}},
 			},
@@ -119,12 +175,12 @@ func TestSelectRules(t *testing.T) {
 				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring", Labels: map[string]string{"monitoring": "enabled"}}},
 				&victoriametricsv1beta1.VMRule{ObjectMeta: metav1.ObjectMeta{Name: "error-alert", Namespace: "default"}, Spec: victoriametricsv1beta1.VMRuleSpec{
 					Groups: []victoriametricsv1beta1.RuleGroup{{Name: "error-alert", Interval: "10s", Rules: []victoriametricsv1beta1.Rule{
-						{Alert: "", Expr: intstr.IntOrString{IntVal: 10}, For: "10s", Labels: nil, Annotations: nil},
+						{Alert: "error", Expr: intstr.IntOrString{IntVal: 10}, For: "10s", Labels: nil, Annotations: nil},
 					}}},
 				}},
 				&victoriametricsv1beta1.VMRule{ObjectMeta: metav1.ObjectMeta{Name: "error-alert-at-monitoring", Namespace: "monitoring"}, Spec: victoriametricsv1beta1.VMRuleSpec{
 					Groups: []victoriametricsv1beta1.RuleGroup{{Name: "error-alert", Interval: "10s", Rules: []victoriametricsv1beta1.Rule{
-						{Alert: "", Expr: intstr.IntOrString{IntVal: 10}, For: "10s", Labels: nil, Annotations: nil},
+						{Alert: "error", Expr: intstr.IntOrString{IntVal: 10}, For: "10s", Labels: nil, Annotations: nil},
 					}}},
 				}},
 			},
@@ -320,3 +376,310 @@ func Test_deduplicateRules(t *testing.T) {
 		})
 	}
 }
+
+func Test_filterInvalidRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     victoriametricsv1beta1.VMRuleSpec
+		want     victoriametricsv1beta1.VMRuleSpec
+		wantErrs []victoriametricsv1beta1.RuleError
+	}{
+		{
+			name: "all rules are valid",
+			spec: victoriametricsv1beta1.VMRuleSpec{Groups: []victoriametricsv1beta1.RuleGroup{
+				{Name: "group-1", Rules: []victoriametricsv1beta1.Rule{
+					{Alert: "down", Expr: intstr.FromString("up == 0"), For: "5m"},
+					{Record: "job:up:sum", Expr: intstr.FromString("sum(up) by (job)")},
+				}},
+			}},
+			want: victoriametricsv1beta1.VMRuleSpec{Groups: []victoriametricsv1beta1.RuleGroup{
+				{Name: "group-1", Rules: []victoriametricsv1beta1.Rule{
+					{Alert: "down", Expr: intstr.FromString("up == 0"), For: "5m"},
+					{Record: "job:up:sum", Expr: intstr.FromString("sum(up) by (job)")},
+				}},
+			}},
+		},
+		{
+			name: "exclude rule with broken expr",
+			spec: victoriametricsv1beta1.VMRuleSpec{Groups: []victoriametricsv1beta1.RuleGroup{
+				{Name: "group-1", Rules: []victoriametricsv1beta1.Rule{
+					{Alert: "down", Expr: intstr.FromString("up == 0")},
+					{Alert: "broken", Expr: intstr.FromString("sum(rate(http_requests_total[5m]) by (job)")},
+				}},
+			}},
+			want: victoriametricsv1beta1.VMRuleSpec{Groups: []victoriametricsv1beta1.RuleGroup{
+				{Name: "group-1", Rules: []victoriametricsv1beta1.Rule{
+					{Alert: "down", Expr: intstr.FromString("up == 0")},
+				}},
+			}},
+			wantErrs: []victoriametricsv1beta1.RuleError{{Group: "group-1", Rule: "broken"}},
+		},
+		{
+			name: "exclude group with broken interval and empty group",
+			spec: victoriametricsv1beta1.VMRuleSpec{Groups: []victoriametricsv1beta1.RuleGroup{
+				{Name: "group-1", Interval: "1 minute", Rules: []victoriametricsv1beta1.Rule{
+					{Alert: "down", Expr: intstr.FromString("up == 0")},
+				}},
+				{Name: "group-2", Rules: []victoriametricsv1beta1.Rule{
+					{Record: "job:up:sum", Expr: intstr.FromString("sum(up) by (job)"), For: "1m"},
+				}},
+			}},
+			want: victoriametricsv1beta1.VMRuleSpec{},
+			wantErrs: []victoriametricsv1beta1.RuleError{
+				{Group: "group-1"},
+				{Group: "group-2", Rule: "job:up:sum"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErrs := filterInvalidRules(tt.spec)
+			if diff := deep.Equal(got, tt.want); len(diff) > 0 {
+				t.Errorf("filterInvalidRules() spec diff: %v", diff)
+			}
+			if len(gotErrs) != len(tt.wantErrs) {
+				t.Fatalf("filterInvalidRules() got %d errors: %v, want %d", len(gotErrs), gotErrs, len(tt.wantErrs))
+			}
+			for i, gotErr := range gotErrs {
+				if gotErr.Group != tt.wantErrs[i].Group || gotErr.Rule != tt.wantErrs[i].Rule || gotErr.Message == "" {
+					t.Errorf("filterInvalidRules() got error: %v, want: %v", gotErr, tt.wantErrs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdateRuleConfigMaps_status(t *testing.T) {
+	cr := &victoriametricsv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base-vmalert", Namespace: "default"},
+		Spec: victoriametricsv1beta1.VMAlertSpec{
+			RuleSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+		},
+	}
+	other := &victoriametricsv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+	}
+	predefinedObjects := []runtime.Object{
+		&victoriametricsv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "rules", Namespace: "default", Labels: map[string]string{"team": "a"}},
+			Spec: victoriametricsv1beta1.VMRuleSpec{Groups: []victoriametricsv1beta1.RuleGroup{
+				{Name: "group-1", Rules: []victoriametricsv1beta1.Rule{
+					{Alert: "down", Expr: intstr.FromString("up == 0")},
+					{Alert: "broken", Expr: intstr.FromString("up ==")},
+				}},
+			}},
+		},
+		&victoriametricsv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "default"},
+			Status: victoriametricsv1beta1.VMRuleStatus{
+				VMAlerts: []string{"default/base-vmalert", "monitoring/other"},
+				Errors: []victoriametricsv1beta1.RuleError{
+					{VMAlert: "default/base-vmalert", Group: "group-1", Message: "duplicate group name: group-1"},
+					{VMAlert: "monitoring/other", Group: "group-1", Message: "duplicate group name: group-1"},
+				},
+			},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...)
+	if _, err := CreateOrUpdateRuleConfigMaps(context.TODO(), cr, fclient); err != nil {
+		t.Fatalf("CreateOrUpdateRuleConfigMaps() unexpected error: %v", err)
+	}
+	if _, err := CreateOrUpdateRuleConfigMaps(context.TODO(), other, fclient); err != nil {
+		t.Fatalf("CreateOrUpdateRuleConfigMaps() unexpected error: %v", err)
+	}
+
+	var cm v1.ConfigMap
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "vm-base-vmalert-rulefiles-0"}, &cm); err != nil {
+		t.Fatalf("cannot get rules configmap: %v", err)
+	}
+	content := cm.Data["default-rules.yaml"]
+	if !strings.Contains(content, "alert: down") || strings.Contains(content, "alert: broken") {
+		t.Errorf("unexpected rules content: %s", content)
+	}
+
+	var got victoriametricsv1beta1.VMRule
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "rules"}, &got); err != nil {
+		t.Fatalf("cannot get vmrule: %v", err)
+	}
+	if !reflect.DeepEqual(got.Status.VMAlerts, []string{"default/base-vmalert", "default/other"}) {
+		t.Errorf("unexpected vmalerts at status: %v", got.Status.VMAlerts)
+	}
+	if len(got.Status.Errors) != 2 ||
+		got.Status.Errors[0].VMAlert != "default/base-vmalert" || got.Status.Errors[0].Rule != "broken" ||
+		got.Status.Errors[1].VMAlert != "default/other" || got.Status.Errors[1].Rule != "broken" {
+		t.Errorf("errors must be set for each vmalert, got: %v", got.Status.Errors)
+	}
+	if got.Status.LastSyncTime == nil {
+		t.Errorf("lastSyncTime must be set")
+	}
+
+	// status must not be updated, if only lastSyncTime is changed
+	resourceVersion := got.ResourceVersion
+	if _, err := CreateOrUpdateRuleConfigMaps(context.TODO(), cr, fclient); err != nil {
+		t.Fatalf("CreateOrUpdateRuleConfigMaps() unexpected error: %v", err)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "rules"}, &got); err != nil {
+		t.Fatalf("cannot get vmrule: %v", err)
+	}
+	if got.ResourceVersion != resourceVersion {
+		t.Errorf("vmrule status must not be updated, resourceVersion changed from %s to %s", resourceVersion, got.ResourceVersion)
+	}
+
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "stale"}, &got); err != nil {
+		t.Fatalf("cannot get vmrule: %v", err)
+	}
+	if !reflect.DeepEqual(got.Status.VMAlerts, []string{"default/other", "monitoring/other"}) {
+		t.Errorf("vmalert must be removed from not selected vmrule status, got: %v", got.Status.VMAlerts)
+	}
+	if len(got.Status.Errors) != 1 || got.Status.Errors[0].VMAlert != "monitoring/other" {
+		t.Errorf("errors of not selecting vmalert must be removed, got: %v", got.Status.Errors)
+	}
+}
+
+func Test_updateRulesStatus_namespaces(t *testing.T) {
+	cr := &victoriametricsv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base-vmalert", Namespace: "default"},
+	}
+	predefinedObjects := []runtime.Object{
+		&victoriametricsv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "rules", Namespace: "monitoring"},
+			Status:     victoriametricsv1beta1.VMRuleStatus{VMAlerts: []string{"default/base-vmalert"}},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...)
+	if err := updateRulesStatus(context.TODO(), fclient, cr, []string{"default"}, nil, nil); err != nil {
+		t.Fatalf("updateRulesStatus() unexpected error: %v", err)
+	}
+	var got victoriametricsv1beta1.VMRule
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "monitoring", Name: "rules"}, &got); err != nil {
+		t.Fatalf("cannot get vmrule: %v", err)
+	}
+	if !reflect.DeepEqual(got.Status.VMAlerts, []string{"default/base-vmalert"}) {
+		t.Errorf("vmrule outside of selected namespaces must not be updated, got: %v", got.Status.VMAlerts)
+	}
+}
+
+func TestRemoveVMAlertFromRulesStatus(t *testing.T) {
+	predefinedObjects := []runtime.Object{
+		&victoriametricsv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "rules", Namespace: "monitoring"},
+			Status: victoriametricsv1beta1.VMRuleStatus{
+				VMAlerts: []string{"default/deleted-vmalert", "default/other-vmalert"},
+				Errors: []victoriametricsv1beta1.RuleError{
+					{Group: "group", Rule: "rule", Message: "bad rule", VMAlert: "default/deleted-vmalert"},
+					{Group: "group", Rule: "rule", Message: "bad rule", VMAlert: "default/other-vmalert"},
+				},
+			},
+		},
+		&victoriametricsv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-selected", Namespace: "default", ResourceVersion: "5"},
+			Status:     victoriametricsv1beta1.VMRuleStatus{VMAlerts: []string{"default/other-vmalert"}},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...)
+	if err := RemoveVMAlertFromRulesStatus(context.TODO(), fclient, types.NamespacedName{Namespace: "default", Name: "deleted-vmalert"}); err != nil {
+		t.Fatalf("RemoveVMAlertFromRulesStatus() unexpected error: %v", err)
+	}
+	var got victoriametricsv1beta1.VMRule
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "monitoring", Name: "rules"}, &got); err != nil {
+		t.Fatalf("cannot get vmrule: %v", err)
+	}
+	if !reflect.DeepEqual(got.Status.VMAlerts, []string{"default/other-vmalert"}) {
+		t.Errorf("deleted vmalert must be removed from status, got: %v", got.Status.VMAlerts)
+	}
+	if len(got.Status.Errors) != 1 || got.Status.Errors[0].VMAlert != "default/other-vmalert" {
+		t.Errorf("errors of deleted vmalert must be removed from status, got: %v", got.Status.Errors)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "not-selected"}, &got); err != nil {
+		t.Fatalf("cannot get vmrule: %v", err)
+	}
+	if got.ResourceVersion != "5" {
+		t.Errorf("vmrule without deleted vmalert must not be updated, got resourceVersion: %s", got.ResourceVersion)
+	}
+}
+
+func Test_enforceNamespaceLabelAtExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "simple selector",
+			expr: `up`,
+			want: `up{namespace="team-a"}`,
+		},
+		{
+			name: "replace user defined namespace matcher",
+			expr: `up{namespace="other", job="node"}`,
+			want: `up{job="node", namespace="team-a"}`,
+		},
+		{
+			name: "replace regexp namespace matcher without metric name",
+			expr: `{__name__=~"node_.*", namespace=~".+"} offset 5m`,
+			want: `{__name__=~"node_.*", namespace="team-a"} offset 5m`,
+		},
+		{
+			name: "nested functions",
+			expr: `sum(rate(http_requests_total{code=~"5.."}[5m])) by (job)`,
+			want: `sum(rate(http_requests_total{code=~"5..", namespace="team-a"}[5m])) by (job)`,
+		},
+		{
+			name: "subquery",
+			expr: `max_over_time(rate(http_requests_total[1m])[10m:1m])`,
+			want: `max_over_time(rate(http_requests_total{namespace="team-a"}[1m])[10m:1m])`,
+		},
+		{
+			name: "binary operators",
+			expr: `sum(rate(errors_total[5m])) / on(job) group_left sum(rate(requests_total[5m])) > 0.1`,
+			want: `(sum(rate(errors_total{namespace="team-a"}[5m])) / on (job) group_left () sum(rate(requests_total{namespace="team-a"}[5m]))) > 0.1`,
+		},
+		{
+			name: "expression without selectors",
+			expr: `vector(1)`,
+			want: `vector(1)`,
+		},
+		{
+			name:    "broken expression",
+			expr:    `sum(up`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enforceNamespaceLabelAtExpr(tt.expr, "namespace", "team-a")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("enforceNamespaceLabelAtExpr() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("enforceNamespaceLabelAtExpr() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_generateContent(t *testing.T) {
+	spec := victoriametricsv1beta1.VMRuleSpec{Groups: []victoriametricsv1beta1.RuleGroup{
+		{Name: "group-1", Rules: []victoriametricsv1beta1.Rule{
+			{Alert: "down", Expr: intstr.FromString(`up{job="node"} == 0`), For: "5m"},
+		}},
+	}}
+	want := `groups:
+- name: group-1
+  rules:
+  - alert: down
+    expr: up{job="node", namespace="team-a"} == 0
+    for: 5m
+    labels:
+      namespace: team-a
+`
+	got, err := generateContent(spec, "namespace", "team-a")
+	if err != nil {
+		t.Fatalf("generateContent() unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("generateContent() got = %v, want %v", got, want)
+	}
+}
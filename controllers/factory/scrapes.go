@@ -19,7 +19,9 @@ import (
 
 var invalidDNS1123Characters = regexp.MustCompile("[^-a-z0-9]+")
 
-func CreateOrUpdateConfigurationSecret(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rclient client.Client, c *config.BaseOperatorConf) error {
+// CreateOrUpdateConfigurationSecret generates scrape configuration for vmagent.
+// secretsCache holds secrets fetched during current reconcile, it's keyed by namespace/name.
+func CreateOrUpdateConfigurationSecret(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rclient client.Client, c *config.BaseOperatorConf, secretsCache map[string]*v1.Secret) error {
 	// If no service or pod scrape selectors are configured, the user wants to
 	// manage configuration themselves. Do create an empty Secret if it doesn't
 	// exist.
@@ -60,23 +62,17 @@ func CreateOrUpdateConfigurationSecret(ctx context.Context, cr *victoriametricsv
 		return fmt.Errorf("selecting VMProbes failed: %w", err)
 	}
 
-	SecretsInNS := &v1.SecretList{}
-	err = rclient.List(ctx, SecretsInNS)
-	if err != nil {
-		return fmt.Errorf("cannot list secrets at vmagent namespace: %w", err)
-	}
-
-	basicAuthSecrets, err := loadBasicAuthSecrets(ctx, rclient, smons, cr.Spec.APIServerConfig, nil, SecretsInNS)
+	basicAuthSecrets, err := loadBasicAuthSecrets(ctx, rclient, smons, cr.Spec.APIServerConfig, nil, cr.Namespace, secretsCache)
 	if err != nil {
 		return fmt.Errorf("cannot load basic secrets for ServiceMonitors: %w", err)
 	}
 
-	bearerTokens, err := loadBearerTokensFromSecrets(ctx, rclient, smons, nil, SecretsInNS)
+	bearerTokens, err := loadBearerTokensFromSecrets(ctx, rclient, smons, nil, cr.Namespace, secretsCache)
 	if err != nil {
 		return fmt.Errorf("cannot load bearer tokens from secrets for ServiceMonitors: %w", err)
 	}
 
-	additionalScrapeConfigs, err := loadAdditionalScrapeConfigsSecret(cr.Spec.AdditionalScrapeConfigs, SecretsInNS)
+	additionalScrapeConfigs, err := loadAdditionalScrapeConfigsSecret(ctx, rclient, cr.Spec.AdditionalScrapeConfigs, cr.Namespace, secretsCache)
 	if err != nil {
 		return fmt.Errorf("loading additional scrape configs from Secret failed: %w", err)
 	}
@@ -144,7 +140,12 @@ func SelectServiceScrapes(ctx context.Context, cr *victoriametricsv1beta1.VMAgen
 	if cr.Spec.ServiceScrapeNamespaceSelector == nil {
 		namespaces = append(namespaces, cr.Namespace)
 	} else if cr.Spec.ServiceScrapeNamespaceSelector.MatchExpressions == nil && cr.Spec.ServiceScrapeNamespaceSelector.MatchLabels == nil {
-		namespaces = nil
+		// all namespaces watched by operator
+		var err error
+		namespaces, err = watchedNamespaces(ctx, rclient)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select watched namespaces: %w", err)
+		}
 	} else {
 		log.Info("namspace selector for serviceScrapes", "selector", cr.Spec.ServiceScrapeNamespaceSelector.String())
 		nsSelector, err := metav1.LabelSelectorAsSelector(cr.Spec.ServiceScrapeNamespaceSelector)
@@ -238,7 +239,12 @@ func SelectPodScrapes(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, r
 	if cr.Spec.PodScrapeNamespaceSelector == nil {
 		namespaces = append(namespaces, cr.Namespace)
 	} else if cr.Spec.PodScrapeNamespaceSelector.MatchExpressions == nil && cr.Spec.PodScrapeNamespaceSelector.MatchLabels == nil {
-		namespaces = nil
+		// all namespaces watched by operator
+		var err error
+		namespaces, err = watchedNamespaces(ctx, rclient)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select watched namespaces: %w", err)
+		}
 	} else {
 		log.Info("selector for podScrape", "vmagent", cr.Name, "selector", cr.Spec.PodScrapeNamespaceSelector.String())
 		nsSelector, err := metav1.LabelSelectorAsSelector(cr.Spec.PodScrapeNamespaceSelector)
@@ -313,10 +319,15 @@ func SelectVMProbes(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rcl
 	// combine result
 	if cr.Spec.ProbeNamespaceSelector == nil {
 		namespaces = append(namespaces, cr.Namespace)
-	} else if cr.Spec.ProbeNamespaceSelector.MatchExpressions == nil && cr.Spec.PodScrapeNamespaceSelector.MatchLabels == nil {
-		namespaces = nil
+	} else if cr.Spec.ProbeNamespaceSelector.MatchExpressions == nil && cr.Spec.ProbeNamespaceSelector.MatchLabels == nil {
+		// all namespaces watched by operator
+		var err error
+		namespaces, err = watchedNamespaces(ctx, rclient)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select watched namespaces: %w", err)
+		}
 	} else {
-		log.Info("selector for VMProbe", "vmagent", cr.Name, "selector", cr.Spec.PodScrapeNamespaceSelector.String())
+		log.Info("selector for VMProbe", "vmagent", cr.Name, "selector", cr.Spec.ProbeNamespaceSelector.String())
 		nsSelector, err := metav1.LabelSelectorAsSelector(cr.Spec.ProbeNamespaceSelector)
 		if err != nil {
 			return nil, fmt.Errorf("cannot convert ProbeNamespaceSelector to labelSelector: %w", err)
@@ -384,11 +395,11 @@ func loadBasicAuthSecrets(
 	mons map[string]*victoriametricsv1beta1.VMServiceScrape,
 	apiserverConfig *victoriametricsv1beta1.APIServerConfig,
 	remoteWriteSpecs []victoriametricsv1beta1.VMAgentRemoteWriteSpec,
-	SecretsInPromNS *v1.SecretList,
+	ns string,
+	nsSecretCache map[string]*v1.Secret,
 ) (map[string]BasicAuthCredentials, error) {
 
 	secrets := map[string]BasicAuthCredentials{}
-	nsSecretCache := make(map[string]*v1.Secret)
 	for _, mon := range mons {
 		for i, ep := range mon.Spec.Endpoints {
 			if ep.BasicAuth != nil {
@@ -404,7 +415,7 @@ func loadBasicAuthSecrets(
 
 	// load apiserver basic auth secret
 	if apiserverConfig != nil && apiserverConfig.BasicAuth != nil {
-		credentials, err := loadBasicAuthSecret(apiserverConfig.BasicAuth, SecretsInPromNS)
+		credentials, err := loadBasicAuthSecretFromAPI(ctx, rclient, apiserverConfig.BasicAuth, ns, nsSecretCache)
 		if err != nil {
 			return nil, fmt.Errorf("could not generate basicAuth for apiserver config. %w", err)
 		}
@@ -416,7 +427,7 @@ func loadBasicAuthSecrets(
 		if rws.BasicAuth == nil {
 			continue
 		}
-		credentials, err := loadBasicAuthSecret(rws.BasicAuth, SecretsInPromNS)
+		credentials, err := loadBasicAuthSecretFromAPI(ctx, rclient, rws.BasicAuth, ns, nsSecretCache)
 		if err != nil {
 			return nil, fmt.Errorf("could not generate basicAuth for remote write spec %s config. %w", rws.URL, err)
 		}
@@ -431,10 +442,10 @@ func loadBearerTokensFromSecrets(
 	rclient client.Client,
 	mons map[string]*victoriametricsv1beta1.VMServiceScrape,
 	remoteWriteSpecs []victoriametricsv1beta1.VMAgentRemoteWriteSpec,
-	SecretsInPromNS *v1.SecretList,
+	ns string,
+	nsSecretCache map[string]*v1.Secret,
 ) (map[string]BearerToken, error) {
 	tokens := map[string]BearerToken{}
-	nsSecretCache := make(map[string]*v1.Secret)
 
 	for _, mon := range mons {
 		for i, ep := range mon.Spec.Endpoints {
@@ -466,56 +477,19 @@ func loadBearerTokensFromSecrets(
 		if rws.BearerTokenSecret == nil {
 			continue
 		}
-		for _, secret := range SecretsInPromNS.Items {
-			if secret.Name != rws.BearerTokenSecret.Name {
-				continue
-			}
-			token, err := extractCredKey(&secret, *rws.BearerTokenSecret)
-			if err != nil {
-				return nil, fmt.Errorf(
-					"failed to extract bearertoken for remoteWriteSpec %s from secret %s. %w ",
-					rws.URL, rws.BearerTokenSecret.Name, err,
-				)
-			}
-			tokens[fmt.Sprintf("remoteWriteSpec/%s", rws.URL)] = BearerToken(token)
+		token, err := getCredFromSecret(ctx, rclient, ns, *rws.BearerTokenSecret, ns+"/"+rws.BearerTokenSecret.Name, nsSecretCache)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to extract bearertoken for remoteWriteSpec %s from secret %s. %w ",
+				rws.URL, rws.BearerTokenSecret.Name, err,
+			)
 		}
+		tokens[fmt.Sprintf("remoteWriteSpec/%s", rws.URL)] = BearerToken(token)
 	}
 
 	return tokens, nil
 }
 
-func loadBasicAuthSecret(basicAuth *victoriametricsv1beta1.BasicAuth, s *v1.SecretList) (BasicAuthCredentials, error) {
-	var username string
-	var password string
-	var err error
-
-	for _, secret := range s.Items {
-
-		if secret.Name == basicAuth.Username.Name {
-			if username, err = extractCredKey(&secret, basicAuth.Username); err != nil {
-				return BasicAuthCredentials{}, err
-			}
-		}
-
-		if secret.Name == basicAuth.Password.Name {
-			if password, err = extractCredKey(&secret, basicAuth.Password); err != nil {
-				return BasicAuthCredentials{}, err
-			}
-
-		}
-		if username != "" && password != "" {
-			break
-		}
-	}
-
-	if username == "" && password == "" {
-		return BasicAuthCredentials{}, fmt.Errorf("basic auth username and password secret not found")
-	}
-
-	return BasicAuthCredentials{username: username, password: password}, nil
-
-}
-
 func extractCredKey(secret *v1.Secret, sel v1.SecretKeySelector) (string, error) {
 	if s, ok := secret.Data[sel.Key]; ok {
 		return string(s), nil
@@ -586,22 +560,26 @@ func loadBasicAuthSecretFromAPI(ctx context.Context, rclient client.Client, basi
 	return BasicAuthCredentials{username: username, password: password}, nil
 }
 
-func loadAdditionalScrapeConfigsSecret(additionalScrapeConfigs *v1.SecretKeySelector, s *v1.SecretList) ([]byte, error) {
-	if additionalScrapeConfigs != nil {
-		for _, secret := range s.Items {
-			if secret.Name == additionalScrapeConfigs.Name {
-				if c, ok := secret.Data[additionalScrapeConfigs.Key]; ok {
-					return c, nil
-				}
-
-				return nil, fmt.Errorf("key %v could not be found in Secret %v", additionalScrapeConfigs.Key, additionalScrapeConfigs.Name)
+func loadAdditionalScrapeConfigsSecret(ctx context.Context, rclient client.Client, additionalScrapeConfigs *v1.SecretKeySelector, ns string, cache map[string]*v1.Secret) ([]byte, error) {
+	if additionalScrapeConfigs == nil {
+		return nil, nil
+	}
+	cacheKey := ns + "/" + additionalScrapeConfigs.Name
+	secret, ok := cache[cacheKey]
+	if !ok {
+		secret = &v1.Secret{}
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: ns, Name: additionalScrapeConfigs.Name}, secret); err != nil {
+			if errors.IsNotFound(err) && additionalScrapeConfigs.Optional != nil && *additionalScrapeConfigs.Optional {
+				return nil, nil
 			}
+			return nil, fmt.Errorf("cannot get additional scrape configs secret %v: %w", additionalScrapeConfigs.Name, err)
 		}
-		if additionalScrapeConfigs.Optional == nil || !*additionalScrapeConfigs.Optional {
-			return nil, fmt.Errorf("secret %v could not be found", additionalScrapeConfigs.Name)
-		}
+		cache[cacheKey] = secret
+	}
+	if c, ok := secret.Data[additionalScrapeConfigs.Key]; ok {
+		return c, nil
 	}
-	return nil, nil
+	return nil, fmt.Errorf("key %v could not be found in Secret %v", additionalScrapeConfigs.Key, additionalScrapeConfigs.Name)
 }
 
 func testForArbitraryFSAccess(e victoriametricsv1beta1.Endpoint) error {
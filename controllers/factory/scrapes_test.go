@@ -37,6 +37,14 @@ func testGetScheme() *runtime.Scheme {
 		&victoriametricsv1beta1.VMRuleList{},
 		&victoriametricsv1beta1.VMProbe{},
 		&victoriametricsv1beta1.VMProbeList{},
+		&victoriametricsv1beta1.VMCluster{},
+		&victoriametricsv1beta1.VMClusterList{},
+		&victoriametricsv1beta1.VMAuth{},
+		&victoriametricsv1beta1.VMAuthList{},
+		&victoriametricsv1beta1.VMUser{},
+		&victoriametricsv1beta1.VMUserList{},
+		&victoriametricsv1beta1.VMAlertmanagerConfig{},
+		&victoriametricsv1beta1.VMAlertmanagerConfigList{},
 	)
 	return s
 }
@@ -388,6 +396,31 @@ func TestSelectVMProbes(t *testing.T) {
 			},
 			want: []string{"default/static-probe"},
 		},
+		{
+			name: "select vmProbe with probe namespace selector only",
+			args: args{
+				cr: &victoriametricsv1beta1.VMAgent{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "example-vmagent",
+						Namespace: "default",
+					},
+					Spec: victoriametricsv1beta1.VMAgentSpec{
+						ProbeNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "infra"}},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "infra", Labels: map[string]string{"team": "infra"}}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+				&victoriametricsv1beta1.VMProbe{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "infra", Name: "infra-probe"},
+				},
+				&victoriametricsv1beta1.VMProbe{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "default-probe"},
+				},
+			},
+			want: []string{"infra/infra-probe"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -411,3 +444,86 @@ func TestSelectVMProbes(t *testing.T) {
 		})
 	}
 }
+
+func Test_loadAdditionalScrapeConfigsSecret(t *testing.T) {
+	optional := true
+	tests := []struct {
+		name              string
+		selector          *v1.SecretKeySelector
+		predefinedObjects []runtime.Object
+		want              []byte
+		wantErr           bool
+	}{
+		{
+			name: "load from own namespace",
+			selector: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "additional"},
+				Key:                  "scrape.yaml",
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "additional", Namespace: "default"},
+					Data:       map[string][]byte{"scrape.yaml": []byte("- job_name: own")},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "additional", Namespace: "other"},
+					Data:       map[string][]byte{"scrape.yaml": []byte("- job_name: other")},
+				},
+			},
+			want: []byte("- job_name: own"),
+		},
+		{
+			name: "missing secret",
+			selector: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "additional"},
+				Key:                  "scrape.yaml",
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "additional", Namespace: "other"},
+					Data:       map[string][]byte{"scrape.yaml": []byte("- job_name: other")},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing optional secret",
+			selector: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "additional"},
+				Key:                  "scrape.yaml",
+				Optional:             &optional,
+			},
+		},
+		{
+			name: "missing key",
+			selector: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "additional"},
+				Key:                  "missing.yaml",
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "additional", Namespace: "default"},
+					Data:       map[string][]byte{"scrape.yaml": []byte("- job_name: own")},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "nil selector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			cache := map[string]*v1.Secret{}
+			got, err := loadAdditionalScrapeConfigsSecret(context.TODO(), fclient, tt.selector, "default", cache)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadAdditionalScrapeConfigsSecret() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadAdditionalScrapeConfigsSecret() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
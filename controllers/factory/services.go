@@ -0,0 +1,181 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyServiceSpec merges service overrides from component spec into generated service.
+// Labels generated by operator cannot be overwritten, since they're used by selectors and scrapes.
+// Ports are merged by name, type and clusterIP of headless service are kept.
+func applyServiceSpec(svc *corev1.Service, spec *v1beta1.ServiceSpec) {
+	if spec == nil {
+		return
+	}
+	if svc.Labels == nil {
+		svc.Labels = make(map[string]string)
+	}
+	for label, value := range spec.Labels {
+		if _, ok := svc.Labels[label]; !ok {
+			svc.Labels[label] = value
+		}
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = make(map[string]string)
+	}
+	for annotation, value := range spec.Annotations {
+		svc.Annotations[annotation] = value
+	}
+
+	override := spec.Spec
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		if override.Type != "" {
+			svc.Spec.Type = override.Type
+		}
+		if override.ClusterIP != "" {
+			svc.Spec.ClusterIP = override.ClusterIP
+		}
+	}
+	for _, port := range override.Ports {
+		var replaced bool
+		for i := range svc.Spec.Ports {
+			if svc.Spec.Ports[i].Name == port.Name {
+				svc.Spec.Ports[i] = port
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			svc.Spec.Ports = append(svc.Spec.Ports, port)
+		}
+	}
+	if override.ExternalTrafficPolicy != "" {
+		svc.Spec.ExternalTrafficPolicy = override.ExternalTrafficPolicy
+	}
+	if override.HealthCheckNodePort != 0 {
+		svc.Spec.HealthCheckNodePort = override.HealthCheckNodePort
+	}
+	if override.LoadBalancerIP != "" {
+		svc.Spec.LoadBalancerIP = override.LoadBalancerIP
+	}
+	if len(override.LoadBalancerSourceRanges) > 0 {
+		svc.Spec.LoadBalancerSourceRanges = override.LoadBalancerSourceRanges
+	}
+	if len(override.ExternalIPs) > 0 {
+		svc.Spec.ExternalIPs = override.ExternalIPs
+	}
+	if override.SessionAffinity != "" {
+		svc.Spec.SessionAffinity = override.SessionAffinity
+	}
+	if override.SessionAffinityConfig != nil {
+		svc.Spec.SessionAffinityConfig = override.SessionAffinityConfig
+	}
+	if override.PublishNotReadyAddresses {
+		svc.Spec.PublishNotReadyAddresses = true
+	}
+	if len(override.TopologyKeys) > 0 {
+		svc.Spec.TopologyKeys = override.TopologyKeys
+	}
+}
+
+// reconcileService creates or updates service,
+// clusterIP and node ports allocated by kubernetes are kept during update.
+func reconcileService(ctx context.Context, rclient client.Client, newService *corev1.Service) error {
+	l := log.WithValues("controller", "service", "name", newService.Name, "namespace", newService.Namespace)
+	currentService := &corev1.Service{}
+	err := rclient.Get(ctx, types.NamespacedName{Name: newService.Name, Namespace: newService.Namespace}, currentService)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			l.Info("creating new service")
+			if err := rclient.Create(ctx, newService); err != nil {
+				return fmt.Errorf("cannot create service: %s, err: %w", newService.Name, err)
+			}
+			return nil
+		}
+		return fmt.Errorf("cannot get service: %s, err: %w", newService.Name, err)
+	}
+	if newService.Annotations == nil {
+		newService.Annotations = make(map[string]string)
+	}
+	for annotation, value := range currentService.Annotations {
+		if _, ok := newService.Annotations[annotation]; !ok {
+			newService.Annotations[annotation] = value
+		}
+	}
+	if currentService.Spec.ClusterIP != "" {
+		newService.Spec.ClusterIP = currentService.Spec.ClusterIP
+	}
+	if newService.Spec.Type == corev1.ServiceTypeNodePort || newService.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		for i := range newService.Spec.Ports {
+			port := &newService.Spec.Ports[i]
+			if port.NodePort != 0 {
+				continue
+			}
+			for _, currentPort := range currentService.Spec.Ports {
+				if currentPort.Name == port.Name {
+					port.NodePort = currentPort.NodePort
+					break
+				}
+			}
+		}
+		if newService.Spec.HealthCheckNodePort == 0 {
+			newService.Spec.HealthCheckNodePort = currentService.Spec.HealthCheckNodePort
+		}
+	}
+	newService.ResourceVersion = currentService.ResourceVersion
+	if err := rclient.Update(ctx, newService); err != nil {
+		return fmt.Errorf("cannot update service: %s, err: %w", newService.Name, err)
+	}
+	l.Info("service was reconciled")
+	return nil
+}
+
+// reconcileExtraServices creates or updates additional services built from generated component service,
+// services of previous reconcile missing at extraServices are removed.
+// Additional services don't have selector labels of component, so they're not selected by VMServiceScrape.
+func reconcileExtraServices(ctx context.Context, rclient client.Client, base *corev1.Service, extraServices []v1beta1.ServiceSpec) error {
+	l := log.WithValues("controller", "service", "name", base.Name, "namespace", base.Namespace)
+	wantServices := make(map[string]struct{}, len(extraServices))
+	for i := range extraServices {
+		extra := &extraServices[i]
+		svc := base.DeepCopy()
+		svc.Name = extra.Name
+		svc.ResourceVersion = ""
+		svc.Labels = map[string]string{v1beta1.AdditionalServiceLabel: base.Name}
+		svc.Annotations = make(map[string]string)
+		svc.Spec.Type = corev1.ServiceTypeClusterIP
+		svc.Spec.ClusterIP = ""
+		applyServiceSpec(svc, extra)
+		if err := reconcileService(ctx, rclient, svc); err != nil {
+			return fmt.Errorf("cannot reconcile additional service for: %s, err: %w", base.Name, err)
+		}
+		wantServices[svc.Name] = struct{}{}
+	}
+
+	currentServices := &corev1.ServiceList{}
+	listOpts := &client.ListOptions{
+		Namespace:     base.Namespace,
+		LabelSelector: labels.SelectorFromSet(map[string]string{v1beta1.AdditionalServiceLabel: base.Name}),
+	}
+	if err := rclient.List(ctx, currentServices, listOpts); err != nil {
+		return fmt.Errorf("cannot list additional services for: %s, err: %w", base.Name, err)
+	}
+	for i := range currentServices.Items {
+		svc := &currentServices.Items[i]
+		if _, ok := wantServices[svc.Name]; ok || !isOwnedBy(svc.OwnerReferences, base.OwnerReferences) {
+			continue
+		}
+		l.Info("additional service was removed from spec, deleting it", "service", svc.Name)
+		if err := rclient.Delete(ctx, svc); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete additional service: %s, err: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package factory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestService(clusterIP string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vmselect-example",
+			Namespace:   "default",
+			Labels:      map[string]string{"app.kubernetes.io/name": "vmselect"},
+			Annotations: map[string]string{},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "operator.victoriametrics.com/v1beta1", Kind: "VMCluster", Name: "example", UID: "uid-1"},
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: clusterIP,
+			Selector:  map[string]string{"app.kubernetes.io/name": "vmselect"},
+			Ports: []corev1.ServicePort{
+				{Name: "http", Protocol: "TCP", Port: 8481, TargetPort: intstr.FromInt(8481)},
+			},
+		},
+	}
+}
+
+func Test_applyServiceSpec(t *testing.T) {
+	tests := []struct {
+		name          string
+		svc           *corev1.Service
+		spec          *v1beta1.ServiceSpec
+		wantType      corev1.ServiceType
+		wantClusterIP string
+		wantPorts     []corev1.ServicePort
+		wantLabels    map[string]string
+	}{
+		{
+			name:      "without overrides",
+			svc:       newTestService(""),
+			wantType:  corev1.ServiceTypeClusterIP,
+			wantPorts: newTestService("").Spec.Ports,
+			wantLabels: map[string]string{
+				"app.kubernetes.io/name": "vmselect",
+			},
+		},
+		{
+			name: "change type and merge ports",
+			svc:  newTestService(""),
+			spec: &v1beta1.ServiceSpec{
+				EmbeddedObjectMetadata: v1beta1.EmbeddedObjectMetadata{
+					Labels: map[string]string{"app.kubernetes.io/name": "other", "team": "infra"},
+				},
+				Spec: corev1.ServiceSpec{
+					Type: corev1.ServiceTypeNodePort,
+					Ports: []corev1.ServicePort{
+						{Name: "http", Protocol: "TCP", Port: 8481, TargetPort: intstr.FromInt(8481), NodePort: 30001},
+						{Name: "https", Protocol: "TCP", Port: 443, TargetPort: intstr.FromInt(8443)},
+					},
+				},
+			},
+			wantType: corev1.ServiceTypeNodePort,
+			wantPorts: []corev1.ServicePort{
+				{Name: "http", Protocol: "TCP", Port: 8481, TargetPort: intstr.FromInt(8481), NodePort: 30001},
+				{Name: "https", Protocol: "TCP", Port: 443, TargetPort: intstr.FromInt(8443)},
+			},
+			wantLabels: map[string]string{
+				"app.kubernetes.io/name": "vmselect",
+				"team":                   "infra",
+			},
+		},
+		{
+			name:          "headless service keeps type",
+			svc:           newTestService(corev1.ClusterIPNone),
+			spec:          &v1beta1.ServiceSpec{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}},
+			wantType:      corev1.ServiceTypeClusterIP,
+			wantClusterIP: corev1.ClusterIPNone,
+			wantPorts:     newTestService("").Spec.Ports,
+			wantLabels: map[string]string{
+				"app.kubernetes.io/name": "vmselect",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyServiceSpec(tt.svc, tt.spec)
+			if tt.svc.Spec.Type != tt.wantType {
+				t.Errorf("applyServiceSpec() type = %s, want %s", tt.svc.Spec.Type, tt.wantType)
+			}
+			if tt.svc.Spec.ClusterIP != tt.wantClusterIP {
+				t.Errorf("applyServiceSpec() clusterIP = %s, want %s", tt.svc.Spec.ClusterIP, tt.wantClusterIP)
+			}
+			if !reflect.DeepEqual(tt.svc.Spec.Ports, tt.wantPorts) {
+				t.Errorf("applyServiceSpec() ports = %v, want %v", tt.svc.Spec.Ports, tt.wantPorts)
+			}
+			if !reflect.DeepEqual(tt.svc.Labels, tt.wantLabels) {
+				t.Errorf("applyServiceSpec() labels = %v, want %v", tt.svc.Labels, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func Test_reconcileService(t *testing.T) {
+	current := newTestService("10.0.0.1")
+	current.Spec.Type = corev1.ServiceTypeNodePort
+	current.Spec.Ports[0].NodePort = 30001
+	current.Annotations = map[string]string{"external": "value", "owner": "old"}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), current)
+
+	newService := newTestService("")
+	newService.Spec.Type = corev1.ServiceTypeNodePort
+	newService.Annotations = map[string]string{"owner": "new"}
+	if err := reconcileService(context.TODO(), fclient, newService); err != nil {
+		t.Fatalf("reconcileService() error = %v", err)
+	}
+	got := &corev1.Service{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmselect-example", Namespace: "default"}, got); err != nil {
+		t.Fatalf("cannot get service: %v", err)
+	}
+	if got.Spec.ClusterIP != "10.0.0.1" {
+		t.Errorf("clusterIP must be kept, got: %s", got.Spec.ClusterIP)
+	}
+	if got.Spec.Ports[0].NodePort != 30001 {
+		t.Errorf("nodePort must be kept, got: %d", got.Spec.Ports[0].NodePort)
+	}
+	wantAnnotations := map[string]string{"external": "value", "owner": "new"}
+	if !reflect.DeepEqual(got.Annotations, wantAnnotations) {
+		t.Errorf("unexpected annotations: %v, want: %v", got.Annotations, wantAnnotations)
+	}
+}
+
+func Test_reconcileExtraServices(t *testing.T) {
+	base := newTestService(corev1.ClusterIPNone)
+	staleService := func(owners []metav1.OwnerReference) *corev1.Service {
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:            "vmselect-stale",
+			Namespace:       "default",
+			Labels:          map[string]string{v1beta1.AdditionalServiceLabel: base.Name},
+			OwnerReferences: owners,
+		}}
+	}
+	tests := []struct {
+		name              string
+		extraServices     []v1beta1.ServiceSpec
+		predefinedObjects []runtime.Object
+		wantServices      []string
+		wantMissing       []string
+	}{
+		{
+			name: "create extra service",
+			extraServices: []v1beta1.ServiceSpec{{
+				EmbeddedObjectMetadata: v1beta1.EmbeddedObjectMetadata{Name: "vmselect-nodeport"},
+				Spec:                   corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort},
+			}},
+			wantServices: []string{"vmselect-nodeport"},
+		},
+		{
+			name:              "remove stale extra service",
+			predefinedObjects: []runtime.Object{staleService(base.OwnerReferences)},
+			wantMissing:       []string{"vmselect-stale"},
+		},
+		{
+			name:              "keep extra service of other owner",
+			predefinedObjects: []runtime.Object{staleService(nil)},
+			wantServices:      []string{"vmselect-stale"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			if err := reconcileExtraServices(context.TODO(), fclient, base, tt.extraServices); err != nil {
+				t.Fatalf("reconcileExtraServices() error = %v", err)
+			}
+			for _, name := range tt.wantServices {
+				if err := fclient.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Service{}); err != nil {
+					t.Errorf("cannot get service: %s, err: %v", name, err)
+				}
+			}
+			for _, name := range tt.wantMissing {
+				if err := fclient.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Service{}); !errors.IsNotFound(err) {
+					t.Errorf("service: %s must be removed, got err: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdateVMSingleService_overrides(t *testing.T) {
+	cr := &v1beta1.VMSingle{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1beta1.VMSingleSpec{
+			ServiceSpec: &v1beta1.ServiceSpec{
+				EmbeddedObjectMetadata: v1beta1.EmbeddedObjectMetadata{
+					Annotations: map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+				},
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			},
+			ExtraServices: []v1beta1.ServiceSpec{{
+				EmbeddedObjectMetadata: v1beta1.EmbeddedObjectMetadata{Name: "vmsingle-nodeport"},
+				Spec:                   corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort},
+			}},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme())
+	svc, err := CreateOrUpdateVMSingleService(context.TODO(), cr, fclient, config.MustGetBaseConfig())
+	if err != nil {
+		t.Fatalf("CreateOrUpdateVMSingleService() error = %v", err)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeLoadBalancer || svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-internal"] != "true" {
+		t.Errorf("service overrides weren't applied: %v", svc)
+	}
+	extra := &corev1.Service{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmsingle-nodeport", Namespace: "default"}, extra); err != nil {
+		t.Fatalf("cannot get extra service: %v", err)
+	}
+	if extra.Spec.Type != corev1.ServiceTypeNodePort || !reflect.DeepEqual(extra.Spec.Selector, cr.SelectorLabels()) {
+		t.Errorf("unexpected extra service spec: %v", extra.Spec)
+	}
+	if _, ok := extra.Labels["app.kubernetes.io/name"]; ok {
+		t.Errorf("extra service must not have selector labels of component: %v", extra.Labels)
+	}
+}
@@ -0,0 +1,525 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigError is returned if operator cannot build configuration for application,
+// for instance referenced secret is missing or selected objects are invalid.
+type ConfigError struct {
+	err error
+}
+
+func newConfigError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ConfigError{err: err}
+}
+
+// Error implements error interface
+func (e *ConfigError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns original error
+func (e *ConfigError) Unwrap() error {
+	return e.err
+}
+
+// IsConfigError checks if error was caused by invalid configuration.
+func IsConfigError(err error) bool {
+	var ce *ConfigError
+	return errors.As(err, &ce)
+}
+
+// workloadStatus is observed state of application, managed with deployment.
+// It has the same fields as VMAgent, VMAlert and VMSingle statuses,
+// so pointers to them can be converted into *workloadStatus.
+type workloadStatus struct {
+	Replicas            int32
+	UpdatedReplicas     int32
+	AvailableReplicas   int32
+	UnavailableReplicas int32
+	ObservedGeneration  int64
+	Conditions          []victoriametricsv1beta1.Condition
+	LastError           string
+}
+
+// setReconcileConditions sets ReconcileFailed and ConfigValid conditions by result of reconcile.
+func setReconcileConditions(conditions []victoriametricsv1beta1.Condition, reconcileErr error) []victoriametricsv1beta1.Condition {
+	if reconcileErr != nil {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:    victoriametricsv1beta1.ConditionReconcileFailed,
+			Status:  corev1.ConditionTrue,
+			Reason:  "ReconcileError",
+			Message: reconcileErr.Error(),
+		})
+	} else {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:   victoriametricsv1beta1.ConditionReconcileFailed,
+			Status: corev1.ConditionFalse,
+			Reason: "ReconcileSucceeded",
+		})
+	}
+
+	switch {
+	case IsConfigError(reconcileErr):
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:    victoriametricsv1beta1.ConditionConfigValid,
+			Status:  corev1.ConditionFalse,
+			Reason:  "InvalidConfig",
+			Message: reconcileErr.Error(),
+		})
+	case reconcileErr == nil:
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:   victoriametricsv1beta1.ConditionConfigValid,
+			Status: corev1.ConditionTrue,
+			Reason: "ConfigGenerated",
+		})
+	case victoriametricsv1beta1.FindCondition(conditions, victoriametricsv1beta1.ConditionConfigValid) == nil:
+		// reconcile failed for other reason, config state is unknown yet.
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:   victoriametricsv1beta1.ConditionConfigValid,
+			Status: corev1.ConditionUnknown,
+			Reason: "ReconcileError",
+		})
+	}
+	return conditions
+}
+
+// setRolloutConditions sets Available and Progressing conditions.
+func setRolloutConditions(conditions []victoriametricsv1beta1.Condition, available, progressing bool, availableMsg, progressingMsg string) []victoriametricsv1beta1.Condition {
+	if available {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:    victoriametricsv1beta1.ConditionAvailable,
+			Status:  corev1.ConditionTrue,
+			Reason:  "MinimumReplicasAvailable",
+			Message: availableMsg,
+		})
+	} else {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:    victoriametricsv1beta1.ConditionAvailable,
+			Status:  corev1.ConditionFalse,
+			Reason:  "MinimumReplicasUnavailable",
+			Message: availableMsg,
+		})
+	}
+	if progressing {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:    victoriametricsv1beta1.ConditionProgressing,
+			Status:  corev1.ConditionTrue,
+			Reason:  "RolloutInProgress",
+			Message: progressingMsg,
+		})
+	} else {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:   victoriametricsv1beta1.ConditionProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: "RolloutComplete",
+		})
+	}
+	return conditions
+}
+
+// newWorkloadStatus builds status from owned deployment and result of reconcile.
+// deploy is nil if deployment doesn't exist.
+func newWorkloadStatus(conditions []victoriametricsv1beta1.Condition, deploy *appsv1.Deployment, reconcileErr error) workloadStatus {
+	// conditions must not share memory with object from cache
+	conditions = append([]victoriametricsv1beta1.Condition(nil), conditions...)
+	var ws workloadStatus
+	if reconcileErr != nil {
+		ws.LastError = reconcileErr.Error()
+	}
+	conditions = setReconcileConditions(conditions, reconcileErr)
+
+	if deploy == nil {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:   victoriametricsv1beta1.ConditionAvailable,
+			Status: corev1.ConditionFalse,
+			Reason: "DeploymentNotFound",
+		})
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:   victoriametricsv1beta1.ConditionProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: "DeploymentNotFound",
+		})
+		ws.Conditions = conditions
+		return ws
+	}
+
+	ws.Replicas = deploy.Status.Replicas
+	ws.UpdatedReplicas = deploy.Status.UpdatedReplicas
+	ws.AvailableReplicas = deploy.Status.AvailableReplicas
+	ws.UnavailableReplicas = deploy.Status.UnavailableReplicas
+	desired := int32(1)
+	if deploy.Spec.Replicas != nil {
+		desired = *deploy.Spec.Replicas
+	}
+	progressing := deploy.Status.ObservedGeneration < deploy.Generation ||
+		ws.UpdatedReplicas < desired ||
+		ws.Replicas > ws.UpdatedReplicas
+	ws.Conditions = setRolloutConditions(conditions, ws.AvailableReplicas >= desired, progressing,
+		fmt.Sprintf("%d of %d replicas available", ws.AvailableReplicas, desired),
+		fmt.Sprintf("%d of %d replicas updated", ws.UpdatedReplicas, desired))
+	return ws
+}
+
+// getOwnedDeployment returns deployment or nil, if it doesn't exist.
+func getOwnedDeployment(ctx context.Context, rclient client.Client, name, namespace string) (*appsv1.Deployment, error) {
+	deploy := &appsv1.Deployment{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, deploy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot get deployment: %s, err: %w", name, err)
+	}
+	return deploy, nil
+}
+
+// updateWorkloadStatus fetches current state of object by key into obj and publishes
+// status of deployment with given name into status, which must point to obj status.
+// Status is updated only if it was changed.
+func updateWorkloadStatus(ctx context.Context, rclient client.Client, key types.NamespacedName, obj runtime.Object, deploymentName string, status *workloadStatus, reconcileErr error) error {
+	if err := rclient.Get(ctx, key, obj); err != nil {
+		return fmt.Errorf("cannot get object: %s for status update: %w", key, err)
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return fmt.Errorf("cannot get metadata of object: %s, err: %w", key, err)
+	}
+	deploy, err := getOwnedDeployment(ctx, rclient, deploymentName, key.Namespace)
+	if err != nil {
+		return err
+	}
+	newStatus := newWorkloadStatus(status.Conditions, deploy, reconcileErr)
+	newStatus.ObservedGeneration = objMeta.GetGeneration()
+	if reflect.DeepEqual(*status, newStatus) {
+		return nil
+	}
+	*status = newStatus
+	if err := rclient.Status().Update(ctx, obj); err != nil {
+		return fmt.Errorf("cannot update status of object: %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// UpdateVMAgentStatus publishes conditions, replica counts and last reconcile error to VMAgent status.
+func UpdateVMAgentStatus(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rclient client.Client, reconcileErr error) error {
+	current := &victoriametricsv1beta1.VMAgent{}
+	return updateWorkloadStatus(ctx, rclient, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, current,
+		cr.PrefixedName(), (*workloadStatus)(&current.Status), reconcileErr)
+}
+
+// UpdateVMAlertStatus publishes conditions, replica counts and last reconcile error to VMAlert status.
+func UpdateVMAlertStatus(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclient client.Client, reconcileErr error) error {
+	current := &victoriametricsv1beta1.VMAlert{}
+	return updateWorkloadStatus(ctx, rclient, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, current,
+		cr.PrefixedName(), (*workloadStatus)(&current.Status), reconcileErr)
+}
+
+// UpdateVMSingleStatus publishes conditions, replica counts and last reconcile error to VMSingle status.
+func UpdateVMSingleStatus(ctx context.Context, cr *victoriametricsv1beta1.VMSingle, rclient client.Client, reconcileErr error) error {
+	current := &victoriametricsv1beta1.VMSingle{}
+	return updateWorkloadStatus(ctx, rclient, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, current,
+		cr.PrefixedName(), (*workloadStatus)(&current.Status), reconcileErr)
+}
+
+// imageTag returns tag of container image or empty string.
+func imageTag(image string) string {
+	idx := strings.LastIndex(image, ":")
+	if idx < 0 || strings.Contains(image[idx:], "/") {
+		return ""
+	}
+	return image[idx+1:]
+}
+
+// containerImageTag returns image tag of container with given name.
+func containerImageTag(containers []corev1.Container, name string) string {
+	for _, c := range containers {
+		if c.Name == name {
+			return imageTag(c.Image)
+		}
+	}
+	return ""
+}
+
+// stsComponentStatus builds status of VMCluster component, managed with statefulset.
+func stsComponentStatus(sts *appsv1.StatefulSet, containerName string) *victoriametricsv1beta1.VMClusterComponentStatus {
+	cs := &victoriametricsv1beta1.VMClusterComponentStatus{
+		Replicas:        1,
+		ReadyReplicas:   sts.Status.ReadyReplicas,
+		UpdatedReplicas: sts.Status.UpdatedReplicas,
+		Version:         containerImageTag(sts.Spec.Template.Spec.Containers, containerName),
+		UpdateStatus:    victoriametricsv1beta1.ClusterStatusOperational,
+	}
+	if sts.Spec.Replicas != nil {
+		cs.Replicas = *sts.Spec.Replicas
+	}
+	if sts.Status.ObservedGeneration < sts.Generation ||
+		sts.Status.CurrentRevision != sts.Status.UpdateRevision ||
+		cs.ReadyReplicas < cs.Replicas ||
+		cs.UpdatedReplicas < cs.Replicas {
+		cs.UpdateStatus = victoriametricsv1beta1.ClusterStatusExpanding
+		cs.RolloutRevision = sts.Status.UpdateRevision
+	} else {
+		cs.LastGoodRevision = sts.Status.UpdateRevision
+	}
+	return cs
+}
+
+// deploymentComponentStatus builds status of VMCluster component, managed with deployment.
+func deploymentComponentStatus(deploy *appsv1.Deployment, containerName string) *victoriametricsv1beta1.VMClusterComponentStatus {
+	cs := &victoriametricsv1beta1.VMClusterComponentStatus{
+		Replicas:        1,
+		ReadyReplicas:   deploy.Status.ReadyReplicas,
+		UpdatedReplicas: deploy.Status.UpdatedReplicas,
+		Version:         containerImageTag(deploy.Spec.Template.Spec.Containers, containerName),
+		UpdateStatus:    victoriametricsv1beta1.ClusterStatusOperational,
+	}
+	if deploy.Spec.Replicas != nil {
+		cs.Replicas = *deploy.Spec.Replicas
+	}
+	if deploy.Status.ObservedGeneration < deploy.Generation ||
+		deploy.Status.Replicas > cs.UpdatedReplicas ||
+		cs.ReadyReplicas < cs.Replicas ||
+		cs.UpdatedReplicas < cs.Replicas {
+		cs.UpdateStatus = victoriametricsv1beta1.ClusterStatusExpanding
+		cs.RolloutRevision = deploy.Annotations[deploymentRevisionAnnotation]
+	} else {
+		cs.LastGoodRevision = deploy.Annotations[deploymentRevisionAnnotation]
+	}
+	return cs
+}
+
+// vmClusterComponentStatus reads owned statefulset or deployment of VMCluster component and builds its status.
+// Status is nil, if component isn't defined at spec.
+func vmClusterComponentStatus(ctx context.Context, rclient client.Client, cr *victoriametricsv1beta1.VMCluster, component string) (*victoriametricsv1beta1.VMClusterComponentStatus, error) {
+	var name string
+	var desired *int32
+	var obj runtime.Object
+	switch {
+	case component == "vmstorage" && cr.Spec.VMStorage != nil && len(cr.Spec.StorageGroups) > 0,
+		component == "vminsert" && cr.Spec.VMInsert != nil && len(cr.Spec.StorageGroups) > 0:
+		return storageGroupsComponentStatus(ctx, rclient, cr, component)
+	case component == "vmstorage" && cr.Spec.VMStorage != nil:
+		name, desired, obj = cr.Spec.VMStorage.GetNameWithPrefix(cr.Name), cr.Spec.VMStorage.ReplicaCount, &appsv1.StatefulSet{}
+	case component == "vmselect" && cr.Spec.VMSelect != nil:
+		name, desired, obj = cr.Spec.VMSelect.GetNameWithPrefix(cr.Name), cr.Spec.VMSelect.ReplicaCount, &appsv1.StatefulSet{}
+	case component == "vminsert" && cr.Spec.VMInsert != nil:
+		name, desired, obj = cr.Spec.VMInsert.GetNameWithPrefix(cr.Name), cr.Spec.VMInsert.ReplicaCount, &appsv1.Deployment{}
+	default:
+		return nil, nil
+	}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: name, Namespace: cr.Namespace}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			// workload isn't created yet
+			cs := &victoriametricsv1beta1.VMClusterComponentStatus{Replicas: 1, UpdateStatus: victoriametricsv1beta1.ClusterStatusExpanding}
+			if desired != nil {
+				cs.Replicas = *desired
+			}
+			return cs, nil
+		}
+		return nil, fmt.Errorf("cannot get %s workload: %s, err: %w", component, name, err)
+	}
+	switch w := obj.(type) {
+	case *appsv1.StatefulSet:
+		return stsComponentStatus(w, component), nil
+	case *appsv1.Deployment:
+		return deploymentComponentStatus(w, component), nil
+	}
+	return nil, nil
+}
+
+// storageGroupsComponentStatus returns vmstorage or vminsert status, which is summed up from workloads of storage groups.
+// Revisions of group workloads differ, so last good revision isn't tracked and groups aren't rolled back.
+func storageGroupsComponentStatus(ctx context.Context, rclient client.Client, cr *victoriametricsv1beta1.VMCluster, component string) (*victoriametricsv1beta1.VMClusterComponentStatus, error) {
+	total := &victoriametricsv1beta1.VMClusterComponentStatus{UpdateStatus: victoriametricsv1beta1.ClusterStatusOperational}
+	for i := range cr.Spec.StorageGroups {
+		cs, err := vmClusterComponentStatus(ctx, rclient, storageGroupCluster(cr, &cr.Spec.StorageGroups[i]), component)
+		if err != nil {
+			return nil, err
+		}
+		total.Replicas += cs.Replicas
+		total.ReadyReplicas += cs.ReadyReplicas
+		total.UpdatedReplicas += cs.UpdatedReplicas
+		if total.Version == "" {
+			total.Version = cs.Version
+		}
+		if cs.UpdateStatus == victoriametricsv1beta1.ClusterStatusExpanding {
+			total.UpdateStatus = victoriametricsv1beta1.ClusterStatusExpanding
+		}
+	}
+	return total, nil
+}
+
+// clusterReconcileResult is the result of VMCluster reconcile, which is published to status.
+type clusterReconcileResult struct {
+	status     string
+	reason     string
+	reconciled bool
+	// failedComponent is the component, which reconcile returned error
+	failedComponent string
+	// failedRevision is the revision of failedComponent, which rollout failed
+	failedRevision string
+	// newFailedAttempt is set, if failed rollout is counted as new failed update attempt of component
+	newFailedAttempt bool
+	failureTime      metav1.Time
+	// rolledBack contains components rolled back during reconcile with revisions
+	rolledBack map[string]string
+	// drainingStorageNodes vmstorage nodes removed by scale down
+	drainingStorageNodes []victoriametricsv1beta1.VMStorageDrainingNode
+	err                  error
+}
+
+// mergeComponentStatus carries over update history and rollout start time of component from previous status.
+func mergeComponentStatus(prev, cs *victoriametricsv1beta1.VMClusterComponentStatus, name string, generation int64, result *clusterReconcileResult) {
+	if prev == nil {
+		prev = &victoriametricsv1beta1.VMClusterComponentStatus{}
+	}
+	if cs.LastGoodRevision == "" {
+		cs.LastGoodRevision = prev.LastGoodRevision
+	}
+	switch {
+	case result.failedComponent == name:
+		cs.UpdateStatus = victoriametricsv1beta1.ClusterStatusFailed
+		cs.FailCount, cs.FailedRevision, cs.LastFailureTime = prev.FailCount, prev.FailedRevision, prev.LastFailureTime
+		if result.newFailedAttempt {
+			cs.FailCount++
+			cs.FailedRevision = result.failedRevision
+			cs.LastFailureTime = &result.failureTime
+		}
+	case result.rolledBack[name] != "", cs.UpdateStatus == victoriametricsv1beta1.ClusterStatusOperational:
+		// failed attempts are reset by rollback or successful update
+	default:
+		cs.FailCount, cs.FailedRevision, cs.LastFailureTime = prev.FailCount, prev.FailedRevision, prev.LastFailureTime
+	}
+	switch {
+	case cs.RolloutRevision == "", cs.RolloutRevision == cs.LastGoodRevision, result.rolledBack[name] != "":
+		// rollout is finished, component is scaled or rollout was rolled back
+		cs.RolloutRevision = ""
+	case prev.RolloutRevision == cs.RolloutRevision && prev.RolloutStartTime != nil:
+		cs.RolloutStartTime = prev.RolloutStartTime
+	default:
+		now := metav1.Now()
+		cs.RolloutStartTime = &now
+	}
+	switch {
+	case result.rolledBack[name] != "":
+		cs.RollbackGeneration = generation
+	case prev.RollbackGeneration == generation:
+		// updates are paused until spec changes
+		cs.RollbackGeneration = prev.RollbackGeneration
+	}
+}
+
+// newVMClusterStatus builds status of VMCluster with per component status and conditions.
+func newVMClusterStatus(prev victoriametricsv1beta1.VMClusterStatus, generation int64, components map[string]*victoriametricsv1beta1.VMClusterComponentStatus, result *clusterReconcileResult) victoriametricsv1beta1.VMClusterStatus {
+	newStatus := victoriametricsv1beta1.VMClusterStatus{
+		UpdateFailCount:    prev.UpdateFailCount,
+		LastSync:           time.Now().UTC().Format(time.RFC3339),
+		ClusterStatus:      result.status,
+		Reason:             result.reason,
+		ObservedGeneration: generation,
+		VMStorage:          components["vmstorage"],
+		VMSelect:           components["vmselect"],
+		VMInsert:           components["vminsert"],
+
+		DrainingStorageNodes: result.drainingStorageNodes,
+	}
+	if result.reconciled {
+		newStatus.UpdateFailCount = 0
+	} else {
+		newStatus.UpdateFailCount++
+	}
+	prevComponents := map[string]*victoriametricsv1beta1.VMClusterComponentStatus{
+		"vmstorage": prev.VMStorage,
+		"vmselect":  prev.VMSelect,
+		"vminsert":  prev.VMInsert,
+	}
+
+	available, progressing := true, false
+	var notReady, notUpdated, rolledBack []string
+	for _, name := range []string{"vmstorage", "vmselect", "vminsert"} {
+		cs := components[name]
+		if cs == nil {
+			continue
+		}
+		mergeComponentStatus(prevComponents[name], cs, name, generation, result)
+		if cs.ReadyReplicas < cs.Replicas {
+			available = false
+			notReady = append(notReady, fmt.Sprintf("%s: %d of %d replicas ready", name, cs.ReadyReplicas, cs.Replicas))
+		}
+		if cs.UpdateStatus == victoriametricsv1beta1.ClusterStatusExpanding {
+			progressing = true
+			notUpdated = append(notUpdated, fmt.Sprintf("%s: %d of %d replicas updated", name, cs.UpdatedReplicas, cs.Replicas))
+		}
+		if cs.RollbackGeneration != 0 {
+			rolledBack = append(rolledBack, fmt.Sprintf("%s: rolled back to revision %s", name, cs.LastGoodRevision))
+		}
+	}
+	availableMsg := "all components are ready"
+	if len(notReady) > 0 {
+		availableMsg = strings.Join(notReady, ", ")
+	}
+	conditions := append([]victoriametricsv1beta1.Condition(nil), prev.Conditions...)
+	conditions = setReconcileConditions(conditions, result.err)
+	conditions = setRolloutConditions(conditions, available, progressing, availableMsg, strings.Join(notUpdated, ", "))
+	if len(rolledBack) > 0 {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:    victoriametricsv1beta1.ConditionDegraded,
+			Status:  corev1.ConditionTrue,
+			Reason:  "RolledBack",
+			Message: strings.Join(rolledBack, ", ") + ", updates are paused until spec change",
+		})
+	} else {
+		conditions = victoriametricsv1beta1.SetCondition(conditions, victoriametricsv1beta1.Condition{
+			Type:   victoriametricsv1beta1.ConditionDegraded,
+			Status: corev1.ConditionFalse,
+			Reason: "AsExpected",
+		})
+	}
+	newStatus.Conditions = conditions
+	return newStatus
+}
+
+// updateVMClusterStatus publishes cluster and per component status to VMCluster.
+// Status is written on every reconcile.
+func updateVMClusterStatus(ctx context.Context, rclient client.Client, cr *victoriametricsv1beta1.VMCluster, result *clusterReconcileResult) error {
+	current := &victoriametricsv1beta1.VMCluster{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, current); err != nil {
+		return fmt.Errorf("cannot get vmcluster for status update: %w", err)
+	}
+	components := make(map[string]*victoriametricsv1beta1.VMClusterComponentStatus)
+	for _, name := range []string{"vmstorage", "vmselect", "vminsert"} {
+		cs, err := vmClusterComponentStatus(ctx, rclient, current, name)
+		if err != nil {
+			return err
+		}
+		if cs != nil {
+			components[name] = cs
+		}
+	}
+	current.Status = newVMClusterStatus(current.Status, current.Generation, components, result)
+	// controller logs reason of reconcile
+	cr.Status = current.Status
+	if err := rclient.Status().Update(ctx, current); err != nil {
+		return fmt.Errorf("cannot update vmcluster status: %w", err)
+	}
+	return nil
+}
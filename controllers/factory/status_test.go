@@ -0,0 +1,422 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func conditionStatuses(conditions []victoriametricsv1beta1.Condition) map[string]corev1.ConditionStatus {
+	statuses := map[string]corev1.ConditionStatus{}
+	for _, c := range conditions {
+		statuses[c.Type] = c.Status
+	}
+	return statuses
+}
+
+func Test_newWorkloadStatus(t *testing.T) {
+	readyDeploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(2)},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+	}
+	tests := []struct {
+		name          string
+		conditions    []victoriametricsv1beta1.Condition
+		deploy        *appsv1.Deployment
+		reconcileErr  error
+		want          map[string]corev1.ConditionStatus
+		wantLastError string
+	}{
+		{
+			name:   "ready deployment",
+			deploy: readyDeploy,
+			want: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionAvailable:       corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionProgressing:     corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionConfigValid:     corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionFalse,
+			},
+		},
+		{
+			name: "rollout in progress",
+			deploy: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(2)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 3, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 1, UnavailableReplicas: 2},
+			},
+			want: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionAvailable:       corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionProgressing:     corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionConfigValid:     corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionFalse,
+			},
+		},
+		{
+			name:         "invalid config",
+			deploy:       readyDeploy,
+			reconcileErr: newConfigError(fmt.Errorf("cannot find secret")),
+			want: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionAvailable:       corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionProgressing:     corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionConfigValid:     corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionTrue,
+			},
+			wantLastError: "cannot find secret",
+		},
+		{
+			name: "api error keeps config state",
+			conditions: []victoriametricsv1beta1.Condition{
+				{Type: victoriametricsv1beta1.ConditionConfigValid, Status: corev1.ConditionTrue},
+			},
+			reconcileErr: fmt.Errorf("cannot create deploy"),
+			want: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionAvailable:       corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionProgressing:     corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionConfigValid:     corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionTrue,
+			},
+			wantLastError: "cannot create deploy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newWorkloadStatus(tt.conditions, tt.deploy, tt.reconcileErr)
+			gotStatuses := conditionStatuses(got.Conditions)
+			if len(gotStatuses) != len(tt.want) {
+				t.Errorf("newWorkloadStatus() conditions = %v, want %v", gotStatuses, tt.want)
+			}
+			for condType, status := range tt.want {
+				if gotStatuses[condType] != status {
+					t.Errorf("newWorkloadStatus() condition %s = %s, want %s", condType, gotStatuses[condType], status)
+				}
+			}
+			if got.LastError != tt.wantLastError {
+				t.Errorf("newWorkloadStatus() lastError = %q, want %q", got.LastError, tt.wantLastError)
+			}
+		})
+	}
+}
+
+func TestUpdateVMAgentStatus(t *testing.T) {
+	cr := &victoriametricsv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default", Generation: 4},
+	}
+	predefinedObjects := []runtime.Object{
+		cr.DeepCopy(),
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmagent-agent", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(1)},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+	}
+	rclient := fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...)
+	if err := UpdateVMAgentStatus(context.TODO(), cr, rclient, nil); err != nil {
+		t.Fatalf("UpdateVMAgentStatus() unexpected error: %v", err)
+	}
+	got := &victoriametricsv1beta1.VMAgent{}
+	if err := rclient.Get(context.TODO(), types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, got); err != nil {
+		t.Fatalf("cannot get vmagent: %v", err)
+	}
+	if got.Status.ObservedGeneration != 4 {
+		t.Errorf("UpdateVMAgentStatus() observedGeneration = %d, want 4", got.Status.ObservedGeneration)
+	}
+	if got.Status.AvailableReplicas != 1 || got.Status.Replicas != 1 {
+		t.Errorf("UpdateVMAgentStatus() replicas = %d, available = %d, want 1", got.Status.Replicas, got.Status.AvailableReplicas)
+	}
+	if !victoriametricsv1beta1.IsConditionTrue(got.Status.Conditions, victoriametricsv1beta1.ConditionAvailable) {
+		t.Errorf("UpdateVMAgentStatus() vmagent must be available, conditions: %v", got.Status.Conditions)
+	}
+
+	reconcileErr := newConfigError(fmt.Errorf("cannot find remote write secret"))
+	if err := UpdateVMAgentStatus(context.TODO(), cr, rclient, reconcileErr); err != nil {
+		t.Fatalf("UpdateVMAgentStatus() unexpected error: %v", err)
+	}
+	if err := rclient.Get(context.TODO(), types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, got); err != nil {
+		t.Fatalf("cannot get vmagent: %v", err)
+	}
+	if got.Status.LastError != reconcileErr.Error() {
+		t.Errorf("UpdateVMAgentStatus() lastError = %q, want %q", got.Status.LastError, reconcileErr.Error())
+	}
+	if victoriametricsv1beta1.IsConditionTrue(got.Status.Conditions, victoriametricsv1beta1.ConditionConfigValid) {
+		t.Errorf("UpdateVMAgentStatus() config must be invalid, conditions: %v", got.Status.Conditions)
+	}
+}
+
+func TestUpdateVMSingleStatus(t *testing.T) {
+	cr := &victoriametricsv1beta1.VMSingle{
+		ObjectMeta: metav1.ObjectMeta{Name: "single", Namespace: "default", Generation: 2},
+	}
+	rclient := fake.NewFakeClientWithScheme(testGetScheme(), cr.DeepCopy())
+	if err := UpdateVMSingleStatus(context.TODO(), cr, rclient, nil); err != nil {
+		t.Fatalf("UpdateVMSingleStatus() unexpected error: %v", err)
+	}
+	got := &victoriametricsv1beta1.VMSingle{}
+	if err := rclient.Get(context.TODO(), types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, got); err != nil {
+		t.Fatalf("cannot get vmsingle: %v", err)
+	}
+	if got.Status.ObservedGeneration != 2 {
+		t.Errorf("UpdateVMSingleStatus() observedGeneration = %d, want 2", got.Status.ObservedGeneration)
+	}
+	if victoriametricsv1beta1.IsConditionTrue(got.Status.Conditions, victoriametricsv1beta1.ConditionAvailable) {
+		t.Errorf("UpdateVMSingleStatus() vmsingle without deployment must be unavailable, conditions: %v", got.Status.Conditions)
+	}
+}
+
+func Test_mergeComponentStatus_rollout(t *testing.T) {
+	startTime := &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	tests := []struct {
+		name          string
+		prev          *victoriametricsv1beta1.VMClusterComponentStatus
+		cs            *victoriametricsv1beta1.VMClusterComponentStatus
+		rolledBack    map[string]string
+		wantRevision  string
+		wantStartTime *metav1.Time
+		wantNewStart  bool
+	}{
+		{
+			name:         "rollout started",
+			prev:         &victoriametricsv1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1"},
+			cs:           &victoriametricsv1beta1.VMClusterComponentStatus{RolloutRevision: "rev-2"},
+			wantRevision: "rev-2",
+			wantNewStart: true,
+		},
+		{
+			name:          "rollout in progress",
+			prev:          &victoriametricsv1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1", RolloutRevision: "rev-2", RolloutStartTime: startTime},
+			cs:            &victoriametricsv1beta1.VMClusterComponentStatus{RolloutRevision: "rev-2"},
+			wantRevision:  "rev-2",
+			wantStartTime: startTime,
+		},
+		{
+			name:         "rollout of new revision",
+			prev:         &victoriametricsv1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1", RolloutRevision: "rev-2", RolloutStartTime: startTime},
+			cs:           &victoriametricsv1beta1.VMClusterComponentStatus{RolloutRevision: "rev-3"},
+			wantRevision: "rev-3",
+			wantNewStart: true,
+		},
+		{
+			name: "rollout finished",
+			prev: &victoriametricsv1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1", RolloutRevision: "rev-2", RolloutStartTime: startTime},
+			cs:   &victoriametricsv1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-2"},
+		},
+		{
+			name: "scale up of good revision",
+			prev: &victoriametricsv1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1"},
+			cs:   &victoriametricsv1beta1.VMClusterComponentStatus{RolloutRevision: "rev-1"},
+		},
+		{
+			name:       "rollout rolled back",
+			prev:       &victoriametricsv1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1", RolloutRevision: "rev-2", RolloutStartTime: startTime},
+			cs:         &victoriametricsv1beta1.VMClusterComponentStatus{RolloutRevision: "rev-2"},
+			rolledBack: map[string]string{"vmstorage": "rev-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mergeComponentStatus(tt.prev, tt.cs, "vmstorage", 1, &clusterReconcileResult{rolledBack: tt.rolledBack})
+			if tt.cs.RolloutRevision != tt.wantRevision {
+				t.Errorf("mergeComponentStatus() rolloutRevision = %q, want %q", tt.cs.RolloutRevision, tt.wantRevision)
+			}
+			switch {
+			case tt.wantNewStart:
+				if tt.cs.RolloutStartTime == nil || tt.cs.RolloutStartTime.Before(startTime) {
+					t.Errorf("mergeComponentStatus() rolloutStartTime = %v, want current time", tt.cs.RolloutStartTime)
+				}
+			case tt.wantStartTime != tt.cs.RolloutStartTime:
+				t.Errorf("mergeComponentStatus() rolloutStartTime = %v, want %v", tt.cs.RolloutStartTime, tt.wantStartTime)
+			}
+		})
+	}
+}
+
+func Test_imageTag(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "victoriametrics/vmstorage:v1.43.0-cluster", want: "v1.43.0-cluster"},
+		{image: "registry:5000/victoriametrics/vmselect:v1.43.0", want: "v1.43.0"},
+		{image: "registry:5000/victoriametrics/vminsert", want: ""},
+		{image: "vminsert", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := imageTag(tt.image); got != tt.want {
+				t.Errorf("imageTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newVMClusterStatus(t *testing.T) {
+	readySts := &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: pointer.Int32Ptr(2),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "vmstorage", Image: "victoriametrics/vmstorage:v1.43.0-cluster"},
+			}}},
+		},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 2, CurrentRevision: "rev-1", UpdateRevision: "rev-1"},
+	}
+	updatingSts := readySts.DeepCopy()
+	updatingSts.Status.ReadyReplicas = 1
+	updatingSts.Status.UpdatedReplicas = 1
+	updatingSts.Status.UpdateRevision = "rev-2"
+
+	tests := []struct {
+		name            string
+		components      map[string]*victoriametricsv1beta1.VMClusterComponentStatus
+		reconciled      bool
+		failedComponent string
+		newAttempt      bool
+		rolledBack      map[string]string
+		reconcileErr    error
+		wantConditions  map[string]corev1.ConditionStatus
+		wantStorage     string
+		wantFailCount   int
+		// per component update history
+		wantStorageFailCount int32
+		wantFailedRevision   string
+		wantLastGood         string
+		wantRollbackGen      int64
+	}{
+		{
+			name:       "operational cluster",
+			components: map[string]*victoriametricsv1beta1.VMClusterComponentStatus{"vmstorage": stsComponentStatus(readySts, "vmstorage")},
+			reconciled: true,
+			wantConditions: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionAvailable:       corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionProgressing:     corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionConfigValid:     corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionDegraded:        corev1.ConditionFalse,
+			},
+			wantStorage:  victoriametricsv1beta1.ClusterStatusOperational,
+			wantLastGood: "rev-1",
+		},
+		{
+			name:       "vmstorage rolling update",
+			components: map[string]*victoriametricsv1beta1.VMClusterComponentStatus{"vmstorage": stsComponentStatus(updatingSts, "vmstorage")},
+			wantConditions: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionAvailable:       corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionProgressing:     corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionConfigValid:     corev1.ConditionTrue,
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionFalse,
+			},
+			wantStorage:          victoriametricsv1beta1.ClusterStatusExpanding,
+			wantFailCount:        2,
+			wantStorageFailCount: 1,
+			wantFailedRevision:   "rev-2",
+			wantLastGood:         "rev-0",
+		},
+		{
+			name:            "vmstorage failed",
+			components:      map[string]*victoriametricsv1beta1.VMClusterComponentStatus{"vmstorage": stsComponentStatus(updatingSts, "vmstorage")},
+			failedComponent: "vmstorage",
+			newAttempt:      true,
+			reconcileErr:    fmt.Errorf("cannot perform rolling update"),
+			wantConditions: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionAvailable:       corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionProgressing:     corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionConfigValid:     corev1.ConditionUnknown,
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionTrue,
+			},
+			wantStorage:          victoriametricsv1beta1.ClusterStatusFailed,
+			wantFailCount:        2,
+			wantStorageFailCount: 2,
+			wantFailedRevision:   "rev-2",
+			wantLastGood:         "rev-0",
+		},
+		{
+			name:            "vmstorage failure retried",
+			components:      map[string]*victoriametricsv1beta1.VMClusterComponentStatus{"vmstorage": stsComponentStatus(updatingSts, "vmstorage")},
+			failedComponent: "vmstorage",
+			reconcileErr:    fmt.Errorf("cannot perform rolling update"),
+			wantStorage:     victoriametricsv1beta1.ClusterStatusFailed,
+			wantFailCount:   2,
+			// previous failed attempt is kept
+			wantStorageFailCount: 1,
+			wantFailedRevision:   "rev-2",
+			wantLastGood:         "rev-0",
+		},
+		{
+			name:       "vmstorage rolled back",
+			components: map[string]*victoriametricsv1beta1.VMClusterComponentStatus{"vmstorage": stsComponentStatus(updatingSts, "vmstorage")},
+			rolledBack: map[string]string{"vmstorage": "rev-0"},
+			wantConditions: map[string]corev1.ConditionStatus{
+				victoriametricsv1beta1.ConditionReconcileFailed: corev1.ConditionFalse,
+				victoriametricsv1beta1.ConditionDegraded:        corev1.ConditionTrue,
+			},
+			wantStorage:     victoriametricsv1beta1.ClusterStatusExpanding,
+			wantFailCount:   2,
+			wantLastGood:    "rev-0",
+			wantRollbackGen: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := victoriametricsv1beta1.VMClusterStatus{
+				UpdateFailCount: 1,
+				VMStorage: &victoriametricsv1beta1.VMClusterComponentStatus{
+					LastGoodRevision: "rev-0",
+					FailCount:        1,
+					FailedRevision:   "rev-2",
+					LastFailureTime:  &metav1.Time{Time: time.Now().Add(-time.Minute)},
+				},
+			}
+			got := newVMClusterStatus(prev, 3, tt.components, &clusterReconcileResult{
+				reconciled:       tt.reconciled,
+				failedComponent:  tt.failedComponent,
+				failedRevision:   "rev-2",
+				newFailedAttempt: tt.newAttempt,
+				failureTime:      metav1.Now(),
+				rolledBack:       tt.rolledBack,
+				err:              tt.reconcileErr,
+			})
+			if got.ObservedGeneration != 3 {
+				t.Errorf("newVMClusterStatus() observedGeneration = %d, want 3", got.ObservedGeneration)
+			}
+			if got.UpdateFailCount != tt.wantFailCount {
+				t.Errorf("newVMClusterStatus() updateFailCount = %d, want %d", got.UpdateFailCount, tt.wantFailCount)
+			}
+			if got.VMStorage == nil || got.VMStorage.UpdateStatus != tt.wantStorage {
+				t.Fatalf("newVMClusterStatus() vmstorage status = %v, want %s", got.VMStorage, tt.wantStorage)
+			}
+			if got.VMStorage.Version != "v1.43.0-cluster" {
+				t.Errorf("newVMClusterStatus() vmstorage version = %q, want v1.43.0-cluster", got.VMStorage.Version)
+			}
+			if got.VMStorage.FailCount != tt.wantStorageFailCount {
+				t.Errorf("newVMClusterStatus() vmstorage failCount = %d, want %d", got.VMStorage.FailCount, tt.wantStorageFailCount)
+			}
+			if got.VMStorage.FailedRevision != tt.wantFailedRevision {
+				t.Errorf("newVMClusterStatus() vmstorage failedRevision = %q, want %q", got.VMStorage.FailedRevision, tt.wantFailedRevision)
+			}
+			if (got.VMStorage.LastFailureTime != nil) != (tt.wantFailedRevision != "") {
+				t.Errorf("newVMClusterStatus() vmstorage lastFailureTime = %v, must be set only with failedRevision", got.VMStorage.LastFailureTime)
+			}
+			if got.VMStorage.LastGoodRevision != tt.wantLastGood {
+				t.Errorf("newVMClusterStatus() vmstorage lastGoodRevision = %q, want %q", got.VMStorage.LastGoodRevision, tt.wantLastGood)
+			}
+			if got.VMStorage.RollbackGeneration != tt.wantRollbackGen {
+				t.Errorf("newVMClusterStatus() vmstorage rollbackGeneration = %d, want %d", got.VMStorage.RollbackGeneration, tt.wantRollbackGen)
+			}
+			gotConditions := conditionStatuses(got.Conditions)
+			for condType, status := range tt.wantConditions {
+				if gotConditions[condType] != status {
+					t.Errorf("newVMClusterStatus() condition %s = %s, want %s", condType, gotConditions[condType], status)
+				}
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -29,39 +30,15 @@ const (
 )
 
 func CreateOrUpdateVMAgentService(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
-	l := log.WithValues("recon.vm.service.name", cr.Name)
-	NewService := newServiceVMAgent(cr, c)
-
-	currentService := &corev1.Service{}
-	err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: NewService.Name}, currentService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			l.Info("creating new service for vm agent")
-			err := rclient.Create(ctx, NewService)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create new service for vmagent: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("cannot get vmagent service for reconcile: %w", err)
-		}
+	newService := newServiceVMAgent(cr, c)
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vmagent: %w", err)
 	}
-	for annotation, value := range currentService.Annotations {
-		NewService.Annotations[annotation] = value
+	applyServiceSpec(newService, cr.Spec.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vmagent: %w", err)
 	}
-	if currentService.Spec.ClusterIP != "" {
-		NewService.Spec.ClusterIP = currentService.Spec.ClusterIP
-	}
-	if currentService.ResourceVersion != "" {
-		NewService.ResourceVersion = currentService.ResourceVersion
-	}
-	err = rclient.Update(ctx, NewService)
-	if err != nil {
-		l.Error(err, "cannot update vmagent service")
-		return nil, err
-	}
-
-	l.Info("vmagent service reconciled")
-	return NewService, nil
+	return newService, nil
 }
 
 func newServiceVMAgent(cr *victoriametricsv1beta1.VMAgent, c *config.BaseOperatorConf) *corev1.Service {
@@ -96,22 +73,31 @@ func newServiceVMAgent(cr *victoriametricsv1beta1.VMAgent, c *config.BaseOperato
 func CreateOrUpdateVMAgent(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rclient client.Client, c *config.BaseOperatorConf) (reconcile.Result, error) {
 	l := log.WithValues("controller", "vmagent.crud")
 
+	// secrets are fetched from vmagent namespace only once per reconcile
+	secretsCache := make(map[string]*corev1.Secret)
 	//we have to create empty or full cm first
-	err := CreateOrUpdateConfigurationSecret(ctx, cr, rclient, c)
+	err := CreateOrUpdateConfigurationSecret(ctx, cr, rclient, c, secretsCache)
 	if err != nil {
 		l.Error(err, "cannot create configmap")
-		return reconcile.Result{}, err
+		return reconcile.Result{}, newConfigError(err)
 	}
 
 	err = CreateOrUpdateTlsAssets(ctx, cr, rclient)
 	if err != nil {
-		return reconcile.Result{}, fmt.Errorf("cannot update tls asset for vmagent: %w", err)
+		return reconcile.Result{}, newConfigError(fmt.Errorf("cannot update tls asset for vmagent: %w", err))
 	}
 
 	// getting secrets for remotewrite spec
-	rwsBasicAuthSecrets, rwsTokens, err := LoadRemoteWriteSecrets(ctx, cr, rclient, l)
+	rwsBasicAuthSecrets, rwsTokens, err := LoadRemoteWriteSecrets(ctx, cr, rclient, l, secretsCache)
 	if err != nil {
-		return reconcile.Result{}, fmt.Errorf("cannot get remote write secrets for vmagent: %w", err)
+		return reconcile.Result{}, newConfigError(fmt.Errorf("cannot get remote write secrets for vmagent: %w", err))
+	}
+	creds := BuildRemoteWriteCredentials(cr, rwsBasicAuthSecrets, rwsTokens)
+	if len(creds) > 0 {
+		credsSecret := makeCredentialsSecret(cr.CredentialsSecretName(), cr.Namespace, c.Labels.Merge(cr.FinalLabels()), cr.AsOwner(), creds)
+		if err := createOrUpdateCredentialsSecret(ctx, rclient, credsSecret); err != nil {
+			return reconcile.Result{}, fmt.Errorf("cannot update credentials secret for vmagent: %w", err)
+		}
 	}
 	l.Info("create or update vm agent deploy")
 
@@ -141,13 +127,33 @@ func CreateOrUpdateVMAgent(ctx context.Context, cr *victoriametricsv1beta1.VMAge
 	for annotation, value := range currentDeploy.Annotations {
 		newDeploy.Annotations[annotation] = value
 	}
+	// replicas are managed by hpa
+	if cr.Spec.HPA != nil && currentDeploy.Spec.Replicas != nil {
+		newDeploy.Spec.Replicas = currentDeploy.Spec.Replicas
+	}
 	for annotation, value := range currentDeploy.Spec.Template.Annotations {
+		// keep actual credentials checksum, it triggers rollout on credentials change
+		if annotation == credentialsChecksumAnnotation {
+			continue
+		}
 		newDeploy.Spec.Template.Annotations[annotation] = value
 	}
 
 	err = rclient.Update(ctx, newDeploy)
 	if err != nil {
 		l.Error(err, "cannot update vmagent deploy")
+	} else if len(creds) == 0 {
+		// pods don't reference credentials secret anymore
+		if err := deleteCredentialsSecret(ctx, rclient, cr.CredentialsSecretName(), cr.Namespace, cr.AsOwner()); err != nil {
+			return reconcile.Result{}, fmt.Errorf("cannot delete credentials secret for vmagent: %w", err)
+		}
+	}
+	hpaTarget := v2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: newDeploy.Name}
+	if err := reconcileHPA(ctx, rclient, cr.Spec.HPA, hpaTarget, newDeploy.ObjectMeta); err != nil {
+		return reconcile.Result{}, fmt.Errorf("cannot reconcile hpa for vmagent: %w", err)
+	}
+	if err := reconcilePDB(ctx, rclient, cr.Spec.PodDisruptionBudget, cr.SelectorLabels(), newDeploy.ObjectMeta); err != nil {
+		return reconcile.Result{}, fmt.Errorf("cannot reconcile pdb for vmagent: %w", err)
 	}
 
 	//its safe to ignore
@@ -241,8 +247,9 @@ func makeSpecForVMAgent(cr *victoriametricsv1beta1.VMAgent, c *config.BaseOperat
 	}
 
 	if len(cr.Spec.RemoteWrite) > 0 {
-		args = append(args, BuildRemoteWrites(cr, rwsBasicAuth, rwsTokens)...)
+		args = append(args, BuildRemoteWrites(cr)...)
 	}
+	creds := BuildRemoteWriteCredentials(cr, rwsBasicAuth, rwsTokens)
 
 	for arg, value := range cr.Spec.ExtraArgs {
 		args = append(args, fmt.Sprintf("--%s=%s", arg, value))
@@ -256,7 +263,7 @@ func makeSpecForVMAgent(cr *victoriametricsv1beta1.VMAgent, c *config.BaseOperat
 	if cr.Spec.LogFormat != "" {
 		args = append(args, fmt.Sprintf("-loggerFormat=%s", cr.Spec.LogFormat))
 	}
-	if len(cr.Spec.ExtraEnvs) > 0 {
+	if len(cr.Spec.ExtraEnvs) > 0 || len(creds) > 0 {
 		args = append(args, "-envflag.enable=true")
 	}
 
@@ -264,6 +271,13 @@ func makeSpecForVMAgent(cr *victoriametricsv1beta1.VMAgent, c *config.BaseOperat
 
 	envs = append(envs, cr.Spec.ExtraEnvs...)
 
+	podAnnotations := cr.PodAnnotations()
+	var envFrom []corev1.EnvFromSource
+	if len(creds) > 0 {
+		envFrom = append(envFrom, credentialsEnvFrom(cr.CredentialsSecretName()))
+		podAnnotations[credentialsChecksumAnnotation] = credentialsChecksum(creds)
+	}
+
 	var ports []corev1.ContainerPort
 	ports = append(ports, corev1.ContainerPort{Name: "http", Protocol: "TCP", ContainerPort: intstr.Parse(cr.Spec.Port).IntVal})
 	var volumes []corev1.Volume
@@ -470,6 +484,7 @@ func makeSpecForVMAgent(cr *victoriametricsv1beta1.VMAgent, c *config.BaseOperat
 			Ports:                    ports,
 			Args:                     args,
 			Env:                      envs,
+			EnvFrom:                  envFrom,
 			VolumeMounts:             agentVolumeMounts,
 			LivenessProbe:            livenessProbe,
 			ReadinessProbe:           readinessProbe,
@@ -486,7 +501,7 @@ func makeSpecForVMAgent(cr *victoriametricsv1beta1.VMAgent, c *config.BaseOperat
 	vmAgentSpec := &corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      cr.PodLabels(),
-			Annotations: cr.PodAnnotations(),
+			Annotations: podAnnotations,
 		},
 		Spec: corev1.PodSpec{
 			Volumes:            volumes,
@@ -725,20 +740,15 @@ func loadTLSAssets(ctx context.Context, rclient client.Client, cr *victoriametri
 	return assets, nil
 }
 
-func LoadRemoteWriteSecrets(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rclient client.Client, l logr.Logger) (map[string]BasicAuthCredentials, map[string]BearerToken, error) {
-	SecretsInNS := &corev1.SecretList{}
-	err := rclient.List(ctx, SecretsInNS)
-	if err != nil {
-		l.Error(err, "cannot list secrets at vmagent namespace")
-		return nil, nil, err
-	}
-	rwsBasicAuthSecrets, err := loadBasicAuthSecrets(ctx, rclient, nil, nil, cr.Spec.RemoteWrite, SecretsInNS)
+// LoadRemoteWriteSecrets fetches credentials for remote write specs from secrets at vmagent namespace.
+func LoadRemoteWriteSecrets(ctx context.Context, cr *victoriametricsv1beta1.VMAgent, rclient client.Client, l logr.Logger, secretsCache map[string]*corev1.Secret) (map[string]BasicAuthCredentials, map[string]BearerToken, error) {
+	rwsBasicAuthSecrets, err := loadBasicAuthSecrets(ctx, rclient, nil, nil, cr.Spec.RemoteWrite, cr.Namespace, secretsCache)
 	if err != nil {
 		l.Error(err, "cannot load basic auth secrets for remote write specs")
 		return nil, nil, err
 	}
 
-	rwsBearerTokens, err := loadBearerTokensFromSecrets(ctx, rclient, nil, cr.Spec.RemoteWrite, SecretsInNS)
+	rwsBearerTokens, err := loadBearerTokensFromSecrets(ctx, rclient, nil, cr.Spec.RemoteWrite, cr.Namespace, secretsCache)
 	if err != nil {
 		l.Error(err, "cannot get bearer tokens for remote write specs")
 		return nil, nil, err
@@ -751,15 +761,14 @@ type remoteFlag struct {
 	flagSetting string
 }
 
-func BuildRemoteWrites(cr *victoriametricsv1beta1.VMAgent, rwsBasicAuth map[string]BasicAuthCredentials, rwsTokens map[string]BearerToken) []string {
+// BuildRemoteWrites returns remote write flags for vmagent.
+// Credentials are passed with env vars, see BuildRemoteWriteCredentials.
+func BuildRemoteWrites(cr *victoriametricsv1beta1.VMAgent) []string {
 	var finalArgs []string
 	var remoteArgs []remoteFlag
 	remoteTargets := cr.Spec.RemoteWrite
 
 	url := remoteFlag{flagSetting: "-remoteWrite.url=", isNotNull: true}
-	authUser := remoteFlag{flagSetting: "-remoteWrite.basicAuth.username="}
-	authPassword := remoteFlag{flagSetting: "-remoteWrite.basicAuth.password="}
-	bearerToken := remoteFlag{flagSetting: "-remoteWrite.bearerToken="}
 	flushInterval := remoteFlag{flagSetting: "-remoteWrite.flushInterval="}
 	labels := remoteFlag{flagSetting: "-remoteWrite.label="}
 	maxBlockSize := remoteFlag{flagSetting: "-remoteWrite.maxBlockSize="}
@@ -818,29 +827,7 @@ func BuildRemoteWrites(cr *victoriametricsv1beta1.VMAgent, rwsBasicAuth map[stri
 		tlsServerName.flagSetting += fmt.Sprintf("%s,", ServerName)
 		tlsInsecure.flagSetting += fmt.Sprintf("%v,", insecure)
 
-		var user string
-		var pass string
-		if rws.BasicAuth != nil {
-			if s, ok := rwsBasicAuth[fmt.Sprintf("remoteWriteSpec/%s", rws.URL)]; ok {
-				authUser.isNotNull = true
-				authPassword.isNotNull = true
-				user = s.username
-				pass = s.password
-			}
-		}
-		authUser.flagSetting += fmt.Sprintf("\"%s\",", strings.Replace(user, `"`, `\"`, -1))
-		authPassword.flagSetting += fmt.Sprintf("\"%s\",", strings.Replace(pass, `"`, `\"`, -1))
-
 		var value string
-		if rws.BearerTokenSecret != nil {
-			if s, ok := rwsTokens[fmt.Sprintf("remoteWriteSpec/%s", rws.URL)]; ok {
-				bearerToken.isNotNull = true
-				value = string(s)
-			}
-		}
-		bearerToken.flagSetting += fmt.Sprintf("\"%s\",", strings.Replace(value, `"`, `\"`, -1))
-
-		value = ""
 		if rws.FlushInterval != nil {
 			flushInterval.isNotNull = true
 			value = *rws.FlushInterval
@@ -905,7 +892,7 @@ func BuildRemoteWrites(cr *victoriametricsv1beta1.VMAgent, rwsBasicAuth map[stri
 		}
 		tmpDataPath.flagSetting += fmt.Sprintf("%s,", value)
 	}
-	remoteArgs = append(remoteArgs, url, authUser, authPassword, bearerToken, flushInterval, labels, maxBlockSize, maxDiskUsage, queues, urlRelabelConfig, sendTimeout, showURL, tmpDataPath)
+	remoteArgs = append(remoteArgs, url, flushInterval, labels, maxBlockSize, maxDiskUsage, queues, urlRelabelConfig, sendTimeout, showURL, tmpDataPath)
 	remoteArgs = append(remoteArgs, tlsServerName, tlsInsecure, tlsKeys, tlsCerts, tlsCAs)
 	for _, remoteArgType := range remoteArgs {
 		if remoteArgType.isNotNull {
@@ -914,3 +901,42 @@ func BuildRemoteWrites(cr *victoriametricsv1beta1.VMAgent, rwsBasicAuth map[stri
 	}
 	return finalArgs
 }
+
+// BuildRemoteWriteCredentials returns remote write credentials flags as env vars.
+// It hides credentials from pod command line, env vars are loaded by vmagent with -envflag.enable.
+func BuildRemoteWriteCredentials(cr *victoriametricsv1beta1.VMAgent, rwsBasicAuth map[string]BasicAuthCredentials, rwsTokens map[string]BearerToken) map[string]string {
+	creds := map[string]string{}
+	var users, passwords, tokens []string
+	var hasBasicAuth, hasBearerToken bool
+	for _, rws := range cr.Spec.RemoteWrite {
+		var user, pass, token string
+		if rws.BasicAuth != nil {
+			if s, ok := rwsBasicAuth[fmt.Sprintf("remoteWriteSpec/%s", rws.URL)]; ok {
+				hasBasicAuth = true
+				user = s.username
+				pass = s.password
+			}
+		}
+		if rws.BearerTokenSecret != nil {
+			if s, ok := rwsTokens[fmt.Sprintf("remoteWriteSpec/%s", rws.URL)]; ok {
+				hasBearerToken = true
+				token = string(s)
+			}
+		}
+		users = append(users, quoteFlagValue(user))
+		passwords = append(passwords, quoteFlagValue(pass))
+		tokens = append(tokens, quoteFlagValue(token))
+	}
+	if hasBasicAuth {
+		creds[envFlagName("remoteWrite.basicAuth.username")] = strings.Join(users, ",")
+		creds[envFlagName("remoteWrite.basicAuth.password")] = strings.Join(passwords, ",")
+	}
+	if hasBearerToken {
+		creds[envFlagName("remoteWrite.bearerToken")] = strings.Join(tokens, ",")
+	}
+	return creds
+}
+
+func quoteFlagValue(value string) string {
+	return fmt.Sprintf("\"%s\"", strings.Replace(value, `"`, `\"`, -1))
+}
@@ -3,12 +3,15 @@ package factory
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 
 	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
 	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -478,3 +481,121 @@ func Test_loadTLSAssets(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildRemoteWriteCredentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteWrites []victoriametricsv1beta1.VMAgentRemoteWriteSpec
+		rwsBasicAuth map[string]BasicAuthCredentials
+		rwsTokens    map[string]BearerToken
+		want         map[string]string
+	}{
+		{
+			name:         "without credentials",
+			remoteWrites: []victoriametricsv1beta1.VMAgentRemoteWriteSpec{{URL: "http://remote-write"}},
+			want:         map[string]string{},
+		},
+		{
+			name: "basic auth and bearer token for different urls",
+			remoteWrites: []victoriametricsv1beta1.VMAgentRemoteWriteSpec{
+				{URL: "http://remote-write", BasicAuth: &victoriametricsv1beta1.BasicAuth{}},
+				{URL: "http://remote-write-2"},
+				{URL: "http://remote-write-3", BearerTokenSecret: &corev1.SecretKeySelector{Key: "token"}},
+			},
+			rwsBasicAuth: map[string]BasicAuthCredentials{
+				"remoteWriteSpec/http://remote-write": {username: "user", password: `pass"word`},
+			},
+			rwsTokens: map[string]BearerToken{
+				"remoteWriteSpec/http://remote-write-3": "token-value",
+			},
+			want: map[string]string{
+				"remoteWrite_basicAuth_username": `"user","",""`,
+				"remoteWrite_basicAuth_password": `"pass\"word","",""`,
+				"remoteWrite_bearerToken":        `"","","token-value"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &victoriametricsv1beta1.VMAgent{Spec: victoriametricsv1beta1.VMAgentSpec{RemoteWrite: tt.remoteWrites}}
+			got := BuildRemoteWriteCredentials(cr, tt.rwsBasicAuth, tt.rwsTokens)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildRemoteWriteCredentials() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdateVMAgent_credentials(t *testing.T) {
+	cr := &victoriametricsv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-agent", Namespace: "default"},
+		Spec: victoriametricsv1beta1.VMAgentSpec{
+			RemoteWrite: []victoriametricsv1beta1.VMAgentRemoteWriteSpec{
+				{
+					URL: "http://remote-write",
+					BasicAuth: &victoriametricsv1beta1.BasicAuth{
+						Username: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "rw-auth"}, Key: "user"},
+						Password: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "rw-auth"}, Key: "password"},
+					},
+				},
+			},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "rw-auth", Namespace: "default"},
+		Data:       map[string][]byte{"user": []byte("user-name"), "password": []byte("user-password")},
+	})
+	if _, err := CreateOrUpdateVMAgent(context.TODO(), cr, fclient, config.MustGetBaseConfig()); err != nil {
+		t.Fatalf("CreateOrUpdateVMAgent() unexpected error: %v", err)
+	}
+
+	var credsSecret corev1.Secret
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: cr.CredentialsSecretName()}, &credsSecret); err != nil {
+		t.Fatalf("cannot get credentials secret: %v", err)
+	}
+	if string(credsSecret.Data["remoteWrite_basicAuth_password"]) != `"user-password"` {
+		t.Errorf("unexpected password at credentials secret: %q", credsSecret.Data["remoteWrite_basicAuth_password"])
+	}
+
+	var deploy appsv1.Deployment
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: cr.PrefixedName()}, &deploy); err != nil {
+		t.Fatalf("cannot get vmagent deployment: %v", err)
+	}
+	if deploy.Spec.Template.Annotations[credentialsChecksumAnnotation] == "" {
+		t.Errorf("credentials checksum annotation must be set")
+	}
+	for _, container := range deploy.Spec.Template.Spec.Containers {
+		if container.Name != "vmagent" {
+			continue
+		}
+		for _, arg := range container.Args {
+			if strings.Contains(arg, "user-password") || strings.Contains(arg, "basicAuth") {
+				t.Errorf("credentials must not be passed with args, got: %s", arg)
+			}
+		}
+		if len(container.EnvFrom) != 1 || container.EnvFrom[0].SecretRef.Name != cr.CredentialsSecretName() {
+			t.Errorf("credentials secret must be passed with envFrom, got: %v", container.EnvFrom)
+		}
+	}
+	// stale credentials secret must be removed, when credentials are removed from spec
+	cr.Spec.RemoteWrite[0].BasicAuth = nil
+	fclient = fake.NewFakeClientWithScheme(testGetScheme(), &credsSecret)
+	if _, err := CreateOrUpdateVMAgent(context.TODO(), cr, fclient, config.MustGetBaseConfig()); err != nil {
+		t.Fatalf("CreateOrUpdateVMAgent() unexpected error: %v", err)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: cr.CredentialsSecretName()}, &corev1.Secret{}); !errors.IsNotFound(err) {
+		t.Errorf("credentials secret must be deleted, got err: %v", err)
+	}
+	deploy = appsv1.Deployment{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: cr.PrefixedName()}, &deploy); err != nil {
+		t.Fatalf("cannot get vmagent deployment: %v", err)
+	}
+	if _, ok := deploy.Spec.Template.Annotations[credentialsChecksumAnnotation]; ok {
+		t.Errorf("credentials checksum annotation must not be set")
+	}
+	for _, container := range deploy.Spec.Template.Spec.Containers {
+		if len(container.EnvFrom) != 0 {
+			t.Errorf("credentials secret must not be passed with envFrom, got: %v", container.EnvFrom)
+		}
+	}
+}
@@ -24,36 +24,17 @@ const (
 )
 
 func CreateOrUpdateVMAlertService(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
-	l := log.WithValues("controller", "vmalert.service.crud", "vmalert", cr.Name)
 	newService := newServiceVMAlert(cr, c)
-
-	currentService := &corev1.Service{}
-	err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: newService.Name}, currentService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			l.Info("creating new service for vm vmalert")
-			err := rclient.Create(ctx, newService)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create new service for vmalert: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("cannot get vmalert service: %w", err)
-		}
-	}
-	for annotation, value := range currentService.Annotations {
-		newService.Annotations[annotation] = value
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vmalert: %w", err)
 	}
-	if currentService.Spec.ClusterIP != "" {
-		newService.Spec.ClusterIP = currentService.Spec.ClusterIP
+	applyServiceSpec(newService, cr.Spec.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vmalert: %w", err)
 	}
-	if currentService.ResourceVersion != "" {
-		newService.ResourceVersion = currentService.ResourceVersion
+	if err := reconcileIngress(ctx, rclient, cr.Spec.Ingress, newService, "http"); err != nil {
+		return nil, fmt.Errorf("cannot reconcile ingress for vmalert: %w", err)
 	}
-	err = rclient.Update(ctx, newService)
-	if err != nil {
-		return nil, fmt.Errorf("cannot update vmalert service: %w", err)
-	}
-	l.Info("vmalert svc reconciled")
 	return newService, nil
 }
 
@@ -88,21 +69,22 @@ func newServiceVMAlert(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperato
 func CreateOrUpdateVMAlert(ctx context.Context, cr *victoriametricsv1beta1.VMAlert, rclient client.Client, c *config.BaseOperatorConf, cmNames []string) (reconcile.Result, error) {
 	l := log.WithValues("controller", "vmalert.crud", "vmalert", cr.Name)
 	//recon deploy
-	secretsInNs := &corev1.SecretList{}
-	err := rclient.List(ctx, secretsInNs, &client.ListOptions{Namespace: cr.Namespace})
-	if err != nil {
-		l.Error(err, "cannot list secretsInNs at vmalert namespace")
-		return reconcile.Result{}, err
-	}
-	remoteSecrets, err := loadVMAlertRemoteSecrets(cr, secretsInNs)
+	remoteSecrets, err := loadVMAlertRemoteSecrets(ctx, rclient, cr)
 	if err != nil {
-		l.Error(err, "cannot get basic auth secretsInNs for vmalert")
-		return reconcile.Result{}, err
+		l.Error(err, "cannot get basic auth secrets for vmalert")
+		return reconcile.Result{}, newConfigError(err)
+	}
+	creds := buildVMAlertCredentials(remoteSecrets)
+	if len(creds) > 0 {
+		credsSecret := makeCredentialsSecret(cr.CredentialsSecretName(), cr.Namespace, c.Labels.Merge(cr.FinalLabels()), cr.AsOwner(), creds)
+		if err := createOrUpdateCredentialsSecret(ctx, rclient, credsSecret); err != nil {
+			return reconcile.Result{}, fmt.Errorf("cannot update credentials secret for vmalert: %w", err)
+		}
 	}
 
 	err = CreateOrUpdateTlsAssetsForVMAlert(ctx, cr, rclient)
 	if err != nil {
-		return reconcile.Result{}, err
+		return reconcile.Result{}, newConfigError(err)
 	}
 	l.Info("generating new deployment")
 	newDeploy, err := newDeployForVMAlert(cr, c, cmNames, remoteSecrets)
@@ -127,6 +109,10 @@ func CreateOrUpdateVMAlert(ctx context.Context, cr *victoriametricsv1beta1.VMAle
 		newDeploy.Annotations[annotation] = value
 	}
 	for annotation, value := range currDeploy.Spec.Template.Annotations {
+		// keep actual credentials checksum, it triggers rollout on credentials change
+		if annotation == credentialsChecksumAnnotation {
+			continue
+		}
 		newDeploy.Spec.Template.Annotations[annotation] = value
 	}
 
@@ -134,6 +120,15 @@ func CreateOrUpdateVMAlert(ctx context.Context, cr *victoriametricsv1beta1.VMAle
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("cannot update vmalert deploy: %w", err)
 	}
+	if len(creds) == 0 {
+		// pods don't reference credentials secret anymore
+		if err := deleteCredentialsSecret(ctx, rclient, cr.CredentialsSecretName(), cr.Namespace, cr.AsOwner()); err != nil {
+			return reconcile.Result{}, fmt.Errorf("cannot delete credentials secret for vmalert: %w", err)
+		}
+	}
+	if err := reconcilePDB(ctx, rclient, cr.Spec.PodDisruptionBudget, cr.SelectorLabels(), newDeploy.ObjectMeta); err != nil {
+		return reconcile.Result{}, fmt.Errorf("cannot reconcile pdb for vmalert: %w", err)
+	}
 	l.Info("reconciled vmalert deploy")
 
 	return reconcile.Result{}, nil
@@ -222,10 +217,6 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 		fmt.Sprintf("-datasource.url=%s", cr.Spec.Datasource.URL),
 	}
 	if cr.Spec.Datasource.BasicAuth != nil {
-		if s, ok := remoteSecrets["datasource"]; ok {
-			args = append(args, fmt.Sprintf("-datasource.basicAuth.username=%s", s.username))
-			args = append(args, fmt.Sprintf("-datasource.basicAuth.password=%s", s.password))
-		}
 		if cr.Spec.Datasource.TLSConfig != nil {
 			tlsConf := cr.Spec.Datasource.TLSConfig
 			if tlsConf.CAFile != "" {
@@ -248,12 +239,6 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 
 		}
 	}
-	if cr.Spec.Notifier.BasicAuth != nil {
-		if s, ok := remoteSecrets["notifier"]; ok {
-			args = append(args, fmt.Sprintf("-notifier.basicAuth.username%s", s.username))
-			args = append(args, fmt.Sprintf("-notifier.basicAuth.password=%s", s.password))
-		}
-	}
 	if cr.Spec.Notifier.TLSConfig != nil {
 		tlsConf := cr.Spec.Notifier.TLSConfig
 		if tlsConf.CAFile != "" {
@@ -279,12 +264,6 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 	if cr.Spec.RemoteWrite != nil {
 		//this param cannot be used until v1.35.5 vm release with flag breaking changes
 		args = append(args, fmt.Sprintf("-remoteWrite.url=%s", cr.Spec.RemoteWrite.URL))
-		if cr.Spec.RemoteWrite.BasicAuth != nil {
-			if s, ok := remoteSecrets["remoteWrite"]; ok {
-				args = append(args, fmt.Sprintf("-remoteWrite.basicAuth.username=%s", s.username))
-				args = append(args, fmt.Sprintf("-remoteWrite.basicAuth.password=%s", s.password))
-			}
-		}
 		if cr.Spec.RemoteWrite.Concurrency != nil {
 			args = append(args, fmt.Sprintf("-remoteWrite.concurrency=%d", *cr.Spec.RemoteWrite.Concurrency))
 		}
@@ -322,12 +301,6 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 	}
 	if cr.Spec.RemoteRead != nil {
 		args = append(args, fmt.Sprintf("-remoteRead.url=%s", cr.Spec.RemoteRead.URL))
-		if cr.Spec.RemoteRead.BasicAuth != nil {
-			if s, ok := remoteSecrets["remoteRead"]; ok {
-				args = append(args, fmt.Sprintf("-remoteRead.basicAuth.username=%s", s.username))
-				args = append(args, fmt.Sprintf("-remoteRead.basicAuth.password=%s", s.password))
-			}
-		}
 		if cr.Spec.RemoteRead.Lookback != nil {
 			args = append(args, fmt.Sprintf("-remoteRead.lookback=%s", *cr.Spec.RemoteRead.Lookback))
 		}
@@ -371,7 +344,7 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 	for _, cm := range ruleConfigMapNames {
 		confReloadArgs = append(confReloadArgs, fmt.Sprintf("-volume-dir=%s", path.Join(vmAlertConfigDir, cm)))
 	}
-	for arg, value := range cr.Spec.ExtraArgs {
+	for arg, value := range victoriametricsv1beta1.ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress) {
 		args = append(args, fmt.Sprintf("--%s=%s", arg, value))
 	}
 
@@ -380,7 +353,8 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 	for _, rulePath := range cr.Spec.RulePath {
 		args = append(args, "-rule="+rulePath)
 	}
-	if len(cr.Spec.ExtraEnvs) > 0 {
+	creds := buildVMAlertCredentials(remoteSecrets)
+	if len(cr.Spec.ExtraEnvs) > 0 || len(creds) > 0 {
 		args = append(args, "-envflag.enable=true")
 	}
 
@@ -388,6 +362,13 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 
 	envs = append(envs, cr.Spec.ExtraEnvs...)
 
+	podAnnotations := cr.PodAnnotations()
+	var envFrom []corev1.EnvFromSource
+	if len(creds) > 0 {
+		envFrom = append(envFrom, credentialsEnvFrom(cr.CredentialsSecretName()))
+		podAnnotations[credentialsChecksumAnnotation] = credentialsChecksum(creds)
+	}
+
 	var volumes []corev1.Volume
 	volumes = append(volumes, cr.Spec.Volumes...)
 
@@ -521,6 +502,7 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 			ReadinessProbe:           readinessProbe,
 			Resources:                cr.Spec.Resources,
 			Env:                      envs,
+			EnvFrom:                  envFrom,
 			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 		}, {
 			Name:                     "config-reloader",
@@ -549,7 +531,7 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels:      cr.PodLabels(),
-				Annotations: cr.PodAnnotations(),
+				Annotations: podAnnotations,
 			},
 			Spec: corev1.PodSpec{
 				Containers:      containers,
@@ -565,17 +547,32 @@ func vmAlertSpecGen(cr *victoriametricsv1beta1.VMAlert, c *config.BaseOperatorCo
 	return spec, nil
 }
 
+// buildVMAlertCredentials returns basic auth flags as env vars.
+// It hides credentials from pod command line, env vars are loaded by vmalert with -envflag.enable.
+func buildVMAlertCredentials(remoteSecrets map[string]BasicAuthCredentials) map[string]string {
+	creds := map[string]string{}
+	// remoteSecrets is keyed by flag prefix: datasource, notifier, remoteWrite or remoteRead
+	for flagPrefix, s := range remoteSecrets {
+		creds[envFlagName(flagPrefix+".basicAuth.username")] = s.username
+		creds[envFlagName(flagPrefix+".basicAuth.password")] = s.password
+	}
+	return creds
+}
+
 func loadVMAlertRemoteSecrets(
+	ctx context.Context,
+	rclient client.Client,
 	cr *victoriametricsv1beta1.VMAlert,
-	SecretsInNS *corev1.SecretList,
 ) (map[string]BasicAuthCredentials, error) {
+	// the same secret is usually shared between vmalert remote endpoints
+	nsSecretCache := make(map[string]*corev1.Secret)
 	datasource := cr.Spec.Datasource
 	remoteWrite := cr.Spec.RemoteWrite
 	remoteRead := cr.Spec.RemoteRead
 	notifier := cr.Spec.Notifier
 	secrets := map[string]BasicAuthCredentials{}
 	if notifier.BasicAuth != nil {
-		credentials, err := loadBasicAuthSecret(notifier.BasicAuth, SecretsInNS)
+		credentials, err := loadBasicAuthSecretFromAPI(ctx, rclient, notifier.BasicAuth, cr.Namespace, nsSecretCache)
 		if err != nil {
 			return nil, fmt.Errorf("could not generate basicAuth for notifier config. %w", err)
 		}
@@ -583,7 +580,7 @@ func loadVMAlertRemoteSecrets(
 	}
 	// load basic auth for datasource configuration
 	if datasource.BasicAuth != nil {
-		credentials, err := loadBasicAuthSecret(datasource.BasicAuth, SecretsInNS)
+		credentials, err := loadBasicAuthSecretFromAPI(ctx, rclient, datasource.BasicAuth, cr.Namespace, nsSecretCache)
 		if err != nil {
 			return nil, fmt.Errorf("could not generate basicAuth for datasource config. %w", err)
 		}
@@ -591,7 +588,7 @@ func loadVMAlertRemoteSecrets(
 	}
 	// load basic auth for remote write configuration
 	if remoteWrite != nil && remoteWrite.BasicAuth != nil {
-		credentials, err := loadBasicAuthSecret(remoteWrite.BasicAuth, SecretsInNS)
+		credentials, err := loadBasicAuthSecretFromAPI(ctx, rclient, remoteWrite.BasicAuth, cr.Namespace, nsSecretCache)
 		if err != nil {
 			return nil, fmt.Errorf("could not generate basicAuth for VMAlert remote write config. %w", err)
 		}
@@ -599,7 +596,7 @@ func loadVMAlertRemoteSecrets(
 	}
 	// load basic auth for remote write configuration
 	if remoteRead != nil && remoteRead.BasicAuth != nil {
-		credentials, err := loadBasicAuthSecret(remoteRead.BasicAuth, SecretsInNS)
+		credentials, err := loadBasicAuthSecretFromAPI(ctx, rclient, remoteRead.BasicAuth, cr.Namespace, nsSecretCache)
 		if err != nil {
 			return nil, fmt.Errorf("could not generate basicAuth for VMAlert remote read config. %w", err)
 		}
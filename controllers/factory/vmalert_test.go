@@ -10,25 +10,85 @@ import (
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
 	"testing"
 )
 
 func Test_loadVMAlertRemoteSecrets(t *testing.T) {
 	type args struct {
-		cr          *victoriametricsv1beta1.VMAlert
-		SecretsInNS *corev1.SecretList
+		cr *victoriametricsv1beta1.VMAlert
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    map[string]BasicAuthCredentials
-		wantErr bool
+		name              string
+		args              args
+		predefinedObjects []runtime.Object
+		want              map[string]BasicAuthCredentials
+		wantErr           bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "load datasource and notifier auth from own namespace",
+			args: args{
+				cr: &victoriametricsv1beta1.VMAlert{
+					ObjectMeta: metav1.ObjectMeta{Name: "vmalert", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMAlertSpec{
+						Datasource: victoriametricsv1beta1.VMAlertDatasourceSpec{
+							BasicAuth: &victoriametricsv1beta1.BasicAuth{
+								Username: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "auth"}, Key: "user"},
+								Password: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "auth"}, Key: "password"},
+							},
+						},
+						Notifier: victoriametricsv1beta1.VMAlertNotifierSpec{
+							BasicAuth: &victoriametricsv1beta1.BasicAuth{
+								Username: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "auth"}, Key: "user"},
+								Password: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "auth"}, Key: "password"},
+							},
+						},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "auth", Namespace: "default"},
+					Data:       map[string][]byte{"user": []byte("admin"), "password": []byte("pass")},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "auth", Namespace: "other"},
+					Data:       map[string][]byte{"user": []byte("other"), "password": []byte("other")},
+				},
+			},
+			want: map[string]BasicAuthCredentials{
+				"datasource": {username: "admin", password: "pass"},
+				"notifier":   {username: "admin", password: "pass"},
+			},
+		},
+		{
+			name: "secret at another namespace",
+			args: args{
+				cr: &victoriametricsv1beta1.VMAlert{
+					ObjectMeta: metav1.ObjectMeta{Name: "vmalert", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMAlertSpec{
+						Datasource: victoriametricsv1beta1.VMAlertDatasourceSpec{
+							BasicAuth: &victoriametricsv1beta1.BasicAuth{
+								Username: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "auth"}, Key: "user"},
+								Password: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "auth"}, Key: "password"},
+							},
+						},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "auth", Namespace: "other"},
+					Data:       map[string][]byte{"user": []byte("other"), "password": []byte("other")},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := loadVMAlertRemoteSecrets(tt.args.cr, tt.args.SecretsInNS)
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			got, err := loadVMAlertRemoteSecrets(context.TODO(), fclient, tt.args.cr)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("loadVMAlertRemoteSecrets() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -202,3 +262,38 @@ func TestCreateOrUpdateVMAlert(t *testing.T) {
 		})
 	}
 }
+
+func Test_vmAlertSpecGen_credentials(t *testing.T) {
+	cr := &victoriametricsv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "basic-vmalert", Namespace: "default"},
+		Spec: victoriametricsv1beta1.VMAlertSpec{
+			Notifier:   victoriametricsv1beta1.VMAlertNotifierSpec{URL: "http://some-alertmanager"},
+			Datasource: victoriametricsv1beta1.VMAlertDatasourceSpec{URL: "http://some-vm-datasource", BasicAuth: &victoriametricsv1beta1.BasicAuth{}},
+			Port:       "8080",
+		},
+	}
+	remoteSecrets := map[string]BasicAuthCredentials{"datasource": {username: "user", password: "secret-password"}}
+	spec, err := vmAlertSpecGen(cr, config.MustGetBaseConfig(), nil, remoteSecrets)
+	if err != nil {
+		t.Fatalf("vmAlertSpecGen() unexpected error: %v", err)
+	}
+	wantEnvs := map[string]string{
+		"datasource_basicAuth_username": "user",
+		"datasource_basicAuth_password": "secret-password",
+	}
+	if got := buildVMAlertCredentials(remoteSecrets); !reflect.DeepEqual(got, wantEnvs) {
+		t.Errorf("buildVMAlertCredentials() got = %v, want %v", got, wantEnvs)
+	}
+	if got := spec.Template.Annotations[credentialsChecksumAnnotation]; got != credentialsChecksum(wantEnvs) {
+		t.Errorf("unexpected credentials checksum annotation: %q", got)
+	}
+	container := spec.Template.Spec.Containers[0]
+	for _, arg := range container.Args {
+		if strings.Contains(arg, "secret-password") || strings.Contains(arg, "basicAuth") {
+			t.Errorf("credentials must not be passed with args, got: %s", arg)
+		}
+	}
+	if len(container.EnvFrom) != 1 || container.EnvFrom[0].SecretRef.Name != cr.CredentialsSecretName() {
+		t.Errorf("credentials secret must be passed with envFrom, got: %v", container.EnvFrom)
+	}
+}
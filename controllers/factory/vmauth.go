@@ -0,0 +1,651 @@
+package factory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	vmAuthConfDir                = "/etc/vmauth/config"
+	vmAuthConOfOutDir            = "/etc/vmauth/config_out"
+	vmAuthConfigFilename         = "vmauth.yaml.gz"
+	vmAuthConfigEnvsubstFilename = "vmauth.env.yaml"
+)
+
+func CreateOrUpdateVMAuthService(ctx context.Context, cr *victoriametricsv1beta1.VMAuth, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
+	newService := newServiceVMAuth(cr, c)
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vmauth: %w", err)
+	}
+	applyServiceSpec(newService, cr.Spec.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vmauth: %w", err)
+	}
+	return newService, nil
+}
+
+func newServiceVMAuth(cr *victoriametricsv1beta1.VMAuth, c *config.BaseOperatorConf) *corev1.Service {
+	cr = cr.DeepCopy()
+	if cr.Spec.Port == "" {
+		cr.Spec.Port = c.VMAuthDefault.Port
+	}
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cr.PrefixedName(),
+			Namespace:       cr.Namespace,
+			Labels:          c.Labels.Merge(cr.FinalLabels()),
+			Annotations:     cr.Annotations(),
+			OwnerReferences: cr.AsOwner(),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: cr.SelectorLabels(),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Protocol:   "TCP",
+					Port:       intstr.Parse(cr.Spec.Port).IntVal,
+					TargetPort: intstr.Parse(cr.Spec.Port),
+				},
+			},
+		},
+	}
+}
+
+// CreateOrUpdateVMAuth builds vmauth config from selected VMUsers
+// and creates or updates vmauth deployment.
+func CreateOrUpdateVMAuth(ctx context.Context, cr *victoriametricsv1beta1.VMAuth, rclient client.Client, c *config.BaseOperatorConf) (reconcile.Result, error) {
+	l := log.WithValues("controller", "vmauth.crud", "vmauth", cr.Name, "namespace", cr.Namespace)
+
+	if err := CreateOrUpdateVMAuthConfig(ctx, cr, rclient, c); err != nil {
+		return reconcile.Result{}, fmt.Errorf("cannot create or update config secret for vmauth: %w", err)
+	}
+
+	newDeploy, err := newDeployForVMAuth(cr, c)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("cannot build new deploy for vmauth: %w", err)
+	}
+
+	currentDeploy := &appsv1.Deployment{}
+	err = rclient.Get(ctx, types.NamespacedName{Name: newDeploy.Name, Namespace: newDeploy.Namespace}, currentDeploy)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			l.Info("vmauth deploy not found, creating new one")
+			if err := rclient.Create(ctx, newDeploy); err != nil {
+				return reconcile.Result{}, fmt.Errorf("cannot create new vmauth deploy: %w", err)
+			}
+			l.Info("new vmauth deploy was created")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("cannot get vmauth deploy: %s,err: %w", newDeploy.Name, err)
+	}
+	for annotation, value := range currentDeploy.Annotations {
+		newDeploy.Annotations[annotation] = value
+	}
+	for annotation, value := range currentDeploy.Spec.Template.Annotations {
+		newDeploy.Spec.Template.Annotations[annotation] = value
+	}
+
+	if err := rclient.Update(ctx, newDeploy); err != nil {
+		return reconcile.Result{}, fmt.Errorf("cannot update vmauth deploy: %w", err)
+	}
+	l.Info("vmauth deploy reconciled")
+
+	return reconcile.Result{}, nil
+}
+
+func newDeployForVMAuth(cr *victoriametricsv1beta1.VMAuth, c *config.BaseOperatorConf) (*appsv1.Deployment, error) {
+	cr = cr.DeepCopy()
+
+	//inject default
+	if cr.Spec.Image.Repository == "" {
+		cr.Spec.Image.Repository = c.VMAuthDefault.Image
+	}
+	if cr.Spec.Image.Tag == "" {
+		cr.Spec.Image.Tag = c.VMAuthDefault.Version
+	}
+	if cr.Spec.Image.PullPolicy == "" {
+		cr.Spec.Image.PullPolicy = corev1.PullIfNotPresent
+	}
+
+	if cr.Spec.Port == "" {
+		cr.Spec.Port = c.VMAuthDefault.Port
+	}
+	if cr.Spec.Resources.Requests == nil {
+		cr.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	if cr.Spec.Resources.Limits == nil {
+		cr.Spec.Resources.Limits = corev1.ResourceList{}
+	}
+
+	var cpuResourceIsSet bool
+	var memResourceIsSet bool
+
+	if _, ok := cr.Spec.Resources.Limits[corev1.ResourceMemory]; ok {
+		memResourceIsSet = true
+	}
+	if _, ok := cr.Spec.Resources.Limits[corev1.ResourceCPU]; ok {
+		cpuResourceIsSet = true
+	}
+	if _, ok := cr.Spec.Resources.Requests[corev1.ResourceMemory]; ok {
+		memResourceIsSet = true
+	}
+	if _, ok := cr.Spec.Resources.Requests[corev1.ResourceCPU]; ok {
+		cpuResourceIsSet = true
+	}
+	if !cpuResourceIsSet {
+		cr.Spec.Resources.Requests[corev1.ResourceCPU] = resource.MustParse(c.VMAuthDefault.Resource.Request.Cpu)
+		cr.Spec.Resources.Limits[corev1.ResourceCPU] = resource.MustParse(c.VMAuthDefault.Resource.Limit.Cpu)
+	}
+	if !memResourceIsSet {
+		cr.Spec.Resources.Requests[corev1.ResourceMemory] = resource.MustParse(c.VMAuthDefault.Resource.Request.Mem)
+		cr.Spec.Resources.Limits[corev1.ResourceMemory] = resource.MustParse(c.VMAuthDefault.Resource.Limit.Mem)
+	}
+
+	podSpec, err := makeSpecForVMAuth(cr, c)
+	if err != nil {
+		return nil, err
+	}
+
+	depSpec := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cr.PrefixedName(),
+			Namespace:       cr.Namespace,
+			Labels:          c.Labels.Merge(cr.FinalLabels()),
+			Annotations:     cr.Annotations(),
+			OwnerReferences: cr.AsOwner(),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: cr.Spec.ReplicaCount,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: cr.SelectorLabels(),
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{},
+			},
+			Template: *podSpec,
+		},
+	}
+	return depSpec, nil
+}
+
+func makeSpecForVMAuth(cr *victoriametricsv1beta1.VMAuth, c *config.BaseOperatorConf) (*corev1.PodTemplateSpec, error) {
+	args := []string{
+		fmt.Sprintf("-auth.config=%s", path.Join(vmAuthConOfOutDir, vmAuthConfigEnvsubstFilename)),
+	}
+
+	for arg, value := range cr.Spec.ExtraArgs {
+		args = append(args, fmt.Sprintf("--%s=%s", arg, value))
+	}
+
+	args = append(args, fmt.Sprintf("-httpListenAddr=:%s", cr.Spec.Port))
+
+	if cr.Spec.LogLevel != "" {
+		args = append(args, fmt.Sprintf("-loggerLevel=%s", cr.Spec.LogLevel))
+	}
+	if cr.Spec.LogFormat != "" {
+		args = append(args, fmt.Sprintf("-loggerFormat=%s", cr.Spec.LogFormat))
+	}
+	if len(cr.Spec.ExtraEnvs) > 0 {
+		args = append(args, "-envflag.enable=true")
+	}
+
+	var envs []corev1.EnvVar
+
+	envs = append(envs, cr.Spec.ExtraEnvs...)
+
+	var ports []corev1.ContainerPort
+	ports = append(ports, corev1.ContainerPort{Name: "http", Protocol: "TCP", ContainerPort: intstr.Parse(cr.Spec.Port).IntVal})
+
+	var volumes []corev1.Volume
+	volumes = append(volumes, cr.Spec.Volumes...)
+	volumes = append(volumes,
+		corev1.Volume{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: cr.ConfigSecretName(),
+				},
+			},
+		},
+		corev1.Volume{
+			Name: "config-out",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+	)
+
+	var vmMounts []corev1.VolumeMount
+	vmMounts = append(vmMounts, cr.Spec.VolumeMounts...)
+	vmMounts = append(vmMounts, corev1.VolumeMount{
+		Name:      "config-out",
+		ReadOnly:  true,
+		MountPath: vmAuthConOfOutDir,
+	})
+
+	for _, s := range cr.Spec.Secrets {
+		volumes = append(volumes, corev1.Volume{
+			Name: SanitizeVolumeName("secret-" + s),
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: s,
+				},
+			},
+		})
+		vmMounts = append(vmMounts, corev1.VolumeMount{
+			Name:      SanitizeVolumeName("secret-" + s),
+			ReadOnly:  true,
+			MountPath: path.Join(SecretsDir, s),
+		})
+	}
+
+	for _, c := range cr.Spec.ConfigMaps {
+		volumes = append(volumes, corev1.Volume{
+			Name: SanitizeVolumeName("configmap-" + c),
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: c,
+					},
+				},
+			},
+		})
+		vmMounts = append(vmMounts, corev1.VolumeMount{
+			Name:      SanitizeVolumeName("configmap-" + c),
+			ReadOnly:  true,
+			MountPath: path.Join(ConfigMapsDir, c),
+		})
+	}
+
+	configReloadVolumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "config",
+			MountPath: vmAuthConfDir,
+		},
+		{
+			Name:      "config-out",
+			MountPath: vmAuthConOfOutDir,
+		},
+	}
+
+	configReloadArgs := []string{
+		fmt.Sprintf("--reload-url=%s", cr.ReloadPathWithPort(cr.Spec.Port)),
+		fmt.Sprintf("--config-file=%s", path.Join(vmAuthConfDir, vmAuthConfigFilename)),
+		fmt.Sprintf("--config-envsubst-file=%s", path.Join(vmAuthConOfOutDir, vmAuthConfigEnvsubstFilename)),
+	}
+
+	livenessProbeHandler := corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Port:   intstr.Parse(cr.Spec.Port),
+			Scheme: "HTTP",
+			Path:   cr.HealthPath(),
+		},
+	}
+	readinessProbeHandler := corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Port:   intstr.Parse(cr.Spec.Port),
+			Scheme: "HTTP",
+			Path:   cr.HealthPath(),
+		},
+	}
+	livenessProbe := &corev1.Probe{
+		Handler:          livenessProbeHandler,
+		PeriodSeconds:    5,
+		TimeoutSeconds:   probeTimeoutSeconds,
+		FailureThreshold: 3,
+	}
+	readinessProbe := &corev1.Probe{
+		Handler:          readinessProbeHandler,
+		TimeoutSeconds:   probeTimeoutSeconds,
+		PeriodSeconds:    5,
+		FailureThreshold: 10,
+	}
+
+	configReloaderResources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{}, Requests: corev1.ResourceList{}}
+	if c.VMAuthDefault.ConfigReloaderCPU != "0" {
+		configReloaderResources.Limits[corev1.ResourceCPU] = resource.MustParse(c.VMAuthDefault.ConfigReloaderCPU)
+		configReloaderResources.Requests[corev1.ResourceCPU] = resource.MustParse(c.VMAuthDefault.ConfigReloaderCPU)
+	}
+	if c.VMAuthDefault.ConfigReloaderMemory != "0" {
+		configReloaderResources.Limits[corev1.ResourceMemory] = resource.MustParse(c.VMAuthDefault.ConfigReloaderMemory)
+		configReloaderResources.Requests[corev1.ResourceMemory] = resource.MustParse(c.VMAuthDefault.ConfigReloaderMemory)
+	}
+
+	sort.Strings(args)
+	operatorContainers := []corev1.Container{
+		{
+			Name:                     "config-reloader",
+			Image:                    c.VMAuthDefault.ConfigReloadImage,
+			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+			Env: []corev1.EnvVar{
+				{
+					Name: "POD_NAME",
+					ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+					},
+				},
+			},
+			Command:      []string{"/bin/prometheus-config-reloader"},
+			Args:         configReloadArgs,
+			VolumeMounts: configReloadVolumeMounts,
+			Resources:    configReloaderResources,
+		},
+		{
+			Name:                     "vmauth",
+			Image:                    fmt.Sprintf("%s:%s", cr.Spec.Image.Repository, cr.Spec.Image.Tag),
+			ImagePullPolicy:          cr.Spec.Image.PullPolicy,
+			Ports:                    ports,
+			Args:                     args,
+			Env:                      envs,
+			VolumeMounts:             vmMounts,
+			LivenessProbe:            livenessProbe,
+			ReadinessProbe:           readinessProbe,
+			Resources:                cr.Spec.Resources,
+			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		},
+	}
+
+	containers, err := MergePatchContainers(operatorContainers, cr.Spec.Containers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      cr.PodLabels(),
+			Annotations: cr.PodAnnotations(),
+		},
+		Spec: corev1.PodSpec{
+			Volumes:            volumes,
+			InitContainers:     cr.Spec.InitContainers,
+			Containers:         containers,
+			ServiceAccountName: cr.Spec.ServiceAccountName,
+			SecurityContext:    cr.Spec.SecurityContext,
+			ImagePullSecrets:   cr.Spec.ImagePullSecrets,
+			Affinity:           cr.Spec.Affinity,
+			Tolerations:        cr.Spec.Tolerations,
+			PriorityClassName:  cr.Spec.PriorityClassName,
+			HostNetwork:        cr.Spec.HostNetwork,
+			DNSPolicy:          cr.Spec.DNSPolicy,
+		},
+	}, nil
+}
+
+// CreateOrUpdateVMAuthConfig generates vmauth config from selected VMUsers
+// and writes it gzipped into the config secret.
+func CreateOrUpdateVMAuthConfig(ctx context.Context, cr *victoriametricsv1beta1.VMAuth, rclient client.Client, c *config.BaseOperatorConf) error {
+	users, err := selectVMUsers(ctx, cr, rclient)
+	if err != nil {
+		return fmt.Errorf("cannot select vmusers: %w", err)
+	}
+	generatedConfig, err := buildVMAuthConfig(ctx, rclient, users, c)
+	if err != nil {
+		return fmt.Errorf("cannot build vmauth config: %w", err)
+	}
+
+	// Compress config to avoid 1mb secret limit for a while
+	var buf bytes.Buffer
+	if err := gzipConfig(&buf, generatedConfig); err != nil {
+		return fmt.Errorf("cannot gzip config for vmauth: %w", err)
+	}
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cr.ConfigSecretName(),
+			Namespace:       cr.Namespace,
+			Labels:          c.Labels.Merge(cr.FinalLabels()),
+			Annotations:     map[string]string{"generated": "true"},
+			OwnerReferences: cr.AsOwner(),
+		},
+		Data: map[string][]byte{
+			vmAuthConfigFilename: buf.Bytes(),
+		},
+	}
+
+	curSecret := &corev1.Secret{}
+	err = rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: s.Name}, curSecret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("creating new configuration secret for vmauth", "vmauth", cr.Name)
+			return rclient.Create(ctx, s)
+		}
+		return fmt.Errorf("cannot get vmauth config secret: %w", err)
+	}
+	if bytes.Equal(curSecret.Data[vmAuthConfigFilename], s.Data[vmAuthConfigFilename]) {
+		log.Info("updating vmauth configuration secret skipped, no configuration change", "vmauth", cr.Name)
+		return nil
+	}
+	for annotation, value := range curSecret.Annotations {
+		if _, ok := s.Annotations[annotation]; !ok {
+			s.Annotations[annotation] = value
+		}
+	}
+	log.Info("updating vmauth configuration secret", "vmauth", cr.Name)
+	return rclient.Update(ctx, s)
+}
+
+func selectVMUsers(ctx context.Context, cr *victoriametricsv1beta1.VMAuth, rclient client.Client) ([]*victoriametricsv1beta1.VMUser, error) {
+	var namespaces []string
+
+	//list namespaces matched by  namespaceselector
+	//for each namespace apply list with  selector
+	//combine result
+	if cr.Spec.UserNamespaceSelector == nil {
+		namespaces = append(namespaces, cr.Namespace)
+	} else if cr.Spec.UserNamespaceSelector.MatchExpressions == nil && cr.Spec.UserNamespaceSelector.MatchLabels == nil {
+		// all namespaces watched by operator
+		var err error
+		namespaces, err = watchedNamespaces(ctx, rclient)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select watched namespaces: %w", err)
+		}
+	} else {
+		nsSelector, err := metav1.LabelSelectorAsSelector(cr.Spec.UserNamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert userNamespaceSelector: %w", err)
+		}
+		namespaces, err = selectNamespaces(ctx, rclient, nsSelector)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select namespaces for vmusers: %w", err)
+		}
+	}
+
+	userSelector := cr.Spec.UserSelector
+	if userSelector == nil {
+		userSelector = &metav1.LabelSelector{}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(userSelector)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert userSelector to labelSelector: %w", err)
+	}
+
+	var usersCombined []victoriametricsv1beta1.VMUser
+	if namespaces == nil {
+		users := &victoriametricsv1beta1.VMUserList{}
+		if err := rclient.List(ctx, users, &client.ListOptions{LabelSelector: selector}); err != nil {
+			return nil, fmt.Errorf("cannot list vmusers from all namespaces: %w", err)
+		}
+		usersCombined = append(usersCombined, users.Items...)
+	} else {
+		for _, ns := range namespaces {
+			users := &victoriametricsv1beta1.VMUserList{}
+			if err := rclient.List(ctx, users, &client.ListOptions{Namespace: ns, LabelSelector: selector}); err != nil {
+				return nil, fmt.Errorf("cannot list vmusers at namespace: %s, err: %w", ns, err)
+			}
+			usersCombined = append(usersCombined, users.Items...)
+		}
+	}
+
+	res := make([]*victoriametricsv1beta1.VMUser, 0, len(usersCombined))
+	selected := make([]string, 0, len(usersCombined))
+	for i := range usersCombined {
+		res = append(res, usersCombined[i].DeepCopy())
+	}
+	// keep generated config stable between reconciles.
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+	for _, user := range res {
+		selected = append(selected, user.Namespace+"/"+user.Name)
+	}
+	log.Info("selected VMUsers", "vmusers", strings.Join(selected, ","), "namespace", cr.Namespace, "vmauth", cr.Name)
+
+	return res, nil
+}
+
+// buildVMAuthConfig generates vmauth config
+// https://victoriametrics.github.io/vmauth.html#auth-config
+// users with broken references are skipped, so single misconfigured
+// VMUser doesn't break access for the others.
+func buildVMAuthConfig(ctx context.Context, rclient client.Client, users []*victoriametricsv1beta1.VMUser, c *config.BaseOperatorConf) ([]byte, error) {
+	secretCache := make(map[string]*corev1.Secret)
+	var usersCfg []yaml.MapSlice
+	for _, user := range users {
+		userCfg, err := generateVMUserConfig(ctx, rclient, user, secretCache, c)
+		if err != nil {
+			log.Error(err, "skipping vmuser", "vmuser", user.Name, "namespace", user.Namespace)
+			continue
+		}
+		usersCfg = append(usersCfg, userCfg)
+	}
+	if usersCfg == nil {
+		usersCfg = []yaml.MapSlice{}
+	}
+	return yaml.Marshal(yaml.MapSlice{{Key: "users", Value: usersCfg}})
+}
+
+func generateVMUserConfig(ctx context.Context, rclient client.Client, user *victoriametricsv1beta1.VMUser, secretCache map[string]*corev1.Secret, c *config.BaseOperatorConf) (yaml.MapSlice, error) {
+	if user.Spec.PasswordRef != nil && user.Spec.BearerTokenRef != nil {
+		return nil, fmt.Errorf("passwordRef and bearerTokenRef cannot be used at the same time")
+	}
+	if len(user.Spec.TargetRefs) == 0 {
+		return nil, fmt.Errorf("at least one targetRef must be defined")
+	}
+	var cfg yaml.MapSlice
+	switch {
+	case user.Spec.BearerTokenRef != nil:
+		token, err := getCredFromSecret(ctx, rclient, user.Namespace, *user.Spec.BearerTokenRef, user.Namespace+"/"+user.Spec.BearerTokenRef.Name, secretCache)
+		if err != nil {
+			return nil, err
+		}
+		cfg = append(cfg, yaml.MapItem{Key: "bearer_token", Value: token})
+	default:
+		cfg = append(cfg, yaml.MapItem{Key: "username", Value: user.UserName()})
+		if user.Spec.PasswordRef != nil {
+			password, err := getCredFromSecret(ctx, rclient, user.Namespace, *user.Spec.PasswordRef, user.Namespace+"/"+user.Spec.PasswordRef.Name, secretCache)
+			if err != nil {
+				return nil, err
+			}
+			cfg = append(cfg, yaml.MapItem{Key: "password", Value: password})
+		}
+	}
+
+	// single target without paths matches any request path
+	if len(user.Spec.TargetRefs) == 1 && len(user.Spec.TargetRefs[0].Paths) == 0 {
+		url, err := getTargetRefURL(ctx, rclient, user.Namespace, user.Spec.TargetRefs[0], c)
+		if err != nil {
+			return nil, err
+		}
+		return append(cfg, yaml.MapItem{Key: "url_prefix", Value: url}), nil
+	}
+
+	var urlMap []yaml.MapSlice
+	for i, ref := range user.Spec.TargetRefs {
+		if len(ref.Paths) == 0 {
+			return nil, fmt.Errorf("paths must be set for targetRef: %d, if more then one targetRef is defined", i)
+		}
+		url, err := getTargetRefURL(ctx, rclient, user.Namespace, ref, c)
+		if err != nil {
+			return nil, err
+		}
+		urlMap = append(urlMap, yaml.MapSlice{
+			{Key: "src_paths", Value: ref.Paths},
+			{Key: "url_prefix", Value: url},
+		})
+	}
+	return append(cfg, yaml.MapItem{Key: "url_map", Value: urlMap}), nil
+}
+
+// getTargetRefURL builds url for the given targetRef,
+// crd refs are resolved into service address of the referenced object.
+func getTargetRefURL(ctx context.Context, rclient client.Client, ns string, ref victoriametricsv1beta1.TargetRef, c *config.BaseOperatorConf) (string, error) {
+	switch {
+	case ref.CRD != nil && ref.Static != nil:
+		return "", fmt.Errorf("crd and static cannot be used at the same targetRef")
+	case ref.Static != nil:
+		if ref.Static.URL == "" {
+			return "", fmt.Errorf("static url cannot be empty")
+		}
+		return strings.TrimSuffix(ref.Static.URL, "/") + ref.TargetPathSuffix, nil
+	case ref.CRD != nil:
+		crdNs := ref.CRD.Namespace
+		if crdNs == "" {
+			crdNs = ns
+		}
+		var svcName, port string
+		switch ref.CRD.Kind {
+		case "VMSingle":
+			vmSingle := &victoriametricsv1beta1.VMSingle{}
+			if err := rclient.Get(ctx, types.NamespacedName{Namespace: crdNs, Name: ref.CRD.Name}, vmSingle); err != nil {
+				return "", fmt.Errorf("cannot get vmsingle: %s/%s, err: %w", crdNs, ref.CRD.Name, err)
+			}
+			svcName, port = vmSingle.PrefixedName(), vmSingle.Spec.Port
+			if port == "" {
+				port = c.VMSingleDefault.Port
+			}
+		case "VMCluster/vmselect", "VMCluster/vminsert":
+			vmCluster := &victoriametricsv1beta1.VMCluster{}
+			if err := rclient.Get(ctx, types.NamespacedName{Namespace: crdNs, Name: ref.CRD.Name}, vmCluster); err != nil {
+				return "", fmt.Errorf("cannot get vmcluster: %s/%s, err: %w", crdNs, ref.CRD.Name, err)
+			}
+			if ref.CRD.Kind == "VMCluster/vmselect" {
+				if vmCluster.Spec.VMSelect == nil {
+					return "", fmt.Errorf("vmselect is not defined at vmcluster: %s/%s", crdNs, ref.CRD.Name)
+				}
+				svcName, port = vmCluster.Spec.VMSelect.GetNameWithPrefix(vmCluster.Name), vmCluster.Spec.VMSelect.Port
+				if port == "" {
+					port = c.VMClusterDefault.VMSelectDefault.Port
+				}
+			} else {
+				if vmCluster.Spec.VMInsert == nil {
+					return "", fmt.Errorf("vminsert is not defined at vmcluster: %s/%s", crdNs, ref.CRD.Name)
+				}
+				// data must be written to zone local vminsert of each group
+				if len(vmCluster.Spec.StorageGroups) > 0 {
+					return "", fmt.Errorf("vmcluster: %s/%s has storage groups, it has vminsert per group only", crdNs, ref.CRD.Name)
+				}
+				svcName, port = vmCluster.Spec.VMInsert.GetNameWithPrefix(vmCluster.Name), vmCluster.Spec.VMInsert.Port
+				if port == "" {
+					port = c.VMClusterDefault.VMInsertDefault.Port
+				}
+			}
+		default:
+			return "", fmt.Errorf("unsupported crd kind: %s", ref.CRD.Kind)
+		}
+		return fmt.Sprintf("http://%s.%s.svc.%s:%s%s", svcName, crdNs, c.ClusterDomainName, port, ref.TargetPathSuffix), nil
+	default:
+		return "", fmt.Errorf("crd or static must be set for targetRef")
+	}
+}
@@ -0,0 +1,219 @@
+package factory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCreateOrUpdateVMAuth(t *testing.T) {
+	type args struct {
+		cr *victoriametricsv1beta1.VMAuth
+		c  *config.BaseOperatorConf
+	}
+	tests := []struct {
+		name              string
+		args              args
+		want              reconcile.Result
+		wantErr           bool
+		predefinedObjects []runtime.Object
+	}{
+		{
+			name: "simple-unmanaged",
+			args: args{
+				c: config.MustGetBaseConfig(),
+				cr: &victoriametricsv1beta1.VMAuth{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "default",
+					},
+				},
+			},
+		},
+		{
+			name: "with users",
+			args: args{
+				c: config.MustGetBaseConfig(),
+				cr: &victoriametricsv1beta1.VMAuth{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "default",
+					},
+					Spec: victoriametricsv1beta1.VMAuthSpec{
+						UserSelector: &metav1.LabelSelector{},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&victoriametricsv1beta1.VMUser{
+					ObjectMeta: metav1.ObjectMeta{Name: "user-1", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMUserSpec{
+						TargetRefs: []victoriametricsv1beta1.TargetRef{
+							{Static: &victoriametricsv1beta1.StaticRef{URL: "http://some-host"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			got, err := CreateOrUpdateVMAuth(context.TODO(), tt.args.cr, fclient, tt.args.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateOrUpdateVMAuth() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateOrUpdateVMAuth() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildVMAuthConfig(t *testing.T) {
+	tests := []struct {
+		name              string
+		users             []*victoriametricsv1beta1.VMUser
+		want              string
+		wantErr           bool
+		predefinedObjects []runtime.Object
+	}{
+		{
+			name: "empty users",
+			want: `users: []
+`,
+		},
+		{
+			name: "user with password and crd refs",
+			users: []*victoriametricsv1beta1.VMUser{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "user-1", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMUserSpec{
+						PasswordRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "user-secret"}, Key: "password"},
+						TargetRefs: []victoriametricsv1beta1.TargetRef{
+							{
+								CRD:   &victoriametricsv1beta1.CRDRef{Kind: "VMSingle", Name: "single"},
+								Paths: []string{"/api/v1/query"},
+							},
+							{
+								CRD:              &victoriametricsv1beta1.CRDRef{Kind: "VMCluster/vmselect", Name: "cluster", Namespace: "monitoring"},
+								Paths:            []string{"/select/.*"},
+								TargetPathSuffix: "/select/0/prometheus",
+							},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "user-2", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMUserSpec{
+						UserName: pointer.StringPtr("static-user"),
+						TargetRefs: []victoriametricsv1beta1.TargetRef{
+							{Static: &victoriametricsv1beta1.StaticRef{URL: "http://some-host/"}},
+						},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "user-secret", Namespace: "default"},
+					Data:       map[string][]byte{"password": []byte("user-password")},
+				},
+				&victoriametricsv1beta1.VMSingle{
+					ObjectMeta: metav1.ObjectMeta{Name: "single", Namespace: "default"},
+				},
+				&victoriametricsv1beta1.VMCluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "monitoring"},
+					Spec: victoriametricsv1beta1.VMClusterSpec{
+						VMSelect: &victoriametricsv1beta1.VMSelect{Port: "8000"},
+					},
+				},
+			},
+			want: `users:
+- username: user-1
+  password: user-password
+  url_map:
+  - src_paths:
+    - /api/v1/query
+    url_prefix: http://vmsingle-single.default.svc.cluster.local:8429
+  - src_paths:
+    - /select/.*
+    url_prefix: http://vmselect-cluster.monitoring.svc.cluster.local:8000/select/0/prometheus
+- username: static-user
+  url_prefix: http://some-host
+`,
+		},
+		{
+			name: "skip broken users",
+			users: []*victoriametricsv1beta1.VMUser{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "missing-secret", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMUserSpec{
+						BearerTokenRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"}, Key: "token"},
+						TargetRefs: []victoriametricsv1beta1.TargetRef{
+							{Static: &victoriametricsv1beta1.StaticRef{URL: "http://some-host"}},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "missing-crd", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMUserSpec{
+						TargetRefs: []victoriametricsv1beta1.TargetRef{
+							{CRD: &victoriametricsv1beta1.CRDRef{Kind: "VMCluster/vminsert", Name: "missing"}},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "without-paths", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMUserSpec{
+						TargetRefs: []victoriametricsv1beta1.TargetRef{
+							{Static: &victoriametricsv1beta1.StaticRef{URL: "http://host-1"}},
+							{Static: &victoriametricsv1beta1.StaticRef{URL: "http://host-2"}},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "token-user", Namespace: "default"},
+					Spec: victoriametricsv1beta1.VMUserSpec{
+						BearerTokenRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "user-token"}, Key: "token"},
+						TargetRefs: []victoriametricsv1beta1.TargetRef{
+							{Static: &victoriametricsv1beta1.StaticRef{URL: "http://some-host"}, TargetPathSuffix: "/api/v1/write"},
+						},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "user-token", Namespace: "default"},
+					Data:       map[string][]byte{"token": []byte("some-token")},
+				},
+			},
+			want: `users:
+- bearer_token: some-token
+  url_prefix: http://some-host/api/v1/write
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			got, err := buildVMAuthConfig(context.TODO(), fclient, tt.users, config.MustGetBaseConfig())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildVMAuthConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("buildVMAuthConfig() got = \n%s, want \n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/coreos/prometheus-operator/pkg/k8sutil"
 	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -19,7 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -36,45 +37,114 @@ const (
 // we manually handle statefulsets rolling updates
 // needed in update checked by revesion status
 // its controlled by k8s controller-manager
-func CreateOrUpdateVMCluster(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf) (string, error) {
-	var expanding, reconciled bool
-	status := v1beta1.ClusterStatusFailed
-	var reason string
+// it doesn't wait for pods readiness, expanding status is returned instead
+// and reconcile must be requeued.
+// if component update fails too many times or its rollout isn't finished within deadline,
+// it's rolled back to the last good revision
+// and updates of its pod template are paused until cluster spec changes,
+// other resources of paused component and other components are reconciled as usual.
+// vmstorage nodes removed by scale down are drained before deletion, see reconcileStorageScaleDown.
+func CreateOrUpdateVMCluster(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf, recorder record.EventRecorder) (clusterStatus string, reconcileErr error) {
+	var expanding bool
+	result := &clusterReconcileResult{
+		status:               v1beta1.ClusterStatusFailed,
+		rolledBack:           map[string]string{},
+		drainingStorageNodes: cr.Status.DrainingStorageNodes,
+	}
+	// component, which is reconciled at the moment
+	var component string
+	// components with rolled back updates, they don't block reconcile of other components
+	var pausedComponents []string
 	defer func() {
-		if cr.Status.ClusterStatus == v1beta1.ClusterStatusOperational {
-			log.Info("no need for resync")
-			return
+		result.err = reconcileErr
+		if reconcileErr != nil {
+			result.failedComponent = component
 		}
-		cr.Status.ClusterStatus = status
-		if reconciled {
-			cr.Status.UpdateFailCount = 0
-		} else {
-			cr.Status.UpdateFailCount += 1
-
-		}
-		cr.Status.Reason = reason
-		cr.Status.LastSync = time.Now().String()
-		err := rclient.Status().Update(ctx, cr)
-		if err != nil {
+		if err := updateVMClusterStatus(ctx, rclient, cr, result); err != nil {
 			log.Error(err, "cannot update cluster status")
 		}
 	}()
-	if cr.Spec.VMStorage != nil {
-		vmStorageSts, err := createOrUpdateVMStorage(ctx, cr, rclient, c)
+	// rollback rolls back component to the last good revision and continues reconcile with it.
+	rollback := func(reason string) (string, error) {
+		revision, err := rollbackComponent(ctx, rclient, cr, component, recorder, reason)
+		if err != nil {
+			return result.status, fmt.Errorf("cannot rollback %s: %s, err: %w", component, reason, err)
+		}
+		result.rolledBack[component] = revision
+		result.reason = fmt.Sprintf("%s was rolled back to revision %s: %s", component, revision, reason)
+		result.status = v1beta1.ClusterStatusExpanding
+		return result.status, nil
+	}
+	// handleUpdateFailure counts failed update attempt of component and rolls it back, if its update failed too many times.
+	handleUpdateFailure := func(currentRevision string, updateErr error) (string, error) {
+		result.failedRevision = currentRevision
+		result.failureTime = metav1.Now()
+		cs := clusterComponentStatus(cr, component)
+		result.newFailedAttempt = isNewFailedAttempt(cs, currentRevision, result.failureTime.Time, c)
+		if !result.newFailedAttempt || !shouldRollback(cs, currentRevision, c) {
+			return result.status, updateErr
+		}
+		return rollback(fmt.Sprintf("%s update to revision %s failed %d times: %s", component, currentRevision, cs.FailCount+1, updateErr))
+	}
+	// rolloutDeadlineExceeded rolls back component, if rollout of its current revision isn't finished within deadline.
+	// Rollout of paused component is the rollback itself, it isn't checked.
+	rolloutDeadlineExceeded := func(currentRevision string) (bool, string, error) {
+		cs := clusterComponentStatus(cr, component)
+		if isComponentPaused(cs, cr.Generation) || !isRolloutDeadlineExceeded(cs, currentRevision, time.Now(), c) {
+			return false, "", nil
+		}
+		status, err := rollback(fmt.Sprintf("%s rollout to revision %s isn't finished within %s", component, currentRevision, c.VMClusterRolloutDeadline))
+		return true, status, err
+	}
+	if cr.Spec.VMStorage != nil && len(cr.Spec.StorageGroups) > 0 {
+		component = "vmstorage"
+		if done, err := reconcileStorageGroups(ctx, cr, rclient, c, result); !done || err != nil {
+			return result.status, err
+		}
+	} else if cr.Spec.VMStorage != nil {
+		component = "vmstorage"
+		drainingNodes, err := reconcileStorageScaleDown(ctx, rclient, cr, "")
+		if err != nil {
+			result.reason = "failed to check vmStorage scale down"
+			return result.status, err
+		}
+		// draining nodes are kept at statefulset and vmselect
+		cr.Status.DrainingStorageNodes = drainingNodes
+		result.drainingStorageNodes = drainingNodes
+		paused := isComponentPaused(cr.Status.VMStorage, cr.Generation)
+		storageSts, err := createOrUpdateVMStorage(ctx, cr, "", rclient, c)
 		if err != nil {
-			reason = v1beta1.StorageCreationFailed
-			return status, err
+			result.reason = v1beta1.StorageCreationFailed
+			return result.status, err
 		}
-		err = performRollingUpdateOnSts(ctx, rclient, vmStorageSts.Name, cr.Namespace, cr.VMStorageSelectorLabels(), c)
+		if err := reconcilePDB(ctx, rclient, cr.Spec.VMStorage.PodDisruptionBudget, cr.VMStorageSelectorLabels(), storageSts.ObjectMeta); err != nil {
+			result.reason = "failed to create vmStorage pdb"
+			return result.status, err
+		}
+		rollingUpdate, err := performRollingUpdateOnSts(ctx, rclient, cr.Spec.VMStorage.GetNameWithPrefix(cr.Name), cr.Namespace, cr.VMStorageSelectorLabels(), c)
 		if err != nil {
-			reason = v1beta1.StorageRollingUpdateFailed
-			return status, err
+			result.reason = v1beta1.StorageRollingUpdateFailed
+			if rollingUpdate == nil || paused {
+				return result.status, err
+			}
+			return handleUpdateFailure(rollingUpdate.revision, err)
+		}
+		if !rollingUpdate.done {
+			if exceeded, status, err := rolloutDeadlineExceeded(rollingUpdate.revision); exceeded {
+				return status, err
+			}
+			result.reason = rollingUpdate.message("vmStorage")
+			result.status = v1beta1.ClusterStatusExpanding
+			return result.status, nil
+		}
+		if paused {
+			pausedComponents = append(pausedComponents, "vmStorage")
 		}
 
-		storageSvc, err := CreateOrUpdateVMStorageService(ctx, cr, rclient, c)
+		storageSvc, err := CreateOrUpdateVMStorageService(ctx, cr, "", rclient, c)
 		if err != nil {
-			reason = "failed to create vmStorage service"
-			return status, err
+			result.reason = "failed to create vmStorage service"
+			return result.status, err
 		}
 		if !c.DisableSelfServiceScrapeCreation {
 			err := CreateVMServiceScrapeFromService(ctx, rclient, storageSvc, cr.MetricPathStorage(), "http")
@@ -83,31 +153,45 @@ func CreateOrUpdateVMCluster(ctx context.Context, cr *v1beta1.VMCluster, rclient
 			}
 		}
 		//wait for expand
-		expanding, err = waitForExpanding(ctx, rclient, cr.Namespace, cr.VMStorageSelectorLabels(), *cr.Spec.VMStorage.ReplicaCount)
+		expanding, err = waitForExpanding(ctx, rclient, cr.Namespace, cr.VMStorageSelectorLabels(), storageNodesCount(cr))
 		if err != nil {
-			reason = "failed to check for vmStorage expanding"
-			return status, err
+			result.reason = "failed to check for vmStorage expanding"
+			return result.status, err
 		}
 		if expanding {
-			reason = "vmStorage is expanding"
-			status = v1beta1.ClusterStatusExpanding
-			return status, err
+			if exceeded, status, err := rolloutDeadlineExceeded(rollingUpdate.revision); exceeded {
+				return status, err
+			}
+			result.reason = "vmStorage is expanding"
+			result.status = v1beta1.ClusterStatusExpanding
+			return result.status, err
 		}
 
 	}
 
 	if cr.Spec.VMSelect != nil {
+		component = "vmselect"
+		paused := isComponentPaused(cr.Status.VMSelect, cr.Generation)
 		//create vmselect
-		vmSelectsts, err := createOrUpdateVMSelect(ctx, cr, rclient, c)
+		selectSts, err := createOrUpdateVMSelect(ctx, cr, "", rclient, c)
 		if err != nil {
-			reason = v1beta1.SelectCreationFailed
-			return status, err
+			result.reason = v1beta1.SelectCreationFailed
+			return result.status, err
+		}
+		selectTarget := v2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: selectSts.Name}
+		if err := reconcileHPA(ctx, rclient, cr.Spec.VMSelect.HPA, selectTarget, selectSts.ObjectMeta); err != nil {
+			result.reason = "failed to create vmSelect hpa"
+			return result.status, err
+		}
+		if err := reconcilePDB(ctx, rclient, cr.Spec.VMSelect.PodDisruptionBudget, cr.VMSelectSelectorLabels(), selectSts.ObjectMeta); err != nil {
+			result.reason = "failed to create vmSelect pdb"
+			return result.status, err
 		}
 		//create vmselect service
-		selectSvc, err := CreateOrUpdateVMSelectService(ctx, cr, rclient, c)
+		selectSvc, err := CreateOrUpdateVMSelectService(ctx, cr, "", rclient, c)
 		if err != nil {
-			reason = "failed to create vmSelect service"
-			return status, err
+			result.reason = "failed to create vmSelect service"
+			return result.status, err
 		}
 		if !c.DisableSelfServiceScrapeCreation {
 			err := CreateVMServiceScrapeFromService(ctx, rclient, selectSvc, cr.MetricPathSelect(), "http")
@@ -116,71 +200,140 @@ func CreateOrUpdateVMCluster(ctx context.Context, cr *v1beta1.VMCluster, rclient
 			}
 		}
 
-		err = performRollingUpdateOnSts(ctx, rclient, vmSelectsts.Name, cr.Namespace, cr.VMSelectSelectorLabels(), c)
+		rollingUpdate, err := performRollingUpdateOnSts(ctx, rclient, cr.Spec.VMSelect.GetNameWithPrefix(cr.Name), cr.Namespace, cr.VMSelectSelectorLabels(), c)
 		if err != nil {
-			reason = v1beta1.SelectRollingUpdateFailed
-			return status, err
+			result.reason = v1beta1.SelectRollingUpdateFailed
+			if rollingUpdate == nil || paused {
+				return result.status, err
+			}
+			return handleUpdateFailure(rollingUpdate.revision, err)
+		}
+		if !rollingUpdate.done {
+			if exceeded, status, err := rolloutDeadlineExceeded(rollingUpdate.revision); exceeded {
+				return status, err
+			}
+			result.reason = rollingUpdate.message("vmSelect")
+			result.status = v1beta1.ClusterStatusExpanding
+			return result.status, nil
+		}
+		if paused {
+			pausedComponents = append(pausedComponents, "vmSelect")
 		}
 
 		//wait for expand
-		expanding, err = waitForExpanding(ctx, rclient, cr.Namespace, cr.VMSelectSelectorLabels(), *cr.Spec.VMSelect.ReplicaCount)
+		selectReplicas, err := componentReplicas(ctx, rclient, cr.Spec.VMSelect.GetNameWithPrefix(cr.Name), cr.Namespace, &appsv1.StatefulSet{}, cr.Spec.VMSelect.ReplicaCount, cr.Spec.VMSelect.HPA)
 		if err != nil {
-			reason = "failed to wait for vmSelect expanding"
-			return status, err
+			result.reason = "failed to check vmSelect replicas"
+			return result.status, err
+		}
+		expanding, err = waitForExpanding(ctx, rclient, cr.Namespace, cr.VMSelectSelectorLabels(), selectReplicas)
+		if err != nil {
+			result.reason = "failed to wait for vmSelect expanding"
+			return result.status, err
 		}
 		if expanding {
-			reason = "expanding vmSelect"
-			status = v1beta1.ClusterStatusExpanding
-			return status, err
+			if exceeded, status, err := rolloutDeadlineExceeded(rollingUpdate.revision); exceeded {
+				return status, err
+			}
+			result.reason = "expanding vmSelect"
+			result.status = v1beta1.ClusterStatusExpanding
+			return result.status, err
+		}
+		if done, err := reconcileGroupVMSelects(ctx, cr, rclient, c, result); !done || err != nil {
+			return result.status, err
 		}
 
 	}
 
-	if cr.Spec.VMInsert != nil {
-		_, err := createOrUpdateVMInsert(ctx, cr, rclient, c)
+	if cr.Spec.VMInsert != nil && len(cr.Spec.StorageGroups) > 0 {
+		component = "vminsert"
+		if done, err := reconcileGroupVMInserts(ctx, cr, rclient, c, result); !done || err != nil {
+			return result.status, err
+		}
+	} else if cr.Spec.VMInsert != nil {
+		component = "vminsert"
+		paused := isComponentPaused(cr.Status.VMInsert, cr.Generation)
+		insertDeploy, err := createOrUpdateVMInsert(ctx, cr, "", rclient, c)
 		if err != nil {
-			reason = v1beta1.InsertCreationFailed
-			return status, err
+			result.reason = v1beta1.InsertCreationFailed
+			return result.status, err
+		}
+		insertTarget := v2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: insertDeploy.Name}
+		if err := reconcileHPA(ctx, rclient, cr.Spec.VMInsert.HPA, insertTarget, insertDeploy.ObjectMeta); err != nil {
+			result.reason = "failed to create vmInsert hpa"
+			return result.status, err
+		}
+		if err := reconcilePDB(ctx, rclient, cr.Spec.VMInsert.PodDisruptionBudget, cr.VMInsertSelectorLabels(), insertDeploy.ObjectMeta); err != nil {
+			result.reason = "failed to create vmInsert pdb"
+			return result.status, err
 		}
-		insertSvc, err := CreateOrUpdateVMInsertService(ctx, cr, rclient, c)
+		insertSvc, err := CreateOrUpdateVMInsertService(ctx, cr, "", rclient, c)
 		if err != nil {
-			reason = "failed to create vmInsert service"
-			return status, err
+			result.reason = "failed to create vmInsert service"
+			return result.status, err
 		}
 		if !c.DisableSelfServiceScrapeCreation {
-			err := CreateVMServiceScrapeFromService(ctx, rclient, insertSvc, cr.MetricPathInsert())
+			err := CreateVMServiceScrapeFromService(ctx, rclient, insertSvc, cr.MetricPathInsert(), "http")
 			if err != nil {
 				log.Error(err, "cannot create VMServiceScrape for vmInsert")
 			}
 		}
-		expanding, err = waitForExpanding(ctx, rclient, cr.Namespace, cr.VMInsertSelectorLabels(), *cr.Spec.VMInsert.ReplicaCount)
+		revision, err := checkDeploymentProgress(ctx, rclient, cr.Spec.VMInsert.GetNameWithPrefix(cr.Name), cr.Namespace)
 		if err != nil {
-			reason = "failed to wait for vmInsert expanding"
-			return status, err
+			result.reason = "vmInsert rollout failed"
+			if paused {
+				return result.status, err
+			}
+			return handleUpdateFailure(revision, err)
+		}
+		insertReplicas, err := componentReplicas(ctx, rclient, cr.Spec.VMInsert.GetNameWithPrefix(cr.Name), cr.Namespace, &appsv1.Deployment{}, cr.Spec.VMInsert.ReplicaCount, cr.Spec.VMInsert.HPA)
+		if err != nil {
+			result.reason = "failed to check vmInsert replicas"
+			return result.status, err
+		}
+		expanding, err = waitForExpanding(ctx, rclient, cr.Namespace, cr.VMInsertSelectorLabels(), insertReplicas)
+		if err != nil {
+			result.reason = "failed to wait for vmInsert expanding"
+			return result.status, err
 		}
 		if expanding {
-			reason = "expanding vmInsert"
-			status = v1beta1.ClusterStatusExpanding
-			return status, err
+			if exceeded, status, err := rolloutDeadlineExceeded(revision); exceeded {
+				return status, err
+			}
+			result.reason = "expanding vmInsert"
+			result.status = v1beta1.ClusterStatusExpanding
+			return result.status, err
+		}
+		if paused {
+			pausedComponents = append(pausedComponents, "vmInsert")
 		}
 
 	}
-	reconciled = true
-	status = v1beta1.ClusterStatusOperational
+	if len(pausedComponents) > 0 {
+		// other components are reconciled, cluster is reconciled again on spec change
+		result.reason = fmt.Sprintf("%s update was rolled back, waiting for cluster spec change", strings.Join(pausedComponents, ", "))
+		return result.status, nil
+	}
+	result.reconciled = true
+	result.status = v1beta1.ClusterStatusOperational
 	log.Info("created or updated vmCluster ")
-	return status, nil
+	return result.status, nil
 
 }
 
-func createOrUpdateVMSelect(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
-	l := log.WithValues("controller", "vmselect", "cluster", cr.Name)
+func createOrUpdateVMSelect(ctx context.Context, cr *v1beta1.VMCluster, group string, rclient client.Client, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
+	l := log.WithValues("controller", "vmselect", "cluster", cr.Name, "group", group)
 	l.Info("create or update vmselect for cluster")
-	newSts, err := genVMSelectSpec(cr, c)
+	newSts, err := genVMSelectSpec(cr, group, c)
 	if err != nil {
 		return nil, err
 	}
 	currentSts := &appsv1.StatefulSet{}
 	err = rclient.Get(ctx, types.NamespacedName{Name: newSts.Name, Namespace: newSts.Namespace}, currentSts)
+	// rolled back pod template is kept until cluster spec changes
+	if err == nil && isComponentPaused(cr.Status.VMSelect, cr.Generation) {
+		newSts.Spec.Template = currentSts.Spec.Template
+	}
 	if err != nil {
 		if errors.IsNotFound(err) {
 			l.Info("vmselect sts not found, creating new one")
@@ -197,6 +350,10 @@ func createOrUpdateVMSelect(ctx context.Context, cr *v1beta1.VMCluster, rclient
 	for annotation, value := range currentSts.Annotations {
 		newSts.Annotations[annotation] = value
 	}
+	// replicas are managed by hpa
+	if cr.Spec.VMSelect.HPA != nil && currentSts.Spec.Replicas != nil {
+		newSts.Spec.Replicas = currentSts.Spec.Replicas
+	}
 
 	for annotation, value := range currentSts.Spec.Template.Annotations {
 		newSts.Spec.Template.Annotations[annotation] = value
@@ -215,49 +372,37 @@ func createOrUpdateVMSelect(ctx context.Context, cr *v1beta1.VMCluster, rclient
 
 }
 
-func CreateOrUpdateVMSelectService(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
-	l := log.WithValues("controller", "vmselect.service.crud")
-	newService := genVMSelectService(cr, c)
-
-	currentService := &corev1.Service{}
-	err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: newService.Name}, currentService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			l.Info("creating new service for vmselect")
-			err := rclient.Create(ctx, newService)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create new service for vmselect")
-			}
-		} else {
-			return nil, fmt.Errorf("cannot get vmselect service: %w", err)
-		}
-	}
-	for annotation, value := range currentService.Annotations {
-		newService.Annotations[annotation] = value
-	}
-	if currentService.Spec.ClusterIP != "" {
-		newService.Spec.ClusterIP = currentService.Spec.ClusterIP
+func CreateOrUpdateVMSelectService(ctx context.Context, cr *v1beta1.VMCluster, group string, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
+	newService := genVMSelectService(cr, group, c)
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.VMSelect.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vmselect: %w", err)
 	}
-	if currentService.ResourceVersion != "" {
-		newService.ResourceVersion = currentService.ResourceVersion
+	applyServiceSpec(newService, cr.Spec.VMSelect.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vmselect: %w", err)
 	}
-	err = rclient.Update(ctx, newService)
-	if err != nil {
-		return nil, fmt.Errorf("cannot update vmselect service: %w", err)
+	// zone local vmselects aren't exposed with ingress
+	if group == "" {
+		if err := reconcileIngress(ctx, rclient, cr.Spec.VMSelect.Ingress, newService, "http"); err != nil {
+			return nil, fmt.Errorf("cannot reconcile ingress for vmselect: %w", err)
+		}
 	}
-	l.Info("vmselect svc reconciled")
 	return newService, nil
 }
 
-func createOrUpdateVMInsert(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf) (*appsv1.Deployment, error) {
-	l := log.WithValues("controller", "vminsert", "cluster", cr.Name)
+func createOrUpdateVMInsert(ctx context.Context, cr *v1beta1.VMCluster, group string, rclient client.Client, c *config.BaseOperatorConf) (*appsv1.Deployment, error) {
+	l := log.WithValues("controller", "vminsert", "cluster", cr.Name, "group", group)
 	l.Info("create or update vminsert for cluster")
-	newDeployment, err := genVMInsertSpec(cr, c)
+	newDeployment, err := genVMInsertSpec(cr, group, c)
 	if err != nil {
 		return nil, err
 	}
 	currentDeployment := &appsv1.Deployment{}
 	err = rclient.Get(ctx, types.NamespacedName{Name: newDeployment.Name, Namespace: newDeployment.Namespace}, currentDeployment)
+	// rolled back pod template is kept until cluster spec changes, zone local vminserts aren't rolled back
+	if err == nil && group == "" && isComponentPaused(cr.Status.VMInsert, cr.Generation) {
+		newDeployment.Spec.Template = currentDeployment.Spec.Template
+	}
 	if err != nil {
 		if errors.IsNotFound(err) {
 			//create new
@@ -275,6 +420,10 @@ func createOrUpdateVMInsert(ctx context.Context, cr *v1beta1.VMCluster, rclient
 	for annotation, value := range currentDeployment.Annotations {
 		newDeployment.Annotations[annotation] = value
 	}
+	// replicas are managed by hpa
+	if cr.Spec.VMInsert.HPA != nil && currentDeployment.Spec.Replicas != nil {
+		newDeployment.Spec.Replicas = currentDeployment.Spec.Replicas
+	}
 
 	for annotation, value := range currentDeployment.Spec.Template.Annotations {
 		newDeployment.Spec.Template.Annotations[annotation] = value
@@ -289,50 +438,31 @@ func createOrUpdateVMInsert(ctx context.Context, cr *v1beta1.VMCluster, rclient
 	return newDeployment, nil
 }
 
-func CreateOrUpdateVMInsertService(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
-	l := log.WithValues("controller", "vminsert.service.crud")
-	newService := genVMInsertService(cr, c)
-
-	currentService := &corev1.Service{}
-	err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: newService.Name}, currentService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			l.Info("creating new service for vminsert")
-			err := rclient.Create(ctx, newService)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create new service for vminsert: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("cannot get vminsert service: %w", err)
-		}
+func CreateOrUpdateVMInsertService(ctx context.Context, cr *v1beta1.VMCluster, group string, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
+	newService := genVMInsertService(cr, group, c)
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.VMInsert.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vminsert: %w", err)
 	}
-	for annotation, value := range currentService.Annotations {
-		newService.Annotations[annotation] = value
+	applyServiceSpec(newService, cr.Spec.VMInsert.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vminsert: %w", err)
 	}
-	if currentService.Spec.ClusterIP != "" {
-		newService.Spec.ClusterIP = currentService.Spec.ClusterIP
-	}
-	if currentService.ResourceVersion != "" {
-		newService.ResourceVersion = currentService.ResourceVersion
-	}
-	err = rclient.Update(ctx, newService)
-	if err != nil {
-		return nil, fmt.Errorf("cannot update vminsert service: %w", err)
-	}
-	l.Info("vminsert svc reconciled")
 	return newService, nil
-
 }
 
-func createOrUpdateVMStorage(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
-	l := log.WithValues("controller", "vmstorage", "cluster", cr.Name)
+func createOrUpdateVMStorage(ctx context.Context, cr *v1beta1.VMCluster, group string, rclient client.Client, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
+	l := log.WithValues("controller", "vmstorage", "cluster", cr.Name, "group", group)
 	l.Info("create or update vmstorage for cluster")
-	newSts, err := GenVMStorageSpec(cr, c)
+	newSts, err := GenVMStorageSpec(cr, group, c)
 	if err != nil {
 		return nil, err
 	}
 	currentSts := &appsv1.StatefulSet{}
 	err = rclient.Get(ctx, types.NamespacedName{Name: newSts.Name, Namespace: newSts.Namespace}, currentSts)
+	// rolled back pod template is kept until cluster spec changes, storage groups aren't rolled back
+	if err == nil && group == "" && isComponentPaused(cr.Status.VMStorage, cr.Generation) {
+		newSts.Spec.Template = currentSts.Spec.Template
+	}
 	if err != nil {
 		if errors.IsNotFound(err) {
 			//create new
@@ -364,42 +494,20 @@ func createOrUpdateVMStorage(ctx context.Context, cr *v1beta1.VMCluster, rclient
 	return newSts, nil
 }
 
-func CreateOrUpdateVMStorageService(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
-	l := log.WithValues("controller", "vmstorage.service.crud")
-	newService := genVMStorageService(cr, c)
-
-	currentService := &corev1.Service{}
-	err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: newService.Name}, currentService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			l.Info("creating new service for vm vmstorage")
-			err := rclient.Create(ctx, newService)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create new service for vmstorage")
-			}
-		} else {
-			return nil, fmt.Errorf("cannot get vmstorage service: %w", err)
-		}
-	}
-	for annotation, value := range currentService.Annotations {
-		newService.Annotations[annotation] = value
-	}
-	if currentService.Spec.ClusterIP != "" {
-		newService.Spec.ClusterIP = currentService.Spec.ClusterIP
+func CreateOrUpdateVMStorageService(ctx context.Context, cr *v1beta1.VMCluster, group string, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
+	newService := genVMStorageService(cr, group, c)
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.VMStorage.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vmstorage: %w", err)
 	}
-	if currentService.ResourceVersion != "" {
-		newService.ResourceVersion = currentService.ResourceVersion
+	applyServiceSpec(newService, cr.Spec.VMStorage.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vmstorage: %w", err)
 	}
-	err = rclient.Update(ctx, newService)
-	if err != nil {
-		return nil, fmt.Errorf("cannot update vmstorage service: %w", err)
-	}
-	l.Info("vmstorage svc was reconciled")
 	return newService, nil
-
 }
 
-func genVMSelectSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
+// genVMSelectSpec builds vmselect statefulset, non empty group builds zone local vmselect for storage group.
+func genVMSelectSpec(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
 	cr = cr.DeepCopy()
 	if cr.Spec.VMSelect.Image.Repository == "" {
 		cr.Spec.VMSelect.Image.Repository = c.VMClusterDefault.VMSelectDefault.Image
@@ -453,7 +561,7 @@ func genVMSelectSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1
 	if cr.Spec.VMSelect.SecurityContext == nil {
 		cr.Spec.VMSelect.SecurityContext = &corev1.PodSecurityContext{}
 	}
-	podSpec, err := makePodSpecForVMSelect(cr, c)
+	podSpec, err := makePodSpecForVMSelect(cr, group, c)
 	if err != nil {
 		return nil, err
 	}
@@ -462,14 +570,14 @@ func genVMSelectSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            cr.Spec.VMSelect.GetNameWithPrefix(cr.Name),
 			Namespace:       cr.Namespace,
-			Labels:          cr.FinalLabels(cr.VMSelectSelectorLabels()),
+			Labels:          cr.FinalLabels(cr.VMSelectGroupSelectorLabels(group)),
 			Annotations:     cr.Annotations(),
 			OwnerReferences: cr.AsOwner(),
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Replicas: cr.Spec.VMSelect.ReplicaCount,
 			Selector: &metav1.LabelSelector{
-				MatchLabels: cr.VMSelectSelectorLabels(),
+				MatchLabels: cr.VMSelectGroupSelectorLabels(group),
 			},
 			PodManagementPolicy: appsv1.ParallelPodManagement,
 			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
@@ -516,7 +624,7 @@ func genVMSelectSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1
 	return stsSpec, nil
 }
 
-func makePodSpecForVMSelect(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*corev1.PodTemplateSpec, error) {
+func makePodSpecForVMSelect(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) (*corev1.PodTemplateSpec, error) {
 	args := []string{
 		fmt.Sprintf("-httpListenAddr=:%s", cr.Spec.VMSelect.Port),
 	}
@@ -526,7 +634,7 @@ func makePodSpecForVMSelect(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 	if cr.Spec.VMSelect.LogFormat != "" {
 		args = append(args, fmt.Sprintf("-loggerFormat=%s", cr.Spec.VMSelect.LogFormat))
 	}
-	if cr.Spec.ReplicationFactor != nil {
+	if isDedupNeeded(cr) {
 		var dedupIsSet bool
 		for arg := range cr.Spec.VMSelect.ExtraArgs {
 			if strings.Contains(arg, "dedup.minScrapeInterval") {
@@ -538,7 +646,7 @@ func makePodSpecForVMSelect(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 		}
 	}
 
-	for arg, value := range cr.Spec.VMSelect.ExtraArgs {
+	for arg, value := range v1beta1.ExtraArgsWithIngressPrefix(cr.Spec.VMSelect.ExtraArgs, cr.Spec.VMSelect.Ingress) {
 		args = append(args, fmt.Sprintf("-%s=%s", arg, value))
 	}
 
@@ -546,26 +654,26 @@ func makePodSpecForVMSelect(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 		if cr.Spec.VMStorage.VMSelectPort == "" {
 			cr.Spec.VMStorage.VMSelectPort = c.VMClusterDefault.VMStorageDefault.VMSelectPort
 		}
-		storageArg := "-storageNode="
-		vmstorageCount := *cr.Spec.VMStorage.ReplicaCount
-		for i := int32(0); i < vmstorageCount; i++ {
-			storageArg += cr.Spec.VMStorage.BuildPodFQDNName(cr.Spec.VMStorage.GetNameWithPrefix(cr.Name), i, cr.Namespace, cr.Spec.VMStorage.VMSelectPort, c.ClusterDomainName)
-		}
-		storageArg = strings.TrimSuffix(storageArg, ",")
+		// vmselect keeps draining nodes for querying their data
+		storageArg := buildStorageNodeArg(cr, cr.Spec.VMStorage.VMSelectPort, true, c)
 
 		log.Info("built args with vmstorage nodes for vmselect", "vmstorage args", storageArg)
 		args = append(args, storageArg)
 
 	}
-	selectArg := "-selectNode="
-	vmselectCount := *cr.Spec.VMSelect.ReplicaCount
-	for i := int32(0); i < vmselectCount; i++ {
-		selectArg += cr.Spec.VMSelect.BuildPodFQDNName(cr.Spec.VMSelect.GetNameWithPrefix(cr.Name), i, cr.Namespace, cr.Spec.VMSelect.Port, c.ClusterDomainName)
-	}
-	selectArg = strings.TrimSuffix(selectArg, ",")
+	// vmselect nodes list changes with each hpa scaling and restarts all pods,
+	// so it isn't set for vmselect with hpa.
+	if cr.Spec.VMSelect.HPA == nil {
+		selectArg := "-selectNode="
+		vmselectCount := *cr.Spec.VMSelect.ReplicaCount
+		for i := int32(0); i < vmselectCount; i++ {
+			selectArg += cr.Spec.VMSelect.BuildPodFQDNName(cr.Spec.VMSelect.GetNameWithPrefix(cr.Name), i, cr.Namespace, cr.Spec.VMSelect.Port, c.ClusterDomainName)
+		}
+		selectArg = strings.TrimSuffix(selectArg, ",")
 
-	log.Info("args for vmselect ", "args", selectArg)
-	args = append(args, selectArg)
+		log.Info("args for vmselect ", "args", selectArg)
+		args = append(args, selectArg)
+	}
 
 	if len(cr.Spec.VMSelect.ExtraEnvs) > 0 {
 		args = append(args, "-envflag.enable=true")
@@ -682,7 +790,7 @@ func makePodSpecForVMSelect(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 
 	vmSelectPodSpec := &corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      cr.VMSelectPodLabels(),
+			Labels:      cr.VMSelectGroupPodLabels(group),
 			Annotations: cr.VMSelectPodAnnotations(),
 		},
 		Spec: corev1.PodSpec{
@@ -706,7 +814,7 @@ func makePodSpecForVMSelect(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 	return vmSelectPodSpec, nil
 }
 
-func genVMSelectService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *corev1.Service {
+func genVMSelectService(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) *corev1.Service {
 	cr = cr.DeepCopy()
 	if cr.Spec.VMSelect.Port == "" {
 		cr.Spec.VMSelect.Port = c.VMClusterDefault.VMSelectDefault.Port
@@ -715,13 +823,13 @@ func genVMSelectService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *core
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            cr.Spec.VMSelect.GetNameWithPrefix(cr.Name),
 			Namespace:       cr.Namespace,
-			Labels:          cr.FinalLabels(cr.VMSelectSelectorLabels()),
+			Labels:          cr.FinalLabels(cr.VMSelectGroupSelectorLabels(group)),
 			Annotations:     cr.Annotations(),
 			OwnerReferences: cr.AsOwner(),
 		},
 		Spec: corev1.ServiceSpec{
 			Type:      corev1.ServiceTypeClusterIP,
-			Selector:  cr.VMSelectSelectorLabels(),
+			Selector:  cr.VMSelectGroupSelectorLabels(group),
 			ClusterIP: "None",
 			Ports: []corev1.ServicePort{
 				{
@@ -735,7 +843,8 @@ func genVMSelectService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *core
 	}
 }
 
-func genVMInsertSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1.Deployment, error) {
+// genVMInsertSpec builds vminsert deployment, non empty group builds zone local vminsert for storage group.
+func genVMInsertSpec(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) (*appsv1.Deployment, error) {
 	cr = cr.DeepCopy()
 
 	if cr.Spec.VMInsert.Image.Repository == "" {
@@ -779,7 +888,7 @@ func genVMInsertSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1
 		cr.Spec.VMInsert.Resources.Requests[corev1.ResourceMemory] = resource.MustParse(c.VMClusterDefault.VMInsertDefault.Resource.Request.Mem)
 		cr.Spec.VMInsert.Resources.Limits[corev1.ResourceMemory] = resource.MustParse(c.VMClusterDefault.VMInsertDefault.Resource.Limit.Mem)
 	}
-	podSpec, err := makePodSpecForVMInsert(cr, c)
+	podSpec, err := makePodSpecForVMInsert(cr, group, c)
 	if err != nil {
 		return nil, err
 	}
@@ -788,14 +897,14 @@ func genVMInsertSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            cr.Spec.VMInsert.GetNameWithPrefix(cr.Name),
 			Namespace:       cr.Namespace,
-			Labels:          cr.FinalLabels(cr.VMInsertSelectorLabels()),
+			Labels:          cr.FinalLabels(cr.VMInsertGroupSelectorLabels(group)),
 			Annotations:     cr.Annotations(),
 			OwnerReferences: cr.AsOwner(),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: cr.Spec.VMInsert.ReplicaCount,
 			Selector: &metav1.LabelSelector{
-				MatchLabels: cr.VMInsertSelectorLabels(),
+				MatchLabels: cr.VMInsertGroupSelectorLabels(group),
 			},
 			Template: *podSpec,
 		},
@@ -803,7 +912,7 @@ func genVMInsertSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1
 	return stsSpec, nil
 }
 
-func makePodSpecForVMInsert(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*corev1.PodTemplateSpec, error) {
+func makePodSpecForVMInsert(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) (*corev1.PodTemplateSpec, error) {
 	args := []string{
 		fmt.Sprintf("-httpListenAddr=:%s", cr.Spec.VMInsert.Port),
 	}
@@ -822,20 +931,15 @@ func makePodSpecForVMInsert(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 		if cr.Spec.VMStorage.VMInsertPort == "" {
 			cr.Spec.VMStorage.VMInsertPort = c.VMClusterDefault.VMStorageDefault.VMInsertPort
 		}
-		storageArg := "-storageNode="
-		storageCount := *cr.Spec.VMStorage.ReplicaCount
-		for i := int32(0); i < storageCount; i++ {
-			storageArg += cr.Spec.VMStorage.BuildPodFQDNName(cr.Spec.VMStorage.GetNameWithPrefix(cr.Name), i, cr.Namespace, cr.Spec.VMStorage.VMInsertPort, c.ClusterDomainName)
-		}
-		storageArg = strings.TrimSuffix(storageArg, ",")
+		storageArg := buildStorageNodeArg(cr, cr.Spec.VMStorage.VMInsertPort, false, c)
 		log.Info("args for vminsert ", "storage arg", storageArg)
 
 		args = append(args, storageArg)
 
 	}
-	if cr.Spec.ReplicationFactor != nil {
-		log.Info("replication enabled for vminsert, with factor", "replicationFactor", *cr.Spec.ReplicationFactor)
-		args = append(args, fmt.Sprintf("-replicationFactor=%d", *cr.Spec.ReplicationFactor))
+	if replicationFactor := cr.Spec.ReplicationFactor; replicationFactor != nil {
+		log.Info("replication enabled for vminsert, with factor", "replicationFactor", *replicationFactor)
+		args = append(args, fmt.Sprintf("-replicationFactor=%d", *replicationFactor))
 	}
 	if len(cr.Spec.VMInsert.ExtraEnvs) > 0 {
 		args = append(args, "-envflag.enable=true")
@@ -948,7 +1052,7 @@ func makePodSpecForVMInsert(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 
 	vmInsertPodSpec := &corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      cr.VMInsertPodLabels(),
+			Labels:      cr.VMInsertGroupPodLabels(group),
 			Annotations: cr.VMInsertPodAnnotations(),
 		},
 		Spec: corev1.PodSpec{
@@ -970,7 +1074,9 @@ func makePodSpecForVMInsert(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (
 	return vmInsertPodSpec, nil
 
 }
-func genVMInsertService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *corev1.Service {
+
+// genVMInsertService builds vminsert service, non empty group builds service for zone local vminsert.
+func genVMInsertService(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) *corev1.Service {
 	cr = cr.DeepCopy()
 	if cr.Spec.VMInsert.Port == "" {
 		cr.Spec.VMInsert.Port = c.VMClusterDefault.VMInsertDefault.Port
@@ -979,13 +1085,13 @@ func genVMInsertService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *core
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            cr.Spec.VMInsert.GetNameWithPrefix(cr.Name),
 			Namespace:       cr.Namespace,
-			Labels:          cr.FinalLabels(cr.VMInsertSelectorLabels()),
+			Labels:          cr.FinalLabels(cr.VMInsertGroupSelectorLabels(group)),
 			Annotations:     cr.Annotations(),
 			OwnerReferences: cr.AsOwner(),
 		},
 		Spec: corev1.ServiceSpec{
 			Type:     corev1.ServiceTypeClusterIP,
-			Selector: cr.VMInsertSelectorLabels(),
+			Selector: cr.VMInsertGroupSelectorLabels(group),
 			Ports: []corev1.ServicePort{
 				{
 					Name:       "http",
@@ -998,7 +1104,8 @@ func genVMInsertService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *core
 	}
 }
 
-func GenVMStorageSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
+// GenVMStorageSpec builds vmstorage statefulset, non empty group builds statefulset for storage group.
+func GenVMStorageSpec(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) (*appsv1.StatefulSet, error) {
 	cr = cr.DeepCopy()
 	if cr.Spec.VMStorage.Image.Repository == "" {
 		cr.Spec.VMStorage.Image.Repository = c.VMClusterDefault.VMStorageDefault.Image
@@ -1064,7 +1171,7 @@ func GenVMStorageSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv
 		cr.Spec.VMStorage.StorageDataPath = vmStorageDefaultDBPath
 	}
 
-	podSpec, err := makePodSpecForVMStorage(cr, c)
+	podSpec, err := makePodSpecForVMStorage(cr, group, c)
 	if err != nil {
 		return nil, err
 	}
@@ -1073,14 +1180,14 @@ func GenVMStorageSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            cr.Spec.VMStorage.GetNameWithPrefix(cr.Name),
 			Namespace:       cr.Namespace,
-			Labels:          cr.FinalLabels(cr.VMStorageSelectorLabels()),
+			Labels:          cr.FinalLabels(cr.VMStorageGroupSelectorLabels(group)),
 			Annotations:     cr.Annotations(),
 			OwnerReferences: cr.AsOwner(),
 		},
 		Spec: appsv1.StatefulSetSpec{
-			Replicas: cr.Spec.VMStorage.ReplicaCount,
+			Replicas: pointer.Int32Ptr(storageNodesCount(cr)),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: cr.VMStorageSelectorLabels(),
+				MatchLabels: cr.VMStorageGroupSelectorLabels(group),
 			},
 			PodManagementPolicy: appsv1.ParallelPodManagement,
 			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
@@ -1125,7 +1232,7 @@ func GenVMStorageSpec(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*appsv
 	return stsSpec, nil
 }
 
-func makePodSpecForVMStorage(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) (*corev1.PodTemplateSpec, error) {
+func makePodSpecForVMStorage(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) (*corev1.PodTemplateSpec, error) {
 	args := []string{
 		fmt.Sprintf("-vminsertAddr=:%s", cr.Spec.VMStorage.VMInsertPort),
 		fmt.Sprintf("-vmselectAddr=:%s", cr.Spec.VMStorage.VMSelectPort),
@@ -1290,7 +1397,7 @@ func makePodSpecForVMStorage(cr *v1beta1.VMCluster, c *config.BaseOperatorConf)
 
 	vmStoragePodSpec := &corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      cr.VMStoragePodLabels(),
+			Labels:      cr.VMStorageGroupPodLabels(group),
 			Annotations: cr.VMStoragePodAnnotations(),
 		},
 		Spec: corev1.PodSpec{
@@ -1314,7 +1421,7 @@ func makePodSpecForVMStorage(cr *v1beta1.VMCluster, c *config.BaseOperatorConf)
 	return vmStoragePodSpec, nil
 }
 
-func genVMStorageService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *corev1.Service {
+func genVMStorageService(cr *v1beta1.VMCluster, group string, c *config.BaseOperatorConf) *corev1.Service {
 	cr = cr.DeepCopy()
 	if cr.Spec.VMStorage.Port == "" {
 		cr.Spec.VMStorage.Port = c.VMClusterDefault.VMStorageDefault.Port
@@ -1330,14 +1437,14 @@ func genVMStorageService(cr *v1beta1.VMCluster, c *config.BaseOperatorConf) *cor
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            cr.Spec.VMStorage.GetNameWithPrefix(cr.Name),
 			Namespace:       cr.Namespace,
-			Labels:          cr.FinalLabels(cr.VMStorageSelectorLabels()),
+			Labels:          cr.FinalLabels(cr.VMStorageGroupSelectorLabels(group)),
 			Annotations:     cr.Annotations(),
 			OwnerReferences: cr.AsOwner(),
 		},
 		Spec: corev1.ServiceSpec{
 			Type:      corev1.ServiceTypeClusterIP,
 			ClusterIP: "None",
-			Selector:  cr.VMStorageSelectorLabels(),
+			Selector:  cr.VMStorageGroupSelectorLabels(group),
 			Ports: []corev1.ServicePort{
 				{
 					Name:       "http",
@@ -1380,54 +1487,74 @@ func waitForExpanding(ctx context.Context, kclient client.Client, namespace stri
 	return readyCount != desiredCount, nil
 }
 
+// stsRollingUpdate describes progress of statefulset rolling update.
+type stsRollingUpdate struct {
+	// revision desired revision of statefulset pods
+	revision string
+	// updated number of pods with desired revision
+	updated int
+	// total number of statefulset pods
+	total int
+	// done is true, if all pods have desired revision
+	done bool
+}
+
+// message returns human-readable progress of rolling update.
+func (ru *stsRollingUpdate) message(component string) string {
+	return fmt.Sprintf("%s rolling update: %d of %d pods updated to revision %s", component, ru.updated, ru.total, ru.revision)
+}
+
 // we perform rolling update on sts by manually deleting pods one by one
 // we check sts revision (kubernetes controller-manager is responsible for that)
 // and compare pods revision label with sts revision
 // if it doesnt match - updated is needed
-func performRollingUpdateOnSts(ctx context.Context, rclient client.Client, stsName string, ns string, podLabels map[string]string, c *config.BaseOperatorConf) error {
-	time.Sleep(time.Second * 2)
+// rolling update doesn't block reconcile, at most one pod is deleted per call.
+// Caller must requeue reconcile, until rolling update is done.
+// Progress is restored from pods revision labels on the next call.
+func performRollingUpdateOnSts(ctx context.Context, rclient client.Client, stsName string, ns string, podLabels map[string]string, c *config.BaseOperatorConf) (*stsRollingUpdate, error) {
 	sts := &appsv1.StatefulSet{}
 	err := rclient.Get(ctx, types.NamespacedName{Name: stsName, Namespace: ns}, sts)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var stsVersion string
+	progress := &stsRollingUpdate{revision: sts.Status.CurrentRevision}
 	if sts.Status.UpdateRevision != sts.Status.CurrentRevision {
 		log.Info("sts update is needed", "sts", sts.Name, "currentVersion", sts.Status.CurrentRevision, "desiredVersion", sts.Status.UpdateRevision)
-		stsVersion = sts.Status.UpdateRevision
-	} else {
-		stsVersion = sts.Status.CurrentRevision
+		progress.revision = sts.Status.UpdateRevision
+	}
+	l := log.WithValues("controller", "sts.rollingupdate", "desiredVersion", progress.revision)
+	if sts.Status.ObservedGeneration < sts.Generation {
+		// statefulset controller must calculate new revision first
+		l.Info("statefulset spec isn't observed by controller-manager yet", "sts", sts.Name)
+		return progress, nil
 	}
-	l := log.WithValues("controller", "sts.rollingupdate", "desiredVersion", stsVersion)
 	l.Info("checking if update needed")
 	podList := &corev1.PodList{}
 	labelSelector := labels.SelectorFromSet(podLabels)
 	listOps := &client.ListOptions{Namespace: ns, LabelSelector: labelSelector}
 	if err := rclient.List(ctx, podList, listOps); err != nil {
-		return err
-	}
-	var updatedNeeded bool
-	for _, pod := range podList.Items {
-		if pod.Labels[podRevisionLabel] != stsVersion {
-			l.Info("pod version doesnt match", "pod", pod.Name, "podVersion", pod.Labels[podRevisionLabel])
-			updatedNeeded = true
-		}
-	}
-	if !updatedNeeded {
-		l.Info("update isn't needed")
-		return nil
+		return nil, err
 	}
-	l.Info("update is needed, start building proper order for update")
-	// first we must ensure, that already updated pods in ready status
-	// then we can update other pods
+	progress.total = len(podList.Items)
+
 	// if pod is not ready
 	// it must be at first place for update
 	podsForUpdate := make([]corev1.Pod, 0, len(podList.Items))
-	// if pods were already updated to some version, we have to wait its readiness
-	updatedPods := make([]corev1.Pod, 0, len(podList.Items))
+	// pods must be ready and not terminating before next pod deletion
+	var waitReason string
 	for _, pod := range podList.Items {
-		if pod.Labels[podRevisionLabel] == stsVersion {
-			updatedPods = append(updatedPods, pod)
+		if pod.DeletionTimestamp != nil {
+			waitReason = fmt.Sprintf("pod %s is terminating", pod.Name)
+		}
+		if pod.Labels[podRevisionLabel] == progress.revision {
+			progress.updated++
+			if !PodIsReady(pod) && pod.DeletionTimestamp == nil {
+				// pod may not start with new version
+				if time.Since(pod.CreationTimestamp.Time) > c.PodWaitReadyInitDelay+c.PodWaitReadyTimeout {
+					return progress, fmt.Errorf("pod %s with revision %s isn't ready after %s", pod.Name, progress.revision, c.PodWaitReadyInitDelay+c.PodWaitReadyTimeout)
+				}
+				waitReason = fmt.Sprintf("pod %s isn't ready yet", pod.Name)
+			}
 			continue
 		}
 		if !PodIsReady(pod) {
@@ -1436,35 +1563,25 @@ func performRollingUpdateOnSts(ctx context.Context, rclient client.Client, stsNa
 		}
 		podsForUpdate = append(podsForUpdate, pod)
 	}
-
-	l.Info("updated pods with desired version:", "count", len(updatedPods))
-
-	for _, pod := range updatedPods {
-		l.Info("checking ready status for already updated pods to desired version", "pod", pod.Name)
-		err := waitForPodReady(ctx, rclient, ns, pod.Name, c)
-		if err != nil {
-			l.Error(err, "cannot get ready status for already updated pod", "pod", pod.Name)
-			return err
-		}
+	if len(podsForUpdate) == 0 {
+		l.Info("update isn't needed")
+		progress.done = true
+		return progress, nil
 	}
-
-	for _, pod := range podsForUpdate {
-		l.Info("updating pod", "pod", pod.Name)
-		//we have to delete pod and wait for it readiness
-		err := rclient.Delete(ctx, &pod, &client.DeleteOptions{GracePeriodSeconds: pointer.Int64Ptr(30)})
-		if err != nil {
-			return err
-		}
-		err = waitForPodReady(ctx, rclient, ns, pod.Name, c)
-		if err != nil {
-			return err
-		}
-		l.Info("pod was updated", "pod", pod.Name)
-		time.Sleep(time.Second * 3)
+	if sts.Spec.Replicas != nil && int32(progress.total) < *sts.Spec.Replicas {
+		waitReason = "deleted pod isn't recreated yet"
+	}
+	if waitReason != "" {
+		l.Info("waiting for pods before the next update", "reason", waitReason, "updated", progress.updated, "total", progress.total)
+		return progress, nil
 	}
 
-	return nil
-
+	pod := podsForUpdate[0]
+	l.Info("updating pod", "pod", pod.Name, "updated", progress.updated, "total", progress.total)
+	if err := rclient.Delete(ctx, &pod, &client.DeleteOptions{GracePeriodSeconds: pointer.Int64Ptr(30)}); err != nil {
+		return progress, fmt.Errorf("cannot delete pod %s for update: %w", pod.Name, err)
+	}
+	return progress, nil
 }
 
 func PodIsReady(pod corev1.Pod) bool {
@@ -1482,24 +1599,3 @@ func PodIsReady(pod corev1.Pod) bool {
 	}
 	return false
 }
-
-func waitForPodReady(ctx context.Context, rclient client.Client, ns, podName string, c *config.BaseOperatorConf) error {
-	// we need some delay
-	time.Sleep(c.PodWaitReadyInitDelay)
-	return wait.Poll(c.PodWaitReadyIntervalCheck, c.PodWaitReadyTimeout, func() (done bool, err error) {
-		pod := &corev1.Pod{}
-		err = rclient.Get(ctx, types.NamespacedName{Namespace: ns, Name: podName}, pod)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				return false, nil
-			}
-			log.Error(err, "cannot get pod", "pod", podName)
-			return false, err
-		}
-		if PodIsReady(*pod) {
-			log.Info("pod update finished with revision", "pod", pod.Name, "revision", pod.Labels[podRevisionLabel])
-			return true, nil
-		}
-		return false, nil
-	})
-}
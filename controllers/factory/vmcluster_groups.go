@@ -0,0 +1,291 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// groupComponentName returns name of component for storage group.
+func groupComponentName(componentName, clusterName, group string) string {
+	if componentName == "" {
+		componentName = clusterName
+	}
+	return componentName + "-" + group
+}
+
+// isDedupNeeded checks if global vmselect must deduplicate samples.
+// Samples are duplicated by replication and by writing the same data to zone local vminsert of each storage group.
+func isDedupNeeded(cr *v1beta1.VMCluster) bool {
+	return cr.Spec.ReplicationFactor != nil || len(cr.Spec.StorageGroups) > 1
+}
+
+// storageGroupCluster returns copy of cluster with vmstorage, vminsert and vmselect specs of storage group.
+// Cluster VMStorage, VMInsert and VMSelect are used as templates, group settings override them.
+// Group cluster has no storage groups, so its components use only vmstorage nodes of group.
+func storageGroupCluster(cr *v1beta1.VMCluster, group *v1beta1.VMStorageGroup) *v1beta1.VMCluster {
+	gcr := cr.DeepCopy()
+	group = group.DeepCopy()
+	gcr.Spec.StorageGroups = nil
+
+	var nodes []v1beta1.VMStorageDrainingNode
+	for _, node := range cr.Status.DrainingStorageNodes {
+		if node.Group == group.Name {
+			nodes = append(nodes, node)
+		}
+	}
+	gcr.Status.DrainingStorageNodes = nodes
+
+	storage := gcr.Spec.VMStorage
+	storage.Name = groupComponentName(storage.Name, cr.Name, group.Name)
+	if group.ReplicaCount != nil {
+		storage.ReplicaCount = group.ReplicaCount
+	}
+	if group.Affinity != nil {
+		storage.Affinity = group.Affinity
+	}
+	if group.Tolerations != nil {
+		storage.Tolerations = group.Tolerations
+	}
+	if group.Storage != nil {
+		storage.Storage = group.Storage
+	}
+	groupExtraServices(storage.ExtraServices, group.Name)
+	if vminsert := gcr.Spec.VMInsert; vminsert != nil {
+		vminsert.Name = groupComponentName(vminsert.Name, cr.Name, group.Name)
+		if group.VMInsertReplicaCount != nil {
+			vminsert.ReplicaCount = group.VMInsertReplicaCount
+		}
+		// zone local vminsert has static replicas
+		vminsert.HPA = nil
+		groupExtraServices(vminsert.ExtraServices, group.Name)
+		if group.Affinity != nil {
+			vminsert.Affinity = group.Affinity
+		}
+		if group.Tolerations != nil {
+			vminsert.Tolerations = group.Tolerations
+		}
+	}
+	if vmselect := gcr.Spec.VMSelect; vmselect != nil {
+		vmselect.Name = groupComponentName(vmselect.Name, cr.Name, group.Name)
+		vmselect.ReplicaCount = group.VMSelectReplicaCount
+		// zone local vmselect has static replicas
+		vmselect.HPA = nil
+		groupExtraServices(vmselect.ExtraServices, group.Name)
+		if group.Affinity != nil {
+			vmselect.Affinity = group.Affinity
+		}
+		if group.Tolerations != nil {
+			vmselect.Tolerations = group.Tolerations
+		}
+	}
+	return gcr
+}
+
+// groupExtraServices suffixes names of additional services with group name,
+// since services of the same component are created for each group.
+func groupExtraServices(services []v1beta1.ServiceSpec, group string) {
+	for i := range services {
+		services[i].Name = services[i].Name + "-" + group
+	}
+}
+
+// buildStorageNodeArg returns -storageNode flag with vmstorage nodes of cluster.
+// Nodes of all storage groups are included, if cluster has groups.
+func buildStorageNodeArg(cr *v1beta1.VMCluster, port string, withDraining bool, c *config.BaseOperatorConf) string {
+	clusters := []*v1beta1.VMCluster{cr}
+	if len(cr.Spec.StorageGroups) > 0 {
+		clusters = clusters[:0]
+		for i := range cr.Spec.StorageGroups {
+			clusters = append(clusters, storageGroupCluster(cr, &cr.Spec.StorageGroups[i]))
+		}
+	}
+	storageArg := "-storageNode="
+	for _, gcr := range clusters {
+		count := *gcr.Spec.VMStorage.ReplicaCount
+		if withDraining {
+			count = storageNodesCount(gcr)
+		}
+		baseName := gcr.Spec.VMStorage.GetNameWithPrefix(gcr.Name)
+		for i := int32(0); i < count; i++ {
+			storageArg += gcr.Spec.VMStorage.BuildPodFQDNName(baseName, i, gcr.Namespace, port, c.ClusterDomainName)
+		}
+	}
+	return strings.TrimSuffix(storageArg, ",")
+}
+
+// reconcileStorageGroups creates or updates statefulsets and services of storage groups.
+// Groups are updated one by one, so only one group may be unavailable during rolling update.
+// It returns true, if all groups are operational.
+func reconcileStorageGroups(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf, result *clusterReconcileResult) (bool, error) {
+	var drainingNodes []v1beta1.VMStorageDrainingNode
+	groupClusters := make([]*v1beta1.VMCluster, 0, len(cr.Spec.StorageGroups))
+	for i := range cr.Spec.StorageGroups {
+		group := &cr.Spec.StorageGroups[i]
+		gcr := storageGroupCluster(cr, group)
+		nodes, err := reconcileStorageScaleDown(ctx, rclient, gcr, group.Name)
+		if err != nil {
+			result.reason = fmt.Sprintf("failed to check vmStorage scale down for group %s", group.Name)
+			return false, err
+		}
+		gcr.Status.DrainingStorageNodes = nodes
+		drainingNodes = append(drainingNodes, nodes...)
+		groupClusters = append(groupClusters, gcr)
+	}
+	// draining nodes are kept at statefulsets and vmselect
+	cr.Status.DrainingStorageNodes = drainingNodes
+	result.drainingStorageNodes = drainingNodes
+
+	for i, gcr := range groupClusters {
+		group := cr.Spec.StorageGroups[i].Name
+		storageSts, err := createOrUpdateVMStorage(ctx, gcr, group, rclient, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("%s for group %s", v1beta1.StorageCreationFailed, group)
+			return false, err
+		}
+		selectorLabels := gcr.VMStorageGroupSelectorLabels(group)
+		if err := reconcilePDB(ctx, rclient, gcr.Spec.VMStorage.PodDisruptionBudget, selectorLabels, storageSts.ObjectMeta); err != nil {
+			result.reason = fmt.Sprintf("failed to create vmStorage pdb for group %s", group)
+			return false, err
+		}
+		rollingUpdate, err := performRollingUpdateOnSts(ctx, rclient, gcr.Spec.VMStorage.GetNameWithPrefix(gcr.Name), gcr.Namespace, selectorLabels, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("%s for group %s", v1beta1.StorageRollingUpdateFailed, group)
+			return false, err
+		}
+		if !rollingUpdate.done {
+			result.reason = rollingUpdate.message("vmStorage group " + group)
+			result.status = v1beta1.ClusterStatusExpanding
+			return false, nil
+		}
+		storageSvc, err := CreateOrUpdateVMStorageService(ctx, gcr, group, rclient, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("failed to create vmStorage service for group %s", group)
+			return false, err
+		}
+		if !c.DisableSelfServiceScrapeCreation {
+			if err := CreateVMServiceScrapeFromService(ctx, rclient, storageSvc, cr.MetricPathStorage(), "http"); err != nil {
+				log.Error(err, "cannot create VMServiceScrape for vmStorage group", "group", group)
+			}
+		}
+		expanding, err := waitForExpanding(ctx, rclient, gcr.Namespace, selectorLabels, storageNodesCount(gcr))
+		if err != nil {
+			result.reason = fmt.Sprintf("failed to check for vmStorage group %s expanding", group)
+			return false, err
+		}
+		if expanding {
+			result.reason = fmt.Sprintf("vmStorage group %s is expanding", group)
+			result.status = v1beta1.ClusterStatusExpanding
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// reconcileGroupVMSelects creates or updates zone local vmselects of storage groups,
+// zone local vmselect queries only vmstorage nodes of its group.
+// It returns true, if all zone local vmselects are operational.
+func reconcileGroupVMSelects(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf, result *clusterReconcileResult) (bool, error) {
+	for i := range cr.Spec.StorageGroups {
+		group := &cr.Spec.StorageGroups[i]
+		if group.VMSelectReplicaCount == nil {
+			continue
+		}
+		gcr := storageGroupCluster(cr, group)
+		selectSts, err := createOrUpdateVMSelect(ctx, gcr, group.Name, rclient, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("%s for group %s", v1beta1.SelectCreationFailed, group.Name)
+			return false, err
+		}
+		selectorLabels := gcr.VMSelectGroupSelectorLabels(group.Name)
+		if err := reconcilePDB(ctx, rclient, gcr.Spec.VMSelect.PodDisruptionBudget, selectorLabels, selectSts.ObjectMeta); err != nil {
+			result.reason = fmt.Sprintf("failed to create vmSelect pdb for group %s", group.Name)
+			return false, err
+		}
+		selectSvc, err := CreateOrUpdateVMSelectService(ctx, gcr, group.Name, rclient, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("failed to create vmSelect service for group %s", group.Name)
+			return false, err
+		}
+		if !c.DisableSelfServiceScrapeCreation {
+			if err := CreateVMServiceScrapeFromService(ctx, rclient, selectSvc, cr.MetricPathSelect(), "http"); err != nil {
+				log.Error(err, "cannot create VMServiceScrape for vmSelect group", "group", group.Name)
+			}
+		}
+		rollingUpdate, err := performRollingUpdateOnSts(ctx, rclient, gcr.Spec.VMSelect.GetNameWithPrefix(gcr.Name), gcr.Namespace, selectorLabels, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("%s for group %s", v1beta1.SelectRollingUpdateFailed, group.Name)
+			return false, err
+		}
+		if !rollingUpdate.done {
+			result.reason = rollingUpdate.message("vmSelect group " + group.Name)
+			result.status = v1beta1.ClusterStatusExpanding
+			return false, nil
+		}
+		expanding, err := waitForExpanding(ctx, rclient, gcr.Namespace, selectorLabels, *group.VMSelectReplicaCount)
+		if err != nil {
+			result.reason = fmt.Sprintf("failed to wait for vmSelect group %s expanding", group.Name)
+			return false, err
+		}
+		if expanding {
+			result.reason = fmt.Sprintf("expanding vmSelect group %s", group.Name)
+			result.status = v1beta1.ClusterStatusExpanding
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// reconcileGroupVMInserts creates or updates zone local vminserts of storage groups,
+// zone local vminsert writes only to vmstorage nodes of its group.
+// Groups are updated one by one, so vminsert of only one group may be unavailable during rollout.
+// It returns true, if all zone local vminserts are operational.
+func reconcileGroupVMInserts(ctx context.Context, cr *v1beta1.VMCluster, rclient client.Client, c *config.BaseOperatorConf, result *clusterReconcileResult) (bool, error) {
+	for i := range cr.Spec.StorageGroups {
+		group := &cr.Spec.StorageGroups[i]
+		gcr := storageGroupCluster(cr, group)
+		insertDeploy, err := createOrUpdateVMInsert(ctx, gcr, group.Name, rclient, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("%s for group %s", v1beta1.InsertCreationFailed, group.Name)
+			return false, err
+		}
+		selectorLabels := gcr.VMInsertGroupSelectorLabels(group.Name)
+		if err := reconcilePDB(ctx, rclient, gcr.Spec.VMInsert.PodDisruptionBudget, selectorLabels, insertDeploy.ObjectMeta); err != nil {
+			result.reason = fmt.Sprintf("failed to create vmInsert pdb for group %s", group.Name)
+			return false, err
+		}
+		insertSvc, err := CreateOrUpdateVMInsertService(ctx, gcr, group.Name, rclient, c)
+		if err != nil {
+			result.reason = fmt.Sprintf("failed to create vmInsert service for group %s", group.Name)
+			return false, err
+		}
+		if !c.DisableSelfServiceScrapeCreation {
+			if err := CreateVMServiceScrapeFromService(ctx, rclient, insertSvc, cr.MetricPathInsert(), "http"); err != nil {
+				log.Error(err, "cannot create VMServiceScrape for vmInsert group", "group", group.Name)
+			}
+		}
+		if _, err := checkDeploymentProgress(ctx, rclient, insertDeploy.Name, gcr.Namespace); err != nil {
+			result.reason = fmt.Sprintf("vmInsert rollout failed for group %s", group.Name)
+			return false, err
+		}
+		replicas := int32(1)
+		if gcr.Spec.VMInsert.ReplicaCount != nil {
+			replicas = *gcr.Spec.VMInsert.ReplicaCount
+		}
+		expanding, err := waitForExpanding(ctx, rclient, gcr.Namespace, selectorLabels, replicas)
+		if err != nil {
+			result.reason = fmt.Sprintf("failed to wait for vmInsert group %s expanding", group.Name)
+			return false, err
+		}
+		if expanding {
+			result.reason = fmt.Sprintf("expanding vmInsert group %s", group.Name)
+			result.status = v1beta1.ClusterStatusExpanding
+			return false, nil
+		}
+	}
+	return true, nil
+}
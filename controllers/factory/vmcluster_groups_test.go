@@ -0,0 +1,271 @@
+package factory
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newGroupsCluster() *v1beta1.VMCluster {
+	return &v1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1beta1.VMClusterSpec{
+			RetentionPeriod: "1",
+			VMStorage: &v1beta1.VMStorage{
+				ReplicaCount: pointer.Int32Ptr(2),
+				VMSelectPort: "8401",
+				VMInsertPort: "8400",
+			},
+			VMSelect: &v1beta1.VMSelect{ReplicaCount: pointer.Int32Ptr(2)},
+			VMInsert: &v1beta1.VMInsert{ReplicaCount: pointer.Int32Ptr(1)},
+			StorageGroups: []v1beta1.VMStorageGroup{
+				{
+					Name: "zone-a",
+					Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"zone-a"}}},
+						}}},
+					}},
+					VMSelectReplicaCount: pointer.Int32Ptr(1),
+					VMInsertReplicaCount: pointer.Int32Ptr(3),
+				},
+				{Name: "zone-b", ReplicaCount: pointer.Int32Ptr(1)},
+			},
+		},
+	}
+}
+
+func Test_storageGroupCluster(t *testing.T) {
+	cr := newGroupsCluster()
+	cr.Status.DrainingStorageNodes = []v1beta1.VMStorageDrainingNode{
+		{Name: "vmstorage-example-zone-a-2", Group: "zone-a", Index: 2},
+		{Name: "vmstorage-example-zone-b-1", Group: "zone-b", Index: 1},
+	}
+	gcr := storageGroupCluster(cr, &cr.Spec.StorageGroups[0])
+	if name := gcr.Spec.VMStorage.GetNameWithPrefix(gcr.Name); name != "vmstorage-example-zone-a" {
+		t.Errorf("unexpected vmstorage name: %s", name)
+	}
+	if name := gcr.Spec.VMSelect.GetNameWithPrefix(gcr.Name); name != "vmselect-example-zone-a" {
+		t.Errorf("unexpected vmselect name: %s", name)
+	}
+	if *gcr.Spec.VMStorage.ReplicaCount != 2 {
+		t.Errorf("vmstorage replica count must be inherited from cluster, got: %d", *gcr.Spec.VMStorage.ReplicaCount)
+	}
+	if *gcr.Spec.VMSelect.ReplicaCount != 1 {
+		t.Errorf("vmselect replica count must be taken from group, got: %d", *gcr.Spec.VMSelect.ReplicaCount)
+	}
+	if name := gcr.Spec.VMInsert.GetNameWithPrefix(gcr.Name); name != "vminsert-example-zone-a" {
+		t.Errorf("unexpected vminsert name: %s", name)
+	}
+	if *gcr.Spec.VMInsert.ReplicaCount != 3 {
+		t.Errorf("vminsert replica count must be taken from group, got: %d", *gcr.Spec.VMInsert.ReplicaCount)
+	}
+	if !reflect.DeepEqual(gcr.Spec.VMInsert.Affinity, cr.Spec.StorageGroups[0].Affinity) {
+		t.Errorf("vminsert affinity must be taken from group, got: %v", gcr.Spec.VMInsert.Affinity)
+	}
+	if !reflect.DeepEqual(gcr.Spec.VMStorage.Affinity, cr.Spec.StorageGroups[0].Affinity) {
+		t.Errorf("vmstorage affinity must be taken from group, got: %v", gcr.Spec.VMStorage.Affinity)
+	}
+	if len(gcr.Spec.StorageGroups) != 0 {
+		t.Errorf("group cluster must not contain storage groups")
+	}
+	if gcr.Spec.ReplicationFactor != nil {
+		t.Errorf("replication factor of group must be inherited from cluster, got: %v", *gcr.Spec.ReplicationFactor)
+	}
+	if len(gcr.Status.DrainingStorageNodes) != 1 || gcr.Status.DrainingStorageNodes[0].Group != "zone-a" {
+		t.Errorf("unexpected draining nodes of group: %v", gcr.Status.DrainingStorageNodes)
+	}
+	// cluster must not be changed
+	if cr.Spec.VMStorage.Name != "" || *cr.Spec.VMSelect.ReplicaCount != 2 || *cr.Spec.VMInsert.ReplicaCount != 1 {
+		t.Errorf("cluster spec was modified: %v", cr.Spec)
+	}
+}
+
+func Test_buildStorageNodeArg(t *testing.T) {
+	c := config.MustGetBaseConfig()
+	tests := []struct {
+		name         string
+		cr           func() *v1beta1.VMCluster
+		port         string
+		withDraining bool
+		want         []string
+	}{
+		{
+			name: "without groups",
+			cr: func() *v1beta1.VMCluster {
+				cr := newGroupsCluster()
+				cr.Spec.StorageGroups = nil
+				return cr
+			},
+			port: "8400",
+			want: []string{
+				"vmstorage-example-0.vmstorage-example.default.svc.cluster.local:8400",
+				"vmstorage-example-1.vmstorage-example.default.svc.cluster.local:8400",
+			},
+		},
+		{
+			name: "with groups",
+			cr:   newGroupsCluster,
+			port: "8400",
+			want: []string{
+				"vmstorage-example-zone-a-0.vmstorage-example-zone-a.default.svc.cluster.local:8400",
+				"vmstorage-example-zone-a-1.vmstorage-example-zone-a.default.svc.cluster.local:8400",
+				"vmstorage-example-zone-b-0.vmstorage-example-zone-b.default.svc.cluster.local:8400",
+			},
+		},
+		{
+			name: "with draining group node",
+			cr: func() *v1beta1.VMCluster {
+				cr := newGroupsCluster()
+				cr.Status.DrainingStorageNodes = []v1beta1.VMStorageDrainingNode{{Name: "vmstorage-example-zone-b-1", Group: "zone-b", Index: 1}}
+				return cr
+			},
+			port:         "8401",
+			withDraining: true,
+			want: []string{
+				"vmstorage-example-zone-a-0.vmstorage-example-zone-a.default.svc.cluster.local:8401",
+				"vmstorage-example-zone-a-1.vmstorage-example-zone-a.default.svc.cluster.local:8401",
+				"vmstorage-example-zone-b-0.vmstorage-example-zone-b.default.svc.cluster.local:8401",
+				"vmstorage-example-zone-b-1.vmstorage-example-zone-b.default.svc.cluster.local:8401",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildStorageNodeArg(tt.cr(), tt.port, tt.withDraining, c)
+			want := "-storageNode=" + strings.Join(tt.want, ",")
+			if got != want {
+				t.Errorf("buildStorageNodeArg() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdateVMCluster_storageGroups(t *testing.T) {
+	cr := newGroupsCluster()
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), []runtime.Object{cr.DeepCopy()}...)
+	status, err := CreateOrUpdateVMCluster(context.TODO(), cr, fclient, config.MustGetBaseConfig(), nil)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateVMCluster() error = %v", err)
+	}
+	// pods aren't created by fake client
+	if status != v1beta1.ClusterStatusExpanding {
+		t.Errorf("CreateOrUpdateVMCluster() status = %s, want %s", status, v1beta1.ClusterStatusExpanding)
+	}
+	sts := &appsv1.StatefulSet{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmstorage-example-zone-a", Namespace: "default"}, sts); err != nil {
+		t.Fatalf("cannot get group statefulset: %v", err)
+	}
+	wantLabels := map[string]string{
+		"app.kubernetes.io/name":      "vmstorage",
+		"app.kubernetes.io/instance":  "example-zone-a",
+		"app.kubernetes.io/component": "monitoring",
+		"managed-by":                  "vm-operator",
+		v1beta1.StorageGroupLabel:     "zone-a",
+	}
+	if !reflect.DeepEqual(sts.Spec.Selector.MatchLabels, wantLabels) {
+		t.Errorf("unexpected group statefulset selector: %v", sts.Spec.Selector.MatchLabels)
+	}
+	if sts.Spec.Template.Spec.Affinity == nil || sts.Spec.Template.Spec.Affinity.NodeAffinity == nil {
+		t.Errorf("group statefulset must have node affinity")
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmstorage-example", Namespace: "default"}, &appsv1.StatefulSet{}); err == nil {
+		t.Errorf("cluster vmstorage statefulset must not be created for storage groups")
+	}
+}
+
+func TestCreateOrUpdateVMCluster_storageGroupsZoneLocalComponents(t *testing.T) {
+	cr := newGroupsCluster()
+	readyPod := func(name string, podLabels map[string]string) runtime.Object {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: podLabels},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: "True"}},
+			},
+		}
+	}
+	objs := []runtime.Object{
+		cr.DeepCopy(),
+		readyPod("vmstorage-example-zone-a-0", cr.VMStorageGroupSelectorLabels("zone-a")),
+		readyPod("vmstorage-example-zone-a-1", cr.VMStorageGroupSelectorLabels("zone-a")),
+		readyPod("vmstorage-example-zone-b-0", cr.VMStorageGroupSelectorLabels("zone-b")),
+		readyPod("vmselect-example-0", cr.VMSelectSelectorLabels()),
+		readyPod("vmselect-example-1", cr.VMSelectSelectorLabels()),
+		readyPod("vmselect-example-zone-a-0", cr.VMSelectGroupSelectorLabels("zone-a")),
+		readyPod("vminsert-example-zone-a-0", cr.VMInsertGroupSelectorLabels("zone-a")),
+		readyPod("vminsert-example-zone-a-1", cr.VMInsertGroupSelectorLabels("zone-a")),
+		readyPod("vminsert-example-zone-a-2", cr.VMInsertGroupSelectorLabels("zone-a")),
+		readyPod("vminsert-example-zone-b-0", cr.VMInsertGroupSelectorLabels("zone-b")),
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), objs...)
+	status, err := CreateOrUpdateVMCluster(context.TODO(), cr, fclient, config.MustGetBaseConfig(), nil)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateVMCluster() error = %v", err)
+	}
+	if status != v1beta1.ClusterStatusOperational {
+		t.Fatalf("CreateOrUpdateVMCluster() status = %s, want %s, reason: %s", status, v1beta1.ClusterStatusOperational, cr.Status.Reason)
+	}
+	containerArgs := func(spec corev1.PodSpec) string {
+		return strings.Join(spec.Containers[0].Args, " ")
+	}
+	zoneA := "vmstorage-example-zone-a-0.vmstorage-example-zone-a.default.svc.cluster.local"
+	zoneB := "vmstorage-example-zone-b-0.vmstorage-example-zone-b.default.svc.cluster.local"
+
+	insertA := &appsv1.Deployment{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vminsert-example-zone-a", Namespace: "default"}, insertA); err != nil {
+		t.Fatalf("cannot get zone local vminsert: %v", err)
+	}
+	if args := containerArgs(insertA.Spec.Template.Spec); !strings.Contains(args, zoneA) || strings.Contains(args, zoneB) {
+		t.Errorf("zone local vminsert must write only to nodes of its group, got args: %s", args)
+	}
+	if *insertA.Spec.Replicas != 3 {
+		t.Errorf("unexpected replicas of zone local vminsert: %d", *insertA.Spec.Replicas)
+	}
+	insertB := &appsv1.Deployment{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vminsert-example-zone-b", Namespace: "default"}, insertB); err != nil {
+		t.Fatalf("cannot get zone local vminsert: %v", err)
+	}
+	if args := containerArgs(insertB.Spec.Template.Spec); !strings.Contains(args, zoneB) || strings.Contains(args, zoneA) {
+		t.Errorf("zone local vminsert must write only to nodes of its group, got args: %s", args)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vminsert-example-zone-b", Namespace: "default"}, &corev1.Service{}); err != nil {
+		t.Errorf("cannot get zone local vminsert service: %v", err)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vminsert-example", Namespace: "default"}, &appsv1.Deployment{}); err == nil {
+		t.Errorf("cluster vminsert must not be created for storage groups")
+	}
+
+	selectA := &appsv1.StatefulSet{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmselect-example-zone-a", Namespace: "default"}, selectA); err != nil {
+		t.Fatalf("cannot get zone local vmselect: %v", err)
+	}
+	if args := containerArgs(selectA.Spec.Template.Spec); !strings.Contains(args, zoneA) || strings.Contains(args, zoneB) {
+		t.Errorf("zone local vmselect must query only nodes of its group, got args: %s", args)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmselect-example-zone-b", Namespace: "default"}, &appsv1.StatefulSet{}); err == nil {
+		t.Errorf("zone local vmselect must not be created without vmSelectReplicaCount")
+	}
+	globalSelect := &appsv1.StatefulSet{}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmselect-example", Namespace: "default"}, globalSelect); err != nil {
+		t.Fatalf("cannot get global vmselect: %v", err)
+	}
+	args := containerArgs(globalSelect.Spec.Template.Spec)
+	if !strings.Contains(args, zoneA) || !strings.Contains(args, zoneB) {
+		t.Errorf("global vmselect must query nodes of all groups, got args: %s", args)
+	}
+	if !strings.Contains(args, "-dedup.minScrapeInterval=1ms") {
+		t.Errorf("global vmselect must deduplicate samples replicated across groups, got args: %s", args)
+	}
+}
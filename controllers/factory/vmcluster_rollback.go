@@ -0,0 +1,178 @@
+package factory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+	podTemplateHashLabel         = "pod-template-hash"
+)
+
+// isComponentPaused checks if component update was rolled back for the current VMCluster generation.
+// Spec of paused component isn't applied until VMCluster spec changes.
+func isComponentPaused(cs *v1beta1.VMClusterComponentStatus, generation int64) bool {
+	return cs != nil && cs.RollbackGeneration != 0 && cs.RollbackGeneration == generation
+}
+
+// isNewFailedAttempt checks if failed rollout of revision must be counted as new failed update attempt of component.
+// Failed reconcile is retried with backoff and returns the same error for the same revision many times,
+// so repeated failures of revision are counted once per VMClusterFailureCountInterval.
+func isNewFailedAttempt(cs *v1beta1.VMClusterComponentStatus, revision string, now time.Time, c *config.BaseOperatorConf) bool {
+	if cs == nil || cs.FailedRevision != revision || cs.LastFailureTime == nil {
+		return true
+	}
+	return now.Sub(cs.LastFailureTime.Time) >= c.VMClusterFailureCountInterval
+}
+
+// shouldRollback checks if component must be rolled back to the last known good revision.
+// Current failure is counted as well.
+func shouldRollback(cs *v1beta1.VMClusterComponentStatus, currentRevision string, c *config.BaseOperatorConf) bool {
+	if c.VMClusterRollbackFailureThreshold <= 0 || cs == nil {
+		return false
+	}
+	if cs.LastGoodRevision == "" || cs.LastGoodRevision == currentRevision {
+		return false
+	}
+	return int(cs.FailCount)+1 >= c.VMClusterRollbackFailureThreshold
+}
+
+// isRolloutDeadlineExceeded checks if rollout of revision isn't finished within VMClusterRolloutDeadline
+// since operator observed it first. Component must be rolled back to the last known good revision in this case.
+func isRolloutDeadlineExceeded(cs *v1beta1.VMClusterComponentStatus, revision string, now time.Time, c *config.BaseOperatorConf) bool {
+	if c.VMClusterRolloutDeadline <= 0 || cs == nil || cs.RolloutStartTime == nil {
+		return false
+	}
+	if cs.RolloutRevision != revision || cs.LastGoodRevision == "" || cs.LastGoodRevision == revision {
+		return false
+	}
+	return now.Sub(cs.RolloutStartTime.Time) >= c.VMClusterRolloutDeadline
+}
+
+// rollbackSts restores statefulset pod template from controller revision.
+// Statefulset controller reuses given revision, so rolling update continues to it.
+func rollbackSts(ctx context.Context, rclient client.Client, stsName, ns, revision string) error {
+	cr := &appsv1.ControllerRevision{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: revision, Namespace: ns}, cr); err != nil {
+		return fmt.Errorf("cannot get controller revision: %s, err: %w", revision, err)
+	}
+	// revision data contains patch for statefulset with full pod template
+	var patch struct {
+		Spec struct {
+			Template *corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(cr.Data.Raw, &patch); err != nil {
+		return fmt.Errorf("cannot parse controller revision: %s, err: %w", revision, err)
+	}
+	if patch.Spec.Template == nil {
+		return fmt.Errorf("controller revision: %s doesn't contain pod template", revision)
+	}
+	sts := &appsv1.StatefulSet{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: stsName, Namespace: ns}, sts); err != nil {
+		return fmt.Errorf("cannot get sts for rollback: %s, err: %w", stsName, err)
+	}
+	sts.Spec.Template = *patch.Spec.Template
+	if err := rclient.Update(ctx, sts); err != nil {
+		return fmt.Errorf("cannot rollback sts: %s, err: %w", stsName, err)
+	}
+	return nil
+}
+
+// rollbackDeployment restores deployment pod template from replica set with given revision.
+func rollbackDeployment(ctx context.Context, rclient client.Client, deployName, ns string, selector map[string]string, revision string) error {
+	rsList := &appsv1.ReplicaSetList{}
+	if err := rclient.List(ctx, rsList, &client.ListOptions{Namespace: ns, LabelSelector: labels.SelectorFromSet(selector)}); err != nil {
+		return fmt.Errorf("cannot list replica sets for rollback: %w", err)
+	}
+	var template *corev1.PodTemplateSpec
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+		if rs.Annotations[deploymentRevisionAnnotation] == revision {
+			template = rs.Spec.Template.DeepCopy()
+			break
+		}
+	}
+	if template == nil {
+		return fmt.Errorf("cannot find replica set for deployment: %s with revision: %s", deployName, revision)
+	}
+	// label is added by deployment controller
+	delete(template.Labels, podTemplateHashLabel)
+	deploy := &appsv1.Deployment{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: deployName, Namespace: ns}, deploy); err != nil {
+		return fmt.Errorf("cannot get deployment for rollback: %s, err: %w", deployName, err)
+	}
+	deploy.Spec.Template = *template
+	if err := rclient.Update(ctx, deploy); err != nil {
+		return fmt.Errorf("cannot rollback deployment: %s, err: %w", deployName, err)
+	}
+	return nil
+}
+
+// checkDeploymentProgress returns error, if deployment rollout exceeded its progress deadline.
+// It returns current revision of deployment.
+func checkDeploymentProgress(ctx context.Context, rclient client.Client, name, ns string) (string, error) {
+	deploy := &appsv1.Deployment{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: name, Namespace: ns}, deploy); err != nil {
+		return "", fmt.Errorf("cannot get deployment: %s, err: %w", name, err)
+	}
+	revision := deploy.Annotations[deploymentRevisionAnnotation]
+	for _, cond := range deploy.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+			return revision, fmt.Errorf("deployment: %s rollout exceeded progress deadline: %s", name, cond.Message)
+		}
+	}
+	return revision, nil
+}
+
+// rollbackComponent rolls back component to the last known good revision.
+// Caller must check, that rollback is needed with shouldRollback or isRolloutDeadlineExceeded.
+// It returns revision, component was rolled back to.
+func rollbackComponent(ctx context.Context, rclient client.Client, cr *v1beta1.VMCluster, component string, recorder record.EventRecorder, reason string) (string, error) {
+	cs := clusterComponentStatus(cr, component)
+	if cs == nil || cs.LastGoodRevision == "" {
+		return "", fmt.Errorf("BUG: %s doesn't have last good revision for rollback", component)
+	}
+	log.Info("rolling back component to the last good revision", "cluster", cr.Name, "component", component, "revision", cs.LastGoodRevision)
+	var err error
+	switch component {
+	case "vmstorage":
+		err = rollbackSts(ctx, rclient, cr.Spec.VMStorage.GetNameWithPrefix(cr.Name), cr.Namespace, cs.LastGoodRevision)
+	case "vmselect":
+		err = rollbackSts(ctx, rclient, cr.Spec.VMSelect.GetNameWithPrefix(cr.Name), cr.Namespace, cs.LastGoodRevision)
+	case "vminsert":
+		err = rollbackDeployment(ctx, rclient, cr.Spec.VMInsert.GetNameWithPrefix(cr.Name), cr.Namespace, cr.VMInsertSelectorLabels(), cs.LastGoodRevision)
+	default:
+		return "", fmt.Errorf("BUG: unsupported component: %s", component)
+	}
+	if err != nil {
+		return "", err
+	}
+	recorder.Eventf(cr, corev1.EventTypeWarning, "RolledBack", "%s, rolled back to revision %s", reason, cs.LastGoodRevision)
+	return cs.LastGoodRevision, nil
+}
+
+// clusterComponentStatus returns status of VMCluster component or nil.
+func clusterComponentStatus(cr *v1beta1.VMCluster, component string) *v1beta1.VMClusterComponentStatus {
+	switch component {
+	case "vmstorage":
+		return cr.Status.VMStorage
+	case "vmselect":
+		return cr.Status.VMSelect
+	case "vminsert":
+		return cr.Status.VMInsert
+	}
+	return nil
+}
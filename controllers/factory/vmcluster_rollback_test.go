@@ -0,0 +1,343 @@
+package factory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_shouldRollback(t *testing.T) {
+	tests := []struct {
+		name            string
+		cs              *v1beta1.VMClusterComponentStatus
+		currentRevision string
+		threshold       int
+		want            bool
+	}{
+		{
+			name:            "threshold reached",
+			cs:              &v1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1", FailCount: 2},
+			currentRevision: "rev-2",
+			threshold:       3,
+			want:            true,
+		},
+		{
+			name:            "threshold not reached",
+			cs:              &v1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1", FailCount: 1},
+			currentRevision: "rev-2",
+			threshold:       3,
+		},
+		{
+			name:            "rollback disabled",
+			cs:              &v1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1", FailCount: 5},
+			currentRevision: "rev-2",
+		},
+		{
+			name:            "no known good revision",
+			cs:              &v1beta1.VMClusterComponentStatus{FailCount: 5},
+			currentRevision: "rev-2",
+			threshold:       3,
+		},
+		{
+			name:            "current revision is good",
+			cs:              &v1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-2", FailCount: 5},
+			currentRevision: "rev-2",
+			threshold:       3,
+		},
+		{
+			name:            "no status",
+			currentRevision: "rev-2",
+			threshold:       3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.BaseOperatorConf{VMClusterRollbackFailureThreshold: tt.threshold}
+			if got := shouldRollback(tt.cs, tt.currentRevision, c); got != tt.want {
+				t.Errorf("shouldRollback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isNewFailedAttempt(t *testing.T) {
+	now := time.Now()
+	c := &config.BaseOperatorConf{VMClusterFailureCountInterval: 5 * time.Minute}
+	tests := []struct {
+		name     string
+		cs       *v1beta1.VMClusterComponentStatus
+		revision string
+		want     bool
+	}{
+		{
+			name:     "first failure",
+			cs:       &v1beta1.VMClusterComponentStatus{LastGoodRevision: "rev-1"},
+			revision: "rev-2",
+			want:     true,
+		},
+		{
+			name:     "no status",
+			revision: "rev-2",
+			want:     true,
+		},
+		{
+			name: "retried failure of the same revision",
+			cs: &v1beta1.VMClusterComponentStatus{
+				FailCount:       1,
+				FailedRevision:  "rev-2",
+				LastFailureTime: &metav1.Time{Time: now.Add(-time.Second)},
+			},
+			revision: "rev-2",
+		},
+		{
+			name: "failure of the same revision after failure count interval",
+			cs: &v1beta1.VMClusterComponentStatus{
+				FailCount:       1,
+				FailedRevision:  "rev-2",
+				LastFailureTime: &metav1.Time{Time: now.Add(-5 * time.Minute)},
+			},
+			revision: "rev-2",
+			want:     true,
+		},
+		{
+			name: "failure of new revision",
+			cs: &v1beta1.VMClusterComponentStatus{
+				FailCount:       1,
+				FailedRevision:  "rev-2",
+				LastFailureTime: &metav1.Time{Time: now.Add(-time.Second)},
+			},
+			revision: "rev-3",
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNewFailedAttempt(tt.cs, tt.revision, now, c); got != tt.want {
+				t.Errorf("isNewFailedAttempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isRolloutDeadlineExceeded(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		cs       *v1beta1.VMClusterComponentStatus
+		revision string
+		deadline time.Duration
+		want     bool
+	}{
+		{
+			name: "deadline exceeded",
+			cs: &v1beta1.VMClusterComponentStatus{
+				LastGoodRevision: "rev-1",
+				RolloutRevision:  "rev-2",
+				RolloutStartTime: &metav1.Time{Time: now.Add(-15 * time.Minute)},
+			},
+			revision: "rev-2",
+			deadline: 15 * time.Minute,
+			want:     true,
+		},
+		{
+			name: "rollout in progress",
+			cs: &v1beta1.VMClusterComponentStatus{
+				LastGoodRevision: "rev-1",
+				RolloutRevision:  "rev-2",
+				RolloutStartTime: &metav1.Time{Time: now.Add(-time.Minute)},
+			},
+			revision: "rev-2",
+			deadline: 15 * time.Minute,
+		},
+		{
+			name: "deadline disabled",
+			cs: &v1beta1.VMClusterComponentStatus{
+				LastGoodRevision: "rev-1",
+				RolloutRevision:  "rev-2",
+				RolloutStartTime: &metav1.Time{Time: now.Add(-time.Hour)},
+			},
+			revision: "rev-2",
+		},
+		{
+			name: "rollout of new revision isn't recorded yet",
+			cs: &v1beta1.VMClusterComponentStatus{
+				LastGoodRevision: "rev-1",
+				RolloutRevision:  "rev-2",
+				RolloutStartTime: &metav1.Time{Time: now.Add(-time.Hour)},
+			},
+			revision: "rev-3",
+			deadline: 15 * time.Minute,
+		},
+		{
+			name: "no known good revision",
+			cs: &v1beta1.VMClusterComponentStatus{
+				RolloutRevision:  "rev-2",
+				RolloutStartTime: &metav1.Time{Time: now.Add(-time.Hour)},
+			},
+			revision: "rev-2",
+			deadline: 15 * time.Minute,
+		},
+		{
+			name:     "no status",
+			revision: "rev-2",
+			deadline: 15 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.BaseOperatorConf{VMClusterRolloutDeadline: tt.deadline}
+			if got := isRolloutDeadlineExceeded(tt.cs, tt.revision, now, c); got != tt.want {
+				t.Errorf("isRolloutDeadlineExceeded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rollbackSts(t *testing.T) {
+	tests := []struct {
+		name              string
+		revision          string
+		predefinedObjects []runtime.Object
+		wantImage         string
+		wantErr           bool
+	}{
+		{
+			name:     "rollback to revision",
+			revision: "vmstorage-example-rev-1",
+			predefinedObjects: []runtime.Object{
+				&appsv1.ControllerRevision{
+					ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-example-rev-1", Namespace: "default"},
+					Data:       runtime.RawExtension{Raw: []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"vmstorage","image":"victoriametrics/vmstorage:v1.42.0-cluster"}]}}}}`)},
+				},
+				&appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-example", Namespace: "default"},
+					Spec: appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+						{Name: "vmstorage", Image: "victoriametrics/vmstorage:v1.43.0-cluster"},
+					}}}},
+				},
+			},
+			wantImage: "victoriametrics/vmstorage:v1.42.0-cluster",
+		},
+		{
+			name:     "missing revision",
+			revision: "vmstorage-example-rev-1",
+			predefinedObjects: []runtime.Object{
+				&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-example", Namespace: "default"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "revision without template",
+			revision: "vmstorage-example-rev-1",
+			predefinedObjects: []runtime.Object{
+				&appsv1.ControllerRevision{
+					ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-example-rev-1", Namespace: "default"},
+					Data:       runtime.RawExtension{Raw: []byte(`{"spec":{}}`)},
+				},
+				&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-example", Namespace: "default"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			err := rollbackSts(context.TODO(), fclient, "vmstorage-example", "default", tt.revision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rollbackSts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			sts := &appsv1.StatefulSet{}
+			if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vmstorage-example", Namespace: "default"}, sts); err != nil {
+				t.Fatalf("cannot get sts: %v", err)
+			}
+			if got := sts.Spec.Template.Spec.Containers[0].Image; got != tt.wantImage {
+				t.Errorf("rollbackSts() image = %s, want %s", got, tt.wantImage)
+			}
+		})
+	}
+}
+
+func Test_rollbackDeployment(t *testing.T) {
+	selector := map[string]string{"app": "vminsert"}
+	newRS := func(revision, image string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "vminsert-example-" + revision,
+				Namespace:   "default",
+				Labels:      selector,
+				Annotations: map[string]string{deploymentRevisionAnnotation: revision},
+			},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "vminsert", podTemplateHashLabel: "hash-" + revision}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "vminsert", Image: image}}},
+			}},
+		}
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "vminsert-example", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "vminsert", Image: "victoriametrics/vminsert:v1.43.0-cluster"},
+		}}}},
+	}
+	tests := []struct {
+		name              string
+		revision          string
+		predefinedObjects []runtime.Object
+		wantImage         string
+		wantErr           bool
+	}{
+		{
+			name:     "rollback to revision",
+			revision: "1",
+			predefinedObjects: []runtime.Object{
+				deploy.DeepCopy(),
+				newRS("1", "victoriametrics/vminsert:v1.42.0-cluster"),
+				newRS("2", "victoriametrics/vminsert:v1.43.0-cluster"),
+			},
+			wantImage: "victoriametrics/vminsert:v1.42.0-cluster",
+		},
+		{
+			name:     "missing replica set",
+			revision: "1",
+			predefinedObjects: []runtime.Object{
+				deploy.DeepCopy(),
+				newRS("2", "victoriametrics/vminsert:v1.43.0-cluster"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			err := rollbackDeployment(context.TODO(), fclient, "vminsert-example", "default", selector, tt.revision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rollbackDeployment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := &appsv1.Deployment{}
+			if err := fclient.Get(context.TODO(), types.NamespacedName{Name: "vminsert-example", Namespace: "default"}, got); err != nil {
+				t.Fatalf("cannot get deployment: %v", err)
+			}
+			if image := got.Spec.Template.Spec.Containers[0].Image; image != tt.wantImage {
+				t.Errorf("rollbackDeployment() image = %s, want %s", image, tt.wantImage)
+			}
+			if _, ok := got.Spec.Template.Labels[podTemplateHashLabel]; ok {
+				t.Errorf("rollbackDeployment() pod template must not contain %s label", podTemplateHashLabel)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// retentionPeriodDuration converts retention period of VMCluster into duration.
+// Value without suffix is number of months, h,d,w,y suffixes are supported.
+func retentionPeriodDuration(value string) (time.Duration, error) {
+	unit := 31 * 24 * time.Hour
+	units := map[string]time.Duration{
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+	number := value
+	if len(value) > 0 {
+		if u, ok := units[value[len(value)-1:]]; ok {
+			unit = u
+			number = value[:len(value)-1]
+		}
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("cannot parse retention period: %q", value)
+	}
+	return time.Duration(f * float64(unit)), nil
+}
+
+// storageNodesCount returns number of vmstorage nodes available for vmselect.
+// It includes nodes, which are draining after scale down.
+func storageNodesCount(cr *v1beta1.VMCluster) int32 {
+	// statefulset default
+	count := int32(1)
+	if cr.Spec.VMStorage.ReplicaCount != nil {
+		count = *cr.Spec.VMStorage.ReplicaCount
+	}
+	for _, node := range cr.Status.DrainingStorageNodes {
+		if node.Index >= count {
+			count = node.Index + 1
+		}
+	}
+	return count
+}
+
+// reconcileStorageScaleDown tracks vmstorage nodes removed by scale down.
+// Removed node is excluded from vminsert at once, but it's kept for vmselect
+// until retention period passes or scale down is confirmed with annotation.
+// Statefulset can remove only pods with the highest index, so node is removed,
+// when all nodes with higher index are drained.
+// Non empty group is the name of storage group, which cr was built for.
+// It returns nodes, which are still draining.
+func reconcileStorageScaleDown(ctx context.Context, rclient client.Client, cr *v1beta1.VMCluster, group string) ([]v1beta1.VMStorageDrainingNode, error) {
+	if cr.Spec.VMStorage.ReplicaCount == nil {
+		return nil, nil
+	}
+	desired := *cr.Spec.VMStorage.ReplicaCount
+	stsName := cr.Spec.VMStorage.GetNameWithPrefix(cr.Name)
+	sts := &appsv1.StatefulSet{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: stsName, Namespace: cr.Namespace}, sts); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot get vmstorage sts: %w", err)
+	}
+	current := int32(1)
+	if sts.Spec.Replicas != nil {
+		current = *sts.Spec.Replicas
+	}
+	if current <= desired {
+		return nil, nil
+	}
+	drainPeriod, err := retentionPeriodDuration(cr.Spec.RetentionPeriod)
+	if err != nil {
+		return nil, newConfigError(err)
+	}
+
+	existing := make(map[int32]v1beta1.VMStorageDrainingNode, len(cr.Status.DrainingStorageNodes))
+	for _, node := range cr.Status.DrainingStorageNodes {
+		existing[node.Index] = node
+	}
+	now := metav1.Now()
+	nodes := make([]v1beta1.VMStorageDrainingNode, 0, current-desired)
+	for i := desired; i < current; i++ {
+		node, ok := existing[i]
+		if !ok {
+			log.Info("vmstorage node was removed from vminsert, draining it", "cluster", cr.Name, "group", group, "node", i)
+			node = v1beta1.VMStorageDrainingNode{
+				Name:           fmt.Sprintf("%s-%d", stsName, i),
+				Group:          group,
+				Index:          i,
+				DrainStartTime: now,
+			}
+		}
+		nodes = append(nodes, node)
+	}
+
+	confirmed := cr.GetAnnotations()[v1beta1.MetaVMStorageScaleDownConfirmKey] == strconv.Itoa(int(desired))
+	var removed []v1beta1.VMStorageDrainingNode
+	for len(nodes) > 0 {
+		node := nodes[len(nodes)-1]
+		if !confirmed && now.Sub(node.DrainStartTime.Time) < drainPeriod {
+			break
+		}
+		log.Info("vmstorage node was drained, removing it", "cluster", cr.Name, "node", node.Index, "confirmed", confirmed)
+		removed = append(removed, node)
+		nodes = nodes[:len(nodes)-1]
+	}
+	if cr.Spec.VMStorage.RemovedNodePVCPolicy == v1beta1.PVCPolicyDelete {
+		for _, node := range removed {
+			if err := deleteStorageNodePVCs(ctx, rclient, sts, node.Index); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	return nodes, nil
+}
+
+// deleteStorageNodePVCs deletes persistent volume claims of statefulset pod with given index.
+// Claims are protected by kubernetes until pod is removed.
+func deleteStorageNodePVCs(ctx context.Context, rclient client.Client, sts *appsv1.StatefulSet, index int32) error {
+	for _, tmpl := range sts.Spec.VolumeClaimTemplates {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s-%d", tmpl.Name, sts.Name, index),
+				Namespace: sts.Namespace,
+			},
+		}
+		if err := rclient.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete pvc: %s of removed vmstorage node, err: %w", pvc.Name, err)
+		}
+		log.Info("deleted pvc of removed vmstorage node", "pvc", pvc.Name)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_retentionPeriodDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "1", want: 31 * 24 * time.Hour},
+		{value: "12h", want: 12 * time.Hour},
+		{value: "2d", want: 48 * time.Hour},
+		{value: "1w", want: 7 * 24 * time.Hour},
+		{value: "0.5y", want: 365 * 12 * time.Hour},
+		{value: "", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "1month", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := retentionPeriodDuration(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retentionPeriodDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("retentionPeriodDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_reconcileStorageScaleDown(t *testing.T) {
+	newCluster := func(replicas int32, draining ...v1beta1.VMStorageDrainingNode) *v1beta1.VMCluster {
+		return &v1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+			Spec: v1beta1.VMClusterSpec{
+				RetentionPeriod: "1",
+				VMStorage:       &v1beta1.VMStorage{ReplicaCount: pointer.Int32Ptr(replicas)},
+			},
+			Status: v1beta1.VMClusterStatus{DrainingStorageNodes: draining},
+		}
+	}
+	newSts := func(replicas int32) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-example", Namespace: "default"},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:             pointer.Int32Ptr(replicas),
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-db"}}},
+			},
+		}
+	}
+	newPVC := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+	node := func(index int32, age time.Duration) v1beta1.VMStorageDrainingNode {
+		return v1beta1.VMStorageDrainingNode{
+			Name:           fmt.Sprintf("vmstorage-example-%d", index),
+			Index:          index,
+			DrainStartTime: metav1.NewTime(time.Now().Add(-age)),
+		}
+	}
+	tests := []struct {
+		name              string
+		cr                *v1beta1.VMCluster
+		predefinedObjects []runtime.Object
+		wantNodes         []int32
+		wantNodesCount    int32
+		wantDeletedPVCs   []string
+		wantKeptPVCs      []string
+		wantErr           bool
+	}{
+		{
+			name:           "new cluster",
+			cr:             newCluster(3),
+			wantNodesCount: 3,
+		},
+		{
+			name:              "scale up",
+			cr:                newCluster(3),
+			predefinedObjects: []runtime.Object{newSts(2)},
+			wantNodesCount:    3,
+		},
+		{
+			name:              "scale down starts draining",
+			cr:                newCluster(2),
+			predefinedObjects: []runtime.Object{newSts(4)},
+			wantNodes:         []int32{2, 3},
+			wantNodesCount:    4,
+		},
+		{
+			name:              "scale up cancels draining",
+			cr:                newCluster(3, node(2, time.Hour), node(3, time.Hour)),
+			predefinedObjects: []runtime.Object{newSts(4)},
+			wantNodes:         []int32{3},
+			wantNodesCount:    4,
+		},
+		{
+			name: "retention passed for the highest node",
+			cr: func() *v1beta1.VMCluster {
+				cr := newCluster(2, node(2, time.Hour), node(3, 32*24*time.Hour))
+				cr.Spec.VMStorage.RemovedNodePVCPolicy = v1beta1.PVCPolicyDelete
+				return cr
+			}(),
+			predefinedObjects: []runtime.Object{
+				newSts(4),
+				newPVC("vmstorage-db-vmstorage-example-2"),
+				newPVC("vmstorage-db-vmstorage-example-3"),
+			},
+			wantNodes:       []int32{2},
+			wantNodesCount:  3,
+			wantDeletedPVCs: []string{"vmstorage-db-vmstorage-example-3"},
+			wantKeptPVCs:    []string{"vmstorage-db-vmstorage-example-2"},
+		},
+		{
+			name: "retention passed only for lower node",
+			cr:   newCluster(2, node(2, 32*24*time.Hour), node(3, time.Hour)),
+			predefinedObjects: []runtime.Object{
+				newSts(4),
+			},
+			wantNodes:      []int32{2, 3},
+			wantNodesCount: 4,
+		},
+		{
+			name: "confirmed scale down retains pvc",
+			cr: func() *v1beta1.VMCluster {
+				cr := newCluster(2, node(2, time.Hour), node(3, time.Hour))
+				cr.ObjectMeta.Annotations = map[string]string{v1beta1.MetaVMStorageScaleDownConfirmKey: "2"}
+				return cr
+			}(),
+			predefinedObjects: []runtime.Object{
+				newSts(4),
+				newPVC("vmstorage-db-vmstorage-example-2"),
+				newPVC("vmstorage-db-vmstorage-example-3"),
+			},
+			wantNodesCount: 2,
+			wantKeptPVCs:   []string{"vmstorage-db-vmstorage-example-2", "vmstorage-db-vmstorage-example-3"},
+		},
+		{
+			name: "confirmation for other replica count",
+			cr: func() *v1beta1.VMCluster {
+				cr := newCluster(2, node(2, time.Hour), node(3, time.Hour))
+				cr.ObjectMeta.Annotations = map[string]string{v1beta1.MetaVMStorageScaleDownConfirmKey: "3"}
+				return cr
+			}(),
+			predefinedObjects: []runtime.Object{newSts(4)},
+			wantNodes:         []int32{2, 3},
+			wantNodesCount:    4,
+		},
+		{
+			name: "incorrect retention period",
+			cr: func() *v1beta1.VMCluster {
+				cr := newCluster(2)
+				cr.Spec.RetentionPeriod = "1month"
+				return cr
+			}(),
+			predefinedObjects: []runtime.Object{newSts(4)},
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)
+			got, err := reconcileStorageScaleDown(context.TODO(), fclient, tt.cr, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reconcileStorageScaleDown() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !IsConfigError(err) {
+					t.Errorf("reconcileStorageScaleDown() error must be config error, got: %v", err)
+				}
+				return
+			}
+			var gotNodes []int32
+			for _, node := range got {
+				gotNodes = append(gotNodes, node.Index)
+				if node.Name != fmt.Sprintf("vmstorage-example-%d", node.Index) {
+					t.Errorf("reconcileStorageScaleDown() unexpected node name: %s", node.Name)
+				}
+				if node.DrainStartTime.IsZero() {
+					t.Errorf("reconcileStorageScaleDown() drain start time must be set for node: %s", node.Name)
+				}
+			}
+			if len(gotNodes) != len(tt.wantNodes) {
+				t.Fatalf("reconcileStorageScaleDown() nodes = %v, want %v", gotNodes, tt.wantNodes)
+			}
+			for i := range gotNodes {
+				if gotNodes[i] != tt.wantNodes[i] {
+					t.Fatalf("reconcileStorageScaleDown() nodes = %v, want %v", gotNodes, tt.wantNodes)
+				}
+			}
+			tt.cr.Status.DrainingStorageNodes = got
+			if count := storageNodesCount(tt.cr); count != tt.wantNodesCount {
+				t.Errorf("storageNodesCount() = %d, want %d", count, tt.wantNodesCount)
+			}
+			for _, name := range tt.wantDeletedPVCs {
+				err := fclient.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, &corev1.PersistentVolumeClaim{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("pvc %s must be deleted, got err: %v", name, err)
+				}
+			}
+			for _, name := range tt.wantKeptPVCs {
+				if err := fclient.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, &corev1.PersistentVolumeClaim{}); err != nil {
+					t.Errorf("pvc %s must be kept, got err: %v", name, err)
+				}
+			}
+		})
+	}
+}
@@ -8,104 +8,16 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
 	"testing"
 	"time"
 )
 
-func Test_waitForPodReady(t *testing.T) {
-
-	type args struct {
-		ns      string
-		podName string
-		c       *config.BaseOperatorConf
-	}
-	tests := []struct {
-		name              string
-		args              args
-		wantErr           bool
-		predefinedObjects []runtime.Object
-	}{
-		{
-			name: "testing pod with unready status",
-			args: args{
-				ns:      "default",
-				podName: "vmselect-example-0",
-				c:       &config.BaseOperatorConf{PodWaitReadyIntervalCheck: time.Second * 1, PodWaitReadyInitDelay: time.Second, PodWaitReadyTimeout: time.Second * 4},
-			},
-			predefinedObjects: []runtime.Object{
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-example-0",
-						Namespace: "default",
-					},
-					Status: corev1.PodStatus{
-						Conditions: []corev1.PodCondition{},
-						Phase:      corev1.PodPending,
-					},
-				},
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-example-1",
-						Namespace: "default",
-					},
-					Status: corev1.PodStatus{
-						Conditions: []corev1.PodCondition{},
-						Phase:      corev1.PodPending,
-					},
-				},
-			},
-			wantErr: true,
-		},
-		{
-			name: "testing pod with ready status",
-			args: args{
-				ns:      "default",
-				podName: "vmselect-example-0",
-				c:       &config.BaseOperatorConf{PodWaitReadyIntervalCheck: time.Second * 1, PodWaitReadyInitDelay: time.Second, PodWaitReadyTimeout: time.Second * 4},
-			},
-			predefinedObjects: []runtime.Object{
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-example-0",
-						Namespace: "default",
-					},
-					Status: corev1.PodStatus{
-						Conditions: []corev1.PodCondition{
-							{Status: "True", Type: corev1.PodReady},
-						},
-						Phase: corev1.PodRunning,
-					},
-				},
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-example-1",
-						Namespace: "default",
-					},
-					Status: corev1.PodStatus{
-						Conditions: []corev1.PodCondition{},
-						Phase:      corev1.PodPending,
-					},
-				},
-			},
-			wantErr: false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			obj := []runtime.Object{}
-			obj = append(obj, tt.predefinedObjects...)
-			fclient := fake.NewFakeClientWithScheme(testGetScheme(), obj...)
-
-			if err := waitForPodReady(context.Background(), fclient, tt.args.ns, tt.args.podName, tt.args.c); (err != nil) != tt.wantErr {
-				t.Errorf("waitForPodReady() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
 func Test_podIsReady(t *testing.T) {
 	type args struct {
 		pod corev1.Pod
@@ -192,160 +104,148 @@ func Test_podIsReady(t *testing.T) {
 }
 
 func Test_performRollingUpdateOnSts(t *testing.T) {
-	type args struct {
-		stsName   string
-		ns        string
-		podLabels map[string]string
-		c         *config.BaseOperatorConf
+	readyStatus := corev1.PodStatus{
+		Phase:      corev1.PodRunning,
+		Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: "True"}},
+	}
+	newSts := func(currentRev, updateRev string) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "vmselect-sts",
+				Namespace: "default",
+				Labels:    map[string]string{"app": "vmselect"},
+			},
+			Spec: appsv1.StatefulSetSpec{Replicas: pointer.Int32Ptr(2)},
+			Status: appsv1.StatefulSetStatus{
+				CurrentRevision: currentRev,
+				UpdateRevision:  updateRev,
+			},
+		}
+	}
+	newPod := func(name, rev string, status corev1.PodStatus, created time.Time) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				Labels:            map[string]string{"app": "vmselect", podRevisionLabel: rev},
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: status,
+		}
+	}
+	c := &config.BaseOperatorConf{
+		PodWaitReadyTimeout:   time.Minute,
+		PodWaitReadyInitDelay: time.Second * 10,
 	}
 	tests := []struct {
-		name                string
-		args                args
-		wantErr             bool
-		predefinedObjets    []runtime.Object
-		updatePodRevByIndex *int32
-		neededPodRev        string
+		name             string
+		predefinedObjets []runtime.Object
+		wantErr          bool
+		wantDone         bool
+		wantUpdated      int
+		wantDeletedPod   string
 	}{
 		{
 			name: "rolling update is not needed",
-			args: args{
-				stsName:   "vmselect-sts",
-				ns:        "default",
-				c:         &config.BaseOperatorConf{},
-				podLabels: map[string]string{"app": "vmselect"},
+			predefinedObjets: []runtime.Object{
+				newSts("rev1", "rev1"),
+				newPod("vmselect-sts-0", "rev1", corev1.PodStatus{}, time.Now()),
+				newPod("vmselect-sts-1", "rev1", readyStatus, time.Now()),
 			},
+			wantDone:    true,
+			wantUpdated: 2,
+		},
+		{
+			name: "rolling update deletes one pod",
 			predefinedObjets: []runtime.Object{
-				&appsv1.StatefulSet{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect"},
-					},
-					Status: appsv1.StatefulSetStatus{
-						CurrentRevision: "rev1",
-						UpdateRevision:  "rev1",
-					},
-				},
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts-0",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect", podRevisionLabel: "rev1"},
-					},
-					Status: corev1.PodStatus{},
-				},
+				newSts("rev1", "rev2"),
+				newPod("vmselect-sts-0", "rev1", readyStatus, time.Now()),
+				newPod("vmselect-sts-1", "rev1", readyStatus, time.Now()),
 			},
+			wantDeletedPod: "vmselect-sts-0",
 		},
 		{
-			name: "rolling update is timeout",
-			args: args{
-				stsName: "vmselect-sts",
-				ns:      "default",
-				c: &config.BaseOperatorConf{
-					PodWaitReadyTimeout:       time.Second * 2,
-					PodWaitReadyInitDelay:     time.Millisecond,
-					PodWaitReadyIntervalCheck: time.Second,
-				},
-				podLabels: map[string]string{"app": "vmselect"},
+			name: "not ready pod is updated first",
+			predefinedObjets: []runtime.Object{
+				newSts("rev1", "rev2"),
+				newPod("vmselect-sts-0", "rev1", readyStatus, time.Now()),
+				newPod("vmselect-sts-1", "rev1", corev1.PodStatus{}, time.Now()),
 			},
+			wantDeletedPod: "vmselect-sts-1",
+		},
+		{
+			name: "rolling update waits for updated pod readiness",
 			predefinedObjets: []runtime.Object{
-				&appsv1.StatefulSet{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect"},
-					},
-					Status: appsv1.StatefulSetStatus{
-						CurrentRevision: "rev1",
-						UpdateRevision:  "rev2",
-					},
-				},
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts-0",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect", podRevisionLabel: "rev1"},
-					},
-					Status: corev1.PodStatus{},
-				},
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts-1",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect", podRevisionLabel: "rev2"},
-					},
-					Status: corev1.PodStatus{},
-				},
+				newSts("rev1", "rev2"),
+				newPod("vmselect-sts-0", "rev2", corev1.PodStatus{}, time.Now()),
+				newPod("vmselect-sts-1", "rev1", readyStatus, time.Now()),
 			},
-			wantErr: true,
+			wantUpdated: 1,
 		},
 		{
-			name: "rolling update is needed with update",
-			args: args{
-				stsName: "vmselect-sts",
-				ns:      "default",
-				c: &config.BaseOperatorConf{
-					PodWaitReadyTimeout:       time.Second * 2,
-					PodWaitReadyInitDelay:     time.Millisecond,
-					PodWaitReadyIntervalCheck: time.Second,
-				},
-				podLabels: map[string]string{"app": "vmselect"},
+			name: "rolling update waits for deleted pod recreation",
+			predefinedObjets: []runtime.Object{
+				newSts("rev1", "rev2"),
+				newPod("vmselect-sts-1", "rev1", readyStatus, time.Now()),
 			},
-
+		},
+		{
+			name: "rolling update is timeout",
 			predefinedObjets: []runtime.Object{
-				&appsv1.StatefulSet{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect"},
-					},
-					Status: appsv1.StatefulSetStatus{
-						CurrentRevision: "rev1",
-						UpdateRevision:  "rev2",
-					},
-				},
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts-0",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect", podRevisionLabel: "rev1"},
-					},
-					Status: corev1.PodStatus{},
-				},
-				&corev1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "vmselect-sts-1",
-						Namespace: "default",
-						Labels:    map[string]string{"app": "vmselect", podRevisionLabel: "rev2"},
-					},
-					Status: corev1.PodStatus{},
-				},
+				newSts("rev1", "rev2"),
+				newPod("vmselect-sts-0", "rev2", corev1.PodStatus{}, time.Now().Add(-time.Hour)),
+				newPod("vmselect-sts-1", "rev1", readyStatus, time.Now()),
 			},
-			updatePodRevByIndex: pointer.Int32Ptr(1),
-			neededPodRev:        "rev2",
-			wantErr:             false,
+			wantErr:     true,
+			wantUpdated: 1,
+		},
+		{
+			name: "rolling update is finished",
+			predefinedObjets: []runtime.Object{
+				newSts("rev1", "rev2"),
+				newPod("vmselect-sts-0", "rev2", readyStatus, time.Now()),
+				newPod("vmselect-sts-1", "rev2", readyStatus, time.Now()),
+			},
+			wantDone:    true,
+			wantUpdated: 2,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			obj := []runtime.Object{}
-			obj = append(obj, tt.predefinedObjets...)
-			fclient := fake.NewFakeClientWithScheme(testGetScheme(), obj...)
-			if tt.updatePodRevByIndex != nil {
-				podInd := obj[int(*tt.updatePodRevByIndex)]
-				pod := podInd.(*corev1.Pod)
-				go func(pod *corev1.Pod, rev string) {
-					time.Sleep(time.Millisecond * 1200)
-					pod.ObjectMeta.Labels[podRevisionLabel] = rev
-					err := fclient.Update(context.Background(), pod)
-					if err != nil {
-						t.Errorf("cannot update pod for rolling update check")
+			fclient := fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjets...)
+			got, err := performRollingUpdateOnSts(context.Background(), fclient, "vmselect-sts", "default", map[string]string{"app": "vmselect"}, c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("performRollingUpdateOnSts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.done != tt.wantDone || got.updated != tt.wantUpdated {
+				t.Errorf("performRollingUpdateOnSts() done = %v, updated = %d, want done = %v, updated = %d", got.done, got.updated, tt.wantDone, tt.wantUpdated)
+			}
+			podList := &corev1.PodList{}
+			if err := fclient.List(context.Background(), podList); err != nil {
+				t.Fatalf("cannot list pods: %v", err)
+			}
+			var deleted []string
+			for _, obj := range tt.predefinedObjets {
+				pod, ok := obj.(*corev1.Pod)
+				if !ok {
+					continue
+				}
+				var found bool
+				for _, p := range podList.Items {
+					if p.Name == pod.Name {
+						found = true
 					}
-				}(pod, tt.neededPodRev)
+				}
+				if !found {
+					deleted = append(deleted, pod.Name)
+				}
 			}
-
-			if err := performRollingUpdateOnSts(context.Background(), fclient, tt.args.stsName, tt.args.ns, tt.args.podLabels, tt.args.c); (err != nil) != tt.wantErr {
-				t.Errorf("performRollingUpdateOnSts() error = %v, wantErr %v", err, tt.wantErr)
+			var wantDeleted []string
+			if tt.wantDeletedPod != "" {
+				wantDeleted = []string{tt.wantDeletedPod}
+			}
+			if !reflect.DeepEqual(deleted, wantDeleted) {
+				t.Errorf("performRollingUpdateOnSts() deleted pods = %v, want %v", deleted, wantDeleted)
 			}
 		})
 	}
@@ -525,7 +425,7 @@ func Test_genVMStorageService(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := genVMStorageService(tt.args.cr, tt.args.c)
+			got := genVMStorageService(tt.args.cr, "", tt.args.c)
 
 			if !reflect.DeepEqual(got.Labels, tt.want.Labels) || got.Name != tt.want.Name {
 				t.Errorf("genVMStorageService() = %v, want %v", got, tt.want)
@@ -578,7 +478,7 @@ func TestCreateOrUpdateVMCluster(t *testing.T) {
 			obj := []runtime.Object{}
 			obj = append(obj, tt.predefinedObjects...)
 			fclient := fake.NewFakeClientWithScheme(testGetScheme(), obj...)
-			got, err := CreateOrUpdateVMCluster(context.TODO(), tt.args.cr, fclient, tt.args.c)
+			got, err := CreateOrUpdateVMCluster(context.TODO(), tt.args.cr, fclient, tt.args.c, record.NewFakeRecorder(10))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateOrUpdateVMCluster() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -589,3 +489,59 @@ func TestCreateOrUpdateVMCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateOrUpdateVMCluster_pausedComponent(t *testing.T) {
+	cr := &v1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-1", Generation: 2},
+		Spec: v1beta1.VMClusterSpec{
+			RetentionPeriod: "2",
+			VMSelect:        &v1beta1.VMSelect{ReplicaCount: pointer.Int32Ptr(1)},
+			VMInsert:        &v1beta1.VMInsert{ReplicaCount: pointer.Int32Ptr(1)},
+		},
+		Status: v1beta1.VMClusterStatus{
+			VMSelect: &v1beta1.VMClusterComponentStatus{RollbackGeneration: 2, LastGoodRevision: "rev-1"},
+		},
+	}
+	readyPod := func(name string, podLabels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: podLabels},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: "True"}},
+			},
+		}
+	}
+	rolledBackSts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: cr.Spec.VMSelect.GetNameWithPrefix(cr.Name), Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "vmselect", Image: "rolled-back:v1"}}},
+			},
+		},
+	}
+	fclient := fake.NewFakeClientWithScheme(testGetScheme(), cr.DeepCopy(), rolledBackSts,
+		readyPod("vmselect-0", cr.VMSelectSelectorLabels()), readyPod("vminsert-0", cr.VMInsertSelectorLabels()))
+	got, err := CreateOrUpdateVMCluster(context.TODO(), cr, fclient, config.MustGetBaseConfig(), record.NewFakeRecorder(10))
+	if err != nil {
+		t.Fatalf("CreateOrUpdateVMCluster() unexpected error: %v", err)
+	}
+	if got != v1beta1.ClusterStatusFailed {
+		t.Errorf("CreateOrUpdateVMCluster() got = %v, want %v", got, v1beta1.ClusterStatusFailed)
+	}
+	if !strings.Contains(cr.Status.Reason, "vmSelect update was rolled back") {
+		t.Errorf("unexpected reason: %s", cr.Status.Reason)
+	}
+	var sts appsv1.StatefulSet
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: rolledBackSts.Name}, &sts); err != nil {
+		t.Fatalf("cannot get vmselect sts: %v", err)
+	}
+	if image := sts.Spec.Template.Spec.Containers[0].Image; image != "rolled-back:v1" {
+		t.Errorf("pod template of paused component must not be updated, got image: %s", image)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: cr.Spec.VMSelect.GetNameWithPrefix(cr.Name)}, &corev1.Service{}); err != nil {
+		t.Errorf("service of paused component must be reconciled: %v", err)
+	}
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: cr.Spec.VMInsert.GetNameWithPrefix(cr.Name)}, &appsv1.Deployment{}); err != nil {
+		t.Errorf("component after paused one must be reconciled: %v", err)
+	}
+}
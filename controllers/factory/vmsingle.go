@@ -115,6 +115,9 @@ func CreateOrUpdateVMSingle(ctx context.Context, cr *victoriametricsv1beta1.VMSi
 	if err != nil {
 		return nil, fmt.Errorf("cannot upddate vmsingle deploy: %w", err)
 	}
+	if err := reconcilePDB(ctx, rclient, cr.Spec.PodDisruptionBudget, cr.SelectorLabels(), newDeploy.ObjectMeta); err != nil {
+		return nil, fmt.Errorf("cannot reconcile pdb for vmsingle: %w", err)
+	}
 	l.Info("single deploy reconciled")
 
 	return newDeploy, nil
@@ -208,7 +211,7 @@ func makeSpecForVMSingle(cr *victoriametricsv1beta1.VMSingle, c *config.BaseOper
 		args = append(args, fmt.Sprintf("-loggerFormat=%s", cr.Spec.LogFormat))
 	}
 
-	for arg, value := range cr.Spec.ExtraArgs {
+	for arg, value := range victoriametricsv1beta1.ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress) {
 		args = append(args, fmt.Sprintf("--%s=%s", arg, value))
 	}
 
@@ -344,7 +347,7 @@ func makeSpecForVMSingle(cr *victoriametricsv1beta1.VMSingle, c *config.BaseOper
 	}, additionalContainers...)
 
 	if cr.Spec.VMBackup != nil {
-		vmBackuper, err := makeSpecForVMBackuper(cr.Spec.VMBackup, c, cr.Spec.Port, vmDataVolumeName, cr.Spec.ExtraArgs)
+		vmBackuper, err := makeSpecForVMBackuper(cr.Spec.VMBackup, c, cr.Spec.Port, vmDataVolumeName, victoriametricsv1beta1.ExtraArgsWithIngressPrefix(cr.Spec.ExtraArgs, cr.Spec.Ingress))
 		if err != nil {
 			return nil, err
 		}
@@ -382,36 +385,17 @@ func makeSpecForVMSingle(cr *victoriametricsv1beta1.VMSingle, c *config.BaseOper
 }
 
 func CreateOrUpdateVMSingleService(ctx context.Context, cr *victoriametricsv1beta1.VMSingle, rclient client.Client, c *config.BaseOperatorConf) (*corev1.Service, error) {
-	l := log.WithValues("controller", "vmalert.service.crud")
 	newService := newServiceVMSingle(cr, c)
-
-	currentService := &corev1.Service{}
-	err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: newService.Name}, currentService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			l.Info("creating new service for vm vmsingle")
-			err := rclient.Create(ctx, newService)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create new service for vmsingle")
-			}
-		} else {
-			return nil, fmt.Errorf("cannot get vmsingle service: %w", err)
-		}
-	}
-	for annotation, value := range currentService.Annotations {
-		newService.Annotations[annotation] = value
+	if err := reconcileExtraServices(ctx, rclient, newService, cr.Spec.ExtraServices); err != nil {
+		return nil, fmt.Errorf("cannot reconcile additional services for vmsingle: %w", err)
 	}
-	if currentService.Spec.ClusterIP != "" {
-		newService.Spec.ClusterIP = currentService.Spec.ClusterIP
+	applyServiceSpec(newService, cr.Spec.ServiceSpec)
+	if err := reconcileService(ctx, rclient, newService); err != nil {
+		return nil, fmt.Errorf("cannot reconcile service for vmsingle: %w", err)
 	}
-	if currentService.ResourceVersion != "" {
-		newService.ResourceVersion = currentService.ResourceVersion
-	}
-	err = rclient.Update(ctx, newService)
-	if err != nil {
-		return nil, fmt.Errorf("cannot update vmsingle service: %w", err)
+	if err := reconcileIngress(ctx, rclient, cr.Spec.Ingress, newService, "http"); err != nil {
+		return nil, fmt.Errorf("cannot reconcile ingress for vmsingle: %w", err)
 	}
-	l.Info("vmsingle svc reconciled")
 	return newService, nil
 }
 
@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"github.com/VictoriaMetrics/operator/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// namespacesFilter skips events for objects from namespaces, which are not watched by operator.
+// Cache is already restricted by allow list, but deny list must be checked for each event.
+func namespacesFilter(c *config.BaseOperatorConf) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(meta metav1.Object, _ runtime.Object) bool {
+		return c.Namespaces.IsAllowed(meta.GetNamespace())
+	})
+}
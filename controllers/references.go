@@ -0,0 +1,451 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// secretsIndexKey indexes objects by names of Secrets, referenced at spec.
+	secretsIndexKey = "spec.referencedSecrets"
+	// configMapsIndexKey indexes objects by names of ConfigMaps, referenced at spec.
+	configMapsIndexKey = "spec.referencedConfigMaps"
+)
+
+var referencesLog = ctrl.Log.WithName("controllers").WithName("references")
+
+var (
+	secretsResource    = v1.SchemeGroupVersion.WithResource("secrets")
+	configMapsResource = v1.SchemeGroupVersion.WithResource("configmaps")
+)
+
+// metadataInformers are metadata-only informers of Secrets and ConfigMaps at watched namespaces,
+// which are shared by controllers of manager.
+// Only metadata of objects is cached, their content is read directly from api server during reconcile.
+type metadataInformers struct {
+	factories []metadatainformer.SharedInformerFactory
+}
+
+var (
+	metadataInformersLock    sync.Mutex
+	managerMetadataInformers = map[ctrl.Manager]*metadataInformers{}
+)
+
+// getMetadataInformers returns metadata informers of manager, informers are started with manager.
+func getMetadataInformers(mgr ctrl.Manager) (*metadataInformers, error) {
+	metadataInformersLock.Lock()
+	defer metadataInformersLock.Unlock()
+	if mi, ok := managerMetadataInformers[mgr]; ok {
+		return mi, nil
+	}
+	mclient, err := metadata.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("cannot create metadata client: %w", err)
+	}
+	namespaces := config.MustGetBaseConfig().Namespaces.Allowed()
+	if len(namespaces) == 0 {
+		namespaces = []string{v1.NamespaceAll}
+	}
+	mi := &metadataInformers{}
+	for _, ns := range namespaces {
+		mi.factories = append(mi.factories, metadatainformer.NewFilteredSharedInformerFactory(mclient, 0, ns, nil))
+	}
+	// factory starts only informers, requested before start, controllers request them at setup.
+	if err := mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		for _, f := range mi.factories {
+			f.Start(stop)
+		}
+		<-stop
+		return nil
+	})); err != nil {
+		return nil, fmt.Errorf("cannot add metadata informers to manager: %w", err)
+	}
+	managerMetadataInformers[mgr] = mi
+	return mi, nil
+}
+
+// watches adds watches of resource metadata at watched namespaces to the controller builder.
+func (mi *metadataInformers) watches(bld *builder.Builder, resource schema.GroupVersionResource, h handler.EventHandler) *builder.Builder {
+	for _, f := range mi.factories {
+		bld = bld.Watches(&source.Informer{Informer: f.ForResource(resource).Informer()}, h)
+	}
+	return bld
+}
+
+// references collects names of Secrets and ConfigMaps referenced by object.
+// Objects can reference only Secrets and ConfigMaps from own namespace.
+type references struct {
+	secrets    map[string]struct{}
+	configMaps map[string]struct{}
+}
+
+func newReferences() *references {
+	return &references{
+		secrets:    map[string]struct{}{},
+		configMaps: map[string]struct{}{},
+	}
+}
+
+func (r *references) addSecret(name string) {
+	if name != "" {
+		r.secrets[name] = struct{}{}
+	}
+}
+
+func (r *references) addConfigMap(name string) {
+	if name != "" {
+		r.configMaps[name] = struct{}{}
+	}
+}
+
+func (r *references) addSecretSelector(sel *v1.SecretKeySelector) {
+	if sel != nil {
+		r.addSecret(sel.Name)
+	}
+}
+
+func (r *references) addConfigMapSelector(sel *v1.ConfigMapKeySelector) {
+	if sel != nil {
+		r.addConfigMap(sel.Name)
+	}
+}
+
+func (r *references) addBasicAuth(ba *victoriametricsv1beta1.BasicAuth) {
+	if ba == nil {
+		return
+	}
+	r.addSecret(ba.Username.Name)
+	r.addSecret(ba.Password.Name)
+}
+
+func (r *references) addSecretOrConfigMap(sc victoriametricsv1beta1.SecretOrConfigMap) {
+	r.addSecretSelector(sc.Secret)
+	r.addConfigMapSelector(sc.ConfigMap)
+}
+
+func (r *references) addTLSConfig(tlsConfig *victoriametricsv1beta1.TLSConfig) {
+	if tlsConfig == nil {
+		return
+	}
+	r.addSecretOrConfigMap(tlsConfig.CA)
+	r.addSecretOrConfigMap(tlsConfig.Cert)
+	r.addSecretSelector(tlsConfig.KeySecret)
+}
+
+func (r *references) addVolumes(secrets, configMaps []string) {
+	for _, s := range secrets {
+		r.addSecret(s)
+	}
+	for _, cm := range configMaps {
+		r.addConfigMap(cm)
+	}
+}
+
+func (r *references) addVMBackup(vmb *victoriametricsv1beta1.VMBackup) {
+	if vmb != nil {
+		r.addSecretSelector(vmb.CredentialsSecret)
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func vmAgentReferences(cr *victoriametricsv1beta1.VMAgent) *references {
+	refs := newReferences()
+	refs.addVolumes(cr.Spec.Secrets, cr.Spec.ConfigMaps)
+	refs.addSecretSelector(cr.Spec.AdditionalScrapeConfigs)
+	refs.addConfigMapSelector(cr.Spec.RelabelConfig)
+	if cr.Spec.APIServerConfig != nil {
+		refs.addBasicAuth(cr.Spec.APIServerConfig.BasicAuth)
+		refs.addTLSConfig(cr.Spec.APIServerConfig.TLSConfig)
+	}
+	for _, rw := range cr.Spec.RemoteWrite {
+		refs.addBasicAuth(rw.BasicAuth)
+		refs.addSecretSelector(rw.BearerTokenSecret)
+		refs.addConfigMapSelector(rw.UrlRelabelConfig)
+		refs.addTLSConfig(rw.TLSConfig)
+	}
+	return refs
+}
+
+func vmAlertReferences(cr *victoriametricsv1beta1.VMAlert) *references {
+	refs := newReferences()
+	refs.addVolumes(cr.Spec.Secrets, cr.Spec.ConfigMaps)
+	refs.addBasicAuth(cr.Spec.Datasource.BasicAuth)
+	refs.addTLSConfig(cr.Spec.Datasource.TLSConfig)
+	refs.addBasicAuth(cr.Spec.Notifier.BasicAuth)
+	refs.addTLSConfig(cr.Spec.Notifier.TLSConfig)
+	if cr.Spec.RemoteWrite != nil {
+		refs.addBasicAuth(cr.Spec.RemoteWrite.BasicAuth)
+		refs.addTLSConfig(cr.Spec.RemoteWrite.TLSConfig)
+	}
+	if cr.Spec.RemoteRead != nil {
+		refs.addBasicAuth(cr.Spec.RemoteRead.BasicAuth)
+		refs.addTLSConfig(cr.Spec.RemoteRead.TLSConfig)
+	}
+	return refs
+}
+
+func vmSingleReferences(cr *victoriametricsv1beta1.VMSingle) *references {
+	refs := newReferences()
+	refs.addVolumes(cr.Spec.Secrets, cr.Spec.ConfigMaps)
+	refs.addVMBackup(cr.Spec.VMBackup)
+	return refs
+}
+
+func vmClusterReferences(cr *victoriametricsv1beta1.VMCluster) *references {
+	refs := newReferences()
+	if cr.Spec.VMSelect != nil {
+		refs.addVolumes(cr.Spec.VMSelect.Secrets, cr.Spec.VMSelect.ConfigMaps)
+	}
+	if cr.Spec.VMInsert != nil {
+		refs.addVolumes(cr.Spec.VMInsert.Secrets, cr.Spec.VMInsert.ConfigMaps)
+	}
+	if cr.Spec.VMStorage != nil {
+		refs.addVolumes(cr.Spec.VMStorage.Secrets, cr.Spec.VMStorage.ConfigMaps)
+		refs.addVMBackup(cr.Spec.VMStorage.VMBackup)
+	}
+	return refs
+}
+
+func vmAlertmanagerReferences(cr *victoriametricsv1beta1.VMAlertmanager) *references {
+	refs := newReferences()
+	refs.addVolumes(cr.Spec.Secrets, cr.Spec.ConfigMaps)
+	// config secret with prefixed name is managed by operator
+	if cr.Spec.ConfigSecret != cr.PrefixedName() {
+		refs.addSecret(cr.Spec.ConfigSecret)
+	}
+	return refs
+}
+
+func vmServiceScrapeReferences(cr *victoriametricsv1beta1.VMServiceScrape) *references {
+	refs := newReferences()
+	for i := range cr.Spec.Endpoints {
+		ep := &cr.Spec.Endpoints[i]
+		refs.addBasicAuth(ep.BasicAuth)
+		refs.addSecretSelector(&ep.BearerTokenSecret)
+		refs.addTLSConfig(ep.TLSConfig)
+	}
+	return refs
+}
+
+func vmUserReferences(cr *victoriametricsv1beta1.VMUser) *references {
+	refs := newReferences()
+	refs.addSecretSelector(cr.Spec.PasswordRef)
+	refs.addSecretSelector(cr.Spec.BearerTokenRef)
+	return refs
+}
+
+func vmAlertmanagerConfigReferences(cr *victoriametricsv1beta1.VMAlertmanagerConfig) *references {
+	refs := newReferences()
+	for _, receiver := range cr.Spec.Receivers {
+		for _, email := range receiver.EmailConfigs {
+			refs.addSecretSelector(email.AuthPassword)
+			refs.addSecretSelector(email.AuthSecret)
+		}
+		for _, slack := range receiver.SlackConfigs {
+			refs.addSecretSelector(slack.APIURL)
+		}
+		for _, pagerDuty := range receiver.PagerDutyConfigs {
+			refs.addSecretSelector(pagerDuty.RoutingKey)
+			refs.addSecretSelector(pagerDuty.ServiceKey)
+		}
+		for _, webhook := range receiver.WebhookConfigs {
+			refs.addSecretSelector(webhook.URLSecret)
+			if webhook.HTTPConfig != nil {
+				refs.addBasicAuth(webhook.HTTPConfig.BasicAuth)
+				refs.addSecretSelector(webhook.HTTPConfig.BearerTokenSecret)
+			}
+		}
+	}
+	return refs
+}
+
+// objectReferences returns references for supported objects.
+func objectReferences(obj runtime.Object) *references {
+	switch cr := obj.(type) {
+	case *victoriametricsv1beta1.VMAgent:
+		return vmAgentReferences(cr)
+	case *victoriametricsv1beta1.VMAlert:
+		return vmAlertReferences(cr)
+	case *victoriametricsv1beta1.VMSingle:
+		return vmSingleReferences(cr)
+	case *victoriametricsv1beta1.VMCluster:
+		return vmClusterReferences(cr)
+	case *victoriametricsv1beta1.VMAlertmanager:
+		return vmAlertmanagerReferences(cr)
+	case *victoriametricsv1beta1.VMServiceScrape:
+		return vmServiceScrapeReferences(cr)
+	case *victoriametricsv1beta1.VMUser:
+		return vmUserReferences(cr)
+	case *victoriametricsv1beta1.VMAlertmanagerConfig:
+		return vmAlertmanagerConfigReferences(cr)
+	default:
+		return newReferences()
+	}
+}
+
+// indexReferences registers indexes of Secrets and ConfigMaps referenced by given object type.
+func indexReferences(mgr ctrl.Manager, obj runtime.Object) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), obj, secretsIndexKey, func(o runtime.Object) []string {
+		return sortedKeys(objectReferences(o).secrets)
+	}); err != nil {
+		return err
+	}
+	return mgr.GetFieldIndexer().IndexField(context.Background(), obj, configMapsIndexKey, func(o runtime.Object) []string {
+		return sortedKeys(objectReferences(o).configMaps)
+	})
+}
+
+// listReferencingObjects returns objects, that reference changed Secret or ConfigMap by index.
+func listReferencingObjects(ctx context.Context, rclient client.Client, indexKey string, newList func() runtime.Object, changed metav1.Object) ([]metav1.Object, error) {
+	list := newList()
+	if err := rclient.List(ctx, list,
+		client.InNamespace(changed.GetNamespace()),
+		client.MatchingFields{indexKey: changed.GetName()}); err != nil {
+		return nil, fmt.Errorf("cannot list objects referencing: %s/%s, err: %w", changed.GetNamespace(), changed.GetName(), err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract list items: %w", err)
+	}
+	objs := make([]metav1.Object, 0, len(items))
+	for _, item := range items {
+		m, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		objs = append(objs, m)
+	}
+	return objs, nil
+}
+
+// enqueueReferencingObjects returns handler, which enqueues objects,
+// that reference changed Secret or ConfigMap by index.
+func enqueueReferencingObjects(rclient client.Client, indexKey string, newList func() runtime.Object) handler.EventHandler {
+	return &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			objs, err := listReferencingObjects(context.Background(), rclient, indexKey, newList, o.Meta)
+			if err != nil {
+				referencesLog.Error(err, "cannot list objects referencing changed object", "name", o.Meta.GetName(), "namespace", o.Meta.GetNamespace())
+				return nil
+			}
+			requests := make([]reconcile.Request, 0, len(objs))
+			for _, m := range objs {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.GetNamespace(), Name: m.GetName()}})
+			}
+			return requests
+		}),
+	}
+}
+
+// watchReferences indexes Secrets and ConfigMaps referenced by obj
+// and adds metadata-only watches for them to the controller builder.
+// Changes of referenced objects trigger reconcile only for objects, that reference them.
+func watchReferences(mgr ctrl.Manager, bld *builder.Builder, obj runtime.Object, newList func() runtime.Object) (*builder.Builder, error) {
+	if err := indexReferences(mgr, obj); err != nil {
+		return nil, fmt.Errorf("cannot index references for %T: %w", obj, err)
+	}
+	mi, err := getMetadataInformers(mgr)
+	if err != nil {
+		return nil, err
+	}
+	bld = mi.watches(bld, secretsResource, enqueueReferencingObjects(mgr.GetClient(), secretsIndexKey, newList))
+	return mi.watches(bld, configMapsResource, enqueueReferencingObjects(mgr.GetClient(), configMapsIndexKey, newList)), nil
+}
+
+// selectedReferencesHandler enqueues objects, which select objects referencing changed Secret or ConfigMap,
+// for instance VMAgents, which select VMServiceScrape with basic auth Secret.
+type selectedReferencesHandler struct {
+	rclient   client.Client
+	indexKey  string
+	newList   func() runtime.Object
+	selectors *selectorsHandler
+}
+
+var _ handler.EventHandler = &selectedReferencesHandler{}
+
+// Create implements handler.EventHandler
+func (h *selectedReferencesHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(q, e.Meta)
+}
+
+// Update implements handler.EventHandler
+func (h *selectedReferencesHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(q, e.MetaNew)
+}
+
+// Delete implements handler.EventHandler
+func (h *selectedReferencesHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(q, e.Meta)
+}
+
+// Generic implements handler.EventHandler
+func (h *selectedReferencesHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(q, e.Meta)
+}
+
+func (h *selectedReferencesHandler) enqueue(q workqueue.RateLimitingInterface, changed metav1.Object) {
+	objs, err := listReferencingObjects(context.Background(), h.rclient, h.indexKey, h.newList, changed)
+	if err != nil {
+		referencesLog.Error(err, "cannot list objects referencing changed object", "name", changed.GetName(), "namespace", changed.GetNamespace())
+		return
+	}
+	if len(objs) == 0 {
+		return
+	}
+	h.selectors.enqueue(q, objs...)
+}
+
+// watchSelectedReferences indexes Secrets and ConfigMaps referenced by obj, which is selected with selectors,
+// and adds metadata-only watches for them to the controller builder.
+// Changes of referenced objects trigger reconcile only for objects, that select referencing objects.
+func watchSelectedReferences(mgr ctrl.Manager, bld *builder.Builder, obj runtime.Object, newList func() runtime.Object, selectors *selectorsHandler) (*builder.Builder, error) {
+	if err := indexReferences(mgr, obj); err != nil {
+		return nil, fmt.Errorf("cannot index references for %T: %w", obj, err)
+	}
+	mi, err := getMetadataInformers(mgr)
+	if err != nil {
+		return nil, err
+	}
+	bld = mi.watches(bld, secretsResource, &selectedReferencesHandler{
+		rclient:   mgr.GetClient(),
+		indexKey:  secretsIndexKey,
+		newList:   newList,
+		selectors: selectors,
+	})
+	return mi.watches(bld, configMapsResource, &selectedReferencesHandler{
+		rclient:   mgr.GetClient(),
+		indexKey:  configMapsIndexKey,
+		newList:   newList,
+		selectors: selectors,
+	}), nil
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func secretSelector(name, key string) *v1.SecretKeySelector {
+	return &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: name}, Key: key}
+}
+
+func Test_objectReferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		obj            runtime.Object
+		wantSecrets    []string
+		wantConfigMaps []string
+	}{
+		{
+			name: "vmagent with remote write auth",
+			obj: &victoriametricsv1beta1.VMAgent{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMAgentSpec{
+					Secrets:                 []string{"extra-secret"},
+					ConfigMaps:              []string{"extra-cm"},
+					AdditionalScrapeConfigs: secretSelector("additional-scrape", "config.yaml"),
+					RelabelConfig: &v1.ConfigMapKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "relabel"},
+						Key:                  "relabel.yaml",
+					},
+					RemoteWrite: []victoriametricsv1beta1.VMAgentRemoteWriteSpec{
+						{
+							URL: "http://vmsingle:8428",
+							BasicAuth: &victoriametricsv1beta1.BasicAuth{
+								Username: *secretSelector("rw-auth", "username"),
+								Password: *secretSelector("rw-auth", "password"),
+							},
+						},
+						{
+							URL:               "https://vmsingle-2:8428",
+							BearerTokenSecret: secretSelector("rw-token", "token"),
+							TLSConfig: &victoriametricsv1beta1.TLSConfig{
+								CA: victoriametricsv1beta1.SecretOrConfigMap{
+									ConfigMap: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "rw-ca"}, Key: "ca"},
+								},
+								KeySecret: secretSelector("rw-tls", "key"),
+							},
+						},
+					},
+				},
+			},
+			wantSecrets:    []string{"additional-scrape", "extra-secret", "rw-auth", "rw-tls", "rw-token"},
+			wantConfigMaps: []string{"extra-cm", "relabel", "rw-ca"},
+		},
+		{
+			name: "vmalert with datasource and notifier auth",
+			obj: &victoriametricsv1beta1.VMAlert{
+				ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMAlertSpec{
+					Datasource: victoriametricsv1beta1.VMAlertDatasourceSpec{
+						URL: "http://vmsingle:8428",
+						BasicAuth: &victoriametricsv1beta1.BasicAuth{
+							Username: *secretSelector("ds-auth", "username"),
+							Password: *secretSelector("ds-auth", "password"),
+						},
+					},
+					Notifier: victoriametricsv1beta1.VMAlertNotifierSpec{
+						URL: "http://alertmanager:9093",
+						TLSConfig: &victoriametricsv1beta1.TLSConfig{
+							Cert: victoriametricsv1beta1.SecretOrConfigMap{Secret: secretSelector("notifier-tls", "cert")},
+						},
+					},
+				},
+			},
+			wantSecrets:    []string{"ds-auth", "notifier-tls"},
+			wantConfigMaps: []string{},
+		},
+		{
+			name: "vmsingle with backup credentials",
+			obj: &victoriametricsv1beta1.VMSingle{
+				ObjectMeta: metav1.ObjectMeta{Name: "single", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMSingleSpec{
+					VMBackup: &victoriametricsv1beta1.VMBackup{CredentialsSecret: secretSelector("backup-creds", "credentials")},
+				},
+			},
+			wantSecrets:    []string{"backup-creds"},
+			wantConfigMaps: []string{},
+		},
+		{
+			name: "vmcluster with components volumes",
+			obj: &victoriametricsv1beta1.VMCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMClusterSpec{
+					VMSelect: &victoriametricsv1beta1.VMSelect{ConfigMaps: []string{"select-cm"}},
+					VMStorage: &victoriametricsv1beta1.VMStorage{
+						Secrets:  []string{"storage-secret"},
+						VMBackup: &victoriametricsv1beta1.VMBackup{CredentialsSecret: secretSelector("backup-creds", "credentials")},
+					},
+				},
+			},
+			wantSecrets:    []string{"backup-creds", "storage-secret"},
+			wantConfigMaps: []string{"select-cm"},
+		},
+		{
+			name: "vmalertmanager with user config secret",
+			obj: &victoriametricsv1beta1.VMAlertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "default"},
+				Spec:       victoriametricsv1beta1.VMAlertmanagerSpec{ConfigSecret: "am-config"},
+			},
+			wantSecrets:    []string{"am-config"},
+			wantConfigMaps: []string{},
+		},
+		{
+			name: "vmalertmanager with operator managed config secret",
+			obj: &victoriametricsv1beta1.VMAlertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "default"},
+				Spec:       victoriametricsv1beta1.VMAlertmanagerSpec{ConfigSecret: "vmalertmanager-am"},
+			},
+			wantSecrets:    []string{},
+			wantConfigMaps: []string{},
+		},
+		{
+			name: "vmservicescrape with endpoints auth",
+			obj: &victoriametricsv1beta1.VMServiceScrape{
+				ObjectMeta: metav1.ObjectMeta{Name: "scrape", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMServiceScrapeSpec{
+					Endpoints: []victoriametricsv1beta1.Endpoint{
+						{
+							Port: "http",
+							BasicAuth: &victoriametricsv1beta1.BasicAuth{
+								Username: *secretSelector("scrape-auth", "username"),
+								Password: *secretSelector("scrape-auth", "password"),
+							},
+						},
+						{
+							Port:              "https",
+							BearerTokenSecret: *secretSelector("scrape-token", "token"),
+							TLSConfig: &victoriametricsv1beta1.TLSConfig{
+								CA: victoriametricsv1beta1.SecretOrConfigMap{ConfigMap: &v1.ConfigMapKeySelector{
+									LocalObjectReference: v1.LocalObjectReference{Name: "scrape-ca"},
+									Key:                  "ca.crt",
+								}},
+								KeySecret: secretSelector("scrape-tls", "tls.key"),
+							},
+						},
+					},
+				},
+			},
+			wantSecrets:    []string{"scrape-auth", "scrape-tls", "scrape-token"},
+			wantConfigMaps: []string{"scrape-ca"},
+		},
+		{
+			name: "vmuser with password",
+			obj: &victoriametricsv1beta1.VMUser{
+				ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: "default"},
+				Spec:       victoriametricsv1beta1.VMUserSpec{PasswordRef: secretSelector("user-password", "password")},
+			},
+			wantSecrets:    []string{"user-password"},
+			wantConfigMaps: []string{},
+		},
+		{
+			name: "vmalertmanagerconfig with receivers credentials",
+			obj: &victoriametricsv1beta1.VMAlertmanagerConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"},
+				Spec: victoriametricsv1beta1.VMAlertmanagerConfigSpec{
+					Receivers: []victoriametricsv1beta1.Receiver{
+						{
+							Name:         "slack",
+							SlackConfigs: []victoriametricsv1beta1.SlackConfig{{APIURL: secretSelector("slack-url", "url")}},
+						},
+						{
+							Name: "webhook",
+							WebhookConfigs: []victoriametricsv1beta1.WebhookConfig{{
+								URLSecret: secretSelector("webhook-url", "url"),
+								HTTPConfig: &victoriametricsv1beta1.HTTPConfig{
+									BearerTokenSecret: secretSelector("webhook-token", "token"),
+								},
+							}},
+						},
+					},
+				},
+			},
+			wantSecrets:    []string{"slack-url", "webhook-token", "webhook-url"},
+			wantConfigMaps: []string{},
+		},
+		{
+			name:           "unsupported object",
+			obj:            &v1.Secret{},
+			wantSecrets:    []string{},
+			wantConfigMaps: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs := objectReferences(tt.obj)
+			if got := sortedKeys(refs.secrets); !reflect.DeepEqual(got, tt.wantSecrets) {
+				t.Errorf("objectReferences() secrets = %v, want %v", got, tt.wantSecrets)
+			}
+			if got := sortedKeys(refs.configMaps); !reflect.DeepEqual(got, tt.wantConfigMaps) {
+				t.Errorf("objectReferences() configMaps = %v, want %v", got, tt.wantConfigMaps)
+			}
+		})
+	}
+}
+
+func Test_selectedReferencesHandler(t *testing.T) {
+	predefinedObjects := []runtime.Object{
+		&victoriametricsv1beta1.VMServiceScrape{
+			ObjectMeta: metav1.ObjectMeta{Name: "scrape", Namespace: "default", Labels: map[string]string{"team": "a"}},
+			Spec: victoriametricsv1beta1.VMServiceScrapeSpec{
+				Endpoints: []victoriametricsv1beta1.Endpoint{{Port: "http", BearerTokenSecret: *secretSelector("scrape-token", "token")}},
+			},
+		},
+		&victoriametricsv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "selecting", Namespace: "default"},
+			Spec: victoriametricsv1beta1.VMAgentSpec{
+				ServiceScrapeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			},
+		},
+		&victoriametricsv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-selecting", Namespace: "default"},
+			Spec: victoriametricsv1beta1.VMAgentSpec{
+				ServiceScrapeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+			},
+		},
+	}
+	rclient := fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...)
+	h := &selectedReferencesHandler{
+		rclient:  rclient,
+		indexKey: secretsIndexKey,
+		newList: func() runtime.Object {
+			return &victoriametricsv1beta1.VMServiceScrapeList{}
+		},
+		selectors: &selectorsHandler{
+			rclient:       rclient,
+			log:           ctrl.Log,
+			listSelectors: vmAgentSelectors(serviceScrapeSelectors),
+		},
+	}
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "scrape-token", Namespace: "default"}}
+	h.Update(event.UpdateEvent{MetaOld: secret, ObjectOld: secret, MetaNew: secret, ObjectNew: secret}, q)
+
+	want := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "selecting"}}
+	if q.Len() != 1 {
+		t.Fatalf("selectedReferencesHandler must enqueue only selecting vmagent, got %d requests", q.Len())
+	}
+	if got, _ := q.Get(); got != want {
+		t.Errorf("selectedReferencesHandler enqueued %v, want %v", got, want)
+	}
+}
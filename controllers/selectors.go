@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// objectSelectors are namespace and object selectors of object, which selects other objects,
+// for instance VMAgent selectors of VMServiceScrapes.
+type objectSelectors struct {
+	name       types.NamespacedName
+	nsSelector *metav1.LabelSelector
+	selector   *metav1.LabelSelector
+}
+
+// listSelectorsFunc returns selectors of all objects, which may select changed object.
+type listSelectorsFunc func(ctx context.Context, rclient client.Client) ([]objectSelectors, error)
+
+// selectorsHandler enqueues objects, which select changed object with label selectors.
+// Requests are added with delay, so burst of changes
+// results in single reconcile for each selecting object.
+type selectorsHandler struct {
+	rclient       client.Client
+	log           logr.Logger
+	debounce      time.Duration
+	listSelectors listSelectorsFunc
+	// namespaces watched by operator
+	namespaces config.Namespaces
+}
+
+var _ handler.EventHandler = &selectorsHandler{}
+
+// Create implements handler.EventHandler
+func (h *selectorsHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(q, e.Meta)
+}
+
+// Update implements handler.EventHandler
+func (h *selectorsHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	if e.MetaOld.GetGeneration() == e.MetaNew.GetGeneration() &&
+		reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels()) {
+		return
+	}
+	// labels could be changed, objects selected old object must be updated as well.
+	h.enqueue(q, e.MetaOld, e.MetaNew)
+}
+
+// Delete implements handler.EventHandler
+func (h *selectorsHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(q, e.Meta)
+}
+
+// Generic implements handler.EventHandler
+func (h *selectorsHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(q, e.Meta)
+}
+
+func (h *selectorsHandler) enqueue(q workqueue.RateLimitingInterface, objs ...metav1.Object) {
+	ctx := context.Background()
+	requests, err := h.matched(ctx, objs...)
+	if err != nil {
+		h.log.Error(err, "cannot select objects for changed object")
+		return
+	}
+	for _, req := range requests {
+		q.AddAfter(req, h.debounce)
+	}
+}
+
+// matched returns requests for objects, which select at least one of given objects.
+func (h *selectorsHandler) matched(ctx context.Context, objs ...metav1.Object) ([]reconcile.Request, error) {
+	selectors, err := h.listSelectors(ctx, h.rclient)
+	if err != nil {
+		return nil, err
+	}
+	// namespaces labels are shared between selecting objects
+	nsLabels := map[string]labels.Set{}
+	var requests []reconcile.Request
+	for _, s := range selectors {
+		for _, obj := range objs {
+			matched, err := isSelectorsMatches(ctx, h.rclient, h.namespaces, s.name.Namespace, s.nsSelector, s.selector, obj, nsLabels)
+			if err != nil {
+				// error at one object selectors must not block others
+				h.log.Error(err, "cannot match changed object", "name", s.name.Name, "namespace", s.name.Namespace, "object", obj.GetName())
+				continue
+			}
+			if matched {
+				requests = append(requests, reconcile.Request{NamespacedName: s.name})
+				break
+			}
+		}
+	}
+	return requests, nil
+}
+
+// isSelectorsMatches checks if object is selected by the given selectors
+// with the same rules as factory.SelectServiceScrapes:
+// nil namespace selector matches only own namespace, empty namespace selector matches any namespace,
+// object selector defaults to match all, if namespace selector is set.
+// Labels of namespaces are cached at nsLabels, nil labels mean that operator has no permissions to get namespace,
+// in this case namespace selector matches any of watched namespaces.
+func isSelectorsMatches(ctx context.Context, rclient client.Client, watchNamespaces config.Namespaces, ownNamespace string, nsSelector, selector *metav1.LabelSelector, obj metav1.Object, nsLabels map[string]labels.Set) (bool, error) {
+	switch {
+	case nsSelector == nil:
+		if obj.GetNamespace() != ownNamespace {
+			return false, nil
+		}
+	case !watchNamespaces.IsAllowed(obj.GetNamespace()):
+		return false, nil
+	case nsSelector.MatchLabels == nil && nsSelector.MatchExpressions == nil:
+	default:
+		nsSel, err := metav1.LabelSelectorAsSelector(nsSelector)
+		if err != nil {
+			return false, fmt.Errorf("cannot convert namespace selector: %w", err)
+		}
+		objNsLabels, ok := nsLabels[obj.GetNamespace()]
+		if !ok {
+			objNsLabels, err = namespaceLabels(ctx, rclient, watchNamespaces, obj.GetNamespace())
+			if err != nil {
+				return false, err
+			}
+			nsLabels[obj.GetNamespace()] = objNsLabels
+		}
+		if objNsLabels != nil && !nsSel.Matches(objNsLabels) {
+			return false, nil
+		}
+	}
+	if nsSelector != nil && selector == nil {
+		selector = &metav1.LabelSelector{}
+	}
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, fmt.Errorf("cannot convert object selector: %w", err)
+	}
+	return sel.Matches(labels.Set(obj.GetLabels())), nil
+}
+
+// namespaceLabels returns labels of namespace, deleted namespace has no labels.
+// Operator restricted to the list of namespaces may have no permissions to get namespace, nil is returned for it.
+func namespaceLabels(ctx context.Context, rclient client.Client, watchNamespaces config.Namespaces, name string) (labels.Set, error) {
+	ns := &v1.Namespace{}
+	if err := rclient.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+		switch {
+		case errors.IsNotFound(err):
+			// namespace may be already deleted with its objects
+			return labels.Set{}, nil
+		case errors.IsForbidden(err) && !watchNamespaces.IsAllNamespaces():
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot get namespace: %s, err: %w", name, err)
+	}
+	if ns.Labels == nil {
+		return labels.Set{}, nil
+	}
+	return ns.Labels, nil
+}
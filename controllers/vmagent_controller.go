@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
 )
@@ -49,14 +50,22 @@ type VMAgentReconciler struct {
 // +kubebuilder:rbac:groups="",resources=services/finalizers,verbs=*
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=*,verbs=*
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;watch;list
-func (r *VMAgentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=*
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmservicescrapes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmservicescrapes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmpodscrapes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmpodscrapes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmprobes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmprobes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
+func (r *VMAgentReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := r.Log.WithValues("vmagent", req.NamespacedName)
 	reqLogger.Info("Reconciling")
 
 	// Fetch the VMAgent instance
 	instance := &victoriametricsv1beta1.VMAgent{}
 	ctx := context.Background()
-	err := r.Get(ctx, req.NamespacedName, instance)
+	err = r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
@@ -68,6 +77,11 @@ func (r *VMAgentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		// Error reading the object - requeue the request.
 		return ctrl.Result{}, err
 	}
+	defer func() {
+		if statusErr := factory.UpdateVMAgentStatus(ctx, instance, r, err); statusErr != nil {
+			reqLogger.Error(statusErr, "cannot update vmagent status")
+		}
+	}()
 
 	//create deploy
 	reconResult, err := factory.CreateOrUpdateVMAgent(ctx, instance, r, r.BaseConf)
@@ -85,7 +99,7 @@ func (r *VMAgentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	//create vmservicescrape for object by default
 	if !r.BaseConf.DisableSelfServiceScrapeCreation {
-		err := factory.CreateVMServiceScrapeFromService(ctx, r, svc, instance.MetricPath())
+		err := factory.CreateVMServiceScrapeFromService(ctx, r, svc, instance.MetricPath(), "http")
 		if err != nil {
 			reqLogger.Error(err, "cannot create serviceScrape for vmagent")
 		}
@@ -96,9 +110,40 @@ func (r *VMAgentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 }
 
 // SetupWithManager general setup method
+// VMServiceScrape, VMPodScrape and VMProbe changes trigger reconcile only for VMAgents, which select changed object.
+// Changes of Secrets referenced by VMServiceScrape trigger reconcile for VMAgents, which select it.
 func (r *VMAgentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	bld := ctrl.NewControllerManagedBy(mgr).
 		For(&victoriametricsv1beta1.VMAgent{}).
+		WithEventFilter(namespacesFilter(r.BaseConf)).
 		Owns(&appsv1.Deployment{}).
-		Complete(r)
+		Watches(&source.Kind{Type: &victoriametricsv1beta1.VMServiceScrape{}}, r.scrapesHandler(serviceScrapeSelectors)).
+		Watches(&source.Kind{Type: &victoriametricsv1beta1.VMPodScrape{}}, r.scrapesHandler(podScrapeSelectors)).
+		Watches(&source.Kind{Type: &victoriametricsv1beta1.VMProbe{}}, r.scrapesHandler(probeSelectors))
+	bld, err := watchReferences(mgr, bld, &victoriametricsv1beta1.VMAgent{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMAgentList{}
+	})
+	if err != nil {
+		return err
+	}
+	// only VMServiceScrape endpoints reference secrets
+	bld, err = watchSelectedReferences(mgr, bld, &victoriametricsv1beta1.VMServiceScrape{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMServiceScrapeList{}
+	}, r.scrapesHandler(serviceScrapeSelectors))
+	if err != nil {
+		return err
+	}
+	return bld.Complete(r)
+}
+
+// scrapesHandler enqueues VMAgents, which select changed scrape object.
+// Burst of scrape object changes results in single reconcile for each VMAgent.
+func (r *VMAgentReconciler) scrapesHandler(selectors scrapeSelectorsFunc) *selectorsHandler {
+	return &selectorsHandler{
+		rclient:       r.Client,
+		log:           r.Log,
+		debounce:      r.BaseConf.VMAgentSyncDebounce,
+		listSelectors: vmAgentSelectors(selectors),
+		namespaces:    r.BaseConf.Namespaces,
+	}
 }
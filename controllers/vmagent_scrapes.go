@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// scrapeSelectorsFunc returns namespace and object selectors of VMAgent for scrape object kind.
+type scrapeSelectorsFunc func(cr *victoriametricsv1beta1.VMAgent) (nsSelector, selector *metav1.LabelSelector)
+
+func serviceScrapeSelectors(cr *victoriametricsv1beta1.VMAgent) (*metav1.LabelSelector, *metav1.LabelSelector) {
+	return cr.Spec.ServiceScrapeNamespaceSelector, cr.Spec.ServiceScrapeSelector
+}
+
+func podScrapeSelectors(cr *victoriametricsv1beta1.VMAgent) (*metav1.LabelSelector, *metav1.LabelSelector) {
+	return cr.Spec.PodScrapeNamespaceSelector, cr.Spec.PodScrapeSelector
+}
+
+func probeSelectors(cr *victoriametricsv1beta1.VMAgent) (*metav1.LabelSelector, *metav1.LabelSelector) {
+	return cr.Spec.ProbeNamespaceSelector, cr.Spec.ProbeSelector
+}
+
+// vmAgentSelectors returns selectors of VMAgents for scrape object kind.
+func vmAgentSelectors(selectors scrapeSelectorsFunc) listSelectorsFunc {
+	return func(ctx context.Context, rclient client.Client) ([]objectSelectors, error) {
+		vmAgents := &victoriametricsv1beta1.VMAgentList{}
+		if err := rclient.List(ctx, vmAgents); err != nil {
+			return nil, fmt.Errorf("cannot list vmagents: %w", err)
+		}
+		result := make([]objectSelectors, 0, len(vmAgents.Items))
+		for i := range vmAgents.Items {
+			cr := &vmAgents.Items[i]
+			nsSelector, selector := selectors(cr)
+			result = append(result, objectSelectors{
+				name:       types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name},
+				nsSelector: nsSelector,
+				selector:   selector,
+			})
+		}
+		return result, nil
+	}
+}
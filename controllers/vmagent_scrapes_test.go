@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func testGetScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(s)
+	_ = victoriametricsv1beta1.AddToScheme(s)
+	return s
+}
+
+func Test_vmAgentSelectors(t *testing.T) {
+	predefinedObjects := []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring", Labels: map[string]string{"monitoring": "enabled"}}},
+		&victoriametricsv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "own-namespace", Namespace: "default"},
+			Spec: victoriametricsv1beta1.VMAgentSpec{
+				ServiceScrapeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			},
+		},
+		&victoriametricsv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "any-namespace", Namespace: "default"},
+			Spec: victoriametricsv1beta1.VMAgentSpec{
+				ServiceScrapeNamespaceSelector: &metav1.LabelSelector{},
+			},
+		},
+		&victoriametricsv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "labeled-namespace", Namespace: "default"},
+			Spec: victoriametricsv1beta1.VMAgentSpec{
+				ServiceScrapeNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "enabled"}},
+				ServiceScrapeSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+			},
+		},
+		&victoriametricsv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "default"},
+		},
+	}
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}
+	}
+	tests := []struct {
+		name string
+		objs []metav1.Object
+		want []reconcile.Request
+	}{
+		{
+			name: "match own namespace selector",
+			objs: []metav1.Object{&metav1.ObjectMeta{Name: "scrape", Namespace: "default", Labels: map[string]string{"team": "a"}}},
+			want: []reconcile.Request{request("own-namespace"), request("any-namespace")},
+		},
+		{
+			name: "match labeled namespace",
+			objs: []metav1.Object{&metav1.ObjectMeta{Name: "scrape", Namespace: "monitoring", Labels: map[string]string{"team": "b"}}},
+			want: []reconcile.Request{request("any-namespace"), request("labeled-namespace")},
+		},
+		{
+			name: "missing namespace",
+			objs: []metav1.Object{&metav1.ObjectMeta{Name: "scrape", Namespace: "deleted", Labels: map[string]string{"team": "b"}}},
+			want: []reconcile.Request{request("any-namespace")},
+		},
+		{
+			name: "labels changed",
+			objs: []metav1.Object{
+				&metav1.ObjectMeta{Name: "scrape", Namespace: "default", Labels: map[string]string{"team": "a"}},
+				&metav1.ObjectMeta{Name: "scrape", Namespace: "monitoring", Labels: map[string]string{"team": "b"}},
+			},
+			want: []reconcile.Request{request("own-namespace"), request("any-namespace"), request("labeled-namespace")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &selectorsHandler{
+				rclient:       fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...),
+				log:           ctrl.Log,
+				listSelectors: vmAgentSelectors(serviceScrapeSelectors),
+			}
+			got, err := h.matched(context.TODO(), tt.objs...)
+			if err != nil {
+				t.Fatalf("matched() unexpected error: %v", err)
+			}
+			want := map[reconcile.Request]bool{}
+			for _, r := range tt.want {
+				want[r] = true
+			}
+			gotSet := map[reconcile.Request]bool{}
+			for _, r := range got {
+				gotSet[r] = true
+			}
+			if len(got) != len(gotSet) {
+				t.Errorf("matched() returned duplicated requests: %v", got)
+			}
+			if !reflect.DeepEqual(gotSet, want) {
+				t.Errorf("matched() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// forbiddenNamespacesClient returns Forbidden error for namespace reads as api server does for namespaced Role.
+type forbiddenNamespacesClient struct {
+	client.Client
+}
+
+func (c *forbiddenNamespacesClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if _, ok := obj.(*v1.Namespace); ok {
+		return errors.NewForbidden(v1.Resource("namespaces"), key.Name, fmt.Errorf("namespaced role"))
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func Test_isSelectorsMatches_watchNamespaces(t *testing.T) {
+	watchNamespaces := config.NewNamespaces([]string{"tenant-a", "tenant-b"}, nil)
+	nsSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "enabled"}}
+	predefinedNs := []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"monitoring": "enabled"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}},
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		forbidden bool
+		want      bool
+	}{
+		{
+			name:      "labels of watched namespace match",
+			namespace: "tenant-a",
+			want:      true,
+		},
+		{
+			name:      "labels of watched namespace don't match",
+			namespace: "tenant-b",
+		},
+		{
+			name:      "namespace isn't watched",
+			namespace: "tenant-c",
+		},
+		{
+			name:      "namespaces are forbidden",
+			namespace: "tenant-b",
+			forbidden: true,
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rclient client.Client = fake.NewFakeClientWithScheme(testGetScheme(), predefinedNs...)
+			if tt.forbidden {
+				rclient = &forbiddenNamespacesClient{Client: rclient}
+			}
+			obj := &metav1.ObjectMeta{Name: "scrape", Namespace: tt.namespace}
+			got, err := isSelectorsMatches(context.TODO(), rclient, watchNamespaces, "tenant-a", nsSelector, nil, obj, map[string]labels.Set{})
+			if err != nil {
+				t.Fatalf("isSelectorsMatches() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isSelectorsMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
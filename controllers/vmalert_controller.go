@@ -41,20 +41,32 @@ type VMAlertReconciler struct {
 // Reconcile general reconile method for controller
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalerts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalerts/status,verbs=get;update;patch
-func (r *VMAlertReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=*
+func (r *VMAlertReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := r.Log.WithValues("vmalert", req.NamespacedName)
 	reqLogger.Info("Reconciling")
 
 	// Fetch the VMAlert instance
 	ctx := context.Background()
 	instance := &victoriametricsv1beta1.VMAlert{}
-	err := r.Get(ctx, req.NamespacedName, instance)
+	err = r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
+			// deleted vmalert must not be kept at status of vmrules
+			if err := factory.RemoveVMAlertFromRulesStatus(ctx, r, req.NamespacedName); err != nil {
+				reqLogger.Error(err, "cannot remove deleted vmalert from vmrules status")
+				return ctrl.Result{}, err
+			}
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	defer func() {
+		if statusErr := factory.UpdateVMAlertStatus(ctx, instance, r, err); statusErr != nil {
+			reqLogger.Error(statusErr, "cannot update vmalert status")
+		}
+	}()
 
 	maps, err := factory.CreateOrUpdateRuleConfigMaps(ctx, instance, r)
 	if err != nil {
@@ -77,7 +89,7 @@ func (r *VMAlertReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	//create vmservicescrape for object by default
 	if !r.BaseConf.DisableSelfServiceScrapeCreation {
-		err := factory.CreateVMServiceScrapeFromService(ctx, r, svc, instance.MetricPath())
+		err := factory.CreateVMServiceScrapeFromService(ctx, r, svc, instance.MetricPath(), "http")
 		if err != nil {
 			reqLogger.Error(err, "cannot create serviceScrape for vmalert")
 		}
@@ -90,8 +102,15 @@ func (r *VMAlertReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 // SetupWithManager general setup method
 func (r *VMAlertReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	bld := ctrl.NewControllerManagedBy(mgr).
 		For(&victoriametricsv1beta1.VMAlert{}).
-		Owns(&appsv1.Deployment{}).
-		Complete(r)
+		WithEventFilter(namespacesFilter(r.BaseConf)).
+		Owns(&appsv1.Deployment{})
+	bld, err := watchReferences(mgr, bld, &victoriametricsv1beta1.VMAlert{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMAlertList{}
+	})
+	if err != nil {
+		return err
+	}
+	return bld.Complete(r)
 }
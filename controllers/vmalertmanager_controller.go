@@ -18,11 +18,16 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+
 	"github.com/VictoriaMetrics/operator/controllers/factory"
 	"github.com/VictoriaMetrics/operator/internal/config"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -43,9 +48,13 @@ type VMAlertmanagerReconciler struct {
 // Reconcile general reconcile method for controller
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalertmanagers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalertmanagers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalertmanagerconfigs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalertmanagerconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=*
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=*
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=*
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=*
 func (r *VMAlertmanagerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := r.Log.WithValues("vmalertmanager", req.NamespacedName)
 	reqLogger.Info("Reconciling")
@@ -76,9 +85,55 @@ func (r *VMAlertmanagerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 }
 
 // SetupWithManager general setup method
+// VMAlertmanagerConfig and receiver Secret changes trigger reconcile only for VMAlertmanagers, which select changed config.
 func (r *VMAlertmanagerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	configsHandler := &selectorsHandler{
+		rclient:       r.Client,
+		log:           r.Log,
+		listSelectors: vmAlertmanagerConfigSelectors,
+		namespaces:    r.BaseConf.Namespaces,
+	}
+	bld := ctrl.NewControllerManagedBy(mgr).
 		For(&victoriametricsv1beta1.VMAlertmanager{}).
+		WithEventFilter(namespacesFilter(r.BaseConf)).
 		Owns(&appsv1.StatefulSet{}).
-		Complete(r)
+		Watches(&source.Kind{Type: &victoriametricsv1beta1.VMAlertmanagerConfig{}}, configsHandler)
+	bld, err := watchReferences(mgr, bld, &victoriametricsv1beta1.VMAlertmanager{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMAlertmanagerList{}
+	})
+	if err != nil {
+		return err
+	}
+	bld, err = watchSelectedReferences(mgr, bld, &victoriametricsv1beta1.VMAlertmanagerConfig{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMAlertmanagerConfigList{}
+	}, configsHandler)
+	if err != nil {
+		return err
+	}
+	return bld.Complete(r)
+}
+
+// vmAlertmanagerConfigSelectors returns VMAlertmanagerConfig selectors of VMAlertmanagers.
+// VMAlertmanagers without config selectors ignore VMAlertmanagerConfigs.
+func vmAlertmanagerConfigSelectors(ctx context.Context, rclient client.Client) ([]objectSelectors, error) {
+	vmAlertmanagers := &victoriametricsv1beta1.VMAlertmanagerList{}
+	if err := rclient.List(ctx, vmAlertmanagers); err != nil {
+		return nil, fmt.Errorf("cannot list vmalertmanagers: %w", err)
+	}
+	result := make([]objectSelectors, 0, len(vmAlertmanagers.Items))
+	for _, cr := range vmAlertmanagers.Items {
+		if !cr.HasConfigSelectors() {
+			continue
+		}
+		selector := cr.Spec.ConfigSelector
+		if selector == nil {
+			selector = &metav1.LabelSelector{}
+		}
+		result = append(result, objectSelectors{
+			name:       types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name},
+			nsSelector: cr.Spec.ConfigNamespaceSelector,
+			selector:   selector,
+		})
+	}
+	return result, nil
 }
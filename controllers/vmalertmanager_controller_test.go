@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func Test_vmAlertmanagerConfigSelectors(t *testing.T) {
+	predefinedObjects := []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"alerting": "enabled"}}},
+		&victoriametricsv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "without-selectors", Namespace: "monitoring"},
+		},
+		&victoriametricsv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "own-namespace", Namespace: "monitoring"},
+			Spec: victoriametricsv1beta1.VMAlertmanagerSpec{
+				ConfigSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}},
+			},
+		},
+		&victoriametricsv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "labeled-namespace", Namespace: "monitoring"},
+			Spec: victoriametricsv1beta1.VMAlertmanagerSpec{
+				ConfigNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"alerting": "enabled"}},
+			},
+		},
+		&victoriametricsv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "any-namespace", Namespace: "monitoring"},
+			Spec: victoriametricsv1beta1.VMAlertmanagerSpec{
+				ConfigNamespaceSelector: &metav1.LabelSelector{},
+				ConfigSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}},
+			},
+		},
+	}
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "monitoring", Name: name}}
+	}
+	tests := []struct {
+		name   string
+		config metav1.Object
+		want   []reconcile.Request
+	}{
+		{
+			name:   "config without labels at alertmanager namespace",
+			config: &metav1.ObjectMeta{Name: "config", Namespace: "monitoring"},
+		},
+		{
+			name:   "labeled config at alertmanager namespace",
+			config: &metav1.ObjectMeta{Name: "config", Namespace: "monitoring", Labels: map[string]string{"team": "ops"}},
+			want:   []reconcile.Request{request("any-namespace"), request("own-namespace")},
+		},
+		{
+			name:   "config at labeled namespace",
+			config: &metav1.ObjectMeta{Name: "config", Namespace: "team-a"},
+			want:   []reconcile.Request{request("labeled-namespace")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &selectorsHandler{
+				rclient:       fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...),
+				log:           ctrl.Log,
+				listSelectors: vmAlertmanagerConfigSelectors,
+			}
+			got, err := h.matched(context.TODO(), tt.config)
+			if err != nil {
+				t.Fatalf("matched() unexpected error: %v", err)
+			}
+			sort.Slice(got, func(i, j int) bool {
+				return got[i].Name < got[j].Name
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
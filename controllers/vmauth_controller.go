@@ -0,0 +1,135 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VictoriaMetrics/operator/controllers/factory"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+)
+
+// VMAuthReconciler reconciles a VMAuth object
+type VMAuthReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	BaseConf *config.BaseOperatorConf
+}
+
+// Reconcile general reconcile method
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmauths,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmauths/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmusers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmusers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=*
+func (r *VMAuthReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	reqLogger := r.Log.WithValues("vmauth", req.NamespacedName)
+	reqLogger.Info("Reconciling")
+
+	instance := &victoriametricsv1beta1.VMAuth{}
+	ctx := context.Background()
+	err := r.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			return ctrl.Result{}, nil
+		}
+		reqLogger.Error(err, "cannot get vmauth object for reconcile")
+		return ctrl.Result{}, err
+	}
+
+	reconResult, err := factory.CreateOrUpdateVMAuth(ctx, instance, r, r.BaseConf)
+	if err != nil {
+		reqLogger.Error(err, "cannot create or update vmauth deploy")
+		return reconResult, err
+	}
+
+	svc, err := factory.CreateOrUpdateVMAuthService(ctx, instance, r, r.BaseConf)
+	if err != nil {
+		reqLogger.Error(err, "cannot create or update vmauth service")
+		return ctrl.Result{}, err
+	}
+
+	if !r.BaseConf.DisableSelfServiceScrapeCreation {
+		err := factory.CreateVMServiceScrapeFromService(ctx, r, svc, instance.MetricPath(), "http")
+		if err != nil {
+			reqLogger.Error(err, "cannot create serviceScrape for vmauth")
+		}
+	}
+	reqLogger.Info("reconciled vmauth")
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager general setup method
+// VMUser and referenced Secret changes trigger reconcile only for VMAuths, which select changed user.
+func (r *VMAuthReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	usersHandler := &selectorsHandler{
+		rclient:       r.Client,
+		log:           r.Log,
+		listSelectors: vmAuthUserSelectors,
+		namespaces:    r.BaseConf.Namespaces,
+	}
+	bld := ctrl.NewControllerManagedBy(mgr).
+		For(&victoriametricsv1beta1.VMAuth{}).
+		WithEventFilter(namespacesFilter(r.BaseConf)).
+		Owns(&appsv1.Deployment{}).
+		Watches(&source.Kind{Type: &victoriametricsv1beta1.VMUser{}}, usersHandler)
+	bld, err := watchSelectedReferences(mgr, bld, &victoriametricsv1beta1.VMUser{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMUserList{}
+	}, usersHandler)
+	if err != nil {
+		return err
+	}
+	return bld.Complete(r)
+}
+
+// vmAuthUserSelectors returns VMUser selectors of VMAuths.
+// VMAuth without selectors selects all VMUsers from own namespace.
+func vmAuthUserSelectors(ctx context.Context, rclient client.Client) ([]objectSelectors, error) {
+	vmAuths := &victoriametricsv1beta1.VMAuthList{}
+	if err := rclient.List(ctx, vmAuths); err != nil {
+		return nil, fmt.Errorf("cannot list vmauths: %w", err)
+	}
+	result := make([]objectSelectors, 0, len(vmAuths.Items))
+	for _, cr := range vmAuths.Items {
+		selector := cr.Spec.UserSelector
+		if selector == nil {
+			selector = &metav1.LabelSelector{}
+		}
+		result = append(result, objectSelectors{
+			name:       types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name},
+			nsSelector: cr.Spec.UserNamespaceSelector,
+			selector:   selector,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func Test_vmAuthUserSelectors(t *testing.T) {
+	predefinedObjects := []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"auth": "enabled"}}},
+		&victoriametricsv1beta1.VMAuth{
+			ObjectMeta: metav1.ObjectMeta{Name: "own-namespace", Namespace: "default"},
+		},
+		&victoriametricsv1beta1.VMAuth{
+			ObjectMeta: metav1.ObjectMeta{Name: "labeled-users", Namespace: "default"},
+			Spec: victoriametricsv1beta1.VMAuthSpec{
+				UserSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "premium"}},
+			},
+		},
+		&victoriametricsv1beta1.VMAuth{
+			ObjectMeta: metav1.ObjectMeta{Name: "labeled-namespace", Namespace: "default"},
+			Spec: victoriametricsv1beta1.VMAuthSpec{
+				UserNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"auth": "enabled"}},
+			},
+		},
+	}
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}
+	}
+	tests := []struct {
+		name string
+		user metav1.Object
+		want []reconcile.Request
+	}{
+		{
+			name: "user without labels at own namespace",
+			user: &metav1.ObjectMeta{Name: "user", Namespace: "default"},
+			want: []reconcile.Request{request("own-namespace")},
+		},
+		{
+			name: "labeled user at own namespace",
+			user: &metav1.ObjectMeta{Name: "user", Namespace: "default", Labels: map[string]string{"tier": "premium"}},
+			want: []reconcile.Request{request("labeled-users"), request("own-namespace")},
+		},
+		{
+			name: "user at labeled namespace",
+			user: &metav1.ObjectMeta{Name: "user", Namespace: "team-a"},
+			want: []reconcile.Request{request("labeled-namespace")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &selectorsHandler{
+				rclient:       fake.NewFakeClientWithScheme(testGetScheme(), predefinedObjects...),
+				log:           ctrl.Log,
+				listSelectors: vmAuthUserSelectors,
+			}
+			got, err := h.matched(context.TODO(), tt.user)
+			if err != nil {
+				t.Fatalf("matched() unexpected error: %v", err)
+			}
+			sort.Slice(got, func(i, j int) bool {
+				return got[i].Name < got[j].Name
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,11 +9,15 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
-	"time"
 )
 
 var log = logf.Log.WithName("controller_vmcluster")
@@ -24,12 +28,17 @@ type VMClusterReconciler struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	BaseConf *config.BaseOperatorConf
+	recorder record.EventRecorder
 }
 
 // Reconcile general reconcile method for controller
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=*
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=*
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=*
 func (r *VMClusterReconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling VMCluster")
@@ -42,21 +51,25 @@ func (r *VMClusterReconciler) Reconcile(request ctrl.Request) (ctrl.Result, erro
 		return reconcile.Result{}, err
 	}
 
-	status, err := factory.CreateOrUpdateVMCluster(ctx, cluster, r.Client, config.MustGetBaseConfig())
+	status, err := factory.CreateOrUpdateVMCluster(ctx, cluster, r.Client, r.BaseConf, r.recorder)
 	if err != nil {
 		reqLogger.Error(err, "cannot update or create vmcluster")
 		return reconcile.Result{}, err
 	}
 	if status == victoriametricsv1beta1.ClusterStatusExpanding {
-		reqLogger.Info("cluster still expanding requeue request")
-		failCnt := cluster.Status.UpdateFailCount
-		if failCnt > 5 {
-			failCnt = 5
-		}
-		reqLogger.Info("re queuing cluster expanding with back-off", "fail update count", failCnt)
-		// add requeue back-off
+		// rolling update and expanding don't block reconcile,
+		// progress is checked on the next reconcile.
+		reqLogger.Info("cluster still expanding requeue request", "reason", cluster.Status.Reason)
+		return reconcile.Result{
+			RequeueAfter: r.BaseConf.PodWaitReadyIntervalCheck,
+		}, nil
+	}
+
+	if len(cluster.Status.DrainingStorageNodes) > 0 {
+		// draining nodes are removed after retention period, check it periodically
+		reqLogger.Info("vmstorage nodes are draining, requeue request", "nodes", len(cluster.Status.DrainingStorageNodes))
 		return reconcile.Result{
-			RequeueAfter: time.Second * time.Duration(failCnt*10),
+			RequeueAfter: r.BaseConf.VMStorageDrainCheckInterval,
 		}, nil
 	}
 
@@ -66,10 +79,37 @@ func (r *VMClusterReconciler) Reconcile(request ctrl.Request) (ctrl.Result, erro
 }
 
 // SetupWithManager general setup method
+// VMCluster status is updated on every reconcile, so only spec changes of VMCluster
+// and vmstorage scale down confirmation trigger reconcile.
 func (r *VMClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&victoriametricsv1beta1.VMCluster{}).
+	r.recorder = mgr.GetEventRecorderFor("vmcluster-controller")
+	bld := ctrl.NewControllerManagedBy(mgr).
+		For(&victoriametricsv1beta1.VMCluster{}, builder.WithPredicates(vmClusterChangedPredicate())).
+		WithEventFilter(namespacesFilter(r.BaseConf)).
 		Owns(&appsv1.Deployment{}).
 		Owns(&appsv1.StatefulSet{}).
-		Complete(r)
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.BaseConf.VMClusterMaxConcurrentReconciles})
+	bld, err := watchReferences(mgr, bld, &victoriametricsv1beta1.VMCluster{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMClusterList{}
+	})
+	if err != nil {
+		return err
+	}
+	return bld.Complete(r)
+}
+
+// vmClusterChangedPredicate skips VMCluster updates, which change only status or metadata.
+func vmClusterChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.MetaOld == nil || e.MetaNew == nil {
+				return false
+			}
+			if e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() {
+				return true
+			}
+			confirmKey := victoriametricsv1beta1.MetaVMStorageScaleDownConfirmKey
+			return e.MetaOld.GetAnnotations()[confirmKey] != e.MetaNew.GetAnnotations()[confirmKey]
+		},
+	}
 }
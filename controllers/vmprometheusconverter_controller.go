@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/VictoriaMetrics/operator/api/v1beta1"
@@ -10,11 +11,21 @@ import (
 	"github.com/VictoriaMetrics/operator/internal/config"
 	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/coreos/prometheus-operator/pkg/client/versioned"
+	"github.com/coreos/prometheus-operator/pkg/listwatch"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -47,93 +58,168 @@ const (
 	IgnoreConversionLabel = "operator.victoriametrics.com/ignore-prometheus-updates"
 	// IgnoreConversion - disables updates from prometheus api
 	IgnoreConversion = "enabled"
+
+	// converterMaxRetries is the number of retries for failed conversion,
+	// after that object is converted again only after update or periodic resync.
+	converterMaxRetries = 10
+)
+
+var (
+	converterConversionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vm_operator_prometheus_converter_conversions_total",
+		Help: "Number of prometheus-operator objects converted into VictoriaMetrics objects",
+	}, []string{"kind"})
+	converterErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vm_operator_prometheus_converter_errors_total",
+		Help: "Number of failed conversions of prometheus-operator objects",
+	}, []string{"kind"})
 )
 
+func init() {
+	metrics.Registry.MustRegister(converterConversionsTotal, converterErrorsTotal)
+}
+
 // ConverterController - watches for prometheus objects
 // and create VictoriaMetrics objects
 type ConverterController struct {
 	promClient versioned.Interface
 	vclient    client.Client
+	recorder   record.EventRecorder
+	queue      workqueue.RateLimitingInterface
 	ruleInf    cache.SharedInformer
 	podInf     cache.SharedInformer
 	serviceInf cache.SharedInformer
 	probeInf   cache.SharedIndexInformer
+	promInf    cache.SharedIndexInformer
+	amInf      cache.SharedIndexInformer
+	// deletionSync enables deletion sync for all converted objects
+	deletionSync bool
+}
+
+// converterQueueItem references changed prometheus-operator object by its kind and namespace/name key.
+type converterQueueItem struct {
+	kind string
+	key  string
+}
+
+// sourceObject is prometheus-operator object, events are recorded for it.
+type sourceObject interface {
+	metav1.Object
+	runtime.Object
 }
 
 // NewConverterController builder for vmprometheusconverter service
-func NewConverterController(promCl versioned.Interface, vclient client.Client) *ConverterController {
+// informers are restricted to namespaces watched by operator.
+// Informers only put keys of changed objects into rate limited queue, conversion is performed by Run workers.
+func NewConverterController(promCl versioned.Interface, vclient client.Client, recorder record.EventRecorder, cfg *config.BaseOperatorConf) *ConverterController {
 	c := &ConverterController{
-		promClient: promCl,
-		vclient:    vclient,
+		promClient:   promCl,
+		vclient:      vclient,
+		recorder:     recorder,
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "vmprometheusconverter"),
+		deletionSync: cfg.EnabledPrometheusConverterDeletionSync,
+	}
+	allowed, denied := cfg.Namespaces.AllowList, cfg.Namespaces.DenyList
+	if len(allowed) == 0 {
+		allowed = map[string]struct{}{metav1.NamespaceAll: {}}
 	}
+	resync := cfg.PrometheusConverterResyncPeriod
 	c.ruleInf = cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return promCl.MonitoringV1().PrometheusRules("").List(context.TODO(), options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return promCl.MonitoringV1().PrometheusRules("").Watch(context.TODO(), options)
-			},
-		},
+		listwatch.MultiNamespaceListerWatcher(kitlog.NewNopLogger(), allowed, denied, func(namespace string) cache.ListerWatcher {
+			return &cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return promCl.MonitoringV1().PrometheusRules(namespace).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return promCl.MonitoringV1().PrometheusRules(namespace).Watch(context.TODO(), options)
+				},
+			}
+		}),
 		&v1.PrometheusRule{},
-		0,
+		resync,
 		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 	)
-	c.ruleInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    c.CreatePrometheusRule,
-		UpdateFunc: c.UpdatePrometheusRule,
-	})
+	c.ruleInf.AddEventHandler(c.handlerFor(v1.PrometheusRuleKind))
 	c.podInf = cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return promCl.MonitoringV1().PodMonitors("").List(context.TODO(), options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return promCl.MonitoringV1().PodMonitors("").Watch(context.TODO(), options)
-			},
-		},
+		listwatch.MultiNamespaceListerWatcher(kitlog.NewNopLogger(), allowed, denied, func(namespace string) cache.ListerWatcher {
+			return &cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return promCl.MonitoringV1().PodMonitors(namespace).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return promCl.MonitoringV1().PodMonitors(namespace).Watch(context.TODO(), options)
+				},
+			}
+		}),
 		&v1.PodMonitor{},
-		0,
+		resync,
 		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 	)
-	c.podInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    c.CreatePodMonitor,
-		UpdateFunc: c.UpdatePodMonitor,
-	})
+	c.podInf.AddEventHandler(c.handlerFor(v1.PodMonitorsKind))
 	c.serviceInf = cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return promCl.MonitoringV1().ServiceMonitors("").List(context.TODO(), options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return promCl.MonitoringV1().ServiceMonitors("").Watch(context.TODO(), options)
-			},
-		},
+		listwatch.MultiNamespaceListerWatcher(kitlog.NewNopLogger(), allowed, denied, func(namespace string) cache.ListerWatcher {
+			return &cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return promCl.MonitoringV1().ServiceMonitors(namespace).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return promCl.MonitoringV1().ServiceMonitors(namespace).Watch(context.TODO(), options)
+				},
+			}
+		}),
 		&v1.ServiceMonitor{},
-		0,
+		resync,
 		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 	)
-	c.serviceInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    c.CreateServiceMonitor,
-		UpdateFunc: c.UpdateServiceMonitor,
-	})
+	c.serviceInf.AddEventHandler(c.handlerFor(v1.ServiceMonitorsKind))
 	c.probeInf = cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return promCl.MonitoringV1().Probes("").List(context.TODO(), options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return promCl.MonitoringV1().Probes("").Watch(context.TODO(), options)
-			},
-		},
+		listwatch.MultiNamespaceListerWatcher(kitlog.NewNopLogger(), allowed, denied, func(namespace string) cache.ListerWatcher {
+			return &cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return promCl.MonitoringV1().Probes(namespace).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return promCl.MonitoringV1().Probes(namespace).Watch(context.TODO(), options)
+				},
+			}
+		}),
 		&v1.Probe{},
-		0,
+		resync,
 		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 	)
-	c.probeInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    c.CreateProbe,
-		UpdateFunc: c.UpdateProbe,
-	})
+	c.probeInf.AddEventHandler(c.handlerFor(v1.ProbesKind))
+	c.promInf = cache.NewSharedIndexInformer(
+		listwatch.MultiNamespaceListerWatcher(kitlog.NewNopLogger(), allowed, denied, func(namespace string) cache.ListerWatcher {
+			return &cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return promCl.MonitoringV1().Prometheuses(namespace).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return promCl.MonitoringV1().Prometheuses(namespace).Watch(context.TODO(), options)
+				},
+			}
+		}),
+		&v1.Prometheus{},
+		resync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	c.promInf.AddEventHandler(c.handlerFor(v1.PrometheusesKind))
+	c.amInf = cache.NewSharedIndexInformer(
+		listwatch.MultiNamespaceListerWatcher(kitlog.NewNopLogger(), allowed, denied, func(namespace string) cache.ListerWatcher {
+			return &cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return promCl.MonitoringV1().Alertmanagers(namespace).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return promCl.MonitoringV1().Alertmanagers(namespace).Watch(context.TODO(), options)
+				},
+			}
+		}),
+		&v1.Alertmanager{},
+		resync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	c.amInf.AddEventHandler(c.handlerFor(v1.AlertmanagersKind))
 	return c
 }
 
@@ -174,13 +260,28 @@ func (c *ConverterController) runInformerWithDiscovery(ctx context.Context, grou
 	if err != nil {
 		return fmt.Errorf("error wait for %s, err: %w", kind, err)
 	}
+	if err := c.collectOrphans(ctx, kind); err != nil {
+		log.Error(err, "cannot collect orphaned objects", "kind", kind)
+	}
 	runInformer(ctx.Done())
 	return nil
 }
 
 // Run - starts vmprometheusconverter with background discovery process for each prometheus api object
+// and worker, which converts objects from queue.
 func (c *ConverterController) Run(ctx context.Context, group *errgroup.Group, cfg *config.BaseOperatorConf) {
-
+	group.Go(func() error {
+		wait.Until(func() {
+			for c.processNextItem(ctx) {
+			}
+		}, time.Second, ctx.Done())
+		return nil
+	})
+	group.Go(func() error {
+		<-ctx.Done()
+		c.queue.ShutDown()
+		return nil
+	})
 	if cfg.EnabledPrometheusConverter.ServiceScrape {
 		group.Go(func() error {
 			return c.runInformerWithDiscovery(ctx, v1.SchemeGroupVersion.String(), v1.ServiceMonitorsKind, c.serviceInf.Run)
@@ -201,163 +302,220 @@ func (c *ConverterController) Run(ctx context.Context, group *errgroup.Group, cf
 	}
 	if cfg.EnabledPrometheusConverter.Probe {
 		group.Go(func() error {
-			return c.runInformerWithDiscovery(ctx, v1.SchemeGroupVersion.String(), v1.ProbeKindKey, c.probeInf.Run)
+			return c.runInformerWithDiscovery(ctx, v1.SchemeGroupVersion.String(), v1.ProbesKind, c.probeInf.Run)
 		})
 
 	}
+	if cfg.EnabledPrometheusConverter.Prometheus {
+		group.Go(func() error {
+			return c.runInformerWithDiscovery(ctx, v1.SchemeGroupVersion.String(), v1.PrometheusesKind, c.promInf.Run)
+		})
 
-}
-
-// CreatePrometheusRule converts prometheus rule to vmrule
-func (c *ConverterController) CreatePrometheusRule(rule interface{}) {
-	promRule := rule.(*v1.PrometheusRule)
-	l := log.WithValues("kind", "alertRule", "name", promRule.Name, "ns", promRule.Namespace)
-	l.Info("syncing prom rule with VMRule")
-	cr := converter.ConvertPromRule(promRule)
+	}
+	if cfg.EnabledPrometheusConverter.Alertmanager {
+		group.Go(func() error {
+			return c.runInformerWithDiscovery(ctx, v1.SchemeGroupVersion.String(), v1.AlertmanagersKind, c.amInf.Run)
+		})
 
-	err := c.vclient.Create(context.Background(), cr)
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			l.Info("AlertRule already exists")
-			c.UpdatePrometheusRule(nil, promRule)
-			return
-		}
-		l.Error(err, "cannot create AlertRule from Prometheusrule")
-		return
 	}
-	l.Info("AlertRule was created")
+
 }
 
-// UpdatePrometheusRule updates vmrule
-func (c *ConverterController) UpdatePrometheusRule(old, new interface{}) {
-	promRuleNew := new.(*v1.PrometheusRule)
-	l := log.WithValues("kind", "VMRule", "name", promRuleNew.Name, "ns", promRuleNew.Namespace)
-	l.Info("updating VMRule")
-	VMRule := converter.ConvertPromRule(promRuleNew)
-	ctx := context.Background()
-	existingVMRule := &v1beta1.VMRule{}
-	err := c.vclient.Get(ctx, types.NamespacedName{Name: VMRule.Name, Namespace: VMRule.Namespace}, existingVMRule)
-	if err != nil {
-		l.Error(err, "cannot get existing VMRule")
-		return
+// handlerFor returns event handler, which puts changed objects of given kind into queue.
+func (c *ConverterController) handlerFor(kind string) cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			log.Error(err, "cannot get key of object", "kind", kind)
+			return
+		}
+		c.queue.Add(converterQueueItem{kind: kind, key: key})
 	}
-	if existingVMRule.Annotations[IgnoreConversionLabel] == IgnoreConversion {
-		l.Info("syncing for object was disabled by annotation", "annotation", IgnoreConversionLabel)
-		return
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(_, new interface{}) {
+			enqueue(new)
+		},
+		DeleteFunc: enqueue,
 	}
-	existingVMRule.Spec = VMRule.Spec
-	metaMergeStrategy := getMetaMergeStrategy(existingVMRule.Annotations)
-	existingVMRule.Annotations = mergeLabelsWithStrategy(existingVMRule.Annotations, VMRule.Annotations, metaMergeStrategy)
-	existingVMRule.Labels = mergeLabelsWithStrategy(existingVMRule.Labels, VMRule.Labels, metaMergeStrategy)
+}
 
-	err = c.vclient.Update(ctx, existingVMRule)
-	if err != nil {
-		l.Error(err, "cannot update VMRule")
-		return
+func (c *ConverterController) informerFor(kind string) cache.SharedInformer {
+	switch kind {
+	case v1.PrometheusRuleKind:
+		return c.ruleInf
+	case v1.PodMonitorsKind:
+		return c.podInf
+	case v1.ServiceMonitorsKind:
+		return c.serviceInf
+	case v1.ProbesKind:
+		return c.probeInf
+	case v1.PrometheusesKind:
+		return c.promInf
+	case v1.AlertmanagersKind:
+		return c.amInf
 	}
-	l.Info("VMRule was updated")
-
+	return nil
 }
 
-// CreateServiceMonitor converts ServiceMonitor to VMServiceScrape
-func (c *ConverterController) CreateServiceMonitor(service interface{}) {
-	serviceMon := service.(*v1.ServiceMonitor)
-	l := log.WithValues("kind", "vmServiceScrape", "name", serviceMon.Name, "ns", serviceMon.Namespace)
-	l.Info("syncing vmServiceScrape")
-	vmServiceScrape := converter.ConvertServiceMonitor(serviceMon)
-	err := c.vclient.Create(context.Background(), vmServiceScrape)
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			l.Info("vmServiceScrape exists")
-			c.UpdateServiceMonitor(nil, serviceMon)
-			return
-		}
-		l.Error(err, "cannot create vmServiceScrape")
-		return
+// processNextItem converts object from queue, failed conversions are retried with rate limit.
+func (c *ConverterController) processNextItem(ctx context.Context) bool {
+	obj, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(obj)
+	item := obj.(converterQueueItem)
+	err := c.sync(ctx, item)
+	if err == nil {
+		c.queue.Forget(obj)
+		return true
+	}
+	converterErrorsTotal.WithLabelValues(item.kind).Inc()
+	l := log.WithValues("kind", item.kind, "key", item.key)
+	if c.queue.NumRequeues(obj) < converterMaxRetries {
+		l.Error(err, "cannot convert object, retrying")
+		c.queue.AddRateLimited(obj)
+		return true
 	}
-	l.Info("vmServiceScrape was created")
+	l.Error(err, "cannot convert object, dropping it from queue")
+	c.queue.Forget(obj)
+	return true
 }
 
-// UpdateServiceMonitor updates VMServiceMonitor
-func (c *ConverterController) UpdateServiceMonitor(_, new interface{}) {
-	serviceMonNew := new.(*v1.ServiceMonitor)
-	l := log.WithValues("kind", "vmServiceScrape", "name", serviceMonNew.Name, "ns", serviceMonNew.Namespace)
-	l.Info("updating vmServiceScrape")
-	vmServiceScrape := converter.ConvertServiceMonitor(serviceMonNew)
-	existingVMServiceScrape := &v1beta1.VMServiceScrape{}
-	ctx := context.Background()
-	err := c.vclient.Get(ctx, types.NamespacedName{Name: vmServiceScrape.Name, Namespace: vmServiceScrape.Namespace}, existingVMServiceScrape)
+// sync converts object referenced by queue item,
+// if object was deleted, deletion policy is applied to converted objects.
+func (c *ConverterController) sync(ctx context.Context, item converterQueueItem) error {
+	inf := c.informerFor(item.kind)
+	if inf == nil {
+		return fmt.Errorf("unsupported kind: %s", item.kind)
+	}
+	obj, exists, err := inf.GetStore().GetByKey(item.key)
 	if err != nil {
-		l.Error(err, "cannot get existing vmServiceScrape")
-		return
+		return fmt.Errorf("cannot get object from informer store: %w", err)
 	}
-
-	if existingVMServiceScrape.Annotations[IgnoreConversionLabel] == IgnoreConversion {
-		l.Info("syncing for object was disabled by annotation", "annotation", IgnoreConversionLabel)
-		return
+	if !exists {
+		namespace, name, err := cache.SplitMetaNamespaceKey(item.key)
+		if err != nil {
+			return fmt.Errorf("cannot parse object key: %w", err)
+		}
+		return c.syncSourceDeletion(ctx, item.kind, &metav1.ObjectMeta{Name: name, Namespace: namespace})
+	}
+	switch src := obj.(type) {
+	case *v1.PrometheusRule:
+		vmRule, issues := converter.ConvertPromRule(src)
+		return c.createOrUpdateConverted(ctx, item.kind, src, vmRule, issues, &v1beta1.VMRule{}, func(existing runtime.Object) {
+			existing.(*v1beta1.VMRule).Spec = vmRule.Spec
+		})
+	case *v1.ServiceMonitor:
+		vmServiceScrape, issues := converter.ConvertServiceMonitor(src)
+		return c.createOrUpdateConverted(ctx, item.kind, src, vmServiceScrape, issues, &v1beta1.VMServiceScrape{}, func(existing runtime.Object) {
+			existing.(*v1beta1.VMServiceScrape).Spec = vmServiceScrape.Spec
+		})
+	case *v1.PodMonitor:
+		podScrape, issues := converter.ConvertPodMonitor(src)
+		return c.createOrUpdateConverted(ctx, item.kind, src, podScrape, issues, &v1beta1.VMPodScrape{}, func(existing runtime.Object) {
+			existing.(*v1beta1.VMPodScrape).Spec = podScrape.Spec
+		})
+	case *v1.Probe:
+		vmProbe, issues := converter.ConvertProbe(src)
+		return c.createOrUpdateConverted(ctx, item.kind, src, vmProbe, issues, &v1beta1.VMProbe{}, func(existing runtime.Object) {
+			existing.(*v1beta1.VMProbe).Spec = vmProbe.Spec
+		})
+	case *v1.Prometheus:
+		vmAgent, agentIssues := converter.ConvertPrometheus(src)
+		if vmAgent == nil {
+			c.recorder.Eventf(src, corev1.EventTypeWarning, "ConversionIssues", "cannot convert into VMAgent: %s", agentIssues)
+			if err := c.syncStaleConverted(ctx, item.kind, src, &v1beta1.VMAgent{}); err != nil {
+				return err
+			}
+		} else {
+			err := c.createOrUpdateConverted(ctx, item.kind, src, vmAgent, agentIssues, &v1beta1.VMAgent{}, func(existing runtime.Object) {
+				existing.(*v1beta1.VMAgent).Spec = vmAgent.Spec
+			})
+			if err != nil {
+				return err
+			}
+		}
+		vmAlert, alertIssues := converter.ConvertPrometheusRules(src)
+		if vmAlert == nil {
+			// ruleSelector or alerting could be removed from Prometheus
+			return c.syncStaleConverted(ctx, item.kind, src, &v1beta1.VMAlert{})
+		}
+		return c.createOrUpdateConverted(ctx, item.kind, src, vmAlert, alertIssues, &v1beta1.VMAlert{}, func(existing runtime.Object) {
+			existing.(*v1beta1.VMAlert).Spec = vmAlert.Spec
+		})
+	case *v1.Alertmanager:
+		vmAlertmanager, issues := converter.ConvertAlertmanager(src)
+		return c.createOrUpdateConverted(ctx, item.kind, src, vmAlertmanager, issues, &v1beta1.VMAlertmanager{}, func(existing runtime.Object) {
+			existing.(*v1beta1.VMAlertmanager).Spec = vmAlertmanager.Spec
+		})
 	}
-	existingVMServiceScrape.Spec = vmServiceScrape.Spec
+	return fmt.Errorf("unexpected object type: %T for kind: %s", obj, item.kind)
+}
 
-	metaMergeStrategy := getMetaMergeStrategy(existingVMServiceScrape.Annotations)
-	existingVMServiceScrape.Annotations = mergeLabelsWithStrategy(existingVMServiceScrape.Annotations, vmServiceScrape.Annotations, metaMergeStrategy)
-	existingVMServiceScrape.Labels = mergeLabelsWithStrategy(existingVMServiceScrape.Labels, vmServiceScrape.Labels, metaMergeStrategy)
-	err = c.vclient.Update(ctx, existingVMServiceScrape)
+// createOrUpdateConverted creates converted object or updates existing one,
+// updateSpec must copy spec of converted object into existing.
+// Events are recorded for source object, if converted object was changed or conversion failed,
+// issues of conversion are recorded as warning event.
+func (c *ConverterController) createOrUpdateConverted(ctx context.Context, kind string, src sourceObject, converted runtime.Object, issues converter.ConversionIssues, existing runtime.Object, updateSpec func(existing runtime.Object)) error {
+	convertedKind := reflect.TypeOf(converted).Elem().Name()
+	convertedMeta, err := meta.Accessor(converted)
 	if err != nil {
-		l.Error(err, "cannot update")
-		return
+		return fmt.Errorf("cannot get metadata of converted object: %w", err)
 	}
-	l.Info("vmServiceScrape was updated")
-}
+	c.trackSource(kind, src, convertedMeta)
+	l := log.WithValues("kind", convertedKind, "name", convertedMeta.GetName(), "ns", convertedMeta.GetNamespace())
 
-// CreatePodMonitor converts PodMonitor to VMPodScrape
-func (c *ConverterController) CreatePodMonitor(pod interface{}) {
-	podMonitor := pod.(*v1.PodMonitor)
-	l := log.WithValues("kind", "podScrape", "name", podMonitor.Name, "ns", podMonitor.Namespace)
-	l.Info("syncing podScrape")
-	podScrape := converter.ConvertPodMonitor(podMonitor)
-	err := c.vclient.Create(context.TODO(), podScrape)
+	err = c.vclient.Get(ctx, types.NamespacedName{Name: convertedMeta.GetName(), Namespace: convertedMeta.GetNamespace()}, existing)
 	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			l.Info("podScrape already exists")
-			c.UpdatePodMonitor(nil, podMonitor)
-			return
+		if !errors.IsNotFound(err) {
+			return c.conversionFailed(src, convertedKind, fmt.Errorf("cannot get existing %s: %w", convertedKind, err))
 		}
-		l.Error(err, "cannot create podScrape")
-		return
+		if err := c.vclient.Create(ctx, converted); err != nil {
+			return c.conversionFailed(src, convertedKind, fmt.Errorf("cannot create %s: %w", convertedKind, err))
+		}
+		l.Info("object was created")
+		c.conversionSucceeded(kind, src, convertedKind, convertedMeta, issues)
+		return nil
 	}
-	log.Info("podScrape was created")
-
-}
-
-// UpdatePodMonitor updates VMPodScrape
-func (c *ConverterController) UpdatePodMonitor(_, new interface{}) {
-	podMonitorNew := new.(*v1.PodMonitor)
-	l := log.WithValues("kind", "podScrape", "name", podMonitorNew.Name, "ns", podMonitorNew.Namespace)
-	podScrape := converter.ConvertPodMonitor(podMonitorNew)
-	ctx := context.Background()
-	existingVMPodScrape := &v1beta1.VMPodScrape{}
-	err := c.vclient.Get(ctx, types.NamespacedName{Name: podScrape.Name, Namespace: podScrape.Namespace}, existingVMPodScrape)
+	existingMeta, err := meta.Accessor(existing)
 	if err != nil {
-		l.Error(err, "cannot get existing podMonitor")
-		return
+		return fmt.Errorf("cannot get metadata of existing object: %w", err)
 	}
-	if existingVMPodScrape.Annotations[IgnoreConversionLabel] == IgnoreConversion {
+	if existingMeta.GetAnnotations()[IgnoreConversionLabel] == IgnoreConversion {
 		l.Info("syncing for object was disabled by annotation", "annotation", IgnoreConversionLabel)
-		return
+		return nil
 	}
+	original := existing.DeepCopyObject()
+	updateSpec(existing)
+	mergeStrategy := getMetaMergeStrategy(existingMeta.GetAnnotations())
+	existingMeta.SetAnnotations(mergeConverterAnnotations(mergeLabelsWithStrategy(existingMeta.GetAnnotations(), convertedMeta.GetAnnotations(), mergeStrategy), convertedMeta.GetAnnotations()))
+	existingMeta.SetLabels(mergeLabelsWithStrategy(existingMeta.GetLabels(), convertedMeta.GetLabels(), mergeStrategy))
+	syncSourceOwner(existingMeta, convertedMeta)
+	// periodic resync triggers conversion of all objects, unchanged objects are skipped.
+	if equality.Semantic.DeepEqual(original, existing) {
+		return nil
+	}
+	if err := c.vclient.Update(ctx, existing); err != nil {
+		return c.conversionFailed(src, convertedKind, fmt.Errorf("cannot update %s: %w", convertedKind, err))
+	}
+	l.Info("object was updated")
+	c.conversionSucceeded(kind, src, convertedKind, convertedMeta, issues)
+	return nil
+}
 
-	existingVMPodScrape.Spec = podScrape.Spec
-	mergeStrategy := getMetaMergeStrategy(existingVMPodScrape.Annotations)
-	existingVMPodScrape.Annotations = mergeLabelsWithStrategy(existingVMPodScrape.Annotations, podScrape.Annotations, mergeStrategy)
-	existingVMPodScrape.Labels = mergeLabelsWithStrategy(existingVMPodScrape.Labels, podScrape.Labels, mergeStrategy)
-
-	err = c.vclient.Update(ctx, existingVMPodScrape)
-	if err != nil {
-		l.Error(err, "cannot update podScrape")
-		return
+func (c *ConverterController) conversionSucceeded(kind string, src sourceObject, convertedKind string, convertedMeta metav1.Object, issues converter.ConversionIssues) {
+	converterConversionsTotal.WithLabelValues(kind).Inc()
+	c.recorder.Eventf(src, corev1.EventTypeNormal, "Converted", "converted into %s %s", convertedKind, convertedMeta.GetName())
+	if len(issues) > 0 {
+		c.recorder.Eventf(src, corev1.EventTypeWarning, "ConversionIssues", "fields were dropped or changed during conversion into %s: %s", convertedKind, issues)
 	}
-	l.Info("podScrape was updated")
+}
 
+func (c *ConverterController) conversionFailed(src sourceObject, convertedKind string, err error) error {
+	c.recorder.Eventf(src, corev1.EventTypeWarning, "ConversionFailed", "cannot convert into %s: %s", convertedKind, err)
+	return err
 }
 
 // default merge strategy - prefer-prometheus
@@ -400,53 +558,22 @@ func getMetaMergeStrategy(vmMeta map[string]string) string {
 	return MetaPreferProm
 }
 
-// CreateProbe converts Probe to VMProbe
-func (c *ConverterController) CreateProbe(obj interface{}) {
-	probe := obj.(*v1.Probe)
-	l := log.WithValues("kind", "vmProbe", "name", probe.Name, "ns", probe.Namespace)
-	l.Info("syncing probes")
-	vmProbe := converter.ConvertProbe(probe)
-	err := c.vclient.Create(context.TODO(), vmProbe)
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			l.Info("vmProbe already exists")
-			c.UpdateProbe(nil, probe)
-			return
-		}
-		l.Error(err, "cannot create vmProbe")
-		return
-	}
-	log.Info("vmProbe was created")
-
-}
+// converterAnnotations are managed by converter,
+// they must be in sync with converted object regardless of meta merge strategy.
+var converterAnnotations = []string{converter.ConversionIssuesAnnotation, SourceAnnotation, DeletionPolicyAnnotation}
 
-// UpdateProbe updates VMProbe
-func (c *ConverterController) UpdateProbe(_, new interface{}) {
-	probeNew := new.(*v1.Probe)
-	l := log.WithValues("kind", "vmProbe", "name", probeNew.Name, "ns", probeNew.Namespace)
-	vmProbe := converter.ConvertProbe(probeNew)
-	ctx := context.Background()
-	existingVMProbe := &v1beta1.VMProbe{}
-	err := c.vclient.Get(ctx, types.NamespacedName{Name: vmProbe.Name, Namespace: vmProbe.Namespace}, existingVMProbe)
-	if err != nil {
-		l.Error(err, "cannot get existing vmProbe")
-		return
-	}
-	if existingVMProbe.Annotations[IgnoreConversionLabel] == IgnoreConversion {
-		l.Info("syncing for object was disabled by annotation", "annotation", IgnoreConversionLabel)
-		return
+// mergeConverterAnnotations returns merged annotations with converter annotations of converted object.
+func mergeConverterAnnotations(merged, converted map[string]string) map[string]string {
+	result := make(map[string]string, len(merged)+len(converterAnnotations))
+	for annotation, value := range merged {
+		result[annotation] = value
 	}
-
-	mergeStrategy := getMetaMergeStrategy(existingVMProbe.Annotations)
-	existingVMProbe.Annotations = mergeLabelsWithStrategy(existingVMProbe.Annotations, probeNew.Annotations, mergeStrategy)
-	existingVMProbe.Labels = mergeLabelsWithStrategy(existingVMProbe.Labels, probeNew.Labels, mergeStrategy)
-
-	existingVMProbe.Spec = vmProbe.Spec
-	err = c.vclient.Update(ctx, existingVMProbe)
-	if err != nil {
-		l.Error(err, "cannot update vmProbe")
-		return
+	for _, annotation := range converterAnnotations {
+		if value, ok := converted[annotation]; ok {
+			result[annotation] = value
+			continue
+		}
+		delete(result, annotation)
 	}
-	l.Info("vmProbe was updated")
-
+	return result
 }
@@ -1,8 +1,21 @@
 package controllers
 
 import (
+	"context"
 	"reflect"
 	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/controllers/converter"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	promfake "github.com/coreos/prometheus-operator/pkg/client/versioned/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func Test_mergeLabelsWithStrategy(t *testing.T) {
@@ -79,3 +92,216 @@ func Test_mergeLabelsWithStrategy(t *testing.T) {
 		})
 	}
 }
+
+func Test_mergeConverterAnnotations(t *testing.T) {
+	tests := []struct {
+		name      string
+		merged    map[string]string
+		converted map[string]string
+		want      map[string]string
+	}{
+		{
+			name:      "stale fields are removed",
+			merged:    map[string]string{"key": "value", "operator.victoriametrics.com/conversion-issues": `[{"field":"spec.thanos"}]`},
+			converted: map[string]string{"key": "value"},
+			want:      map[string]string{"key": "value"},
+		},
+		{
+			name:      "fields are updated",
+			merged:    map[string]string{"operator.victoriametrics.com/conversion-issues": `[{"field":"spec.thanos"}]`},
+			converted: map[string]string{"operator.victoriametrics.com/conversion-issues": `[{"field":"spec.storage"}]`},
+			want:      map[string]string{"operator.victoriametrics.com/conversion-issues": `[{"field":"spec.storage"}]`},
+		},
+		{
+			name:      "source reference is added to empty annotations",
+			converted: map[string]string{"operator.victoriametrics.com/converted-from": "ServiceMonitor/default/example"},
+			want:      map[string]string{"operator.victoriametrics.com/converted-from": "ServiceMonitor/default/example"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeConverterAnnotations(tt.merged, tt.converted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeConverterAnnotations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverterController_sync(t *testing.T) {
+	serviceMonitor := func(labels map[string]string) *v1.ServiceMonitor {
+		return &v1.ServiceMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default", Labels: labels},
+			Spec:       v1.ServiceMonitorSpec{Endpoints: []v1.Endpoint{{Port: "http"}}},
+		}
+	}
+	tests := []struct {
+		name              string
+		kind              string
+		source            interface{}
+		predefinedObjects []runtime.Object
+		wantEvents        []string
+		validate          func(t *testing.T, c *ConverterController)
+	}{
+		{
+			name:       "create vmservicescrape",
+			kind:       v1.ServiceMonitorsKind,
+			source:     serviceMonitor(map[string]string{"team": "infra"}),
+			wantEvents: []string{"Normal Converted converted into VMServiceScrape example"},
+			validate: func(t *testing.T, c *ConverterController) {
+				got := &victoriametricsv1beta1.VMServiceScrape{}
+				if err := c.vclient.Get(context.TODO(), types.NamespacedName{Name: "example", Namespace: "default"}, got); err != nil {
+					t.Fatalf("cannot get vmservicescrape: %v", err)
+				}
+				if got.Labels["team"] != "infra" || len(got.Spec.Endpoints) != 1 {
+					t.Errorf("unexpected vmservicescrape: %v", got)
+				}
+			},
+		},
+		{
+			name:   "unchanged vmservicescrape isn't updated",
+			kind:   v1.ServiceMonitorsKind,
+			source: serviceMonitor(nil),
+			predefinedObjects: []runtime.Object{
+				func() runtime.Object {
+					vmServiceScrape, _ := converter.ConvertServiceMonitor(serviceMonitor(nil))
+					return vmServiceScrape
+				}(),
+			},
+		},
+		{
+			name:   "vmservicescrape with ignored updates",
+			kind:   v1.ServiceMonitorsKind,
+			source: serviceMonitor(nil),
+			predefinedObjects: []runtime.Object{
+				&victoriametricsv1beta1.VMServiceScrape{ObjectMeta: metav1.ObjectMeta{
+					Name:        "example",
+					Namespace:   "default",
+					Annotations: map[string]string{IgnoreConversionLabel: IgnoreConversion},
+				}},
+			},
+			validate: func(t *testing.T, c *ConverterController) {
+				got := &victoriametricsv1beta1.VMServiceScrape{}
+				if err := c.vclient.Get(context.TODO(), types.NamespacedName{Name: "example", Namespace: "default"}, got); err != nil {
+					t.Fatalf("cannot get vmservicescrape: %v", err)
+				}
+				if len(got.Spec.Endpoints) != 0 {
+					t.Errorf("vmservicescrape with ignored updates must not be updated: %v", got.Spec)
+				}
+			},
+		},
+		{
+			name: "prometheus with conversion issues",
+			kind: v1.PrometheusesKind,
+			source: &v1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+				Spec: v1.PrometheusSpec{
+					RemoteWrite: []v1.RemoteWriteSpec{{URL: "http://vmsingle:8429/api/v1/write"}},
+					Thanos:      &v1.ThanosSpec{},
+				},
+			},
+			wantEvents: []string{
+				"Normal Converted converted into VMAgent example",
+				"Warning ConversionIssues fields were dropped or changed during conversion into VMAgent: spec.thanos dropped: thanos sidecar isn't supported",
+			},
+		},
+		{
+			name: "prometheus without rules removes tracked vmalert",
+			kind: v1.PrometheusesKind,
+			source: &v1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+				Spec: v1.PrometheusSpec{
+					RemoteWrite: []v1.RemoteWriteSpec{{URL: "http://vmsingle:8429/api/v1/write"}},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&victoriametricsv1beta1.VMAlert{ObjectMeta: metav1.ObjectMeta{
+					Name:        "example",
+					Namespace:   "default",
+					Annotations: map[string]string{SourceAnnotation: "Prometheus/default/example"},
+				}},
+			},
+			wantEvents: []string{"Normal Converted converted into VMAgent example"},
+			validate: func(t *testing.T, c *ConverterController) {
+				err := c.vclient.Get(context.TODO(), types.NamespacedName{Name: "example", Namespace: "default"}, &victoriametricsv1beta1.VMAlert{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("stale vmalert must be removed, got err: %v", err)
+				}
+			},
+		},
+		{
+			name: "prometheus with local storage only",
+			kind: v1.PrometheusesKind,
+			source: &v1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+				Spec:       v1.PrometheusSpec{Retention: "30d"},
+			},
+			predefinedObjects: []runtime.Object{
+				&victoriametricsv1beta1.VMAgent{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"}},
+			},
+			wantEvents: []string{
+				"Warning ConversionIssues cannot convert into VMAgent: spec.remoteWrite dropped: VMAgent requires remote write, Prometheus with local storage only cannot be converted",
+				"Warning StaleConversion VMAgent example cannot be converted anymore and is kept, since deletion sync is disabled for it",
+			},
+			validate: func(t *testing.T, c *ConverterController) {
+				if err := c.vclient.Get(context.TODO(), types.NamespacedName{Name: "example", Namespace: "default"}, &victoriametricsv1beta1.VMAgent{}); err != nil {
+					t.Errorf("untracked vmagent must be kept, got err: %v", err)
+				}
+			},
+		},
+		{
+			name: "deleted source",
+			kind: v1.ServiceMonitorsKind,
+			predefinedObjects: []runtime.Object{
+				&victoriametricsv1beta1.VMServiceScrape{ObjectMeta: metav1.ObjectMeta{
+					Name:        "example",
+					Namespace:   "default",
+					Annotations: map[string]string{SourceAnnotation: "ServiceMonitor/default/example"},
+				}},
+			},
+			validate: func(t *testing.T, c *ConverterController) {
+				err := c.vclient.Get(context.TODO(), types.NamespacedName{Name: "example", Namespace: "default"}, &victoriametricsv1beta1.VMServiceScrape{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("vmservicescrape of deleted source must be removed, got err: %v", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			c := NewConverterController(promfake.NewSimpleClientset(), fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...), recorder, config.MustGetBaseConfig())
+			if tt.source != nil {
+				if err := c.informerFor(tt.kind).GetStore().Add(tt.source); err != nil {
+					t.Fatalf("cannot add source to informer store: %v", err)
+				}
+			}
+			if err := c.sync(context.TODO(), converterQueueItem{kind: tt.kind, key: "default/example"}); err != nil {
+				t.Fatalf("sync() error = %v", err)
+			}
+			close(recorder.Events)
+			var gotEvents []string
+			for event := range recorder.Events {
+				gotEvents = append(gotEvents, event)
+			}
+			if !reflect.DeepEqual(gotEvents, tt.wantEvents) {
+				t.Errorf("sync() events = %v, want %v", gotEvents, tt.wantEvents)
+			}
+			if tt.validate != nil {
+				tt.validate(t, c)
+			}
+		})
+	}
+}
+
+func TestConverterController_processNextItem(t *testing.T) {
+	c := NewConverterController(promfake.NewSimpleClientset(), fake.NewFakeClientWithScheme(testGetScheme()), record.NewFakeRecorder(10), config.MustGetBaseConfig())
+	defer c.queue.ShutDown()
+	item := converterQueueItem{kind: "Unknown", key: "default/example"}
+	c.queue.Add(item)
+	if !c.processNextItem(context.TODO()) {
+		t.Fatalf("processNextItem() must continue processing")
+	}
+	if c.queue.NumRequeues(item) != 1 {
+		t.Errorf("failed item must be requeued with rate limit, got requeues: %d", c.queue.NumRequeues(item))
+	}
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/VictoriaMetrics/operator/api/v1beta1"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// SourceAnnotation references prometheus-operator object, which VMObject was converted from.
+	// It's added only if deletion sync is enabled for object, value has format kind/namespace/name.
+	SourceAnnotation = "operator.victoriametrics.com/converted-from"
+	// DeletionPolicyAnnotation enables deletion sync for prometheus-operator object
+	// and defines what happens with VMObject, when its source is deleted:
+	// annotations:
+	//   operator.victoriametrics.com/converter-deletion-policy: orphan
+	DeletionPolicyAnnotation = "operator.victoriametrics.com/converter-deletion-policy"
+	// DeletionPolicyDelete - VMObject is deleted with its source, default policy.
+	DeletionPolicyDelete = "delete"
+	// DeletionPolicyOrphan - VMObject is kept, but source reference is removed from it.
+	DeletionPolicyOrphan = "orphan"
+)
+
+// convertedObjects returns empty objects converted from prometheus-operator kind.
+func convertedObjects(kind string) []runtime.Object {
+	switch kind {
+	case v1.ServiceMonitorsKind:
+		return []runtime.Object{&v1beta1.VMServiceScrape{}}
+	case v1.PodMonitorsKind:
+		return []runtime.Object{&v1beta1.VMPodScrape{}}
+	case v1.PrometheusRuleKind:
+		return []runtime.Object{&v1beta1.VMRule{}}
+	case v1.ProbesKind:
+		return []runtime.Object{&v1beta1.VMProbe{}}
+	case v1.PrometheusesKind:
+		return []runtime.Object{&v1beta1.VMAgent{}, &v1beta1.VMAlert{}}
+	case v1.AlertmanagersKind:
+		return []runtime.Object{&v1beta1.VMAlertmanager{}}
+	}
+	return nil
+}
+
+// convertedLists returns empty lists of objects converted from prometheus-operator kind.
+func convertedLists(kind string) []runtime.Object {
+	switch kind {
+	case v1.ServiceMonitorsKind:
+		return []runtime.Object{&v1beta1.VMServiceScrapeList{}}
+	case v1.PodMonitorsKind:
+		return []runtime.Object{&v1beta1.VMPodScrapeList{}}
+	case v1.PrometheusRuleKind:
+		return []runtime.Object{&v1beta1.VMRuleList{}}
+	case v1.ProbesKind:
+		return []runtime.Object{&v1beta1.VMProbeList{}}
+	case v1.PrometheusesKind:
+		return []runtime.Object{&v1beta1.VMAgentList{}, &v1beta1.VMAlertList{}}
+	case v1.AlertmanagersKind:
+		return []runtime.Object{&v1beta1.VMAlertmanagerList{}}
+	}
+	return nil
+}
+
+func sourceReference(kind string, src metav1.Object) string {
+	return fmt.Sprintf("%s/%s/%s", kind, src.GetNamespace(), src.GetName())
+}
+
+// deletionSyncEnabled checks if deletion sync is enabled globally or by annotation of prometheus-operator object.
+func (c *ConverterController) deletionSyncEnabled(src metav1.Object) bool {
+	switch src.GetAnnotations()[DeletionPolicyAnnotation] {
+	case DeletionPolicyDelete, DeletionPolicyOrphan:
+		return true
+	}
+	return c.deletionSync
+}
+
+// trackSource adds source reference to converted object, if deletion sync is enabled.
+// Owner reference is added only for delete policy, so kubernetes garbage collector removes
+// converted object even if operator isn't running.
+func (c *ConverterController) trackSource(kind string, src metav1.Object, converted metav1.Object) {
+	if !c.deletionSyncEnabled(src) {
+		return
+	}
+	annotations := make(map[string]string, len(converted.GetAnnotations())+1)
+	for annotation, value := range converted.GetAnnotations() {
+		annotations[annotation] = value
+	}
+	annotations[SourceAnnotation] = sourceReference(kind, src)
+	// policy is kept at converted object, since source isn't available after deletion
+	policy := src.GetAnnotations()[DeletionPolicyAnnotation]
+	if policy != "" {
+		annotations[DeletionPolicyAnnotation] = policy
+	}
+	converted.SetAnnotations(annotations)
+	if policy == DeletionPolicyOrphan {
+		return
+	}
+	converted.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: v1.SchemeGroupVersion.String(),
+			Kind:       kind,
+			Name:       src.GetName(),
+			UID:        src.GetUID(),
+		},
+	})
+}
+
+// syncSourceOwner replaces owner references to prometheus-operator objects at existing object with converted ones.
+func syncSourceOwner(existing, converted metav1.Object) {
+	var refs []metav1.OwnerReference
+	for _, ref := range existing.GetOwnerReferences() {
+		if ref.APIVersion != v1.SchemeGroupVersion.String() {
+			refs = append(refs, ref)
+		}
+	}
+	existing.SetOwnerReferences(append(refs, converted.GetOwnerReferences()...))
+}
+
+// syncSourceDeletion applies deletion policy to objects converted from deleted prometheus-operator object.
+// Objects without source reference were converted before deletion sync was enabled, they're kept as is.
+func (c *ConverterController) syncSourceDeletion(ctx context.Context, kind string, src metav1.Object) error {
+	for _, converted := range convertedObjects(kind) {
+		err := c.vclient.Get(ctx, types.NamespacedName{Name: src.GetName(), Namespace: src.GetNamespace()}, converted)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("cannot get object converted from %s: %w", sourceReference(kind, src), err)
+		}
+		convertedMeta, err := meta.Accessor(converted)
+		if err != nil {
+			return fmt.Errorf("cannot get metadata of converted object: %w", err)
+		}
+		if convertedMeta.GetAnnotations()[SourceAnnotation] != sourceReference(kind, src) {
+			continue
+		}
+		if err := c.applyDeletionPolicy(ctx, converted, convertedMeta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncStaleConverted applies deletion policy to object converted from existing source earlier,
+// which cannot be converted anymore, for instance VMAlert of Prometheus without ruleSelector.
+// Objects without source reference are kept, since deletion sync is disabled for them.
+func (c *ConverterController) syncStaleConverted(ctx context.Context, kind string, src sourceObject, stale runtime.Object) error {
+	staleKind := reflect.TypeOf(stale).Elem().Name()
+	err := c.vclient.Get(ctx, types.NamespacedName{Name: src.GetName(), Namespace: src.GetNamespace()}, stale)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot get %s converted from %s: %w", staleKind, sourceReference(kind, src), err)
+	}
+	staleMeta, err := meta.Accessor(stale)
+	if err != nil {
+		return fmt.Errorf("cannot get metadata of converted object: %w", err)
+	}
+	if staleMeta.GetAnnotations()[SourceAnnotation] != sourceReference(kind, src) {
+		c.recorder.Eventf(src, corev1.EventTypeWarning, "StaleConversion", "%s %s cannot be converted anymore and is kept, since deletion sync is disabled for it", staleKind, staleMeta.GetName())
+		return nil
+	}
+	return c.applyDeletionPolicy(ctx, stale, staleMeta)
+}
+
+// applyDeletionPolicy deletes or orphans converted object, which source was deleted.
+func (c *ConverterController) applyDeletionPolicy(ctx context.Context, converted runtime.Object, convertedMeta metav1.Object) error {
+	l := log.WithValues("name", convertedMeta.GetName(), "ns", convertedMeta.GetNamespace(), "source", convertedMeta.GetAnnotations()[SourceAnnotation])
+	if convertedMeta.GetAnnotations()[IgnoreConversionLabel] == IgnoreConversion {
+		l.Info("syncing for object was disabled by annotation", "annotation", IgnoreConversionLabel)
+		return nil
+	}
+	if convertedMeta.GetAnnotations()[DeletionPolicyAnnotation] == DeletionPolicyOrphan {
+		annotations := make(map[string]string, len(convertedMeta.GetAnnotations()))
+		for annotation, value := range convertedMeta.GetAnnotations() {
+			annotations[annotation] = value
+		}
+		delete(annotations, SourceAnnotation)
+		convertedMeta.SetAnnotations(annotations)
+		syncSourceOwner(convertedMeta, &metav1.ObjectMeta{})
+		if err := c.vclient.Update(ctx, converted); err != nil {
+			return fmt.Errorf("cannot orphan converted object: %w", err)
+		}
+		l.Info("source was deleted, converted object was orphaned")
+		return nil
+	}
+	if err := c.vclient.Delete(ctx, converted); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete converted object: %w", err)
+	}
+	l.Info("source was deleted, converted object was removed")
+	return nil
+}
+
+// sourceExists checks if prometheus-operator object referenced by converted object still exists.
+func (c *ConverterController) sourceExists(ctx context.Context, kind, namespace, name string) (bool, error) {
+	var err error
+	monitoring := c.promClient.MonitoringV1()
+	switch kind {
+	case v1.ServiceMonitorsKind:
+		_, err = monitoring.ServiceMonitors(namespace).Get(ctx, name, metav1.GetOptions{})
+	case v1.PodMonitorsKind:
+		_, err = monitoring.PodMonitors(namespace).Get(ctx, name, metav1.GetOptions{})
+	case v1.PrometheusRuleKind:
+		_, err = monitoring.PrometheusRules(namespace).Get(ctx, name, metav1.GetOptions{})
+	case v1.ProbesKind:
+		_, err = monitoring.Probes(namespace).Get(ctx, name, metav1.GetOptions{})
+	case v1.PrometheusesKind:
+		_, err = monitoring.Prometheuses(namespace).Get(ctx, name, metav1.GetOptions{})
+	case v1.AlertmanagersKind:
+		_, err = monitoring.Alertmanagers(namespace).Get(ctx, name, metav1.GetOptions{})
+	default:
+		return false, fmt.Errorf("unsupported source kind: %s", kind)
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// collectOrphans applies deletion policy to converted objects,
+// which sources were deleted while operator wasn't running.
+func (c *ConverterController) collectOrphans(ctx context.Context, kind string) error {
+	l := log.WithValues("kind", kind)
+	for _, list := range convertedLists(kind) {
+		if err := c.vclient.List(ctx, list); err != nil {
+			return fmt.Errorf("cannot list converted objects for kind: %s, err: %w", kind, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return fmt.Errorf("cannot extract converted objects for kind: %s, err: %w", kind, err)
+		}
+		for _, converted := range items {
+			convertedMeta, err := meta.Accessor(converted)
+			if err != nil {
+				return fmt.Errorf("cannot get metadata of converted object: %w", err)
+			}
+			source := strings.SplitN(convertedMeta.GetAnnotations()[SourceAnnotation], "/", 3)
+			if len(source) != 3 || source[0] != kind {
+				continue
+			}
+			exists, err := c.sourceExists(ctx, kind, source[1], source[2])
+			if err != nil {
+				return fmt.Errorf("cannot check source of converted object: %s, err: %w", convertedMeta.GetName(), err)
+			}
+			if exists {
+				continue
+			}
+			l.Info("source of converted object was deleted while operator was down", "name", convertedMeta.GetName(), "ns", convertedMeta.GetNamespace())
+			if err := c.applyDeletionPolicy(ctx, converted, convertedMeta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	promfake "github.com/coreos/prometheus-operator/pkg/client/versioned/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConverterController_trackSource(t *testing.T) {
+	tests := []struct {
+		name         string
+		deletionSync bool
+		annotations  map[string]string
+		wantSource   string
+		wantOwner    bool
+	}{
+		{
+			name: "deletion sync disabled",
+		},
+		{
+			name:         "deletion sync enabled by config",
+			deletionSync: true,
+			wantSource:   "ServiceMonitor/default/example",
+			wantOwner:    true,
+		},
+		{
+			name:        "orphan policy enabled by annotation",
+			annotations: map[string]string{DeletionPolicyAnnotation: DeletionPolicyOrphan},
+			wantSource:  "ServiceMonitor/default/example",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ConverterController{deletionSync: tt.deletionSync}
+			src := &v1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default", UID: "uid-1", Annotations: tt.annotations}}
+			converted := &victoriametricsv1beta1.VMServiceScrape{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default", Annotations: tt.annotations}}
+			c.trackSource(v1.ServiceMonitorsKind, src, converted)
+			if got := converted.Annotations[SourceAnnotation]; got != tt.wantSource {
+				t.Errorf("trackSource() source = %s, want %s", got, tt.wantSource)
+			}
+			if _, ok := src.Annotations[SourceAnnotation]; ok {
+				t.Errorf("trackSource() must not modify annotations of source")
+			}
+			if gotOwner := len(converted.OwnerReferences) > 0; gotOwner != tt.wantOwner {
+				t.Fatalf("trackSource() owner references = %v, want owner: %v", converted.OwnerReferences, tt.wantOwner)
+			}
+			if tt.wantOwner && (converted.OwnerReferences[0].Kind != v1.ServiceMonitorsKind || converted.OwnerReferences[0].UID != src.UID) {
+				t.Errorf("trackSource() unexpected owner reference: %v", converted.OwnerReferences[0])
+			}
+		})
+	}
+}
+
+func TestConverterController_syncSourceDeletion(t *testing.T) {
+	convertedScrape := func(annotations map[string]string) *victoriametricsv1beta1.VMServiceScrape {
+		return &victoriametricsv1beta1.VMServiceScrape{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default", Annotations: annotations}}
+	}
+	tests := []struct {
+		name              string
+		predefinedObjects []runtime.Object
+		wantDeleted       bool
+		wantSource        bool
+	}{
+		{
+			name:              "delete converted object",
+			predefinedObjects: []runtime.Object{convertedScrape(map[string]string{SourceAnnotation: "ServiceMonitor/default/example"})},
+			wantDeleted:       true,
+		},
+		{
+			name: "orphan converted object",
+			predefinedObjects: []runtime.Object{convertedScrape(map[string]string{
+				SourceAnnotation:         "ServiceMonitor/default/example",
+				DeletionPolicyAnnotation: DeletionPolicyOrphan,
+			})},
+		},
+		{
+			name:              "keep object converted without deletion sync",
+			predefinedObjects: []runtime.Object{convertedScrape(nil)},
+		},
+		{
+			name: "keep object with ignored updates",
+			predefinedObjects: []runtime.Object{convertedScrape(map[string]string{
+				SourceAnnotation:      "ServiceMonitor/default/example",
+				IgnoreConversionLabel: IgnoreConversion,
+			})},
+			wantSource: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ConverterController{vclient: fake.NewFakeClientWithScheme(testGetScheme(), tt.predefinedObjects...)}
+			src := &v1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"}}
+			if err := c.syncSourceDeletion(context.TODO(), v1.ServiceMonitorsKind, src); err != nil {
+				t.Fatalf("syncSourceDeletion() error = %v", err)
+			}
+			got := &victoriametricsv1beta1.VMServiceScrape{}
+			err := c.vclient.Get(context.TODO(), types.NamespacedName{Name: "example", Namespace: "default"}, got)
+			if tt.wantDeleted {
+				if !errors.IsNotFound(err) {
+					t.Fatalf("converted object must be deleted, got err: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cannot get converted object: %v", err)
+			}
+			if _, ok := got.Annotations[SourceAnnotation]; ok != tt.wantSource {
+				t.Errorf("unexpected source annotation: %v", got.Annotations)
+			}
+		})
+	}
+}
+
+func TestConverterController_collectOrphans(t *testing.T) {
+	tracked := func(name string) *victoriametricsv1beta1.VMRule {
+		return &victoriametricsv1beta1.VMRule{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: map[string]string{SourceAnnotation: "PrometheusRule/default/" + name},
+		}}
+	}
+	c := &ConverterController{
+		promClient: promfake.NewSimpleClientset(&v1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: "alive", Namespace: "default"}}),
+		vclient: fake.NewFakeClientWithScheme(testGetScheme(),
+			tracked("alive"),
+			tracked("deleted"),
+			&victoriametricsv1beta1.VMRule{ObjectMeta: metav1.ObjectMeta{Name: "untracked", Namespace: "default"}},
+		),
+	}
+	if err := c.collectOrphans(context.TODO(), v1.PrometheusRuleKind); err != nil {
+		t.Fatalf("collectOrphans() error = %v", err)
+	}
+	for name, wantExists := range map[string]bool{"alive": true, "deleted": false, "untracked": true} {
+		err := c.vclient.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, &victoriametricsv1beta1.VMRule{})
+		if wantExists && err != nil {
+			t.Errorf("vmrule: %s must exist, got err: %v", name, err)
+		}
+		if !wantExists && !errors.IsNotFound(err) {
+			t.Errorf("vmrule: %s must be deleted, got err: %v", name, err)
+		}
+	}
+}
@@ -18,6 +18,8 @@ package controllers
 
 import (
 	"context"
+	"reflect"
+
 	"github.com/VictoriaMetrics/operator/controllers/factory"
 	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/go-logr/logr"
@@ -25,6 +27,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
 )
@@ -95,5 +99,14 @@ func (r *VMRuleReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 func (r *VMRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&victoriametricsv1beta1.VMRule{}).
+		WithEventFilter(namespacesFilter(r.BaseConf)).
+		WithEventFilter(predicate.Funcs{
+			// status of VMRule is updated during reconcile,
+			// skip such updates to prevent reconcile loop.
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+					!reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels())
+			},
+		}).
 		Complete(r)
 }
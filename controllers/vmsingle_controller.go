@@ -44,19 +44,26 @@ type VMSingleReconciler struct {
 // +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=*
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=*
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmsingles/status,verbs=get;update;patch
-func (r *VMSingleReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=*
+func (r *VMSingleReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := r.Log.WithValues("vmsingle", req.NamespacedName)
 	reqLogger.Info("Reconciling vmsingle")
 
 	ctx := context.Background()
 	instance := &victoriametricsv1beta1.VMSingle{}
-	err := r.Get(ctx, req.NamespacedName, instance)
+	err = r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	defer func() {
+		if statusErr := factory.UpdateVMSingleStatus(ctx, instance, r, err); statusErr != nil {
+			reqLogger.Error(statusErr, "cannot update vmsingle status")
+		}
+	}()
 
 	if instance.Spec.Storage != nil {
 		reqLogger.Info("storage specified reconcile it")
@@ -80,7 +87,7 @@ func (r *VMSingleReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	//create vmservicescrape for object by default
 	if !r.BaseConf.DisableSelfServiceScrapeCreation {
-		err := factory.CreateVMServiceScrapeFromService(ctx, r, svc, instance.MetricPath())
+		err := factory.CreateVMServiceScrapeFromService(ctx, r, svc, instance.MetricPath(), "http")
 		if err != nil {
 			reqLogger.Error(err, "cannot create serviceScrape for vmsingle")
 		}
@@ -92,8 +99,15 @@ func (r *VMSingleReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 // SetupWithManager general setup method
 func (r *VMSingleReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	bld := ctrl.NewControllerManagedBy(mgr).
 		For(&victoriametricsv1beta1.VMSingle{}).
-		Owns(&appsv1.Deployment{}).
-		Complete(r)
+		WithEventFilter(namespacesFilter(r.BaseConf)).
+		Owns(&appsv1.Deployment{})
+	bld, err := watchReferences(mgr, bld, &victoriametricsv1beta1.VMSingle{}, func() runtime.Object {
+		return &victoriametricsv1beta1.VMSingleList{}
+	})
+	if err != nil {
+		return err
+	}
+	return bld.Complete(r)
 }
@@ -3,14 +3,18 @@ module github.com/VictoriaMetrics/operator
 go 1.13
 
 require (
+	github.com/VictoriaMetrics/metricsql v0.10.0
 	github.com/blang/semver v3.5.1+incompatible
 	github.com/coreos/prometheus-operator v0.41.0
 	github.com/ghodss/yaml v1.0.1-0.20190212211648-25d852aebe32
+	github.com/go-kit/kit v0.10.0
 	github.com/go-logr/logr v0.1.0
 	github.com/go-test/deep v1.0.7
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/onsi/ginkgo v1.12.1
 	github.com/onsi/gomega v1.10.1
+	github.com/prometheus/client_golang v1.6.0
+	github.com/prometheus/common v0.10.0
 	github.com/spf13/pflag v1.0.5
 	golang.org/x/net v0.0.0-20200625001655-4c5254603344
 	golang.org/x/sync v0.0.0-20200317015054-43a5402ce75a
@@ -21,7 +25,6 @@ require (
 	k8s.io/utils v0.0.0-20200603063816-c1c6865ac451
 	sigs.k8s.io/controller-runtime v0.6.2
 	sigs.k8s.io/testing_frameworks v0.1.2 // indirect
-
 )
 
 // Pinned to kubernetes-1.18.6
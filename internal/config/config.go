@@ -1,11 +1,13 @@
 package config
 
 import (
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	v1 "k8s.io/api/core/v1"
 )
 
 var (
@@ -122,6 +124,25 @@ type BaseOperatorConf struct {
 		}
 	}
 
+	VMAuthDefault struct {
+		Image             string `default:"victoriametrics/vmauth"`
+		Version           string `default:"v1.49.0"`
+		ConfigReloadImage string `default:"quay.io/coreos/prometheus-config-reloader:v0.42.0"`
+		Port              string `default:"8427"`
+		Resource          struct {
+			Limit struct {
+				Mem string `default:"200Mi"`
+				Cpu string `default:"200m"`
+			}
+			Request struct {
+				Mem string `default:"100Mi"`
+				Cpu string `default:"50m"`
+			}
+		}
+		ConfigReloaderCPU    string `default:"100m"`
+		ConfigReloaderMemory string `default:"25Mi"`
+	}
+
 	VMAlertManager struct {
 		ConfigReloaderImage          string `default:"jimmidyson/configmap-reload:v0.3.0"`
 		ConfigReloaderCPU            string `default:"100m"`
@@ -155,7 +176,16 @@ type BaseOperatorConf struct {
 		ServiceScrape  bool `default:"true"`
 		PrometheusRule bool `default:"true"`
 		Probe          bool `default:"true"`
+		// Prometheus and Alertmanager conversion creates new workloads, so it must be enabled explicitly.
+		Prometheus   bool `default:"false"`
+		Alertmanager bool `default:"false"`
 	}
+	// EnabledPrometheusConverterDeletionSync removes or orphans converted objects, when prometheus-operator objects are deleted.
+	// It can be enabled for single object with operator.victoriametrics.com/converter-deletion-policy annotation.
+	EnabledPrometheusConverterDeletionSync bool `default:"false"`
+	// PrometheusConverterResyncPeriod defines how often all prometheus-operator objects are converted again.
+	PrometheusConverterResyncPeriod time.Duration `default:"5m"`
+
 	Host                      string `default:"0.0.0.0"`
 	ListenAddress             string `default:"0.0.0.0"`
 	DefaultLabels             string `default:"managed-by=vm-operator"`
@@ -166,6 +196,29 @@ type BaseOperatorConf struct {
 	PodWaitReadyTimeout       time.Duration `default:"80s"`
 	PodWaitReadyIntervalCheck time.Duration `default:"5s"`
 	PodWaitReadyInitDelay     time.Duration `default:"10s"`
+	// VMClusterMaxConcurrentReconciles is the number of VMClusters, which can be reconciled in parallel.
+	VMClusterMaxConcurrentReconciles int `default:"5"`
+	// VMClusterRollbackFailureThreshold is the number of failed updates of VMCluster component,
+	// after which component is rolled back to the last known good revision. Zero disables rollback.
+	VMClusterRollbackFailureThreshold int `default:"3"`
+	// VMClusterFailureCountInterval is the interval, after which repeated rollout failure of the same VMCluster component revision
+	// is counted as new failed update attempt. Failures of new revision are counted at once.
+	VMClusterFailureCountInterval time.Duration `default:"5m"`
+	// VMClusterRolloutDeadline is the time, during which new revision of VMCluster component must become ready.
+	// Otherwise component is rolled back to the last known good revision. Zero disables deadline.
+	VMClusterRolloutDeadline time.Duration `default:"15m"`
+	// VMStorageDrainCheckInterval is the interval between checks of draining vmstorage nodes after scale down.
+	VMStorageDrainCheckInterval time.Duration `default:"1m"`
+	// VMAgentSyncDebounce delays VMAgent reconcile after scrape objects changes,
+	// multiple changes during this interval are merged into single reconcile.
+	VMAgentSyncDebounce time.Duration `default:"5s"`
+	// WatchNamespaces restricts operator to the given comma separated list of namespaces.
+	// Operator watches all namespaces by default.
+	WatchNamespaces []string
+	// DenyNamespaces excludes given namespaces from watching,
+	// it has no effect, if WatchNamespaces is set.
+	DenyNamespaces []string
+	Namespaces     Namespaces `ignored:"true"`
 }
 
 func MustGetBaseConfig() *BaseOperatorConf {
@@ -183,6 +236,7 @@ func MustGetBaseConfig() *BaseOperatorConf {
 			}
 			c.Labels = defL
 		}
+		c.Namespaces = NewNamespaces(c.WatchNamespaces, c.DenyNamespaces)
 		opConf = c
 	})
 	return opConf
@@ -233,3 +287,59 @@ type Namespaces struct {
 	// allow list for prometheus/alertmanager custom resources
 
 }
+
+// NewNamespaces builds allow and deny lists.
+// Empty allow list means all namespaces (v1.NamespaceAll),
+// deny list is applied only in this case.
+func NewNamespaces(allowed, denied []string) Namespaces {
+	ns := Namespaces{
+		AllowList: map[string]struct{}{},
+		DenyList:  map[string]struct{}{},
+	}
+	for _, n := range allowed {
+		n = strings.TrimSpace(n)
+		if n != "" {
+			ns.AllowList[n] = struct{}{}
+		}
+	}
+	if len(ns.AllowList) == 0 {
+		ns.AllowList[v1.NamespaceAll] = struct{}{}
+		for _, n := range denied {
+			n = strings.TrimSpace(n)
+			if n != "" {
+				ns.DenyList[n] = struct{}{}
+			}
+		}
+	}
+	return ns
+}
+
+// IsAllNamespaces returns true if operator isn't restricted by allow list.
+func (ns Namespaces) IsAllNamespaces() bool {
+	_, ok := ns.AllowList[v1.NamespaceAll]
+	return ok || len(ns.AllowList) == 0
+}
+
+// IsAllowed checks if objects from given namespace must be managed by operator.
+func (ns Namespaces) IsAllowed(namespace string) bool {
+	if ns.IsAllNamespaces() {
+		_, denied := ns.DenyList[namespace]
+		return !denied
+	}
+	_, ok := ns.AllowList[namespace]
+	return ok
+}
+
+// Allowed returns sorted list of allowed namespaces,
+// it returns nil if operator watches all namespaces.
+func (ns Namespaces) Allowed() []string {
+	if ns.IsAllNamespaces() {
+		return nil
+	}
+	allowed := make([]string, 0, len(ns.AllowList))
+	for n := range ns.AllowList {
+		allowed = append(allowed, n)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
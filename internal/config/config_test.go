@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespaces_IsAllowed(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		denied      []string
+		namespace   string
+		want        bool
+		wantAllowed []string
+	}{
+		{
+			name:      "all namespaces",
+			namespace: "default",
+			want:      true,
+		},
+		{
+			name:      "denied namespace",
+			denied:    []string{"kube-system", "default"},
+			namespace: "default",
+			want:      false,
+		},
+		{
+			name:        "allowed namespace",
+			allowed:     []string{"tenant-b", " tenant-a"},
+			namespace:   "tenant-a",
+			want:        true,
+			wantAllowed: []string{"tenant-a", "tenant-b"},
+		},
+		{
+			name:        "not allowed namespace",
+			allowed:     []string{"tenant-a"},
+			namespace:   "default",
+			want:        false,
+			wantAllowed: []string{"tenant-a"},
+		},
+		{
+			name:        "deny list ignored with allow list",
+			allowed:     []string{"tenant-a"},
+			denied:      []string{"tenant-a"},
+			namespace:   "tenant-a",
+			want:        true,
+			wantAllowed: []string{"tenant-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := NewNamespaces(tt.allowed, tt.denied)
+			if got := ns.IsAllowed(tt.namespace); got != tt.want {
+				t.Errorf("IsAllowed() = %v, want %v", got, tt.want)
+			}
+			if got := ns.Allowed(); !reflect.DeepEqual(got, tt.wantAllowed) {
+				t.Errorf("Allowed() = %v, want %v", got, tt.wantAllowed)
+			}
+		})
+	}
+}
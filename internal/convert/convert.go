@@ -0,0 +1,252 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	victoriametricsv1beta1 "github.com/VictoriaMetrics/operator/api/v1beta1"
+	"github.com/VictoriaMetrics/operator/controllers/converter"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Command is the name of subcommand, which converts prometheus-operator manifests.
+const Command = "convert"
+
+const usage = `Usage: manager convert [-report] [file ...]
+
+Converts prometheus-operator objects from multi-document yaml files into VictoriaMetrics objects
+and prints them to stdout. Objects are read from stdin, if files aren't set or file is "-".
+Supported kinds: ServiceMonitor, PodMonitor, PrometheusRule, Probe, Prometheus and Alertmanager.
+Cluster connection isn't required.
+
+`
+
+// convertedObject is VictoriaMetrics object with issues of its conversion.
+type convertedObject struct {
+	obj    runtime.Object
+	issues converter.ConversionIssues
+}
+
+// Run converts prometheus-operator manifests from files or stdin into VictoriaMetrics manifests.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet(Command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	report := flags.Bool("report", false, "Print fields, which were dropped or changed during conversion, to stderr.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var converted []convertedObject
+	for _, file := range files {
+		objs, err := convertFile(file, stdin, stderr)
+		if err != nil {
+			return err
+		}
+		converted = append(converted, objs...)
+	}
+	if err := writeObjects(stdout, converted); err != nil {
+		return err
+	}
+	if *report {
+		return writeReport(stderr, converted)
+	}
+	return nil
+}
+
+func convertFile(file string, stdin io.Reader, stderr io.Writer) ([]convertedObject, error) {
+	r := stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	var converted []convertedObject
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return converted, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read document from %s: %w", file, err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		objs, err := convertDocument(doc, stderr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert document from %s: %w", file, err)
+		}
+		converted = append(converted, objs...)
+	}
+}
+
+// convertDocument converts single yaml document, List kind is converted item by item.
+// Objects of unsupported kinds are skipped with warning.
+func convertDocument(doc []byte, stderr io.Writer) ([]convertedObject, error) {
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+		return nil, fmt.Errorf("cannot parse object kind: %w", err)
+	}
+	// comments only document
+	if typeMeta.Kind == "" && typeMeta.APIVersion == "" {
+		return nil, nil
+	}
+	if typeMeta.Kind == "List" {
+		var list struct {
+			Items []runtime.RawExtension `json:"items"`
+		}
+		if err := yaml.Unmarshal(doc, &list); err != nil {
+			return nil, fmt.Errorf("cannot parse list: %w", err)
+		}
+		var converted []convertedObject
+		for _, item := range list.Items {
+			objs, err := convertDocument(item.Raw, stderr)
+			if err != nil {
+				return nil, err
+			}
+			converted = append(converted, objs...)
+		}
+		return converted, nil
+	}
+	if typeMeta.GroupVersionKind().Group != v1.SchemeGroupVersion.Group {
+		fmt.Fprintf(stderr, "skipping object with unsupported apiVersion: %s, kind: %s\n", typeMeta.APIVersion, typeMeta.Kind)
+		return nil, nil
+	}
+	var converted []convertedObject
+	add := func(obj runtime.Object, issues converter.ConversionIssues) {
+		converted = append(converted, convertedObject{obj: obj, issues: issues})
+	}
+	switch typeMeta.Kind {
+	case v1.ServiceMonitorsKind:
+		var src v1.ServiceMonitor
+		if err := yaml.Unmarshal(doc, &src); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", typeMeta.Kind, err)
+		}
+		add(converter.ConvertServiceMonitor(&src))
+	case v1.PodMonitorsKind:
+		var src v1.PodMonitor
+		if err := yaml.Unmarshal(doc, &src); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", typeMeta.Kind, err)
+		}
+		add(converter.ConvertPodMonitor(&src))
+	case v1.PrometheusRuleKind:
+		var src v1.PrometheusRule
+		if err := yaml.Unmarshal(doc, &src); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", typeMeta.Kind, err)
+		}
+		add(converter.ConvertPromRule(&src))
+	case v1.ProbesKind:
+		var src v1.Probe
+		if err := yaml.Unmarshal(doc, &src); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", typeMeta.Kind, err)
+		}
+		add(converter.ConvertProbe(&src))
+	case v1.PrometheusesKind:
+		var src v1.Prometheus
+		if err := yaml.Unmarshal(doc, &src); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", typeMeta.Kind, err)
+		}
+		if vmAgent, issues := converter.ConvertPrometheus(&src); vmAgent != nil {
+			add(vmAgent, issues)
+		} else {
+			fmt.Fprintf(stderr, "skipping %s %s/%s: %s\n", typeMeta.Kind, src.Namespace, src.Name, issues)
+		}
+		if vmAlert, issues := converter.ConvertPrometheusRules(&src); vmAlert != nil {
+			add(vmAlert, issues)
+		}
+	case v1.AlertmanagersKind:
+		var src v1.Alertmanager
+		if err := yaml.Unmarshal(doc, &src); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", typeMeta.Kind, err)
+		}
+		add(converter.ConvertAlertmanager(&src))
+	default:
+		fmt.Fprintf(stderr, "skipping object with unsupported kind: %s\n", typeMeta.Kind)
+	}
+	return converted, nil
+}
+
+// objectKind returns kind of VictoriaMetrics object by its go type.
+func objectKind(obj runtime.Object) string {
+	switch obj.(type) {
+	case *victoriametricsv1beta1.VMServiceScrape:
+		return "VMServiceScrape"
+	case *victoriametricsv1beta1.VMPodScrape:
+		return "VMPodScrape"
+	case *victoriametricsv1beta1.VMRule:
+		return "VMRule"
+	case *victoriametricsv1beta1.VMProbe:
+		return "VMProbe"
+	case *victoriametricsv1beta1.VMAgent:
+		return "VMAgent"
+	case *victoriametricsv1beta1.VMAlert:
+		return "VMAlert"
+	case *victoriametricsv1beta1.VMAlertmanager:
+		return "VMAlertmanager"
+	}
+	return ""
+}
+
+// writeObjects prints converted objects as multi-document yaml.
+// Status and creationTimestamp are managed by cluster, they're omitted from manifests.
+func writeObjects(w io.Writer, converted []convertedObject) error {
+	for i, c := range converted {
+		c.obj.GetObjectKind().SetGroupVersionKind(victoriametricsv1beta1.GroupVersion.WithKind(objectKind(c.obj)))
+		manifest, err := runtime.DefaultUnstructuredConverter.ToUnstructured(c.obj)
+		if err != nil {
+			return fmt.Errorf("cannot convert object into manifest: %w", err)
+		}
+		unstructured.RemoveNestedField(manifest, "status")
+		unstructured.RemoveNestedField(manifest, "metadata", "creationTimestamp")
+		data, err := yaml.Marshal(manifest)
+		if err != nil {
+			return fmt.Errorf("cannot marshal converted object: %w", err)
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReport prints issues of converted objects, objects without issues are omitted.
+func writeReport(w io.Writer, converted []convertedObject) error {
+	for _, c := range converted {
+		objMeta, err := meta.Accessor(c.obj)
+		if err != nil {
+			return fmt.Errorf("cannot get metadata of converted object: %w", err)
+		}
+		for _, issue := range c.issues {
+			if _, err := fmt.Fprintf(w, "%s %s/%s: %s %s: %s\n", objectKind(c.obj), objMeta.GetNamespace(), objMeta.GetName(), issue.Field, issue.Action, issue.Reason); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
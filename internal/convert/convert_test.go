@@ -0,0 +1,154 @@
+package convert
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		input      string
+		wantErr    bool
+		wantOutput []string
+		wantStderr []string
+	}{
+		{
+			name: "multi-document yaml with report",
+			args: []string{"-report"},
+			input: `# scrape config
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: example
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: example
+  endpoints:
+  - port: http
+    relabelings:
+    - action: drop
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: example
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: example
+  namespace: default
+spec:
+  groups:
+  - name: example
+    rules:
+    - record: up:sum
+      expr: sum(up)
+`,
+			wantOutput: []string{
+				"apiVersion: operator.victoriametrics.com/v1beta1\nkind: VMServiceScrape",
+				"operator.victoriametrics.com/conversion-issues",
+				"---\napiVersion: operator.victoriametrics.com/v1beta1\nkind: VMRule",
+				"expr: sum(up)",
+			},
+			wantStderr: []string{
+				"skipping object with unsupported apiVersion: v1, kind: ConfigMap",
+				"VMServiceScrape default/example: spec.endpoints[0].relabelings[0] dropped: source labels are empty for action drop",
+			},
+		},
+		{
+			name: "list of objects",
+			input: `apiVersion: v1
+kind: List
+items:
+- apiVersion: monitoring.coreos.com/v1
+  kind: PodMonitor
+  metadata:
+    name: example
+  spec:
+    podMetricsEndpoints:
+    - port: http
+- apiVersion: monitoring.coreos.com/v1
+  kind: Probe
+  metadata:
+    name: example
+  spec:
+    targets:
+      staticConfig:
+        static:
+        - example.com
+`,
+			wantOutput: []string{"kind: VMPodScrape", "kind: VMProbe", "- example.com"},
+		},
+		{
+			name: "prometheus with local storage only",
+			input: `apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+metadata:
+  name: k8s
+  namespace: monitoring
+spec:
+  retention: 30d
+`,
+			wantStderr: []string{"skipping Prometheus monitoring/k8s: spec.remoteWrite dropped"},
+		},
+		{
+			name:    "invalid yaml",
+			input:   "apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nspec: [",
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			args:    []string{"/not/existing/file.yaml"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := Run(tt.args, strings.NewReader(tt.input), &stdout, &stderr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Run() output must contain: %q, got:\n%s", want, stdout.String())
+				}
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("Run() stderr must contain: %q, got:\n%s", want, stderr.String())
+				}
+			}
+		})
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "update golden files at testdata")
+
+func TestRun_golden(t *testing.T) {
+	const goldenFile = "testdata/vm-operator.golden.yaml"
+	var stdout, stderr bytes.Buffer
+	if err := Run([]string{"testdata/prometheus-operator.yaml"}, nil, &stdout, &stderr); err != nil {
+		t.Fatalf("Run() error = %v, stderr: %s", err, stderr.String())
+	}
+	if *updateGolden {
+		if err := ioutil.WriteFile(goldenFile, stdout.Bytes(), 0644); err != nil {
+			t.Fatalf("cannot update golden file: %v", err)
+		}
+	}
+	want, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("cannot read golden file: %v", err)
+	}
+	if got := stdout.String(); got != string(want) {
+		t.Errorf("Run() output doesn't match %s, run tests with -update flag to update it.\ngot:\n%s\nwant:\n%s", goldenFile, got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// uncachedClient reads Namespace, Secret and ConfigMap objects directly from api server.
+// Cache restricted to the watched namespaces cannot serve cluster-wide Namespace objects,
+// and operator may have no permissions to watch them, Forbidden error is returned for such reads.
+// Secrets and ConfigMaps are read only during reconcile, cache would keep all of them in memory,
+// their changes are watched with metadata-only informers.
+type uncachedClient struct {
+	client.Client
+	apiReader client.Reader
+}
+
+// isUncached checks if object must be read without cache.
+func isUncached(obj runtime.Object) bool {
+	switch obj.(type) {
+	case *corev1.Namespace, *corev1.NamespaceList,
+		*corev1.Secret, *corev1.SecretList,
+		*corev1.ConfigMap, *corev1.ConfigMapList:
+		return true
+	}
+	return false
+}
+
+// Get implements client.Reader
+func (c *uncachedClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if isUncached(obj) {
+		return c.apiReader.Get(ctx, key, obj)
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+// List implements client.Reader
+func (c *uncachedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if isUncached(list) {
+		return c.apiReader.List(ctx, list, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+// newUncachedClient builds default manager client, which reads Namespace, Secret and ConfigMap objects without cache.
+// Reader is built the same way as manager api reader, which isn't available during manager creation.
+func newUncachedClient(cache cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := ctrlmanager.DefaultNewClient(cache, config, options)
+	if err != nil {
+		return nil, err
+	}
+	apiReader, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return &uncachedClient{Client: c, apiReader: apiReader}, nil
+}
@@ -3,7 +3,11 @@ package manager
 import (
 	"context"
 	"flag"
+	"fmt"
+	"strings"
+
 	"github.com/VictoriaMetrics/operator/internal/config"
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/coreos/prometheus-operator/pkg/client/versioned"
 	"github.com/spf13/pflag"
 	"golang.org/x/sync/errgroup"
@@ -12,6 +16,7 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
@@ -29,16 +34,22 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(victoriametricsv1beta1.AddToScheme(scheme))
+	// prometheus-operator objects are required for events of vmprometheusconverter
+	utilruntime.Must(monitoringv1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func RunManager(ctx context.Context) error {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var enableWebhooks bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable validating webhooks for operator custom resources. "+
+			"Webhook server requires tls certificate and key at /tmp/k8s-webhook-server/serving-certs.")
 
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
@@ -61,13 +72,29 @@ func RunManager(ctx context.Context) error {
 
 	setupLog.Info("Registering Components.")
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	baseConfig := config.MustGetBaseConfig()
+	mgrOptions := ctrl.Options{
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
 		Port:               9443,
 		LeaderElection:     enableLeaderElection,
 		LeaderElectionID:   "57410f0d.victoriametrics.com",
-	})
+		// namespaces, secrets and configmaps are read without cache.
+		NewClient: newUncachedClient,
+	}
+	// restrict cache to the watched namespaces,
+	// with single namespace operator requires only namespaced Role.
+	// namespaces labels are matched with namespace selectors.
+	switch watchNamespaces := baseConfig.Namespaces.Allowed(); len(watchNamespaces) {
+	case 0:
+	case 1:
+		setupLog.Info("operator watches single namespace", "namespace", watchNamespaces[0])
+		mgrOptions.Namespace = watchNamespaces[0]
+	default:
+		setupLog.Info("operator watches multiple namespaces", "namespaces", strings.Join(watchNamespaces, ","))
+		mgrOptions.NewCache = cache.MultiNamespacedCacheBuilder(watchNamespaces)
+	}
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		return err
@@ -100,15 +127,6 @@ func RunManager(ctx context.Context) error {
 		setupLog.Error(err, "unable to create controller", "controller", "VMAlertmanager")
 		return err
 	}
-	if err = (&controllers.VMPodScrapeReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("VMPodScrape"),
-		Scheme:   mgr.GetScheme(),
-		BaseConf: config.MustGetBaseConfig(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "VMPodScrape")
-		return err
-	}
 	if err = (&controllers.VMRuleReconciler{
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controllers").WithName("VMRule"),
@@ -118,15 +136,6 @@ func RunManager(ctx context.Context) error {
 		setupLog.Error(err, "unable to create controller", "controller", "VMRule")
 		return err
 	}
-	if err = (&controllers.VMServiceScrapeReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("VMServiceScrape"),
-		Scheme:   mgr.GetScheme(),
-		BaseConf: config.MustGetBaseConfig(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "VMServiceScrape")
-		return err
-	}
 	if err = (&controllers.VMSingleReconciler{
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controllers").WithName("VMSingle"),
@@ -146,16 +155,23 @@ func RunManager(ctx context.Context) error {
 		setupLog.Error(err, "unable to create controller", "controller", "VMCluster")
 		return err
 	}
-	if err = (&controllers.VMProbeReconciler{
+	if err = (&controllers.VMAuthReconciler{
 		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("VMProbe"),
+		Log:      ctrl.Log.WithName("controllers").WithName("VMAuth"),
 		Scheme:   mgr.GetScheme(),
 		BaseConf: config.MustGetBaseConfig(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "VMProbe")
+		setupLog.Error(err, "unable to create controller", "controller", "VMAuth")
 		return err
 	}
 
+	if enableWebhooks {
+		if err = addWebhooks(mgr); err != nil {
+			setupLog.Error(err, "unable to register webhooks")
+			return err
+		}
+	}
+
 	// +kubebuilder:scaffold:builder
 	setupLog.Info("starting vmconverter clients")
 
@@ -164,10 +180,10 @@ func RunManager(ctx context.Context) error {
 		setupLog.Error(err, "cannot build promClient")
 		return err
 	}
-	converterController := controllers.NewConverterController(prom, mgr.GetClient())
+	converterController := controllers.NewConverterController(prom, mgr.GetClient(), mgr.GetEventRecorderFor("vmprometheusconverter"), baseConfig)
 
 	errG := &errgroup.Group{}
-	converterController.Run(ctx, errG, config.MustGetBaseConfig())
+	converterController.Run(ctx, errG, baseConfig)
 	setupLog.Info("vmconverter was started")
 
 	setupLog.Info("starting manager")
@@ -185,3 +201,24 @@ func RunManager(ctx context.Context) error {
 	return nil
 
 }
+
+func addWebhooks(mgr ctrl.Manager) error {
+	for _, obj := range []interface {
+		SetupWebhookWithManager(mgr ctrl.Manager) error
+	}{
+		&victoriametricsv1beta1.VMAgent{},
+		&victoriametricsv1beta1.VMAlert{},
+		&victoriametricsv1beta1.VMSingle{},
+		&victoriametricsv1beta1.VMCluster{},
+		&victoriametricsv1beta1.VMAlertmanager{},
+		&victoriametricsv1beta1.VMRule{},
+		&victoriametricsv1beta1.VMServiceScrape{},
+		&victoriametricsv1beta1.VMPodScrape{},
+		&victoriametricsv1beta1.VMProbe{},
+	} {
+		if err := obj.SetupWebhookWithManager(mgr); err != nil {
+			return fmt.Errorf("cannot register webhook for %T: %w", obj, err)
+		}
+	}
+	return nil
+}
@@ -18,11 +18,14 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
+	"github.com/VictoriaMetrics/operator/internal/convert"
 	"github.com/VictoriaMetrics/operator/internal/manager"
 )
 
@@ -31,6 +34,15 @@ var (
 )
 
 func main() {
+	// convert subcommand works offline, manager isn't started for it
+	if len(os.Args) > 1 && os.Args[1] == convert.Command {
+		err := convert.Run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr)
+		if err != nil && err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "cannot convert manifests: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	stop := signals.SetupSignalHandler()
 	go func() {